@@ -2,102 +2,106 @@ package all
 
 import (
 	"api/app"
-	"bytes"
-	"strconv"
+	"api/logging"
+	"context"
 	"sync"
-
-	"github.com/gofiber/fiber/v2/log"
+	"time"
 )
 
-// 定义所有可用的来源映射，避免循环导入
-var sourceFuncMap = map[string]func() (map[string]interface{}, error){
-	"360search":    app.Search360,
-	"bilibili":     app.Bilibili,
-	"acfun":        app.Acfun,
-	"csdn":         app.CSDN,
-	"dongqiudi":    app.Dongqiudi,
-	"douban":       app.Douban,
-	"douyin":       app.Douyin,
-	"github":       app.Github,
-	"guojiadili":   app.Guojiadili,
-	"historytoday": app.HistoryToday, // 更新函数名和键名
-	"hupu":         app.Hupu,
-	"ithome":       app.Ithome,
-	"lishipin":     app.Lishipin,
-	"pengpai":      app.Pengpai,
-	"qqnews":       app.Qqnews,
-	"shaoshupai":   app.Shaoshupai,
-	"sougou":       app.Sougou,
-	"toutiao":      app.Toutiao,
-	"v2ex":         app.V2ex,
-	"wangyinews":   app.WangyiNews,
-	"weibo":        app.WeiboHot,
-	"xinjingbao":   app.Xinjingbao,
-	"zhihu":        app.Zhihu,
-	"quark":        app.Quark,
-	"souhu":        app.Souhu,
-	"baidu":        app.Baidu,
-	"renmin":       app.Renminwang,
-	"nanfang":      app.Nanfangzhoumo,
-	"360doc":       app.Doc360,
-	"cctv":         app.CCTV,
-}
-
 // All 获取所有平台热搜数据
 //
+// ctx 没有截止时间时使用配置的总超时时间，超时后返回已经成功获取的部分结果
+//
 //	@Summary		获取所有平台热搜数据
 //	@Description	获取所有平台的热搜列表
 //	@Tags			all
 //	@Accept			json
 //	@Produce		json
-//	@Success		200	{object}	map[string]interface{}
+//	@Success		200	{object}	Response
 //	@Failure		500	{object}	map[string]interface{}
 //	@Router			/all [get]
-func All() map[string]interface{} {
-	allResult := make(map[string]interface{})
+func All(ctx context.Context) map[string]*app.Result {
+	fetcher := app.DefaultFetcher()
+	if _, ok := ctx.Deadline(); !ok && fetcher.AllTimeout() > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, fetcher.AllTimeout())
+		defer cancel()
+	}
+
+	start := time.Now()
+	allResult := make(map[string]*app.Result)
 	var wg sync.WaitGroup
 	var mu sync.Mutex
 
-	for key, fn := range sourceFuncMap {
+	for _, source := range app.Sources() {
+		if !app.Enabled(source.RouteName) {
+			continue
+		}
 		wg.Add(1)
-		go func(k string, f func() (map[string]interface{}, error)) {
+		go func(source app.Source) {
 			defer wg.Done()
-			result, err := f()
+			result, err := fetcher.Fetch(ctx, source)
 			if err != nil {
-				var buf bytes.Buffer
-				buf.WriteString(k)
-				buf.WriteString(" 请求失败: ")
-				buf.WriteString(err.Error())
-				log.Error(buf.String())
+				logging.FromContext(ctx).Error("请求失败", logging.KeySource, source.RouteName, logging.KeyError, err)
 				return
 			}
 
-			if result["code"] == 200 {
-				mu.Lock()
-				allResult[k] = result["obj"]
-				mu.Unlock()
-			}
-		}(key, fn)
+			mu.Lock()
+			allResult[source.RouteName] = result
+			mu.Unlock()
+		}(source)
 	}
 
-	wg.Wait()
-	var buf bytes.Buffer
-	buf.WriteString("成功获取的热搜数量: ")
-	buf.WriteString(strconv.Itoa(len(allResult)))
-	log.Info(buf.String())
+	// 超过截止时间后直接返回已经完成的部分结果，未完成的请求会随 ctx 取消
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		logging.FromContext(ctx).Warn("获取所有平台热搜超时，返回部分结果", logging.KeyError, ctx.Err())
+	}
 
-	return map[string]interface{}{
-		"code": 200,
-		"obj":  allResult,
+	mu.Lock()
+	results := make(map[string]*app.Result, len(allResult))
+	for source, result := range allResult {
+		results[source] = result
 	}
+	mu.Unlock()
+
+	logging.FromContext(ctx).Info("获取所有平台热搜完成", "count", len(results), "total", len(app.Sources()), logging.KeyDuration, time.Since(start))
+
+	return results
 }
 
-// GetAllSourceNames 获取所有可用的来源名称列表
-func GetAllSourceNames() []string {
-	var sources []string
-	for key := range sourceFuncMap {
-		sources = append(sources, key)
+// Response 所有平台的响应格式，obj 以路由名称为键
+type Response struct {
+	Code  int                   `json:"code"`
+	Obj   map[string][]app.Item `json:"obj"`
+	Stale map[string]int64      `json:"stale,omitempty"` // 返回已保存快照的平台及快照距今的秒数
+}
+
+// NewResponse 将各平台的抓取结果转换为响应格式
+func NewResponse(results map[string]*app.Result) Response {
+	response := Response{
+		Code: 200,
+		Obj:  make(map[string][]app.Item, len(results)),
 	}
+	for source, result := range results {
+		response.Obj[source] = result.Response().Obj
+		if result.Stale {
+			if response.Stale == nil {
+				response.Stale = make(map[string]int64)
+			}
+			response.Stale[source] = result.Age()
+		}
+	}
+	return response
+}
 
-	return sources
+// GetAllSourceNames 获取所有可用的来源名称列表
+func GetAllSourceNames() []string {
+	return app.GetAllRouteNames()
 }
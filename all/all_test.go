@@ -1,31 +1,100 @@
 package all
 
 import (
-	"reflect"
+	"api/app"
+	"context"
 	"testing"
+	"time"
 )
 
 func TestAll(t *testing.T) {
 	// 由于All函数会调用多个API，这里只测试返回值的结构
-	result := All()
-
-	// 检查返回结果的基本结构
-	if code, ok := result["code"]; !ok || code != 200 {
-		t.Errorf("Expected result to have code 200, got %v", code)
-	}
-
-	if obj, ok := result["obj"]; !ok {
-		t.Error("Expected result to have obj field")
-	} else {
-		// obj应该是一个map
-		objMap, ok := obj.(map[string]interface{})
-		if !ok {
-			t.Errorf("Expected obj to be a map, got %T", obj)
-		} else {
-			// obj可以是空的，因为API可能失败，但我们至少检查它的类型
-			t.Logf("obj contains %d entries", len(objMap))
+	results := All(context.Background())
+
+	// 结果可以是空的，因为API可能失败，但成功的结果应与来源名称一致
+	for source, result := range results {
+		if result == nil {
+			t.Errorf("Expected result for %s, got nil", source)
+			continue
+		}
+		if result.Source != source {
+			t.Errorf("Expected result source %s, got %s", source, result.Source)
 		}
 	}
+	t.Logf("results contain %d entries", len(results))
+
+	response := NewResponse(results)
+	if response.Code != 200 {
+		t.Errorf("Expected response to have code 200, got %v", response.Code)
+	}
+	if len(response.Obj) != len(results) {
+		t.Errorf("Expected %d entries in obj, got %d", len(results), len(response.Obj))
+	}
+}
+
+func init() {
+	// 用于测试超时后返回部分结果的数据源
+	app.Register(app.Source{
+		RouteName: "all_test_fast",
+		Fetch: func(ctx context.Context, f *app.Fetcher) (*app.Result, error) {
+			return app.NewResult("all_test_fast", []app.Item{{Index: 1, Title: "Fast"}}), nil
+		},
+	})
+	app.Register(app.Source{
+		RouteName: "all_test_slow",
+		Fetch: func(ctx context.Context, f *app.Fetcher) (*app.Result, error) {
+			// 故意忽略 ctx，模拟不响应取消的数据源
+			time.Sleep(2 * time.Second)
+			return app.NewResult("all_test_slow", nil), nil
+		},
+	})
+}
+
+func TestAllReturnsPartialResultsOnDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	results := All(ctx)
+	elapsed := time.Since(start)
+
+	if elapsed > time.Second {
+		t.Errorf("Expected All to return shortly after the deadline, took %v", elapsed)
+	}
+	if _, ok := results["all_test_fast"]; !ok {
+		t.Error("Expected results to contain all_test_fast")
+	}
+	if _, ok := results["all_test_slow"]; ok {
+		t.Error("Expected results not to contain all_test_slow")
+	}
+}
+
+func TestNewResponse(t *testing.T) {
+	response := NewResponse(map[string]*app.Result{
+		"weibo": app.NewResult("weibo", []app.Item{{Index: 1, Title: "Title", URL: "http://example.com"}}),
+		"zhihu": app.NewResult("zhihu", nil),
+	})
+
+	if response.Code != 200 {
+		t.Errorf("Expected response to have code 200, got %v", response.Code)
+	}
+	if len(response.Obj["weibo"]) != 1 || response.Obj["weibo"][0].Title != "Title" {
+		t.Errorf("Unexpected weibo items: %v", response.Obj["weibo"])
+	}
+	// 没有数据的平台应为空数组而不是 null
+	if items, ok := response.Obj["zhihu"]; !ok || items == nil {
+		t.Errorf("Expected empty zhihu items, got %v", items)
+	}
+	if response.Stale != nil {
+		t.Errorf("Expected no stale sources, got %v", response.Stale)
+	}
+
+	// 使用已保存快照的平台列在 stale 中
+	stale := &app.Result{Source: "weibo", FetchedAt: time.Now().Add(-time.Minute), Stale: true}
+	response = NewResponse(map[string]*app.Result{"weibo": stale})
+	if age, ok := response.Stale["weibo"]; !ok || age < 59 {
+		t.Errorf("Expected weibo to be stale for about 60s, got %v", response.Stale)
+	}
 }
 
 func TestGetAllSourceNames(t *testing.T) {
@@ -61,30 +130,17 @@ func TestGetAllSourceNames(t *testing.T) {
 	}
 }
 
-func TestSourceFuncMapNotEmpty(t *testing.T) {
-	// 检查sourceFuncMap是否非空
-	if len(sourceFuncMap) == 0 {
-		t.Error("Expected sourceFuncMap to be non-empty")
-	}
+func TestGetAllSourceNamesMatchesRegistry(t *testing.T) {
+	sources := GetAllSourceNames()
 
-	// 检查一些预期的键是否存在
-	expectedKeys := []string{"baidu", "bilibili", "zhihu", "weibo"}
-	for _, key := range expectedKeys {
-		if _, exists := sourceFuncMap[key]; !exists {
-			t.Errorf("Expected key '%s' to exist in sourceFuncMap", key)
-		}
+	// 来源列表应与数据源注册表保持一致
+	registered := app.Sources()
+	if len(sources) != len(registered) {
+		t.Fatalf("Expected %d sources, got %d", len(registered), len(sources))
 	}
-}
-
-func TestSourceFuncMapValuesAreFunctions(t *testing.T) {
-	for key, fn := range sourceFuncMap {
-		if fn == nil {
-			t.Errorf("Function for key '%s' is nil", key)
-		}
-		// 尝试调用函数，检查类型是否正确
-		// 注意：这里不实际调用API，只是验证函数类型
-		if reflect.TypeOf(fn).Kind() != reflect.Func {
-			t.Errorf("Value for key '%s' is not a function", key)
+	for i, source := range registered {
+		if sources[i] != source.RouteName {
+			t.Errorf("Expected source '%s' at position %d, got '%s'", source.RouteName, i, sources[i])
 		}
 	}
 }
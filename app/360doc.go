@@ -1,14 +1,22 @@
 package app
 
 import (
+	"api/logging"
 	"api/utils"
+	"context"
 	"io"
-	"net/http"
-	"time"
-
-	"github.com/gofiber/fiber/v2/log"
 )
 
+func init() {
+	Register(Source{
+		RouteName: "360doc",
+		Name:      "360doc",
+		Icon:      "https://www.360doc.cn/favicon.ico",
+		Category:  CategoryCulture,
+		Fetch:     Doc360,
+	})
+}
+
 // Doc360 获取360doc热搜数据
 //
 //	@Summary		获取360doc热搜数据
@@ -19,46 +27,35 @@ import (
 //	@Success		200	{object}	map[string]interface{}
 //	@Failure		500	{object}	map[string]interface{}
 //	@Router			/360doc [get]
-func Doc360() (map[string]interface{}, error) {
-	// 创建带超时的 HTTP 客户端
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-
+func Doc360(ctx context.Context, f *Fetcher) (*Result, error) {
 	url := "http://www.360doc.com/"
-	resp, err := client.Get(url)
+	resp, err := f.Get(ctx, url)
 	if err != nil {
-		log.Error("http.Get error: %v", err)
+		logging.FromContext(ctx).Error("http.Get error", logging.KeyError, err)
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	pageBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
-		log.Error("io.ReadAll error: %v", err)
+		logging.FromContext(ctx).Error("io.ReadAll error", logging.KeyError, err)
 		return nil, err
 	}
 
 	pattern := `<div class=" num\d* yzphlist hei"><a href="(.*?)".*?>(?:<span class="icon_yuan2"></span>)?(.*?)</a></div>`
 	matched := utils.ExtractMatches(string(pageBytes), pattern)
 
-	var obj []map[string]interface{}
+	var items []Item
 	for index, item := range matched {
 		// 添加边界检查，确保有足够的匹配项
 		if len(item) >= 3 {
-			obj = append(obj, map[string]interface{}{
-				"index": index + 1,
-				"title": item[2],
-				"url":   item[1],
+			items = append(items, Item{
+				Index: index + 1,
+				Title: item[2],
+				URL:   item[1],
 			})
 		}
 	}
 
-	api := map[string]interface{}{
-		"code":    200,
-		"message": "360doc",
-		"icon":    "http://www.360doc.com/favicon.ico", // 16 x 16
-		"obj":     obj,
-	}
-	return api, nil
+	return NewResult("360doc", items), nil
 }
@@ -1,13 +1,11 @@
 package app
 
 import (
+	"api/logging"
+	"context"
 	"encoding/json"
 	"io"
-	"net/http"
 	"strconv"
-	"time"
-
-	"github.com/gofiber/fiber/v2/log"
 )
 
 type search360Item struct {
@@ -17,6 +15,18 @@ type search360Item struct {
 	Rank      string `json:"rank"`
 }
 
+func init() {
+	Register(Source{
+		RouteName: "360search",
+		Name:      "360搜索",
+		Icon:      "https://ss.360tres.com/static/121a1737750aa53d.ico",
+		Category:  CategorySearch,
+		Fetch:     Search360,
+		// 接口参数限制为 50 条
+		Expect: itemRange(30, 60),
+	})
+}
+
 // Search360 获取360搜索热搜数据
 //
 //	@Summary		获取360搜索热搜数据
@@ -27,14 +37,11 @@ type search360Item struct {
 //	@Success		200	{object}	map[string]interface{}
 //	@Failure		500	{object}	map[string]interface{}
 //	@Router			/360search [get]
-func Search360() (map[string]interface{}, error) {
+func Search360(ctx context.Context, f *Fetcher) (*Result, error) {
 	url := "https://ranks.hao.360.com/mbsug-api/hotnewsquery?type=news&realhot_limit=50"
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-	resp, err := client.Get(url)
+	resp, err := f.Get(ctx, url)
 	if err != nil {
-		log.Error("http.Get error: %v", err)
+		logging.FromContext(ctx).Error("http.Get error", logging.KeyError, err)
 		return nil, err
 	}
 	defer resp.Body.Close()
@@ -42,19 +49,19 @@ func Search360() (map[string]interface{}, error) {
 	// 2.读取页面内容
 	pageBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
-		log.Error("io.ReadAll error: %v", err)
+		logging.FromContext(ctx).Error("io.ReadAll error", logging.KeyError, err)
 		return nil, err
 	}
 
 	var resultSlice []search360Item
 	err = json.Unmarshal(pageBytes, &resultSlice)
 	if err != nil {
-		log.Error("json.Unmarshal error: %v", err)
+		logging.FromContext(ctx).Error("json.Unmarshal error", logging.KeyError, err)
 		return nil, err
 	}
 
-	var obj []map[string]interface{}
-	for _, item := range resultSlice {
+	var items []Item
+	for index, item := range resultSlice {
 		title := item.Title
 		if item.LongTitle != "" {
 			title = item.LongTitle
@@ -68,21 +75,22 @@ func Search360() (map[string]interface{}, error) {
 			// log.Printf("parse hot value error for item %s: %v", title, err)
 		}
 
+		// 接口返回的排名为字符串，无法解析时使用列表中的位置
+		rank, err := strconv.Atoi(item.Rank)
+		if err != nil {
+			rank = index + 1
+		}
+
 		// 将 hot/10000 格式化为一位小数的字符串，然后拼接 "万"
 		hotValueStr := strconv.FormatFloat(hot/10000, 'f', 1, 64) + "万"
-		obj = append(obj, map[string]interface{}{
-			"index":    item.Rank,
-			"title":    title,
-			"hotValue": hotValueStr,
-			"url":      "https://www.so.com/s?q=" + title,
+		items = append(items, Item{
+			Index:    rank,
+			Title:    title,
+			HotValue: hotValueStr,
+			HotScore: hot,
+			URL:      "https://www.so.com/s?q=" + title,
 		})
 	}
 
-	api := map[string]interface{}{
-		"code":    200,
-		"message": "360search",
-		"icon":    "https://ss.360tres.com/static/121a1737750aa53d.ico",
-		"obj":     obj,
-	}
-	return api, nil
+	return NewResult("360search", items), nil
 }
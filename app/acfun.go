@@ -1,12 +1,10 @@
 package app
 
 import (
+	"api/logging"
+	"context"
 	"encoding/json"
 	"io"
-	"net/http"
-	"time"
-
-	"github.com/gofiber/fiber/v2/log"
 )
 
 type acfunResponse struct {
@@ -17,6 +15,18 @@ type acfunData struct {
 	URL   string `json:"shareUrl"`
 }
 
+func init() {
+	Register(Source{
+		RouteName: "acfun",
+		Name:      "AcFun",
+		Icon:      "https://cdn.aixifan.com/ico/favicon.ico",
+		Category:  CategoryVideo,
+		Fetch:     Acfun,
+		// 接口参数限制为 30 条
+		Expect: itemRange(15, 30),
+	})
+}
+
 // Acfun 获取AcFun热搜数据
 //
 //	@Summary		获取AcFun热搜数据
@@ -27,58 +37,45 @@ type acfunData struct {
 //	@Success		200	{object}	map[string]interface{}
 //	@Failure		500	{object}	map[string]interface{}
 //	@Router			/acfun [get]
-func Acfun() (map[string]interface{}, error) {
-	// 创建带超时的 HTTP 客户端
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-
+func Acfun(ctx context.Context, f *Fetcher) (*Result, error) {
 	url := "https://www.acfun.cn/rest/pc-direct/rank/channel?channelId=&subChannelId=&rankLimit=30&rankPeriod=DAY"
 	// 创建一个自定义请求
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := f.NewRequest(ctx, url)
 	if err != nil {
-		log.Error("http.NewRequest error: %v", err)
+		logging.FromContext(ctx).Error("http.NewRequest error", logging.KeyError, err)
 		return nil, err
 	}
 
 	// 设置 Headers
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/121.0.0.0 Safari/537.36")
 
-	resp, err := client.Do(req)
+	resp, err := f.Do(req)
 	if err != nil {
-		log.Error("http.Client.Do error: %v", err)
+		logging.FromContext(ctx).Error("http.Client.Do error", logging.KeyError, err)
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	pageBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
-		log.Error("io.ReadAll error: %v", err)
+		logging.FromContext(ctx).Error("io.ReadAll error", logging.KeyError, err)
 		return nil, err
 	}
 
 	var resultMap acfunResponse
 	err = json.Unmarshal(pageBytes, &resultMap)
 	if err != nil {
-		log.Error("json.Unmarshal error: %v", err)
+		logging.FromContext(ctx).Error("json.Unmarshal error", logging.KeyError, err)
 		return nil, err
 	}
 
-	var obj []map[string]interface{}
+	var items []Item
 	for index, item := range resultMap.RankList {
-		obj = append(obj, map[string]interface{}{
-			"index": index + 1,
-			"title": item.Title,
-			"url":   item.URL,
+		items = append(items, Item{
+			Index: index + 1,
+			Title: item.Title,
+			URL:   item.URL,
 		})
 	}
 
-	api := map[string]interface{}{
-		"code":    200,
-		"message": "AcFun",
-		"icon":    "https://cdn.aixifan.com/ico/favicon.ico", // 32 x 32
-		"obj":     obj,
-	}
-
-	return api, nil
+	return NewResult("acfun", items), nil
 }
@@ -0,0 +1,86 @@
+package app
+
+import (
+	"api/logging"
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// 告警级别
+const (
+	AlertWarning  = "warning"  // 数据源的抓取结果未通过检查
+	AlertResolved = "resolved" // 数据源的抓取结果恢复正常
+)
+
+// Alert 数据源的抓取结果开始或停止未通过检查时发出的告警
+type Alert struct {
+	Source   string    `json:"source"`
+	Level    string    `json:"level"` // warning 或 resolved
+	Message  string    `json:"message"`
+	Problems []string  `json:"problems,omitempty"`
+	Time     time.Time `json:"time"`
+}
+
+// AlertHandler 告警处理函数
+type AlertHandler func(alert Alert)
+
+// OnAlert 添加告警处理函数，处理函数在抓取的协程中同步调用，耗时的操作需要自行异步执行
+func (f *Fetcher) OnAlert(handler AlertHandler) {
+	f.alertMu.Lock()
+	defer f.alertMu.Unlock()
+	f.alertHandlers = append(f.alertHandlers, handler)
+}
+
+// alert 记录日志并通知所有告警处理函数
+func (f *Fetcher) alert(alert Alert) {
+	logger := slog.Default().With(logging.KeySource, alert.Source)
+	if alert.Level == AlertWarning {
+		logger.Warn("抓取结果未通过检查", "problems", strings.Join(alert.Problems, "；"))
+	} else {
+		logger.Info("抓取结果已恢复正常")
+	}
+
+	f.alertMu.RLock()
+	handlers := append([]AlertHandler(nil), f.alertHandlers...)
+	f.alertMu.RUnlock()
+	for _, handler := range handlers {
+		handler(alert)
+	}
+}
+
+// webhookAlertHandler 创建将告警以 JSON 格式 POST 到指定地址的处理函数
+func (f *Fetcher) webhookAlertHandler(webhook string) AlertHandler {
+	return func(alert Alert) {
+		go func() {
+			logger := slog.Default().With(logging.KeySource, alert.Source, "webhook", webhook)
+			body, err := json.Marshal(alert)
+			if err != nil {
+				return
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook, bytes.NewReader(body))
+			if err != nil {
+				logger.Error("创建告警请求失败", logging.KeyError, err)
+				return
+			}
+			req.Header.Set("Content-Type", "application/json")
+
+			// 告警地址不是数据源，不经过基础地址改写和重试
+			resp, err := f.client.Do(req)
+			if err != nil {
+				logger.Error("发送告警失败", logging.KeyError, err)
+				return
+			}
+			resp.Body.Close()
+			if resp.StatusCode >= 300 {
+				logger.Error("发送告警失败", "status", resp.StatusCode)
+			}
+		}()
+	}
+}
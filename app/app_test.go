@@ -1,200 +1,114 @@
 package app
 
 import (
-	"strings"
+	"context"
+	"encoding/json"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
 
-// isNetworkError 检查错误是否为网络相关错误
-func isNetworkError(errMsg string) bool {
-	return strings.Contains(errMsg, "服务器内部错误") ||
-		strings.Contains(errMsg, "context deadline exceeded") ||
-		strings.Contains(errMsg, "timeout") ||
-		strings.Contains(errMsg, "connection refused") ||
-		strings.Contains(errMsg, "no such host") ||
-		strings.Contains(errMsg, "network") ||
-		strings.Contains(errMsg, "EOF")
-}
-
-// assertNetworkError 检查错误是否为网络相关错误
-func assertNetworkError(t *testing.T, err error) {
-	if err != nil {
-		assert.True(t, isNetworkError(err.Error()), "错误信息应该包含预期的网络错误类型")
-	}
-}
-
-func TestBilibili(t *testing.T) {
-	// 由于Bilibili API需要网络请求，我们只测试函数是否能正常执行
-	// 在测试环境中可能无法访问外部API，所以主要验证函数不会崩溃
-	result, err := Bilibili()
-
-	// API请求可能失败（网络问题等），这是正常的
-	// 我们主要验证函数是否能返回正确的格式
-	if err != nil {
-		// 如果有错误，检查是否是网络相关错误
-		assertNetworkError(t, err)
-	} else {
-		// 如果成功，验证返回格式
-		assert.NotNil(t, result)
-		assert.Contains(t, result, "code")
-		assert.Contains(t, result, "message")
-
-		code, ok := result["code"].(int)
-		assert.True(t, ok)
-
-		// 检查是否为成功状态或错误状态
-		if code == 200 {
-			// 成功时应该包含obj字段
-			assert.Contains(t, result, "obj")
-			message, ok := result["message"].(string)
-			assert.True(t, ok)
-			assert.Equal(t, "bilibili", message)
-
-			obj, ok := result["obj"]
-			assert.True(t, ok)
-			assert.NotNil(t, obj)
-		} else {
-			// 错误状态时检查是否包含错误信息
-			assert.Contains(t, result, "message")
+// 测试抓取结果转换为统一响应格式
+func TestResultResponse(t *testing.T) {
+	fetchedAt := time.Date(2099, 1, 1, 8, 0, 0, 0, time.UTC)
+	result := &Result{
+		Source:    "weibo",
+		FetchedAt: fetchedAt,
+		Items: []Item{
+			{Index: 1, Title: "Title", URL: "https://s.weibo.com/weibo?q=Title", HotValue: "123456"},
+		},
+	}
+
+	response := result.Response()
+	assert.Equal(t, 200, response.Code)
+	assert.Equal(t, "weibo", response.Message)
+	assert.Equal(t, "https://weibo.com/favicon.ico", response.Icon) // 图标来自注册表
+	assert.Equal(t, fetchedAt, *response.FetchedAt)
+	assert.Equal(t, result.Items, response.Obj)
+
+	// 序列化后字段名与之前的map格式保持一致，额外字段不再丢失
+	data, err := json.Marshal(response)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"code": 200,
+		"message": "weibo",
+		"icon": "https://weibo.com/favicon.ico",
+		"fetchedAt": "2099-01-01T08:00:00Z",
+		"obj": [{"index": 1, "title": "Title", "url": "https://s.weibo.com/weibo?q=Title", "hotValue": "123456"}]
+	}`, string(data))
+
+	// 没有数据时 obj 为空数组而不是 null
+	empty := NewResult("unknown", nil).Response()
+	assert.Equal(t, []Item{}, empty.Obj)
+	assert.Empty(t, empty.Icon)
+	assert.False(t, empty.Stale)
+	assert.Nil(t, empty.Age)
+
+	// 已保存的快照返回 stale 和快照距今的秒数
+	stale := &Result{Source: "weibo", FetchedAt: time.Now().Add(-90 * time.Second), Stale: true}
+	response = stale.Response()
+	assert.True(t, response.Stale)
+	if assert.NotNil(t, response.Age) {
+		assert.InDelta(t, 90, *response.Age, 1)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	items := func(n int) []Item {
+		result := make([]Item, n)
+		for i := range result {
+			result[i] = Item{Index: i + 1, Title: fmt.Sprintf("热搜 %d", i+1), URL: fmt.Sprintf("https://example.com/%d", i+1)}
+		}
+		return result
+	}
+
+	assert.Empty(t, Validate(&Result{Items: items(10)}, DefaultExpectations))
+
+	// 条目数不在预期范围内
+	assert.Len(t, Validate(&Result{Items: items(2)}, DefaultExpectations), 1)
+	assert.Len(t, Validate(&Result{Items: items(10)}, Expectations{MinItems: 1, MaxItems: 5}), 1)
+	assert.Len(t, Validate(&Result{}, DefaultExpectations), 1)
+
+	// 标题为空或截取到HTML片段
+	broken := items(10)
+	broken[0].Title = ""
+	broken[1].Title = `热搜</div`
+	broken[2].Title = `<a href="/s?wd=x">热搜</a>`
+	problems := Validate(&Result{Items: broken}, DefaultExpectations)
+	assert.Equal(t, []string{"1 条标题为空、2 条标题含有HTML"}, problems)
+
+	// 链接不是绝对地址
+	broken = items(10)
+	broken[0].URL = "/s?wd=x"
+	broken[1].URL = ""
+	problems = Validate(&Result{Items: broken}, DefaultExpectations)
+	assert.Equal(t, []string{"2 条链接不是有效的绝对地址"}, problems)
+
+	// 重复条目过多
+	broken = items(10)
+	for i := 1; i < 4; i++ {
+		broken[i].URL = broken[0].URL
+	}
+	problems = Validate(&Result{Items: broken}, DefaultExpectations)
+	assert.Equal(t, []string{"3 条标题或链接重复"}, problems)
+
+	// 少量问题在允许的比例内
+	broken = items(20)
+	broken[0].Title = ""
+	assert.Empty(t, Validate(&Result{Items: broken}, DefaultExpectations))
+
+	// 榜单长度固定的数据源按各自的范围检查，正则只匹配到一部分时也能发现
+	for name, normal := range map[string]int{"weibo": 50, "baidu": 50, "github": 25, "renmin": 20} {
+		source, ok := LookupSource(name)
+		if assert.True(t, ok, name) {
+			assert.NotEmpty(t, Validate(&Result{Items: items(6)}, source.expectations()), name)
+			assert.Empty(t, Validate(&Result{Items: items(normal)}, source.expectations()), name)
 		}
 	}
-}
-
-func TestZhihu(t *testing.T) {
-	// 由于Zhihu API需要网络请求，我们只测试函数是否能正常执行
-	result, err := Zhihu()
-
-	// API请求可能失败（网络问题等），这是正常的
-	// 我们主要验证函数是否能返回正确的格式
-	if err != nil {
-		// 如果有错误，检查是否是网络相关错误
-		assertNetworkError(t, err)
-	} else {
-		// 如果成功，验证返回格式
-		assert.NotNil(t, result)
-		assert.Contains(t, result, "code")
-		assert.Contains(t, result, "message")
-		assert.Contains(t, result, "obj")
-
-		code, ok := result["code"].(int)
-		assert.True(t, ok)
-		assert.Equal(t, 200, code)
-
-		message, ok := result["message"].(string)
-		assert.True(t, ok)
-		assert.Equal(t, "zhihu", message)
-	}
-}
-
-func TestAllFunctionsReturnCorrectFormat(t *testing.T) {
-	// 测试所有API函数返回的数据格式是否一致
-	testCases := []struct {
-		name     string
-		function func() (map[string]interface{}, error)
-	}{
-		{"Bilibili", Bilibili},
-		{"Zhihu", Zhihu},
-		// 这里可以添加其他API函数进行测试
-	}
-
-	for _, tc := range testCases {
-		t.Run(tc.name, func(t *testing.T) {
-			result, err := tc.function()
-
-			// 检查是否返回了正确的格式（即使有错误）
-			if err != nil {
-				// 如果有错误，至少应该返回一个包含code的map
-				if result != nil {
-					assert.Contains(t, result, "code")
-					code, ok := result["code"].(int)
-					assert.True(t, ok)
-					assert.Equal(t, 500, code) // API错误时应该返回500
-				}
-			} else {
-				// 检查是否有返回结果
-				assert.NotNil(t, result)
-				assert.Contains(t, result, "code")
-				assert.Contains(t, result, "message")
-
-				code, ok := result["code"].(int)
-				assert.True(t, ok)
-
-				// 根据返回的code值判断是否为成功状态
-				if code == 200 {
-					// 成功时应该返回完整的数据结构
-					assert.Contains(t, result, "obj")
-					message, ok := result["message"].(string)
-					assert.True(t, ok)
-					assert.NotEmpty(t, message)
-
-					obj, ok := result["obj"]
-					assert.True(t, ok)
-					// obj应该是一个数组或map
-					assert.NotNil(t, obj)
-				} else {
-					// 错误时(code != 200)，有些API可能包含obj字段，有些可能不包含，所以不强制要求
-					// 这是为了兼容不同API的错误处理方式
-				}
-			}
-		})
-	}
-}
-
-// 为每个API函数添加单独的测试函数
-func TestBaidu(t *testing.T) {
-	// 测试百度API函数是否能正常调用
-	_, err := Baidu()
-	if err != nil {
-		// API请求可能失败，这是正常的
-		assertNetworkError(t, err)
-	}
-}
-
-func TestWeibo(t *testing.T) {
-	// 测试微博API函数是否能正常调用
-	_, err := WeiboHot()
-	if err != nil {
-		// API请求可能失败，这是正常的
-		assertNetworkError(t, err)
-	}
-}
-
-func TestToutiao(t *testing.T) {
-	// 测试今日头条API函数是否能正常调用
-	_, err := Toutiao()
-	if err != nil {
-		// API请求可能失败，这是正常的
-		assertNetworkError(t, err)
-	}
-}
-
-func TestDouban(t *testing.T) {
-	// 测试豆瓣API函数是否能正常调用
-	_, err := Douban()
-	if err != nil {
-		// API请求可能失败，这是正常的
-		assertNetworkError(t, err)
-	}
-}
-
-func TestGithub(t *testing.T) {
-	// 测试GitHub API函数是否能正常调用
-	_, err := Github()
-	if err != nil {
-		// API请求可能失败，这是正常的
-		assertNetworkError(t, err)
-	}
-}
-
-func TestV2ex(t *testing.T) {
-	// 测试V2EX API函数是否能正常调用
-	_, err := V2ex()
-	if err != nil {
-		// API请求可能失败，这是正常的
-		assertNetworkError(t, err)
-	}
+	weibo, _ := LookupSource("weibo")
+	assert.NotEmpty(t, Validate(&Result{Items: items(200)}, weibo.expectations()))
 }
 
 // 测试新添加的 ListSources 函数
@@ -234,11 +148,13 @@ func TestListSources(t *testing.T) {
 				_, hasRouteName := platformMap["routeName"]
 				_, hasName := platformMap["name"]
 				_, hasIcon := platformMap["icon"]
+				_, hasCategory := platformMap["category"]
 
 				// 检查所有字段都存在
 				assert.True(t, hasRouteName, "平台信息应该包含路由名")
 				assert.True(t, hasName, "平台信息应该包含中文名")
 				assert.True(t, hasIcon, "平台信息应该包含图标")
+				assert.True(t, hasCategory, "平台信息应该包含分类")
 
 				// 检查路由名是否为字符串
 				if routeNameStr, isString := platformMap["routeName"].(string); isString && routeNameStr == "baidu" {
@@ -264,78 +180,6 @@ func TestListSources(t *testing.T) {
 	}
 }
 
-// 测试360doc函数
-func TestDoc360(t *testing.T) {
-	_, err := Doc360()
-	if err != nil {
-		// API请求可能失败，这是正常的
-		assertNetworkError(t, err)
-	}
-}
-
-// 测试360search函数
-func TestSearch360(t *testing.T) {
-	_, err := Search360()
-	if err != nil {
-		// API请求可能失败，这是正常的
-		assertNetworkError(t, err)
-	}
-}
-
-// 测试Acfun函数
-func TestAcfun(t *testing.T) {
-	_, err := Acfun()
-	if err != nil {
-		// API请求可能失败，这是正常的
-		assertNetworkError(t, err)
-	}
-}
-
-// 测试CCTV函数
-func TestCCTV(t *testing.T) {
-	_, err := CCTV()
-	if err != nil {
-		// API请求可能失败，这是正常的
-		assertNetworkError(t, err)
-	}
-}
-
-// 测试CSDN函数
-func TestCSDN(t *testing.T) {
-	_, err := CSDN()
-	if err != nil {
-		// API请求可能失败，这是正常的
-		assertNetworkError(t, err)
-	}
-}
-
-// 测试Dongqiudi函数
-func TestDongqiudi(t *testing.T) {
-	_, err := Dongqiudi()
-	if err != nil {
-		// API请求可能失败，这是正常的
-		assertNetworkError(t, err)
-	}
-}
-
-// 测试Douyin函数
-func TestDouyin(t *testing.T) {
-	_, err := Douyin()
-	if err != nil {
-		// API请求可能失败，这是正常的
-		assertNetworkError(t, err)
-	}
-}
-
-// 测试Guojiadili函数
-func TestGuojiadili(t *testing.T) {
-	_, err := Guojiadili()
-	if err != nil {
-		// API请求可能失败，这是正常的
-		assertNetworkError(t, err)
-	}
-}
-
 // 测试stripHTML函数
 func TestStripHTML(t *testing.T) {
 	// 测试去除HTML标签
@@ -362,132 +206,6 @@ func TestStripHTML(t *testing.T) {
 	assert.NotNil(t, result4)
 }
 
-// 测试HistoryToday函数
-func TestHistoryToday(t *testing.T) {
-	_, err := HistoryToday()
-	if err != nil {
-		// API请求可能失败，这是正常的
-		assertNetworkError(t, err)
-	}
-}
-
-// 测试Hupu函数
-func TestHupu(t *testing.T) {
-	_, err := Hupu()
-	if err != nil {
-		// API请求可能失败，这是正常的
-		assertNetworkError(t, err)
-	}
-}
-
-// 测试Ithome函数
-func TestIthome(t *testing.T) {
-	_, err := Ithome()
-	if err != nil {
-		// API请求可能失败，这是正常的
-		assertNetworkError(t, err)
-	}
-}
-
-// 测试Lishipin函数
-func TestLishipin(t *testing.T) {
-	_, err := Lishipin()
-	if err != nil {
-		// API请求可能失败，这是正常的
-		assertNetworkError(t, err)
-	}
-}
-
-// 测试Pengpai函数
-func TestPengpai(t *testing.T) {
-	_, err := Pengpai()
-	if err != nil {
-		// API请求可能失败，这是正常的
-		assertNetworkError(t, err)
-	}
-}
-
-// 测试Qqnews函数
-func TestQqnews(t *testing.T) {
-	_, err := Qqnews()
-	if err != nil {
-		// API请求可能失败，这是正常的
-		assertNetworkError(t, err)
-	}
-}
-
-// 测试Quark函数
-func TestQuark(t *testing.T) {
-	_, err := Quark()
-	if err != nil {
-		// API请求可能失败，这是正常的
-		assertNetworkError(t, err)
-	}
-}
-
-// 测试Renminwang函数
-func TestRenminwang(t *testing.T) {
-	_, err := Renminwang()
-	if err != nil {
-		// API请求可能失败，这是正常的
-		assertNetworkError(t, err)
-	}
-}
-
-// 测试Shaoshupai函数
-func TestShaoshupai(t *testing.T) {
-	_, err := Shaoshupai()
-	if err != nil {
-		// API请求可能失败，这是正常的
-		assertNetworkError(t, err)
-	}
-}
-
-// 测试Sougou函数
-func TestSougou(t *testing.T) {
-	_, err := Sougou()
-	if err != nil {
-		// API请求可能失败，这是正常的
-		assertNetworkError(t, err)
-	}
-}
-
-// 测试Souhu函数
-func TestSouhu(t *testing.T) {
-	_, err := Souhu()
-	if err != nil {
-		// API请求可能失败，这是正常的
-		assertNetworkError(t, err)
-	}
-}
-
-// 测试WangyiNews函数
-func TestWangyiNews(t *testing.T) {
-	_, err := WangyiNews()
-	if err != nil {
-		// API请求可能失败，这是正常的
-		assertNetworkError(t, err)
-	}
-}
-
-// 测试Xinjingbao函数
-func TestXinjingbao(t *testing.T) {
-	_, err := Xinjingbao()
-	if err != nil {
-		// API请求可能失败，这是正常的
-		assertNetworkError(t, err)
-	}
-}
-
-// 测试Nanfangzhoumo函数
-func TestNanfangzhoumo(t *testing.T) {
-	_, err := Nanfangzhoumo()
-	if err != nil {
-		// API请求可能失败，这是正常的
-		assertNetworkError(t, err)
-	}
-}
-
 // 测试GetAllRouteNames函数
 func TestGetAllRouteNames(t *testing.T) {
 	routeNames := GetAllRouteNames()
@@ -508,3 +226,111 @@ func TestGetAllRouteNames(t *testing.T) {
 		assert.True(t, contains, "GetAllRouteNames should contain route: %s", expectedRoute)
 	}
 }
+
+// 测试数据源注册表
+func TestSourceRegistry(t *testing.T) {
+	sources := Sources()
+	assert.Equal(t, 30, len(sources))
+
+	seen := make(map[string]bool)
+	for i, source := range sources {
+		// 注册信息应完整
+		assert.NotEmpty(t, source.RouteName)
+		assert.NotEmpty(t, source.Name, "source %s should have a name", source.RouteName)
+		assert.NotEmpty(t, source.Icon, "source %s should have an icon", source.RouteName)
+		assert.NotEmpty(t, source.Category, "source %s should have a category", source.RouteName)
+		assert.NotNil(t, source.Fetch, "source %s should have a fetch function", source.RouteName)
+
+		// 按路由名称排序且不重复
+		if i > 0 {
+			assert.Less(t, sources[i-1].RouteName, source.RouteName)
+		}
+		assert.False(t, seen[source.RouteName])
+		seen[source.RouteName] = true
+	}
+
+	// 之前在各处列表中遗漏或命名不一致的数据源
+	for _, name := range []string{"shaoshupai", "historytoday", "guojiadili"} {
+		_, ok := LookupSource(name)
+		assert.True(t, ok, "source %s should be registered", name)
+	}
+}
+
+// 测试通过别名查找数据源
+func TestLookupSourceAlias(t *testing.T) {
+	source, ok := LookupSource("kuake")
+	assert.True(t, ok)
+	assert.Equal(t, "quark", source.RouteName)
+
+	_, ok = LookupSource("nonexistent")
+	assert.False(t, ok)
+}
+
+// 测试停用数据源
+func TestSetDisabledSources(t *testing.T) {
+	defer SetDisabledSources(nil)
+
+	// 可以使用别名
+	assert.NoError(t, SetDisabledSources([]string{"v2ex", "kuake"}))
+	assert.False(t, Enabled("v2ex"))
+	assert.False(t, Enabled("quark"))
+	assert.True(t, Enabled("weibo"))
+
+	// 停用的数据源不再抓取
+	var calls int
+	source := Source{RouteName: "v2ex", Fetch: func(ctx context.Context, f *Fetcher) (*Result, error) {
+		calls++
+		return NewResult("v2ex", nil), nil
+	}}
+	_, err := Fetch(context.Background(), source)
+	assert.ErrorIs(t, err, ErrSourceDisabled)
+	assert.Zero(t, calls)
+
+	// 数据源不存在时不修改原来的设置
+	assert.Error(t, SetDisabledSources([]string{"weibo", "nonexistent"}))
+	assert.True(t, Enabled("weibo"))
+	assert.False(t, Enabled("v2ex"))
+
+	// 重新设置后恢复抓取
+	assert.NoError(t, SetDisabledSources(nil))
+	assert.True(t, Enabled("v2ex"))
+	_, err = Fetch(context.Background(), source)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, calls)
+}
+
+// 测试重复注册
+func TestRegisterDuplicate(t *testing.T) {
+	assert.Panics(t, func() {
+		Register(Source{RouteName: "weibo", Fetch: WeiboHot})
+	})
+	assert.Panics(t, func() {
+		Register(Source{RouteName: "kuake", Fetch: Quark})
+	})
+}
+
+// 测试热度展示文本解析为数值
+func TestParseHotValue(t *testing.T) {
+	tests := map[string]float64{
+		"123456":   123456,
+		"123.4万":   1234000,
+		"1.2亿":     120000000,
+		"1,234":    1234,
+		"热度 56万":   560000,
+		"2千热度":     2000,
+		"":         0,
+		"暂无":       0,
+		" 88.5 万 ": 885000,
+	}
+	for value, expected := range tests {
+		assert.InDelta(t, expected, ParseHotValue(value), 0.001, value)
+	}
+
+	// 创建结果时自动从展示文本解析热度数值，已设置的数值保持不变
+	result := NewResult("test", []Item{
+		{Index: 1, HotValue: "12万"},
+		{Index: 2, HotValue: "12.0万", HotScore: 123456},
+	})
+	assert.Equal(t, 120000.0, result.Items[0].HotScore)
+	assert.Equal(t, 123456.0, result.Items[1].HotScore)
+}
@@ -1,15 +1,25 @@
 package app
 
 import (
+	"api/logging"
 	"api/utils"
+	"context"
 	"io"
-	"net/http"
 	"strings"
-	"time"
-
-	"github.com/gofiber/fiber/v2/log"
 )
 
+func init() {
+	Register(Source{
+		RouteName: "baidu",
+		Name:      "百度",
+		Icon:      "https://www.baidu.com/favicon.ico",
+		Category:  CategorySearch,
+		Fetch:     Baidu,
+		// 实时热搜榜约 50 条
+		Expect: itemRange(30, 60),
+	})
+}
+
 // Baidu 获取百度热搜数据
 //
 //	@Summary		获取百度热搜数据
@@ -20,23 +30,18 @@ import (
 //	@Success		200	{object}	map[string]interface{}
 //	@Failure		500	{object}	map[string]interface{}
 //	@Router			/baidu [get]
-func Baidu() (map[string]interface{}, error) {
-	// 创建带超时的 HTTP 客户端
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-
+func Baidu(ctx context.Context, f *Fetcher) (*Result, error) {
 	url := "https://top.baidu.com/board?tab=realtime"
-	resp, err := client.Get(url)
+	resp, err := f.Get(ctx, url)
 	if err != nil {
-		log.Error("http.Get error: %v", err)
+		logging.FromContext(ctx).Error("http.Get error", logging.KeyError, err)
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	pageBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
-		log.Error("io.ReadAll error: %v", err)
+		logging.FromContext(ctx).Error("io.ReadAll error", logging.KeyError, err)
 		return nil, err
 	}
 
@@ -44,24 +49,18 @@ func Baidu() (map[string]interface{}, error) {
 	// 注意：这里假设 utils.ExtractMatches 没有改变，如果它也返回 error，需要修改
 	matched := utils.ExtractMatches(string(pageBytes), pattern)
 
-	var obj []map[string]interface{}
+	var items []Item
 	for index, item := range matched {
 		// 添加边界检查
 		if len(item) >= 2 {
 			title := strings.TrimSpace(item[1])
-			obj = append(obj, map[string]interface{}{
-				"index": index + 1,
-				"title": title,
-				"url":   "https://www.baidu.com/s?wd=" + title,
+			items = append(items, Item{
+				Index: index + 1,
+				Title: title,
+				URL:   "https://www.baidu.com/s?wd=" + title,
 			})
 		}
 	}
 
-	api := map[string]interface{}{
-		"code":    200,
-		"message": "baidu",
-		"icon":    "https://www.baidu.com/favicon.ico", // 64 x 64
-		"obj":     obj,
-	}
-	return api, nil
+	return NewResult("baidu", items), nil
 }
@@ -1,12 +1,11 @@
 package app
 
 import (
+	"api/logging"
+	"context"
 	"encoding/json"
+	"errors"
 	"io"
-	"net/http"
-	"time"
-
-	"github.com/gofiber/fiber/v2/log"
 )
 
 type bilibiliResponse struct {
@@ -21,6 +20,18 @@ type bilibiliData struct {
 	Bvid  string `json:"bvid"`
 }
 
+func init() {
+	Register(Source{
+		RouteName: "bilibili",
+		Name:      "哔哩哔哩",
+		Icon:      "https://static.hdslb.com/mobile/img/512.png",
+		Category:  CategoryVideo,
+		Fetch:     Bilibili,
+		// 排行榜 100 条
+		Expect: itemRange(50, 100),
+	})
+}
+
 // Bilibili 获取哔哩哔哩热搜数据
 //
 //	@Summary		获取哔哩哔哩热搜数据
@@ -31,64 +42,48 @@ type bilibiliData struct {
 //	@Success		200	{object}	map[string]interface{}
 //	@Failure		500	{object}	map[string]interface{}
 //	@Router			/bilibili [get]
-func Bilibili() (map[string]interface{}, error) {
-	// 创建带超时的 HTTP 客户端
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-
+func Bilibili(ctx context.Context, f *Fetcher) (*Result, error) {
 	url := "https://api.bilibili.com/x/web-interface/ranking"
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := f.NewRequest(ctx, url)
 	if err != nil {
-		log.Error("http.NewRequest error: %v", err)
+		logging.FromContext(ctx).Error("http.NewRequest error", logging.KeyError, err)
 		return nil, err
 	}
 
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/121.0.0.0 Safari/537.36")
-
-	resp, err := client.Do(req)
+	resp, err := f.Do(req)
 	if err != nil {
-		log.Error("http.Client.Do error: %v", err)
+		logging.FromContext(ctx).Error("http.Client.Do error", logging.KeyError, err)
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	pageBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
-		log.Error("io.ReadAll error: %v", err)
+		logging.FromContext(ctx).Error("io.ReadAll error", logging.KeyError, err)
 		return nil, err
 	}
 
 	var resultMap bilibiliResponse
 	err = json.Unmarshal(pageBytes, &resultMap)
 	if err != nil {
-		log.Error("json.Unmarshal error: %v", err)
+		logging.FromContext(ctx).Error("json.Unmarshal error", logging.KeyError, err)
 		return nil, err
 	}
 
 	// 检查数据是否为空
 	if len(resultMap.Data.List) == 0 {
-		return map[string]interface{}{
-			"code":    500,
-			"message": "API返回数据为空或格式不正确",
-		}, nil // 这里返回 nil error，因为这是业务逻辑错误，不是程序错误
+		return nil, errors.New("API返回数据为空或格式不正确")
 	}
 
-	var obj []map[string]interface{}
+	var items []Item
 	for index, item := range resultMap.Data.List {
-		obj = append(obj, map[string]interface{}{
-			"index": index + 1,
-			"title": item.Title,
-			"url":   "https://www.bilibili.com/video/" + item.Bvid,
+		items = append(items, Item{
+			Index: index + 1,
+			Title: item.Title,
+			URL:   "https://www.bilibili.com/video/" + item.Bvid,
 		})
 	}
 
-	api := map[string]interface{}{
-		"code":    200,
-		"message": "bilibili",
-		"icon":    "https://www.bilibili.com/favicon.ico", // 32 x 32
-		"obj":     obj,
-	}
-	return api, nil
+	return NewResult("bilibili", items), nil
 }
@@ -0,0 +1,171 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// BreakerState 熔断器状态
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "closed"    // 正常请求
+	BreakerOpen     BreakerState = "open"      // 已熔断，直接拒绝请求
+	BreakerHalfOpen BreakerState = "half-open" // 冷却结束，允许一次探测请求
+)
+
+// ErrCircuitOpen 数据源已熔断时返回的错误
+var ErrCircuitOpen = errors.New("数据源已熔断")
+
+// BreakerStatus 单个数据源的熔断器状态
+type BreakerStatus struct {
+	Source      string       `json:"source"`
+	State       BreakerState `json:"state"`
+	Failures    int          `json:"failures"`              // 连续失败次数
+	LastError   string       `json:"lastError,omitempty"`   // 最近一次失败的错误信息
+	LastFailure *time.Time   `json:"lastFailure,omitempty"` // 最近一次失败的时间
+	OpenedAt    *time.Time   `json:"openedAt,omitempty"`    // 熔断开始时间
+	RetryAt     *time.Time   `json:"retryAt,omitempty"`     // 熔断后允许探测的时间
+}
+
+// breaker 单个数据源的熔断器
+//
+// 连续失败达到阈值后熔断，冷却时间内的请求直接失败；冷却结束后放行一次探测请求，
+// 探测成功则恢复，失败则重新熔断
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu          sync.Mutex
+	state       BreakerState
+	failures    int
+	probing     bool
+	lastError   string
+	lastFailure time.Time
+	openedAt    time.Time
+}
+
+// allow 判断是否允许发出请求
+func (b *breaker) allow(source string, now time.Time) error {
+	if b.threshold <= 0 {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		retryAt := b.openedAt.Add(b.cooldown)
+		if now.Before(retryAt) {
+			return fmt.Errorf("%w: %s，%s 后重试", ErrCircuitOpen, source, retryAt.Format("15:04:05"))
+		}
+		b.state = BreakerHalfOpen
+		b.probing = true
+		return nil
+	case BreakerHalfOpen:
+		// 同一时间只放行一次探测请求
+		if b.probing {
+			return fmt.Errorf("%w: %s，正在探测", ErrCircuitOpen, source)
+		}
+		b.probing = true
+	}
+	return nil
+}
+
+// record 记录请求结果
+func (b *breaker) record(err error, now time.Time) {
+	if b.threshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if err == nil {
+		b.state = BreakerClosed
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	b.lastError = err.Error()
+	b.lastFailure = now
+	if b.state == BreakerHalfOpen || b.failures >= b.threshold {
+		b.state = BreakerOpen
+		b.openedAt = now
+	}
+}
+
+// release 放弃本次请求的结果，调用方主动取消时不计入成功或失败
+func (b *breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// status 获取熔断器的当前状态
+func (b *breaker) status(source string) BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	status := BreakerStatus{
+		Source:    source,
+		State:     b.state,
+		Failures:  b.failures,
+		LastError: b.lastError,
+	}
+	if !b.lastFailure.IsZero() {
+		lastFailure := b.lastFailure
+		status.LastFailure = &lastFailure
+	}
+	if b.state != BreakerClosed {
+		openedAt := b.openedAt
+		retryAt := b.openedAt.Add(b.cooldown)
+		status.OpenedAt = &openedAt
+		status.RetryAt = &retryAt
+	}
+	return status
+}
+
+// breaker 获取指定数据源的熔断器，不存在时创建
+func (f *Fetcher) breaker(source string) *breaker {
+	f.breakersMu.Lock()
+	defer f.breakersMu.Unlock()
+
+	if f.breakers == nil {
+		f.breakers = make(map[string]*breaker)
+	}
+	b, ok := f.breakers[source]
+	if !ok {
+		b = &breaker{
+			threshold: f.cfg.BreakerThreshold,
+			cooldown:  f.cfg.BreakerCooldown,
+			state:     BreakerClosed,
+		}
+		f.breakers[source] = b
+	}
+	return b
+}
+
+// Breaker 获取指定数据源的熔断器状态
+func (f *Fetcher) Breaker(source string) BreakerStatus {
+	return f.breaker(source).status(source)
+}
+
+// Breakers 获取所有已注册数据源的熔断器状态，按路由名称排序
+func (f *Fetcher) Breakers() []BreakerStatus {
+	sources := Sources()
+	statuses := make([]BreakerStatus, 0, len(sources))
+	for _, source := range sources {
+		statuses = append(statuses, f.Breaker(source.RouteName))
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Source < statuses[j].Source
+	})
+	return statuses
+}
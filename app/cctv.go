@@ -1,13 +1,12 @@
 package app
 
 import (
+	"api/logging"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"net/http"
-	"time"
-
-	"github.com/gofiber/fiber/v2/log"
 )
 
 type cctvResponse struct {
@@ -22,6 +21,16 @@ type cctvList struct {
 	URL   string `json:"url"`
 }
 
+func init() {
+	Register(Source{
+		RouteName: "cctv",
+		Name:      "央视网",
+		Icon:      "https://tv.cctv.com/favicon.ico",
+		Category:  CategoryNews,
+		Fetch:     CCTV,
+	})
+}
+
 // CCTV 获取CCTV新闻热搜数据
 //
 //	@Summary		获取CCTV新闻热搜数据
@@ -32,29 +41,24 @@ type cctvList struct {
 //	@Success		200	{object}	map[string]interface{}
 //	@Failure		500	{object}	map[string]interface{}
 //	@Router			/cctv [get]
-func CCTV() (map[string]interface{}, error) {
-	// 创建带超时的 HTTP 客户端
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-
+func CCTV(ctx context.Context, f *Fetcher) (*Result, error) {
 	url := "https://news.cctv.com/2019/07/gaiban/cmsdatainterface/page/world_1.jsonp"
-	resp, err := client.Get(url)
+	resp, err := f.Get(ctx, url)
 	if err != nil {
-		log.Error("http.Get error: %v", err)
+		logging.FromContext(ctx).Error("http.Get error", logging.KeyError, err)
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	pageBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
-		log.Error("io.ReadAll error: %v", err)
+		logging.FromContext(ctx).Error("io.ReadAll error", logging.KeyError, err)
 		return nil, err
 	}
 
 	// 检查响应长度是否足够
 	if len(pageBytes) <= 6 {
-		log.Error("API返回数据长度不足")
+		logging.FromContext(ctx).Error("API返回数据长度不足")
 		return nil, fmt.Errorf("API返回数据长度不足")
 	}
 
@@ -62,34 +66,23 @@ func CCTV() (map[string]interface{}, error) {
 	// 删除 JSONP 回调函数包裹，解析实际 JSON 数据
 	err = json.Unmarshal(pageBytes[6:len(pageBytes)-1], &resultMap)
 	if err != nil {
-		log.Error("json.Unmarshal error: %v", err)
+		logging.FromContext(ctx).Error("json.Unmarshal error", logging.KeyError, err)
 		return nil, err
 	}
 
 	// 检查数据是否为空
 	if len(resultMap.Data.List) == 0 {
-		return map[string]interface{}{
-			"code":    500,
-			"message": "API返回数据为空",
-			"icon":    "https://news.cctv.com/favicon.ico",
-			"obj":     []map[string]interface{}{},
-		}, nil
+		return nil, errors.New("API返回数据为空")
 	}
 
-	var obj []map[string]interface{}
+	var items []Item
 	for index, item := range resultMap.Data.List {
-		obj = append(obj, map[string]interface{}{
-			"index": index + 1,
-			"title": item.Title,
-			"url":   item.URL,
+		items = append(items, Item{
+			Index: index + 1,
+			Title: item.Title,
+			URL:   item.URL,
 		})
 	}
 
-	api := map[string]interface{}{
-		"code":    200,
-		"message": "cctv",
-		"icon":    "https://news.cctv.com/favicon.ico", // 16 x 16
-		"obj":     obj,
-	}
-	return api, nil
+	return NewResult("cctv", items), nil
 }
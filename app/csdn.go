@@ -1,14 +1,12 @@
 package app
 
 import (
-	"crypto/tls"
+	"api/logging"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
-	"time"
-
-	"github.com/gofiber/fiber/v2/log"
 )
 
 type csdbResponse struct {
@@ -20,6 +18,18 @@ type csdnData struct {
 	HotValue string `json:"pcHotRankScore"`
 }
 
+func init() {
+	Register(Source{
+		RouteName: "csdn",
+		Name:      "CSDN",
+		Icon:      "https://g.csdnimg.cn/static/logo/favicon32.ico",
+		Category:  CategoryTech,
+		Fetch:     CSDN,
+		// 接口参数限制为 100 条
+		Expect: itemRange(20, 100),
+	})
+}
+
 // CSDN 获取CSDN热搜数据
 //
 //	@Summary		获取CSDN热搜数据
@@ -30,28 +40,21 @@ type csdnData struct {
 //	@Success		200	{object}	map[string]interface{}
 //	@Failure		500	{object}	map[string]interface{}
 //	@Router			/csdn [get]
-func CSDN() (map[string]interface{}, error) {
-	// 创建自定义 Transport，跳过 TLS 验证（仅用于测试）
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{
-			InsecureSkipVerify: true, // 跳过证书验证
-		},
-	}
-
-	client := &http.Client{
-		Transport: tr,
-		Timeout:   10 * time.Second,
-	}
-
+func CSDN(ctx context.Context, f *Fetcher) (*Result, error) {
 	url := "https://blog.csdn.net/phoenix/web/blog/hotRank?&pageSize=100"
-	resp, err := client.Get(url)
+	req, err := f.NewRequest(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	// 跳过 TLS 证书验证
+	resp, err := f.DoInsecure(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 	// 检查状态码
 	if resp.StatusCode != http.StatusOK {
-		log.Error("HTTP请求失败，状态码: %d", resp.StatusCode)
+		logging.FromContext(ctx).Error("HTTP请求失败", "status", resp.StatusCode)
 		return nil, fmt.Errorf("HTTP请求失败，状态码: %d", resp.StatusCode)
 	}
 	pageBytes, err := io.ReadAll(resp.Body)
@@ -66,21 +69,15 @@ func CSDN() (map[string]interface{}, error) {
 	}
 
 	data := resultMap.Data
-	var obj []map[string]interface{}
+	var items []Item
 	for index, item := range data {
-		obj = append(obj, map[string]interface{}{
-			"index":    index + 1,
-			"title":    item.Title,
-			"url":      item.URL,
-			"hotValue": item.HotValue,
+		items = append(items, Item{
+			Index:    index + 1,
+			Title:    item.Title,
+			URL:      item.URL,
+			HotValue: item.HotValue,
 		})
 	}
 
-	api := map[string]interface{}{
-		"code":    200,
-		"message": "CSDN",
-		"icon":    "https://csdnimg.cn/public/favicon.ico",
-		"obj":     obj,
-	}
-	return api, nil
+	return NewResult("csdn", items), nil
 }
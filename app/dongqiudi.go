@@ -1,11 +1,11 @@
 package app
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"net/http"
-	"time"
 )
 
 type dqdresponse struct {
@@ -19,6 +19,16 @@ type dqddata struct {
 	URL   string `json:"share"`
 }
 
+func init() {
+	Register(Source{
+		RouteName: "dongqiudi",
+		Name:      "懂球帝",
+		Icon:      "https://page-dongqiudi.com/zb_users/theme/zblog5_blog/image/favicon.ico",
+		Category:  CategorySports,
+		Fetch:     Dongqiudi,
+	})
+}
+
 // Dongqiudi 获取懂球帝热搜数据
 //
 //	@Summary		获取懂球帝热搜数据
@@ -29,14 +39,9 @@ type dqddata struct {
 //	@Success		200	{object}	map[string]interface{}
 //	@Failure		500	{object}	map[string]interface{}
 //	@Router			/dongqiudi [get]
-func Dongqiudi() (map[string]interface{}, error) {
-	// 创建带超时的 HTTP 客户端
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-
+func Dongqiudi(ctx context.Context, f *Fetcher) (*Result, error) {
 	url := "https://dongqiudi.com/api/v3/archive/pc/index/getIndex"
-	resp, err := client.Get(url)
+	resp, err := f.Get(ctx, url)
 	if err != nil {
 		return nil, fmt.Errorf("http.Get error: %w", err)
 	}
@@ -55,28 +60,17 @@ func Dongqiudi() (map[string]interface{}, error) {
 
 	// 检查数据是否为空
 	if len(resultMap.Data.NewList) == 0 {
-		return map[string]interface{}{
-			"code":    500,
-			"message": "API返回数据为空",
-			"icon":    "https://www.dongqiudi.com/images/dqd-logo.png",
-			"obj":     []map[string]interface{}{},
-		}, nil
+		return nil, errors.New("API返回数据为空")
 	}
 
-	var obj []map[string]interface{}
+	var items []Item
 	for index, item := range resultMap.Data.NewList {
-		obj = append(obj, map[string]interface{}{
-			"index": index + 1,
-			"title": item.Title,
-			"url":   item.URL,
+		items = append(items, Item{
+			Index: index + 1,
+			Title: item.Title,
+			URL:   item.URL,
 		})
 	}
 
-	api := map[string]interface{}{
-		"code":    200,
-		"message": "dongqiudi",
-		"icon":    "https://www.dongqiudi.com/images/dqd-logo.png", // 800 x 206
-		"obj":     obj,
-	}
-	return api, nil
+	return NewResult("dongqiudi", items), nil
 }
@@ -1,12 +1,12 @@
 package app
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"net/http"
 	"strings"
-	"time"
 )
 
 type doubanItem struct {
@@ -15,6 +15,18 @@ type doubanItem struct {
 	URI   string  `json:"uri"`
 }
 
+func init() {
+	Register(Source{
+		RouteName: "douban",
+		Name:      "豆瓣",
+		Icon:      "https://img3.doubanio.com/favicon.ico",
+		Category:  CategorySocial,
+		Fetch:     Douban,
+		// 接口参数限制为 10 条
+		Expect: itemRange(5, 10),
+	})
+}
+
 // Douban 获取豆瓣热搜数据
 //
 //	@Summary		获取豆瓣热搜数据
@@ -25,25 +37,19 @@ type doubanItem struct {
 //	@Success		200	{object}	map[string]interface{}
 //	@Failure		500	{object}	map[string]interface{}
 //	@Router			/douban [get]
-func Douban() (map[string]interface{}, error) {
-	// 创建带超时的 HTTP 客户端
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-
+func Douban(ctx context.Context, f *Fetcher) (*Result, error) {
 	url := "https://m.douban.com/rexxar/api/v2/chart/hot_search_board?count=10&start=0"
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := f.NewRequest(ctx, url)
 	if err != nil {
 		return nil, fmt.Errorf("http.NewRequest error: %w", err)
 	}
 
 	// 设置 Headers（模拟浏览器）
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/121.0.0.0 Safari/537.36")
 	req.Header.Set("Referer", "https://www.douban.com/gallery/")
 
 	// 发送请求
-	resp, err := client.Do(req)
+	resp, err := f.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("http.Client.Do error: %w", err)
 	}
@@ -55,24 +61,19 @@ func Douban() (map[string]interface{}, error) {
 		return nil, fmt.Errorf("io.ReadAll error: %w", err)
 	}
 
-	var items []doubanItem
-	err = json.Unmarshal(pageBytes, &items)
+	var boardItems []doubanItem
+	err = json.Unmarshal(pageBytes, &boardItems)
 	if err != nil {
 		return nil, fmt.Errorf("json.Unmarshal error: %w", err)
 	}
 
 	// 检查数据是否为空
-	if len(items) == 0 {
-		return map[string]interface{}{
-			"code":    500,
-			"message": "API返回数据为空",
-			"icon":    "https://www.douban.com/favicon.ico",
-			"obj":     []map[string]interface{}{},
-		}, nil
+	if len(boardItems) == 0 {
+		return nil, errors.New("API返回数据为空")
 	}
 
-	var obj []map[string]interface{}
-	for index, item := range items {
+	var items []Item
+	for index, item := range boardItems {
 		hotValue := ""
 		if item.Score > 0 {
 			hotValue = fmt.Sprintf("%.2f万", item.Score/10000)
@@ -90,19 +91,14 @@ func Douban() (map[string]interface{}, error) {
 			}
 		}
 
-		obj = append(obj, map[string]interface{}{
-			"index":    index + 1,
-			"title":    item.Name,
-			"url":      convertedURL,
-			"hotValue": hotValue,
+		items = append(items, Item{
+			Index:    index + 1,
+			Title:    item.Name,
+			URL:      convertedURL,
+			HotValue: hotValue,
+			HotScore: item.Score,
 		})
 	}
 
-	api := map[string]interface{}{
-		"code":    200,
-		"message": "douban",
-		"icon":    "https://www.douban.com/favicon.ico", // 32 x 32
-		"obj":     obj,
-	}
-	return api, nil
-}
\ No newline at end of file
+	return NewResult("douban", items), nil
+}
@@ -1,14 +1,13 @@
 package app
 
 import (
+	"api/logging"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"net/http"
 	"net/url"
-	"time"
-
-	"github.com/gofiber/fiber/v2/log"
 )
 
 type Douyinresponse struct {
@@ -20,6 +19,18 @@ type Douyindata struct {
 	HotVaule float64 `json:"hot_value"`
 }
 
+func init() {
+	Register(Source{
+		RouteName: "douyin",
+		Name:      "抖音",
+		Icon:      "https://lf1-cdn-tos.bytegoofy.com/goofy/ies/douyin_web/public/favicon.ico",
+		Category:  CategoryVideo,
+		Fetch:     Douyin,
+		// 热搜榜 50 条
+		Expect: itemRange(30, 60),
+	})
+}
+
 // Douyin 获取抖音热搜数据
 //
 //	@Summary		获取抖音热搜数据
@@ -30,44 +41,34 @@ type Douyindata struct {
 //	@Success		200	{object}	map[string]interface{}
 //	@Failure		500	{object}	map[string]interface{}
 //	@Router			/douyin [get]
-func Douyin() (map[string]interface{}, error) {
-	// 创建带超时的 HTTP 客户端
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-
+func Douyin(ctx context.Context, f *Fetcher) (*Result, error) {
 	urlStr := "https://www.iesdouyin.com/web/api/v2/hotsearch/billboard/word/"
-	resp, err := client.Get(urlStr)
+	resp, err := f.Get(ctx, urlStr)
 	if err != nil {
-		log.Error("http.Get error: %v", err)
+		logging.FromContext(ctx).Error("http.Get error", logging.KeyError, err)
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	pageBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
-		log.Error("io.ReadAll error: %v", err)
+		logging.FromContext(ctx).Error("io.ReadAll error", logging.KeyError, err)
 		return nil, err
 	}
 
 	var resultMap Douyinresponse
 	err = json.Unmarshal(pageBytes, &resultMap)
 	if err != nil {
-		log.Error("json.Unmarshal error: %v", err)
+		logging.FromContext(ctx).Error("json.Unmarshal error", logging.KeyError, err)
 		return nil, err
 	}
 
 	// 检查数据是否为空
 	if len(resultMap.WordList) == 0 {
-		return map[string]interface{}{
-			"code":    500,
-			"message": "API返回数据为空",
-			"icon":    "https://lf1-cdn-tos.bytegoofy.com/goofy/ies/douyin_web/public/favicon.ico",
-			"obj":     []map[string]interface{}{},
-		}, nil
+		return nil, errors.New("API返回数据为空")
 	}
 
-	var obj []map[string]interface{}
+	var items []Item
 	for index, item := range resultMap.WordList {
 		// URL 编码标题，确保特殊字符正确处理
 		encodedTitle := url.QueryEscape(item.Title)
@@ -77,19 +78,14 @@ func Douyin() (map[string]interface{}, error) {
 			hotValue = fmt.Sprintf("%.2f万", item.HotVaule/10000)
 		}
 
-		obj = append(obj, map[string]interface{}{
-			"index":    index + 1,
-			"title":    item.Title,
-			"url":      "https://www.douyin.com/search/" + encodedTitle,
-			"hotValue": hotValue,
+		items = append(items, Item{
+			Index:    index + 1,
+			Title:    item.Title,
+			URL:      "https://www.douyin.com/search/" + encodedTitle,
+			HotValue: hotValue,
+			HotScore: item.HotVaule,
 		})
 	}
 
-	api := map[string]interface{}{
-		"code":    200,
-		"message": "douyin",
-		"icon":    "https://lf1-cdn-tos.bytegoofy.com/goofy/ies/douyin_web/public/favicon.ico", // 32 x 32
-		"obj":     obj,
-	}
-	return api, nil
+	return NewResult("douyin", items), nil
 }
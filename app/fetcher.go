@@ -0,0 +1,355 @@
+package app
+
+import (
+	"api/config"
+	"api/logging"
+	"api/metrics"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Fetcher 所有数据源共享的HTTP抓取器
+//
+// 复用连接池，统一处理代理、超时、User-Agent、重试和熔断，抓取时通过 ctx 传递取消信号和截止时间
+type Fetcher struct {
+	client         *http.Client
+	insecureClient *http.Client
+	baseURL        *url.URL
+	cfg            config.FetcherConfig
+
+	breakersMu sync.Mutex
+	breakers   map[string]*breaker
+	trackersMu sync.Mutex
+	trackers   map[string]*healthTracker
+
+	alertMu       sync.RWMutex
+	alertHandlers []AlertHandler
+}
+
+// NewFetcher 根据配置创建抓取器
+func NewFetcher(cfg config.FetcherConfig) (*Fetcher, error) {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.UserAgent == "" {
+		cfg.UserAgent = config.DefaultUserAgent
+	}
+	if cfg.MaxIdleConnsPerHost <= 0 {
+		cfg.MaxIdleConnsPerHost = 10
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = 500 * time.Millisecond
+	}
+	if cfg.RetryMaxBackoff <= 0 {
+		cfg.RetryMaxBackoff = 5 * time.Second
+	}
+	if cfg.BreakerCooldown <= 0 {
+		cfg.BreakerCooldown = 5 * time.Minute
+	}
+
+	proxy := http.ProxyFromEnvironment
+	if cfg.Proxy != "" {
+		proxyURL, err := url.Parse(cfg.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("代理地址格式错误: %w", err)
+		}
+		proxy = http.ProxyURL(proxyURL)
+	}
+
+	var baseURL *url.URL
+	if cfg.BaseURL != "" {
+		parsed, err := url.Parse(cfg.BaseURL)
+		if err != nil {
+			return nil, fmt.Errorf("基础地址格式错误: %w", err)
+		}
+		baseURL = parsed
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxy
+	transport.MaxIdleConnsPerHost = cfg.MaxIdleConnsPerHost
+
+	// 部分数据源的证书配置有问题，使用单独的连接池跳过证书验证
+	insecureTransport := transport.Clone()
+	insecureTransport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+
+	// 超时主要由 ctx 控制，客户端的超时只作为没有截止时间时的兜底
+	clientTimeout := cfg.Timeout
+	for _, timeout := range cfg.SourceTimeouts {
+		if timeout > clientTimeout {
+			clientTimeout = timeout
+		}
+	}
+
+	f := &Fetcher{
+		client:         &http.Client{Transport: transport, Timeout: clientTimeout},
+		insecureClient: &http.Client{Transport: insecureTransport, Timeout: clientTimeout},
+		baseURL:        baseURL,
+		cfg:            cfg,
+	}
+	if cfg.AlertWebhook != "" {
+		f.OnAlert(f.webhookAlertHandler(cfg.AlertWebhook))
+	}
+	return f, nil
+}
+
+var (
+	defaultFetcherMu sync.RWMutex
+	defaultFetcher   *Fetcher
+)
+
+// DefaultFetcher 获取默认抓取器，未设置时使用默认配置创建
+func DefaultFetcher() *Fetcher {
+	defaultFetcherMu.RLock()
+	f := defaultFetcher
+	defaultFetcherMu.RUnlock()
+	if f != nil {
+		return f
+	}
+
+	defaultFetcherMu.Lock()
+	defer defaultFetcherMu.Unlock()
+	if defaultFetcher == nil {
+		defaultFetcher, _ = NewFetcher(config.FetcherConfig{})
+	}
+	return defaultFetcher
+}
+
+// SetDefaultFetcher 设置默认抓取器，通常在启动时根据配置调用一次
+func SetDefaultFetcher(f *Fetcher) {
+	defaultFetcherMu.Lock()
+	defer defaultFetcherMu.Unlock()
+	defaultFetcher = f
+}
+
+// Timeout 获取指定数据源的超时时间
+func (f *Fetcher) Timeout(source string) time.Duration {
+	if timeout, ok := f.cfg.SourceTimeouts[source]; ok && timeout > 0 {
+		return timeout
+	}
+	return f.cfg.Timeout
+}
+
+// AllTimeout 获取一次抓取所有数据源的总超时时间，未配置时为 0
+func (f *Fetcher) AllTimeout() time.Duration {
+	return f.cfg.AllTimeout
+}
+
+// Fetch 抓取指定数据源，超时时间取该数据源的配置
+//
+// 抓取结果会按数据源的预期检查，结果和耗时记录到数据源的健康状态中，开始或停止未通过检查时发出告警；
+// 数据源连续失败达到阈值后熔断，冷却时间内直接返回 ErrCircuitOpen；停用的数据源直接返回 ErrSourceDisabled
+func (f *Fetcher) Fetch(ctx context.Context, source Source) (*Result, error) {
+	ctx = logging.With(ctx, logging.KeySource, source.RouteName)
+	if !Enabled(source.RouteName) {
+		metrics.FetchTotal.Inc(source.RouteName, "disabled")
+		return nil, ErrSourceDisabled
+	}
+	b := f.breaker(source.RouteName)
+	if err := b.allow(source.RouteName, time.Now()); err != nil {
+		metrics.FetchTotal.Inc(source.RouteName, "rejected")
+		return nil, err
+	}
+
+	fetchCtx, cancel := context.WithTimeout(ctx, f.Timeout(source.RouteName))
+	defer cancel()
+	start := time.Now()
+	result, err := source.Fetch(fetchCtx, f)
+
+	// 调用方主动取消不代表数据源有问题
+	if err != nil && errors.Is(ctx.Err(), context.Canceled) {
+		b.release()
+		metrics.FetchTotal.Inc(source.RouteName, "canceled")
+		return nil, err
+	}
+	// 检查抓取结果，未通过检查的结果标记为 degraded，由调用方决定是否使用
+	if err == nil && result != nil {
+		if problems := Validate(result, source.expectations()); len(problems) > 0 {
+			result.Degraded = true
+			result.Problems = problems
+		}
+	}
+
+	now := time.Now()
+	b.record(err, now)
+	recordFetchMetrics(source.RouteName, result, err, now.Sub(start))
+	logFetch(ctx, result, err, now.Sub(start))
+	if alert, ok := f.tracker(source.RouteName).record(result, err, now.Sub(start), now); ok {
+		alert.Source = source.RouteName
+		f.alert(alert)
+	}
+	return result, err
+}
+
+// NewRequest 创建带上下文的GET请求
+func (f *Fetcher) NewRequest(ctx context.Context, url string) (*http.Request, error) {
+	return http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+}
+
+// Do 发送请求，请求未设置 User-Agent 时使用配置的默认值，遇到临时错误时按配置重试
+func (f *Fetcher) Do(req *http.Request) (*http.Response, error) {
+	return f.do(f.client, req)
+}
+
+// DoInsecure 跳过TLS证书验证发送请求，仅用于证书配置有问题的数据源
+func (f *Fetcher) DoInsecure(req *http.Request) (*http.Response, error) {
+	return f.do(f.insecureClient, req)
+}
+
+// do 发送请求，网络错误、5xx 和 429 响应按指数退避重试
+//
+// 只重试没有请求体的请求，重试用尽后返回最后一次的响应或错误
+func (f *Fetcher) do(client *http.Client, req *http.Request) (*http.Response, error) {
+	req = f.prepare(req)
+	retries := f.cfg.Retries
+	if req.Body != nil && req.GetBody == nil {
+		retries = 0
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := client.Do(req)
+		if attempt >= retries || !retryable(resp, err) {
+			return resp, err
+		}
+
+		wait := f.backoff(attempt, resp)
+		logger := logging.FromContext(req.Context()).With("url", req.URL.String(), "attempt", attempt+1, "wait", wait)
+		if err != nil {
+			logger.Debug("请求失败，稍后重试", logging.KeyError, err)
+		} else {
+			logger.Debug("请求失败，稍后重试", "status", resp.StatusCode)
+		}
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+	}
+}
+
+// retryable 判断请求结果是否为可以重试的临时错误
+func retryable(resp *http.Response, err error) bool {
+	if err == nil {
+		return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	// 域名不存在等不会很快恢复的解析错误不重试
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTemporary || dnsErr.IsTimeout
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	// 连接被拒绝、连接被重置等
+	var opErr *net.OpError
+	return errors.As(err, &opErr) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF)
+}
+
+// backoff 计算第 attempt 次重试前的等待时间
+//
+// 等待时间从 RetryBackoff 开始每次翻倍，加上随机抖动，不超过 RetryMaxBackoff；
+// 响应带有 Retry-After 时优先使用
+func (f *Fetcher) backoff(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+			return min(time.Duration(seconds)*time.Second, f.cfg.RetryMaxBackoff)
+		}
+	}
+
+	wait := f.cfg.RetryBackoff << attempt
+	if wait <= 0 || wait > f.cfg.RetryMaxBackoff {
+		wait = f.cfg.RetryMaxBackoff
+	}
+	// 在 [wait/2, wait) 之间随机，避免多个请求同时重试
+	return wait/2 + rand.N(wait/2+1)
+}
+
+// Get 发送GET请求
+func (f *Fetcher) Get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := f.NewRequest(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	return f.Do(req)
+}
+
+// prepare 补充请求的默认请求头，配置了基础地址时改写请求地址
+//
+// 如 https://top.baidu.com/board?tab=realtime 会被改写为 {BaseURL}/top.baidu.com/board?tab=realtime
+func (f *Fetcher) prepare(req *http.Request) *http.Request {
+	if req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", f.cfg.UserAgent)
+	}
+	if f.baseURL != nil {
+		rewritten := *req.URL
+		rewritten.Scheme = f.baseURL.Scheme
+		rewritten.Host = f.baseURL.Host
+		rewritten.Path = strings.TrimSuffix(f.baseURL.Path, "/") + "/" + req.URL.Host + req.URL.Path
+		rewritten.RawPath = ""
+		req.URL = &rewritten
+		req.Host = ""
+	}
+	return req
+}
+
+// Fetch 使用默认抓取器抓取指定数据源
+func Fetch(ctx context.Context, source Source) (*Result, error) {
+	return DefaultFetcher().Fetch(ctx, source)
+}
+
+// recordFetchMetrics 记录一次抓取的次数、耗时和条目数
+func recordFetchMetrics(source string, result *Result, err error, latency time.Duration) {
+	metrics.FetchDuration.Observe(latency.Seconds(), source)
+	switch {
+	case err != nil:
+		metrics.FetchTotal.Inc(source, "error")
+	case result != nil && result.Degraded:
+		metrics.FetchTotal.Inc(source, "degraded")
+	default:
+		metrics.FetchTotal.Inc(source, "success")
+	}
+	if err == nil && result != nil {
+		metrics.FetchItems.Set(float64(len(result.Items)), source)
+	}
+}
+
+// logFetch 记录一次抓取的结果和耗时，失败由调用方决定如何记录
+func logFetch(ctx context.Context, result *Result, err error, latency time.Duration) {
+	logger := logging.FromContext(ctx)
+	switch {
+	case err != nil:
+		logger.Debug("抓取失败", logging.KeyDuration, latency, logging.KeyError, err)
+	case result != nil:
+		logger.Debug("抓取完成", logging.KeyDuration, latency, "count", len(result.Items), "degraded", result.Degraded)
+	}
+}
@@ -0,0 +1,362 @@
+package app
+
+import (
+	"api/config"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewFetcherDefaults(t *testing.T) {
+	f, err := NewFetcher(config.FetcherConfig{
+		SourceTimeouts: map[string]time.Duration{"weibo": 3 * time.Second},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 10*time.Second, f.Timeout("zhihu"))
+	assert.Equal(t, 3*time.Second, f.Timeout("weibo"))
+	assert.Equal(t, time.Duration(0), f.AllTimeout())
+
+	_, err = NewFetcher(config.FetcherConfig{Proxy: "://bad"})
+	assert.Error(t, err)
+}
+
+func TestFetcherUserAgent(t *testing.T) {
+	var userAgents []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgents = append(userAgents, r.UserAgent())
+	}))
+	defer server.Close()
+
+	f, err := NewFetcher(config.FetcherConfig{UserAgent: "azhot-test"})
+	assert.NoError(t, err)
+
+	// 未设置 User-Agent 时使用配置的默认值
+	resp, err := f.Get(context.Background(), server.URL)
+	assert.NoError(t, err)
+	resp.Body.Close()
+
+	// 数据源自己设置的 User-Agent 不会被覆盖
+	req, err := f.NewRequest(context.Background(), server.URL)
+	assert.NoError(t, err)
+	req.Header.Set("User-Agent", "custom")
+	resp, err = f.Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, []string{"azhot-test", "custom"}, userAgents)
+}
+
+func TestFetcherSourceTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(2 * time.Second):
+		}
+	}))
+	defer server.Close()
+
+	f, err := NewFetcher(config.FetcherConfig{
+		SourceTimeouts: map[string]time.Duration{"slow": 50 * time.Millisecond},
+	})
+	assert.NoError(t, err)
+
+	source := Source{
+		RouteName: "slow",
+		Fetch: func(ctx context.Context, f *Fetcher) (*Result, error) {
+			resp, err := f.Get(ctx, server.URL)
+			if err != nil {
+				return nil, err
+			}
+			resp.Body.Close()
+			return NewResult("slow", nil), nil
+		},
+	}
+
+	start := time.Now()
+	_, err = f.Fetch(context.Background(), source)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "应该因为数据源超时而失败: %v", err)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestFetcherBaseURL(t *testing.T) {
+	var requested string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.RequestURI()
+	}))
+	defer server.Close()
+
+	f, err := NewFetcher(config.FetcherConfig{BaseURL: server.URL + "/prefix/"})
+	assert.NoError(t, err)
+
+	resp, err := f.Get(context.Background(), "https://top.baidu.com/board?tab=realtime")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "/prefix/top.baidu.com/board?tab=realtime", requested)
+}
+
+func TestFetcherRetry(t *testing.T) {
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	f, err := NewFetcher(config.FetcherConfig{Retries: 2, RetryBackoff: time.Millisecond})
+	assert.NoError(t, err)
+
+	// 5xx 响应重试后成功
+	resp, err := f.Get(context.Background(), server.URL)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 3, attempts)
+
+	// 重试用尽后返回最后一次的响应
+	attempts = -10
+	resp, err = f.Get(context.Background(), server.URL)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, -7, attempts)
+}
+
+func TestFetcherNoRetry(t *testing.T) {
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	f, err := NewFetcher(config.FetcherConfig{Retries: 2, RetryBackoff: time.Millisecond})
+	assert.NoError(t, err)
+
+	// 4xx 响应不重试
+	resp, err := f.Get(context.Background(), server.URL)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, 1, attempts)
+
+	// 等待重试时 ctx 取消则立即返回
+	f, err = NewFetcher(config.FetcherConfig{Retries: 2, RetryBackoff: time.Minute, RetryMaxBackoff: time.Minute})
+	assert.NoError(t, err)
+	badGateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer badGateway.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = f.Get(ctx, badGateway.URL)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "应该因为 ctx 超时而停止重试: %v", err)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestRetryable(t *testing.T) {
+	assert.True(t, retryable(&http.Response{StatusCode: http.StatusTooManyRequests}, nil))
+	assert.True(t, retryable(&http.Response{StatusCode: http.StatusBadGateway}, nil))
+	assert.False(t, retryable(&http.Response{StatusCode: http.StatusForbidden}, nil))
+	assert.False(t, retryable(nil, context.Canceled))
+	assert.True(t, retryable(nil, &net.OpError{Op: "dial", Err: errors.New("connection refused")}))
+	assert.False(t, retryable(nil, &net.OpError{Op: "dial", Err: &net.DNSError{IsNotFound: true}}))
+	assert.True(t, retryable(nil, &net.OpError{Op: "dial", Err: &net.DNSError{IsTemporary: true}}))
+	assert.False(t, retryable(nil, errors.New("解析失败")))
+}
+
+func TestFetcherCircuitBreaker(t *testing.T) {
+	f, err := NewFetcher(config.FetcherConfig{BreakerThreshold: 2, BreakerCooldown: 50 * time.Millisecond})
+	assert.NoError(t, err)
+
+	var calls int
+	fail := true
+	source := Source{
+		RouteName: "flaky",
+		Fetch: func(ctx context.Context, f *Fetcher) (*Result, error) {
+			calls++
+			if fail {
+				return nil, errors.New("请求失败")
+			}
+			return NewResult("flaky", nil), nil
+		},
+	}
+
+	// 连续失败达到阈值后熔断
+	for i := 0; i < 2; i++ {
+		_, err = f.Fetch(context.Background(), source)
+		assert.EqualError(t, err, "请求失败")
+	}
+	status := f.Breaker("flaky")
+	assert.Equal(t, BreakerOpen, status.State)
+	assert.Equal(t, 2, status.Failures)
+	assert.Equal(t, "请求失败", status.LastError)
+	assert.NotNil(t, status.RetryAt)
+
+	// 熔断期间不再请求数据源
+	_, err = f.Fetch(context.Background(), source)
+	assert.True(t, errors.Is(err, ErrCircuitOpen))
+	assert.Equal(t, 2, calls)
+
+	// 冷却结束后探测失败，重新熔断
+	time.Sleep(60 * time.Millisecond)
+	_, err = f.Fetch(context.Background(), source)
+	assert.EqualError(t, err, "请求失败")
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, BreakerOpen, f.Breaker("flaky").State)
+
+	// 探测成功后恢复
+	time.Sleep(60 * time.Millisecond)
+	fail = false
+	_, err = f.Fetch(context.Background(), source)
+	assert.NoError(t, err)
+	status = f.Breaker("flaky")
+	assert.Equal(t, BreakerClosed, status.State)
+	assert.Equal(t, 0, status.Failures)
+	assert.Nil(t, status.RetryAt)
+
+	// 调用方主动取消不计入失败
+	fail = true
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for i := 0; i < 3; i++ {
+		f.Fetch(ctx, source)
+	}
+	assert.Equal(t, BreakerClosed, f.Breaker("flaky").State)
+}
+
+func TestFetcherHealth(t *testing.T) {
+	f, err := NewFetcher(config.FetcherConfig{BreakerThreshold: 3, BreakerCooldown: time.Minute})
+	assert.NoError(t, err)
+
+	assert.Equal(t, HealthUnknown, f.Health("tracked").Status)
+
+	fail := false
+	source := Source{
+		RouteName: "tracked",
+		Fetch: func(ctx context.Context, f *Fetcher) (*Result, error) {
+			time.Sleep(10 * time.Millisecond)
+			if fail {
+				return nil, errors.New("页面结构可能已变更")
+			}
+			return NewResult("tracked", []Item{
+				{Index: 1, Title: "A", URL: "http://example.com/a"},
+				{Index: 2, Title: "B", URL: "http://example.com/b"},
+			}), nil
+		},
+		Expect: &Expectations{MinItems: 1},
+	}
+
+	_, err = f.Fetch(context.Background(), source)
+	assert.NoError(t, err)
+	health := f.Health("tracked")
+	assert.Equal(t, HealthOK, health.Status)
+	assert.Equal(t, 2, health.LastCount)
+	assert.Equal(t, 1, health.Successes)
+	assert.NotNil(t, health.LastSuccess)
+	assert.Nil(t, health.LastFailure)
+	latency, err := time.ParseDuration(health.AvgLatency)
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, latency, 10*time.Millisecond)
+
+	// 失败后保留最近一次成功的信息
+	fail = true
+	for i := 0; i < 2; i++ {
+		f.Fetch(context.Background(), source)
+	}
+	health = f.Health("tracked")
+	assert.Equal(t, HealthFailing, health.Status)
+	assert.Equal(t, 2, health.ConsecutiveFailures)
+	assert.Equal(t, "页面结构可能已变更", health.LastError)
+	assert.Equal(t, 2, health.LastCount)
+	assert.NotNil(t, health.LastSuccess)
+	assert.Equal(t, BreakerClosed, health.Breaker)
+
+	// 熔断后状态为 down
+	f.Fetch(context.Background(), source)
+	health = f.Health("tracked")
+	assert.Equal(t, HealthDown, health.Status)
+	assert.Equal(t, BreakerOpen, health.Breaker)
+	assert.NotNil(t, health.RetryAt)
+	assert.Equal(t, 3, health.Failures)
+
+	// 熔断期间被拒绝的请求不计入抓取次数
+	f.Fetch(context.Background(), source)
+	assert.Equal(t, 3, f.Health("tracked").Failures)
+}
+
+func TestFetcherAlert(t *testing.T) {
+	received := make(chan Alert, 4)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var alert Alert
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&alert))
+		received <- alert
+	}))
+	defer webhook.Close()
+
+	f, err := NewFetcher(config.FetcherConfig{AlertWebhook: webhook.URL})
+	assert.NoError(t, err)
+	var alerts []Alert
+	f.OnAlert(func(alert Alert) {
+		alerts = append(alerts, alert)
+	})
+
+	broken := true
+	source := Source{
+		RouteName: "validated",
+		Fetch: func(ctx context.Context, f *Fetcher) (*Result, error) {
+			title := "正常标题"
+			if broken {
+				title = "标题</div"
+			}
+			return NewResult("validated", []Item{{Index: 1, Title: title, URL: "https://example.com/1"}}), nil
+		},
+		Expect: &Expectations{MinItems: 1},
+	}
+
+	// 未通过检查的结果标记为 degraded，只在状态变化时告警
+	for i := 0; i < 2; i++ {
+		result, err := f.Fetch(context.Background(), source)
+		assert.NoError(t, err)
+		assert.True(t, result.Degraded)
+		assert.NotEmpty(t, result.Problems)
+	}
+	health := f.Health("validated")
+	assert.Equal(t, HealthDegraded, health.Status)
+	assert.NotEmpty(t, health.Problems)
+
+	broken = false
+	result, err := f.Fetch(context.Background(), source)
+	assert.NoError(t, err)
+	assert.False(t, result.Degraded)
+	assert.Equal(t, HealthOK, f.Health("validated").Status)
+
+	if assert.Len(t, alerts, 2) {
+		assert.Equal(t, AlertWarning, alerts[0].Level)
+		assert.Equal(t, "validated", alerts[0].Source)
+		assert.Equal(t, AlertResolved, alerts[1].Level)
+	}
+
+	// 告警同时发送到配置的地址，发送是异步的，顺序不固定
+	var levels []string
+	for i := 0; i < 2; i++ {
+		select {
+		case alert := <-received:
+			assert.Equal(t, "validated", alert.Source)
+			levels = append(levels, alert.Level)
+		case <-time.After(5 * time.Second):
+			t.Fatal("没有收到告警")
+		}
+	}
+	assert.ElementsMatch(t, []string{AlertWarning, AlertResolved}, levels)
+}
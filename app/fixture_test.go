@@ -0,0 +1,195 @@
+package app
+
+import (
+	"api/config"
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 录制数据和期望输出的维护方式：
+//
+//	go test ./app -run TestScrapersWithFixtures            使用录制的数据离线测试
+//	go test ./app -run TestScrapersWithFixtures -record    从真实接口重新录制数据（需要网络），同时更新期望输出
+//	go test ./app -run TestScrapersWithFixtures -update    只根据当前的解析结果更新期望输出
+var (
+	recordFixtures = flag.Bool("record", false, "从真实接口重新录制 testdata/fixtures 下的数据")
+	updateGolden   = flag.Bool("update", false, "根据解析结果重写 testdata/golden 下的期望输出")
+)
+
+const (
+	fixturesDir = "testdata/fixtures"
+	goldenDir   = "testdata/golden"
+)
+
+// fixtureTime 回放录制数据时使用的当前时间，历史上的今天依赖该时间选择日期
+var fixtureTime = time.Date(2025, 10, 18, 12, 0, 0, 0, time.Local)
+
+// unsafeFixtureChars 录制数据文件名中不允许出现的字符
+var unsafeFixtureChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// fixtureName 根据请求地址生成录制数据的文件名，过长时截断并附加哈希
+func fixtureName(host, escapedPath, rawQuery string) string {
+	key := host + escapedPath
+	if rawQuery != "" {
+		key += "?" + rawQuery
+	}
+	name := strings.Trim(unsafeFixtureChars.ReplaceAllString(key, "_"), "_")
+	if len(name) > 100 {
+		sum := sha1.Sum([]byte(key))
+		name = name[:80] + "_" + hex.EncodeToString(sum[:4])
+	}
+	return name
+}
+
+// recordingTransport 转发请求到真实接口，并将成功的响应保存为录制数据
+type recordingTransport struct {
+	t    *testing.T
+	base http.RoundTripper
+	dir  string
+}
+
+func (r *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := r.base.RoundTrip(req)
+	if err != nil || resp.StatusCode >= 300 && resp.StatusCode < 400 {
+		return resp, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if resp.StatusCode != http.StatusOK {
+		r.t.Errorf("录制 %s 失败，状态码: %d", req.URL, resp.StatusCode)
+		return resp, nil
+	}
+
+	// 跟随重定向时以最初的请求地址保存，回放时直接返回最终的响应
+	original := req
+	for original.Response != nil {
+		original = original.Response.Request
+	}
+	name := fixtureName(original.URL.Host, original.URL.EscapedPath(), original.URL.RawQuery)
+	if err := os.WriteFile(filepath.Join(r.dir, name), body, 0o644); err != nil {
+		r.t.Errorf("保存录制数据失败: %v", err)
+	}
+	return resp, nil
+}
+
+// newFixtureFetcher 创建测试数据源使用的抓取器
+//
+// 录制模式下请求真实接口并保存响应，否则将请求转发到回放录制数据的本地服务器
+func newFixtureFetcher(t *testing.T, source string) *Fetcher {
+	dir := filepath.Join(fixturesDir, source)
+
+	if *recordFixtures {
+		assert.NoError(t, os.RemoveAll(dir))
+		assert.NoError(t, os.MkdirAll(dir, 0o755))
+		f, err := NewFetcher(config.FetcherConfig{Timeout: 30 * time.Second})
+		assert.NoError(t, err)
+		f.client.Transport = &recordingTransport{t: t, base: f.client.Transport, dir: dir}
+		f.insecureClient.Transport = &recordingTransport{t: t, base: f.insecureClient.Transport, dir: dir}
+		return f
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 请求路径为 /原始主机名/原始路径
+		host, escapedPath, _ := strings.Cut(strings.TrimPrefix(r.URL.EscapedPath(), "/"), "/")
+		name := fixtureName(host, "/"+escapedPath, r.URL.RawQuery)
+		body, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Errorf("缺少录制数据 %s: %v", filepath.Join(dir, name), err)
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+
+	f, err := NewFetcher(config.FetcherConfig{BaseURL: server.URL})
+	assert.NoError(t, err)
+	return f
+}
+
+// assertGolden 比较解析结果与期望输出，更新模式下重写期望输出
+func assertGolden(t *testing.T, source string, items []Item) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	assert.NoError(t, encoder.Encode(items))
+
+	path := filepath.Join(goldenDir, source+".json")
+	if *updateGolden || *recordFixtures {
+		assert.NoError(t, os.MkdirAll(goldenDir, 0o755))
+		assert.NoError(t, os.WriteFile(path, buf.Bytes(), 0o644))
+		return
+	}
+
+	expected, err := os.ReadFile(path)
+	if !assert.NoError(t, err, "缺少期望输出，可以使用 -update 生成") {
+		return
+	}
+	assert.JSONEq(t, string(expected), buf.String())
+}
+
+// sampleFixtureItems 手工编写的示例数据包含的条目数
+//
+// 示例数据只有几条，达不到数据源的条目数预期，回放时要求解析出的条目数与示例完全一致，
+// 正则只匹配到一部分条目时也能发现。使用 -record 录制真实数据后应删除对应的项，改用数据源自身的预期
+var sampleFixtureItems = map[string]int{
+	"360doc": 2, "360search": 3, "acfun": 2, "baidu": 2, "bilibili": 2,
+	"cctv": 2, "csdn": 2, "dongqiudi": 2, "douban": 2, "douyin": 2,
+	"github": 2, "guojiadili": 2, "historytoday": 2, "hupu": 2, "ithome": 2,
+	"lishipin": 2, "nanfang": 2, "pengpai": 2, "qqnews": 2, "quark": 2,
+	"renmin": 3, "shaoshupai": 2, "sougou": 2, "souhu": 3, "toutiao": 2,
+	"v2ex": 2, "wangyinews": 2, "weibo": 2, "xinjingbao": 2, "zhihu": 2,
+}
+
+// fixtureSource 返回回放录制数据时使用的数据源，示例数据改用与示例条目数一致的预期
+func fixtureSource(source Source) Source {
+	if n, ok := sampleFixtureItems[source.RouteName]; ok && !*recordFixtures {
+		expect := source.expectations()
+		expect.MinItems = n
+		expect.MaxItems = n
+		source.Expect = &expect
+	}
+	return source
+}
+
+// 使用录制的数据测试所有数据源的解析结果
+func TestScrapersWithFixtures(t *testing.T) {
+	if !*recordFixtures {
+		now = func() time.Time { return fixtureTime }
+		defer func() { now = time.Now }()
+	}
+
+	for _, source := range Sources() {
+		t.Run(source.RouteName, func(t *testing.T) {
+			f := newFixtureFetcher(t, source.RouteName)
+			result, err := f.Fetch(context.Background(), fixtureSource(source))
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, source.RouteName, result.Source)
+			assert.False(t, result.Degraded, result.Problems)
+			assertGolden(t, source.RouteName, result.Items)
+		})
+	}
+}
@@ -2,13 +2,25 @@ package app
 
 import (
 	"api/utils"
+	"context"
+	"errors"
 	"fmt"
 	"io"
-	"net/http"
 	"strings"
-	"time"
 )
 
+func init() {
+	Register(Source{
+		RouteName: "github",
+		Name:      "GitHub",
+		Icon:      "https://github.githubassets.com/favicons/favicon.png",
+		Category:  CategoryTech,
+		Fetch:     Github,
+		// Trending 页面 25 个仓库，没有描述的仓库匹配不到
+		Expect: itemRange(10, 30),
+	})
+}
+
 // Github 获取GitHub Trending数据
 //
 //	@Summary		获取GitHub Trending数据
@@ -19,14 +31,9 @@ import (
 //	@Success		200	{object}	map[string]interface{}
 //	@Failure		500	{object}	map[string]interface{}
 //	@Router			/github [get]
-func Github() (map[string]interface{}, error) {
-	// 创建带超时的 HTTP 客户端
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-
+func Github(ctx context.Context, f *Fetcher) (*Result, error) {
 	url := "https://github.com/trending"
-	resp, err := client.Get(url)
+	resp, err := f.Get(ctx, url)
 	if err != nil {
 		return nil, fmt.Errorf("http.Get error: %w", err)
 	}
@@ -43,15 +50,10 @@ func Github() (map[string]interface{}, error) {
 
 	// 检查是否匹配到数据
 	if len(matched) == 0 {
-		return map[string]interface{}{
-			"code":    500,
-			"message": "未匹配到 trending 数据，可能页面结构已变更",
-			"icon":    "https://github.githubassets.com/favicons/favicon.png",
-			"obj":     []map[string]interface{}{},
-		}, nil
+		return nil, errors.New("未匹配到 trending 数据，可能页面结构已变更")
 	}
 
-	var obj []map[string]interface{}
+	var items []Item
 	for index, item := range matched {
 		// 添加边界检查
 		if len(item) >= 4 {
@@ -66,20 +68,14 @@ func Github() (map[string]interface{}, error) {
 				desc = strings.TrimSpace(item[3])
 			}
 
-			obj = append(obj, map[string]interface{}{
-				"index": index + 1,
-				"title": trimed,
-				"desc":  desc,
-				"url":   "https://github.com/" + trimed,
+			items = append(items, Item{
+				Index: index + 1,
+				Title: trimed,
+				Desc:  desc,
+				URL:   "https://github.com/" + trimed,
 			})
 		}
 	}
 
-	api := map[string]interface{}{
-		"code":    200,
-		"message": "GitHub",
-		"icon":    "https://github.githubassets.com/favicons/favicon.png", // 32 x 32
-		"obj":     obj,
-	}
-	return api, nil
+	return NewResult("github", items), nil
 }
@@ -2,12 +2,22 @@ package app
 
 import (
 	"api/utils"
+	"context"
+	"errors"
 	"fmt"
 	"io"
-	"net/http"
-	"time"
 )
 
+func init() {
+	Register(Source{
+		RouteName: "guojiadili",
+		Name:      "国家地理",
+		Icon:      "http://www.dili360.com/favicon.ico",
+		Category:  CategoryCulture,
+		Fetch:     Guojiadili,
+	})
+}
+
 // Guojiadili 获取国家地理热搜数据
 //
 //	@Summary		获取国家地理热搜数据
@@ -18,14 +28,9 @@ import (
 //	@Success		200	{object}	map[string]interface{}
 //	@Failure		500	{object}	map[string]interface{}
 //	@Router			/guojiadili [get]
-func Guojiadili() (map[string]interface{}, error) {
-	// 创建带超时的 HTTP 客户端
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-
+func Guojiadili(ctx context.Context, f *Fetcher) (*Result, error) {
 	url := "https://www.dili360.com/"
-	resp, err := client.Get(url)
+	resp, err := f.Get(ctx, url)
 	if err != nil {
 		return nil, fmt.Errorf("http.Get error: %w", err)
 	}
@@ -45,15 +50,10 @@ func Guojiadili() (map[string]interface{}, error) {
 
 	// 检查是否匹配到数据
 	if len(matched) == 0 {
-		return map[string]interface{}{
-			"code":    500,
-			"message": "未匹配到数据，可能页面结构已变更",
-			"icon":    "https://www.dili360.com/favicon.ico",
-			"obj":     []map[string]interface{}{},
-		}, nil
+		return nil, errors.New("未匹配到数据，可能页面结构已变更")
 	}
 
-	var obj []map[string]interface{}
+	var items []Item
 	for index, item := range matched {
 		// 添加边界检查
 		if len(item) >= 3 {
@@ -65,19 +65,13 @@ func Guojiadili() (map[string]interface{}, error) {
 				fullURL = urlPath
 			}
 
-			obj = append(obj, map[string]interface{}{
-				"index": index + 1,
-				"title": item[2],
-				"url":   fullURL,
+			items = append(items, Item{
+				Index: index + 1,
+				Title: item[2],
+				URL:   fullURL,
 			})
 		}
 	}
 
-	api := map[string]interface{}{
-		"code":    200,
-		"message": "guojiadili",
-		"icon":    "http://www.dili360.com/favicon.ico", // 32 x 32
-		"obj":     obj,
-	}
-	return api, nil
+	return NewResult("guojiadili", items), nil
 }
@@ -0,0 +1,168 @@
+package app
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// 数据源的健康状态
+const (
+	HealthUnknown  = "unknown"  // 还没有抓取过
+	HealthOK       = "ok"       // 最近一次抓取成功
+	HealthDegraded = "degraded" // 最近一次抓取成功，但结果未通过检查
+	HealthFailing  = "failing"  // 最近一次抓取失败
+	HealthDown     = "down"     // 连续失败后已熔断
+)
+
+// latencyWindow 计算平均耗时使用的最近抓取次数
+const latencyWindow = 20
+
+// SourceHealth 单个数据源的抓取情况
+type SourceHealth struct {
+	Source              string       `json:"source"`
+	Status              string       `json:"status"`                // unknown、ok、degraded、failing 或 down
+	LastSuccess         *time.Time   `json:"lastSuccess,omitempty"` // 最近一次成功的时间
+	LastFailure         *time.Time   `json:"lastFailure,omitempty"` // 最近一次失败的时间
+	LastError           string       `json:"lastError,omitempty"`   // 最近一次失败的错误信息
+	Problems            []string     `json:"problems,omitempty"`    // 最近一次抓取结果未通过检查时发现的问题
+	ConsecutiveFailures int          `json:"consecutiveFailures"`   // 连续失败次数
+	AvgLatency          string       `json:"avgLatency,omitempty"`  // 最近 20 次抓取的平均耗时
+	LastCount           int          `json:"lastCount"`             // 最近一次成功抓取到的条目数
+	Successes           int          `json:"successes"`             // 启动以来成功的次数
+	Failures            int          `json:"failures"`              // 启动以来失败的次数
+	Breaker             BreakerState `json:"breaker"`               // 熔断器状态
+	RetryAt             *time.Time   `json:"retryAt,omitempty"`     // 熔断后允许探测的时间
+}
+
+// healthTracker 记录单个数据源的抓取情况
+type healthTracker struct {
+	mu                  sync.Mutex
+	lastSuccess         time.Time
+	lastFailure         time.Time
+	lastError           string
+	problems            []string
+	degraded            bool
+	consecutiveFailures int
+	lastCount           int
+	successes           int
+	failures            int
+	latencies           [latencyWindow]time.Duration
+	latencyCount        int
+}
+
+// record 记录一次抓取的结果和耗时，结果开始或停止未通过检查时返回需要发出的告警
+func (h *healthTracker) record(result *Result, err error, latency time.Duration, now time.Time) (Alert, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.latencies[h.latencyCount%latencyWindow] = latency
+	h.latencyCount++
+
+	if err != nil {
+		h.failures++
+		h.consecutiveFailures++
+		h.lastFailure = now
+		h.lastError = err.Error()
+		return Alert{}, false
+	}
+	h.successes++
+	h.consecutiveFailures = 0
+	h.lastSuccess = now
+	if result == nil {
+		return Alert{}, false
+	}
+	h.lastCount = len(result.Items)
+
+	wasDegraded := h.degraded
+	h.degraded = result.Degraded
+	h.problems = result.Problems
+	switch {
+	case result.Degraded && !wasDegraded:
+		return Alert{Level: AlertWarning, Message: "抓取结果未通过检查，可能是页面结构已变更", Problems: result.Problems, Time: now}, true
+	case !result.Degraded && wasDegraded:
+		return Alert{Level: AlertResolved, Message: "抓取结果已恢复正常", Time: now}, true
+	}
+	return Alert{}, false
+}
+
+// health 获取数据源的抓取情况，状态根据最近一次抓取结果和熔断器状态计算
+func (h *healthTracker) health(source string, breaker BreakerStatus) SourceHealth {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	health := SourceHealth{
+		Source:              source,
+		Status:              HealthUnknown,
+		LastError:           h.lastError,
+		Problems:            h.problems,
+		ConsecutiveFailures: h.consecutiveFailures,
+		LastCount:           h.lastCount,
+		Successes:           h.successes,
+		Failures:            h.failures,
+		Breaker:             breaker.State,
+		RetryAt:             breaker.RetryAt,
+	}
+	if !h.lastSuccess.IsZero() {
+		lastSuccess := h.lastSuccess
+		health.LastSuccess = &lastSuccess
+	}
+	if !h.lastFailure.IsZero() {
+		lastFailure := h.lastFailure
+		health.LastFailure = &lastFailure
+	}
+
+	if n := min(h.latencyCount, latencyWindow); n > 0 {
+		var total time.Duration
+		for _, latency := range h.latencies[:n] {
+			total += latency
+		}
+		health.AvgLatency = (total / time.Duration(n)).Round(time.Millisecond).String()
+	}
+
+	switch {
+	case breaker.State != BreakerClosed:
+		health.Status = HealthDown
+	case h.consecutiveFailures > 0:
+		health.Status = HealthFailing
+	case h.degraded:
+		health.Status = HealthDegraded
+	case h.successes > 0:
+		health.Status = HealthOK
+	}
+	return health
+}
+
+// tracker 获取指定数据源的抓取情况记录，不存在时创建
+func (f *Fetcher) tracker(source string) *healthTracker {
+	f.trackersMu.Lock()
+	defer f.trackersMu.Unlock()
+
+	if f.trackers == nil {
+		f.trackers = make(map[string]*healthTracker)
+	}
+	h, ok := f.trackers[source]
+	if !ok {
+		h = &healthTracker{}
+		f.trackers[source] = h
+	}
+	return h
+}
+
+// Health 获取指定数据源的抓取情况
+func (f *Fetcher) Health(source string) SourceHealth {
+	return f.tracker(source).health(source, f.Breaker(source))
+}
+
+// HealthAll 获取所有已注册数据源的抓取情况，按路由名称排序
+func (f *Fetcher) HealthAll() []SourceHealth {
+	sources := Sources()
+	healths := make([]SourceHealth, 0, len(sources))
+	for _, source := range sources {
+		healths = append(healths, f.Health(source.RouteName))
+	}
+	sort.Slice(healths, func(i, j int) bool {
+		return healths[i].Source < healths[j].Source
+	})
+	return healths
+}
@@ -1,16 +1,19 @@
 package app
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
-	"net/http"
 	"strings"
 	"time"
 
 	"golang.org/x/net/html"
 )
 
+// now 获取当前时间，测试时可替换为固定时间
+var now = time.Now
+
 func stripHTML(htmlString string) string {
 	// 使用 html.Parse 解析 HTML 字符串
 	doc, err := html.Parse(strings.NewReader(htmlString))
@@ -42,6 +45,16 @@ func stripHTML(htmlString string) string {
 	return result.String()
 }
 
+func init() {
+	Register(Source{
+		RouteName: "historytoday",
+		Name:      "历史上的今天",
+		Icon:      "https://www.baidu.com/favicon.ico",
+		Category:  CategoryCulture,
+		Fetch:     HistoryToday,
+	})
+}
+
 // HistoryToday 获取历史上的今天数据
 //
 //	@Summary		获取历史上的今天数据
@@ -52,18 +65,13 @@ func stripHTML(htmlString string) string {
 //	@Success		200	{object}	map[string]interface{}
 //	@Failure		500	{object}	map[string]interface{}
 //	@Router			/historytoday [get]
-func HistoryToday() (map[string]interface{}, error) {
-	// 创建带超时的 HTTP 客户端
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-
-	currentTime := time.Now()
+func HistoryToday(ctx context.Context, f *Fetcher) (*Result, error) {
+	currentTime := now()
 	month := fmt.Sprintf("%02d", currentTime.Month())
 	day := fmt.Sprintf("%02d", currentTime.Day())
 	url := "https://baike.baidu.com/cms/home/eventsOnHistory/" + month + ".json"
 
-	resp, err := client.Get(url)
+	resp, err := f.Get(ctx, url)
 	if err != nil {
 		return nil, fmt.Errorf("http.Get error: %w", err)
 	}
@@ -103,15 +111,10 @@ func HistoryToday() (map[string]interface{}, error) {
 
 	// 检查数据是否为空
 	if len(dateList) == 0 {
-		return map[string]interface{}{
-			"code":    500,
-			"message": "今天(" + month + "月" + day + "日)没有历史事件数据",
-			"icon":    "https://baike.baidu.com/favicon.ico",
-			"obj":     []map[string]interface{}{},
-		}, nil
+		return nil, fmt.Errorf("今天(%s月%s日)没有历史事件数据", month, day)
 	}
 
-	var obj []map[string]interface{}
+	var items []Item
 	for index, item := range dateList {
 		itemMap, ok := item.(map[string]interface{})
 		if !ok {
@@ -137,18 +140,12 @@ func HistoryToday() (map[string]interface{}, error) {
 			}
 		}
 
-		obj = append(obj, map[string]interface{}{
-			"index": index + 1,
-			"title": stripHTML(title),
-			"url":   urlStr,
+		items = append(items, Item{
+			Index: index + 1,
+			Title: stripHTML(title),
+			URL:   urlStr,
 		})
 	}
 
-	api := map[string]interface{}{
-		"code":    200,
-		"message": "history",
-		"icon":    "https://baike.baidu.com/favicon.ico", // 64 x 64
-		"obj":     obj,
-	}
-	return api, nil
+	return NewResult("historytoday", items), nil
 }
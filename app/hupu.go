@@ -2,12 +2,23 @@ package app
 
 import (
 	"api/utils"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"time"
 )
 
+func init() {
+	Register(Source{
+		RouteName: "hupu",
+		Name:      "虎扑",
+		Icon:      "https://www.hupu.com/favicon.ico",
+		Category:  CategorySports,
+		Fetch:     Hupu,
+	})
+}
+
 // Hupu 获取虎扑热搜数据
 //
 //	@Summary		获取虎扑热搜数据
@@ -18,14 +29,9 @@ import (
 //	@Success		200	{object}	map[string]interface{}
 //	@Failure		500	{object}	map[string]interface{}
 //	@Router			/hupu [get]
-func Hupu() (map[string]interface{}, error) {
-	// 创建带超时的 HTTP 客户端
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-
+func Hupu(ctx context.Context, f *Fetcher) (*Result, error) {
 	url := "https://www.hupu.com/"
-	resp, err := client.Get(url)
+	resp, err := f.Get(ctx, url)
 	if err != nil {
 		return nil, fmt.Errorf("http.Get error: %w", err)
 	}
@@ -46,15 +52,10 @@ func Hupu() (map[string]interface{}, error) {
 
 	// 检查是否匹配到数据
 	if len(matches) == 0 {
-		return map[string]interface{}{
-			"code":    500,
-			"message": "未匹配到数据，可能页面结构已变更",
-			"icon":    "https://www.hupu.com/favicon.ico",
-			"obj":     []map[string]interface{}{},
-		}, nil
+		return nil, errors.New("未匹配到数据，可能页面结构已变更")
 	}
 
-	var obj []map[string]interface{}
+	var items []Item
 	for index, item := range matches {
 		// 添加边界检查
 		if len(item) >= 3 {
@@ -66,19 +67,13 @@ func Hupu() (map[string]interface{}, error) {
 				url = "https://www.hupu.com" + url
 			}
 
-			obj = append(obj, map[string]interface{}{
-				"index": index + 1,
-				"title": title,
-				"url":   url,
+			items = append(items, Item{
+				Index: index + 1,
+				Title: title,
+				URL:   url,
 			})
 		}
 	}
 
-	api := map[string]interface{}{
-		"code":    200,
-		"message": "hupu",
-		"icon":    "https://www.hupu.com/favicon.ico", // 32 x 32
-		"obj":     obj,
-	}
-	return api, nil
+	return NewResult("hupu", items), nil
 }
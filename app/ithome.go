@@ -2,12 +2,23 @@ package app
 
 import (
 	"api/utils"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"time"
 )
 
+func init() {
+	Register(Source{
+		RouteName: "ithome",
+		Name:      "IT之家",
+		Icon:      "https://www.ithome.com/favicon.ico",
+		Category:  CategoryTech,
+		Fetch:     Ithome,
+	})
+}
+
 // Ithome 获取IT之家热搜数据
 //
 //	@Summary		获取IT之家热搜数据
@@ -18,14 +29,9 @@ import (
 //	@Success		200	{object}	map[string]interface{}
 //	@Failure		500	{object}	map[string]interface{}
 //	@Router			/ithome [get]
-func Ithome() (map[string]interface{}, error) {
-	// 创建带超时的 HTTP 客户端
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-
+func Ithome(ctx context.Context, f *Fetcher) (*Result, error) {
 	url := "https://m.ithome.com/rankm/"
-	resp, err := client.Get(url)
+	resp, err := f.Get(ctx, url)
 	if err != nil {
 		return nil, fmt.Errorf("http.Get error: %w", err)
 	}
@@ -46,12 +52,7 @@ func Ithome() (map[string]interface{}, error) {
 
 	// 检查是否匹配到数据
 	if len(matches) == 0 {
-		return map[string]interface{}{
-			"code":    500,
-			"message": "未匹配到数据，可能页面结构已变更",
-			"icon":    "https://www.ithome.com/favicon.ico",
-			"obj":     []map[string]interface{}{},
-		}, nil
+		return nil, errors.New("未匹配到数据，可能页面结构已变更")
 	}
 
 	// 确定要取多少条数据（最多12条）
@@ -60,34 +61,23 @@ func Ithome() (map[string]interface{}, error) {
 		count = 12
 	}
 
-	var obj []map[string]interface{}
+	var items []Item
 	for index := 0; index < count; index++ {
 		item := matches[index]
 		// 添加边界检查
 		if len(item) >= 3 {
-			obj = append(obj, map[string]interface{}{
-				"index": index + 1,
-				"title": item[2],
-				"url":   item[1],
+			items = append(items, Item{
+				Index: index + 1,
+				Title: item[2],
+				URL:   item[1],
 			})
 		}
 	}
 
 	// 确保有有效数据
-	if len(obj) == 0 {
-		return map[string]interface{}{
-			"code":    500,
-			"message": "处理后的数据为空",
-			"icon":    "https://www.ithome.com/favicon.ico",
-			"obj":     []map[string]interface{}{},
-		}, nil
+	if len(items) == 0 {
+		return nil, errors.New("处理后的数据为空")
 	}
 
-	api := map[string]interface{}{
-		"code":    200,
-		"message": "ithome",
-		"icon":    "https://www.ithome.com/favicon.ico", // 32 x 32
-		"obj":     obj,
-	}
-	return api, nil
+	return NewResult("ithome", items), nil
 }
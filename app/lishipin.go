@@ -2,12 +2,23 @@ package app
 
 import (
 	"api/utils"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"time"
 )
 
+func init() {
+	Register(Source{
+		RouteName: "lishipin",
+		Name:      "梨视频",
+		Icon:      "https://page.pearvideo.com/webres/img/logo.png",
+		Category:  CategoryVideo,
+		Fetch:     Lishipin,
+	})
+}
+
 // Lishipin 获取梨视频热搜数据
 //
 //	@Summary		获取梨视频热搜数据
@@ -18,14 +29,9 @@ import (
 //	@Success		200	{object}	map[string]interface{}
 //	@Failure		500	{object}	map[string]interface{}
 //	@Router			/lishipin [get]
-func Lishipin() (map[string]interface{}, error) {
-	// 创建带超时的 HTTP 客户端
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-
+func Lishipin(ctx context.Context, f *Fetcher) (*Result, error) {
 	url := "https://www.pearvideo.com/popular"
-	resp, err := client.Get(url)
+	resp, err := f.Get(ctx, url)
 	if err != nil {
 		return nil, fmt.Errorf("http.Get error: %w", err)
 	}
@@ -44,27 +50,16 @@ func Lishipin() (map[string]interface{}, error) {
 	matched := utils.ExtractMatches(string(pageBytes), pattern)
 	// 检查是否匹配到数据
 	if len(matched) == 0 {
-		return map[string]interface{}{
-			"code":    500,
-			"message": "未匹配到数据，可能页面结构已变更",
-			"icon":    "https://page.pearvideo.com/webres/img/logo.png",
-			"obj":     []map[string]interface{}{},
-		}, nil
+		return nil, errors.New("未匹配到数据，可能页面结构已变更")
 	}
-	var obj []map[string]interface{}
+	var items []Item
 	for index, item := range matched {
-		obj = append(obj, map[string]interface{}{
-			"index": index + 1,
-			"title": item[2],
-			"url":   "https://www.pearvideo.com/" + fmt.Sprint(item[1]),
-			"desc":  item[3],
+		items = append(items, Item{
+			Index: index + 1,
+			Title: item[2],
+			URL:   "https://www.pearvideo.com/" + fmt.Sprint(item[1]),
+			Desc:  item[3],
 		})
 	}
-	api := map[string]interface{}{
-		"code":    200,
-		"message": "lishipin",
-		"icon":    "https://page.pearvideo.com/webres/img/logo.png", // 76 x 98
-		"obj":     obj,
-	}
-	return api, nil
+	return NewResult("lishipin", items), nil
 }
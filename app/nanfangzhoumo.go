@@ -1,12 +1,13 @@
 package app
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strconv"
-	"time"
 )
 
 type nfResponse struct {
@@ -20,6 +21,16 @@ type contents struct {
 	ID    float64 `json:"id"`
 }
 
+func init() {
+	Register(Source{
+		RouteName: "nanfang",
+		Name:      "南方周末",
+		Icon:      "https://icdn.infzm.com/wap/img/infzm-meta-icon.46b02e1.png",
+		Category:  CategoryNews,
+		Fetch:     Nanfangzhoumo,
+	})
+}
+
 // Nanfangzhoumo 获取南方周末热搜数据
 //
 //	@Summary		获取南方周末热搜数据
@@ -29,15 +40,10 @@ type contents struct {
 //	@Produce		json
 //	@Success		200	{object}	map[string]interface{}
 //	@Failure		500	{object}	map[string]interface{}
-//	@Router			/nanfangzhoumo [get]
-func Nanfangzhoumo() (map[string]interface{}, error) {
-	// 创建带超时的 HTTP 客户端
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-
+//	@Router			/nanfang [get]
+func Nanfangzhoumo(ctx context.Context, f *Fetcher) (*Result, error) {
 	url := "https://www.infzm.com/hot_contents?format=json"
-	resp, err := client.Get(url)
+	resp, err := f.Get(ctx, url)
 	if err != nil {
 		return nil, fmt.Errorf("http.Get error: %w", err)
 	}
@@ -59,26 +65,15 @@ func Nanfangzhoumo() (map[string]interface{}, error) {
 	wordList := resultMap.NfzmData.HotContents
 	// 检查数据是否为空
 	if len(wordList) == 0 {
-		return map[string]interface{}{
-			"code":    500,
-			"message": "API返回数据为空",
-			"icon":    "https://www.infzm.com/favicon.ico",
-			"obj":     []map[string]interface{}{},
-		}, nil
+		return nil, errors.New("API返回数据为空")
 	}
-	var obj []map[string]interface{}
+	var items []Item
 	for index, item := range wordList {
-		obj = append(obj, map[string]interface{}{
-			"index": index + 1,
-			"title": item.Title,
-			"url":   "https://www.infzm.com/contents/" + strconv.FormatFloat(item.ID, 'f', -1, 64),
+		items = append(items, Item{
+			Index: index + 1,
+			Title: item.Title,
+			URL:   "https://www.infzm.com/contents/" + strconv.FormatFloat(item.ID, 'f', -1, 64),
 		})
 	}
-	api := map[string]interface{}{
-		"code":    200,
-		"message": "nanfangzhoumo",
-		"icon":    "https://www.infzm.com/favicon.ico", // 32 x 32
-		"obj":     obj,
-	}
-	return api, nil
+	return NewResult("nanfang", items), nil
 }
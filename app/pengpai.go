@@ -1,11 +1,12 @@
 package app
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"time"
 )
 
 type ppResponse struct {
@@ -19,6 +20,16 @@ type news struct {
 	ContId string `json:"contId"`
 }
 
+func init() {
+	Register(Source{
+		RouteName: "pengpai",
+		Name:      "澎湃新闻",
+		Icon:      "https://www.thepaper.cn/favicon.ico",
+		Category:  CategoryNews,
+		Fetch:     Pengpai,
+	})
+}
+
 // Pengpai 获取澎湃新闻热搜数据
 //
 //	@Summary		获取澎湃新闻热搜数据
@@ -29,14 +40,9 @@ type news struct {
 //	@Success		200	{object}	map[string]interface{}
 //	@Failure		500	{object}	map[string]interface{}
 //	@Router			/pengpai [get]
-func Pengpai() (map[string]interface{}, error) {
-	// 创建带超时的 HTTP 客户端
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-
+func Pengpai(ctx context.Context, f *Fetcher) (*Result, error) {
 	url := "https://cache.thepaper.cn/contentapi/wwwIndex/rightSidebar"
-	resp, err := client.Get(url)
+	resp, err := f.Get(ctx, url)
 	if err != nil {
 		return nil, fmt.Errorf("http.Get error: %w", err)
 	}
@@ -57,43 +63,27 @@ func Pengpai() (map[string]interface{}, error) {
 	}
 	// 检查数据是否为空
 	if len(resultMap.Data.HotNews) == 0 {
-		return map[string]interface{}{
-			"code":    500,
-			"message": "API返回数据为空",
-			"icon":    "https://www.thepaper.cn/favicon.ico",
-			"obj":     []map[string]interface{}{},
-		}, nil
+		return nil, errors.New("API返回数据为空")
 	}
 
 	data := resultMap.Data.HotNews
-	var obj []map[string]interface{}
+	var items []Item
 	for index, item := range data {
 		// 确保 ContId 不为空
 		if item.ContId == "" {
 			continue // 跳过没有 ContId 的新闻
 		}
 
-		obj = append(obj, map[string]interface{}{
-			"index": index + 1,
-			"title": item.Title,
-			"url":   "https://www.thepaper.cn/newsDetail_forward_" + item.ContId,
+		items = append(items, Item{
+			Index: index + 1,
+			Title: item.Title,
+			URL:   "https://www.thepaper.cn/newsDetail_forward_" + item.ContId,
 		})
 	}
 
 	// 如果所有数据都因为没有 ContId 被跳过
-	if len(obj) == 0 {
-		return map[string]interface{}{
-			"code":    500,
-			"message": "API返回数据格式异常，缺少必要字段",
-			"icon":    "https://www.thepaper.cn/favicon.ico",
-			"obj":     []map[string]interface{}{},
-		}, nil
-	}
-	api := map[string]interface{}{
-		"code":    200,
-		"message": "pengpai",
-		"icon":    "https://www.thepaper.cn/favicon.ico", // 32 x 32
-		"obj":     obj,
+	if len(items) == 0 {
+		return nil, errors.New("API返回数据格式异常，缺少必要字段")
 	}
-	return api, nil
+	return NewResult("pengpai", items), nil
 }
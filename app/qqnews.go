@@ -1,11 +1,12 @@
 package app
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"time"
 )
 
 type qqResponse struct {
@@ -28,6 +29,18 @@ type hotEvent struct {
 	HotScore float64 `json:"hotScore"`
 }
 
+func init() {
+	Register(Source{
+		RouteName: "qqnews",
+		Name:      "腾讯新闻",
+		Icon:      "https://mat1.gtimg.com/qqcdn/qqindex2021/favicon.ico",
+		Category:  CategoryNews,
+		Fetch:     Qqnews,
+		// 接口参数限制为 51 条
+		Expect: itemRange(30, 60),
+	})
+}
+
 // Qqnews 获取腾讯新闻热搜数据
 //
 //	@Summary		获取腾讯新闻热搜数据
@@ -38,14 +51,9 @@ type hotEvent struct {
 //	@Success		200	{object}	map[string]interface{}
 //	@Failure		500	{object}	map[string]interface{}
 //	@Router			/qqnews [get]
-func Qqnews() (map[string]interface{}, error) {
-	// 创建带超时的 HTTP 客户端
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-
+func Qqnews(ctx context.Context, f *Fetcher) (*Result, error) {
 	url := "https://r.inews.qq.com/gw/event/hot_ranking_list?page_size=51"
-	resp, err := client.Get(url)
+	resp, err := f.Get(ctx, url)
 	if err != nil {
 		return nil, fmt.Errorf("http.Get error: %w", err)
 	}
@@ -69,18 +77,13 @@ func Qqnews() (map[string]interface{}, error) {
 
 	// 检查数据是否为空或格式不正确
 	if len(result.IdList) == 0 || len(result.IdList[0].NewsList) == 0 {
-		return map[string]interface{}{
-			"code":    500,
-			"message": "API返回数据为空",
-			"icon":    "https://mat1.gtimg.com/qqcdn/qqindex2021/favicon.ico",
-			"obj":     []map[string]interface{}{},
-		}, nil
+		return nil, errors.New("API返回数据为空")
 	}
 
 	// 获取新闻列表数据
 	newsListData := result.IdList[0].NewsList
 
-	var obj []map[string]interface{}
+	var items []Item
 	for index, item := range newsListData {
 		if index == 0 {
 			continue
@@ -88,19 +91,13 @@ func Qqnews() (map[string]interface{}, error) {
 		hot := item.HotEvent.HotScore / 10000
 		hotValue := fmt.Sprintf("%.1f万", hot)
 
-		obj = append(obj, map[string]interface{}{
-			"index":    index,
-			"title":    item.Title,
-			"url":      item.Url,
-			"time":     item.Time,
-			"hotValue": hotValue,
+		items = append(items, Item{
+			Index:    index,
+			Title:    item.Title,
+			URL:      item.Url,
+			HotValue: hotValue,
+			Extra:    map[string]string{"time": item.Time},
 		})
 	}
-	api := map[string]interface{}{
-		"code":    200,
-		"message": "qqnews",
-		"icon":    "https://mat1.gtimg.com/qqcdn/qqindex2021/favicon.ico", // 96 x 96
-		"obj":     obj,
-	}
-	return api, nil
+	return NewResult("qqnews", items), nil
 }
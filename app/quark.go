@@ -1,12 +1,13 @@
 package app
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strconv"
-	"time"
 )
 
 type quarkResponse struct {
@@ -24,6 +25,17 @@ type quarkItem struct {
 	HotValue string `json:"hot"`
 }
 
+func init() {
+	Register(Source{
+		RouteName: "quark",
+		Name:      "夸克",
+		Icon:      "https://gw.alicdn.com/imgextra/i3/O1CN018r2tKf28YP7ev0fPF_!!6000000007944-2-tps-48-48.png",
+		Category:  CategorySearch,
+		Aliases:   []string{"kuake"},
+		Fetch:     Quark,
+	})
+}
+
 // Quark 获取夸克热搜数据
 //
 //	@Summary		获取夸克热搜数据
@@ -34,14 +46,9 @@ type quarkItem struct {
 //	@Success		200	{object}	map[string]interface{}
 //	@Failure		500	{object}	map[string]interface{}
 //	@Router			/quark [get]
-func Quark() (map[string]interface{}, error) {
-	// 创建带超时的 HTTP 客户端
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-
+func Quark(ctx context.Context, f *Fetcher) (*Result, error) {
 	url := "https://biz.quark.cn/api/trending/ranking/getNewsRanking?modules=hotNews&uc_param_str=dnfrpfbivessbtbmnilauputogpintnwmtsvcppcprsnnnchmicckpgixsnx"
-	resp, err := client.Get(url)
+	resp, err := f.Get(ctx, url)
 	if err != nil {
 		return nil, fmt.Errorf("http.Get error: %w", err)
 	}
@@ -61,15 +68,10 @@ func Quark() (map[string]interface{}, error) {
 	}
 	// 检查数据是否为空
 	if len(resultMap.Data.HotNews.Item) == 0 {
-		return map[string]interface{}{
-			"code":    500,
-			"message": "API返回数据为空",
-			"icon":    "https://gw.alicdn.com/imgextra/i3/O1CN018r2tKf28YP7ev0fPF_!!6000000007944-2-tps-48-48.png",
-			"obj":     []map[string]interface{}{},
-		}, nil
+		return nil, errors.New("API返回数据为空")
 	}
 	data := resultMap.Data.HotNews.Item
-	obj := make([]map[string]interface{}, 0, len(data))
+	items := make([]Item, 0, len(data))
 
 	for i, item := range data {
 		hot, err := strconv.ParseFloat(item.HotValue, 64)
@@ -77,19 +79,14 @@ func Quark() (map[string]interface{}, error) {
 			hot = 0
 		}
 
-		obj = append(obj, map[string]interface{}{
-			"index":    i + 1,
-			"title":    item.Title,
-			"url":      item.URL,
-			"hotValue": fmt.Sprintf("%.1f万", hot/10000),
+		items = append(items, Item{
+			Index:    i + 1,
+			Title:    item.Title,
+			URL:      item.URL,
+			HotValue: fmt.Sprintf("%.1f万", hot/10000),
+			HotScore: hot,
 		})
 	}
 
-	api := map[string]interface{}{
-		"code":    200,
-		"message": "quark",
-		"obj":     obj,
-		"icon":    "https://gw.alicdn.com/imgextra/i3/O1CN018r2tKf28YP7ev0fPF_!!6000000007944-2-tps-48-48.png",
-	}
-	return api, nil
+	return NewResult("quark", items), nil
 }
@@ -0,0 +1,126 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// 数据源分类
+const (
+	CategorySearch  = "search"  // 搜索引擎
+	CategorySocial  = "social"  // 社交社区
+	CategoryNews    = "news"    // 新闻资讯
+	CategoryTech    = "tech"    // 科技开发
+	CategoryVideo   = "video"   // 视频
+	CategorySports  = "sports"  // 体育
+	CategoryCulture = "culture" // 文化阅读
+)
+
+// FetchFunc 数据源的抓取函数，通过共享的抓取器发送请求，ctx 控制超时和取消
+type FetchFunc func(ctx context.Context, f *Fetcher) (*Result, error)
+
+// Source 数据源的注册信息
+//
+// 每个数据源在自己的文件中通过 init 调用 Register 注册，
+// HTTP路由、WebSocket、MCP、定时任务等都基于注册表生成
+type Source struct {
+	RouteName string        // 路由名称，如 weibo
+	Name      string        // 中文名称
+	Icon      string        // 图标URL
+	Category  string        // 分类，见 Category* 常量
+	Aliases   []string      // 路由别名，如 kuake 是 quark 的别名
+	Fetch     FetchFunc     // 抓取函数
+	Expect    *Expectations // 抓取结果的预期，为空时使用 DefaultExpectations
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Source)
+	aliases    = make(map[string]string)
+	disabled   = make(map[string]bool) // 停用的数据源路由名称
+)
+
+// ErrSourceDisabled 数据源已在配置中停用
+var ErrSourceDisabled = errors.New("数据源已停用")
+
+// Register 注册一个数据源，路由名称或别名重复时 panic
+func Register(source Source) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if source.RouteName == "" || source.Fetch == nil {
+		panic("app: source must have a route name and a fetch function")
+	}
+	names := append([]string{source.RouteName}, source.Aliases...)
+	for _, name := range names {
+		if _, exists := registry[name]; exists {
+			panic(fmt.Sprintf("app: source %q registered twice", name))
+		}
+		if _, exists := aliases[name]; exists {
+			panic(fmt.Sprintf("app: source %q registered twice", name))
+		}
+	}
+
+	registry[source.RouteName] = source
+	for _, alias := range source.Aliases {
+		aliases[alias] = source.RouteName
+	}
+}
+
+// LookupSource 根据路由名称或别名查找数据源
+func LookupSource(name string) (Source, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	if routeName, ok := aliases[name]; ok {
+		name = routeName
+	}
+	source, ok := registry[name]
+	return source, ok
+}
+
+// Sources 获取所有已注册的数据源，按路由名称排序
+func Sources() []Source {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	sources := make([]Source, 0, len(registry))
+	for _, source := range registry {
+		sources = append(sources, source)
+	}
+	sort.Slice(sources, func(i, j int) bool {
+		return sources[i].RouteName < sources[j].RouteName
+	})
+	return sources
+}
+
+// SetDisabledSources 设置停用的数据源，替换之前的设置，名称可以是别名
+//
+// 有数据源不存在时返回错误，不修改原来的设置
+func SetDisabledSources(names []string) error {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	next := make(map[string]bool, len(names))
+	for _, name := range names {
+		if routeName, ok := aliases[name]; ok {
+			name = routeName
+		}
+		if _, ok := registry[name]; !ok {
+			return fmt.Errorf("停用的数据源 %s 不存在", name)
+		}
+		next[name] = true
+	}
+	disabled = next
+	return nil
+}
+
+// Enabled 数据源是否启用，name 为路由名称
+func Enabled(name string) bool {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return !disabled[name]
+}
@@ -0,0 +1,133 @@
+package app
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Item 单条热搜数据
+type Item struct {
+	Index    int               `json:"index"`              // 排名，从1开始
+	Title    string            `json:"title"`              // 标题
+	URL      string            `json:"url"`                // 链接
+	HotValue string            `json:"hotValue,omitempty"` // 热度，保留平台原始的展示格式，如 "123.4万"
+	HotScore float64           `json:"hotScore,omitempty"` // 热度数值，用于排序和比较
+	Desc     string            `json:"desc,omitempty"`     // 描述
+	Image    string            `json:"image,omitempty"`    // 封面图片URL
+	Extra    map[string]string `json:"extra,omitempty"`    // 平台特有的其他字段
+}
+
+// Result 一次抓取的结果
+type Result struct {
+	Source    string    `json:"source"`    // 数据源路由名称
+	FetchedAt time.Time `json:"fetchedAt"` // 抓取时间
+	Items     []Item    `json:"items"`
+	Stale     bool      `json:"stale,omitempty"`    // 是否为上游失败或超时时返回的已保存快照
+	Degraded  bool      `json:"degraded,omitempty"` // 是否未通过结果检查，可能是页面结构变化导致解析错误
+	Problems  []string  `json:"problems,omitempty"` // 结果检查发现的问题
+}
+
+// Response 单个数据源的响应格式，HTTP、WebSocket 和 MCP 均使用该格式输出
+type Response struct {
+	Code      int        `json:"code"`
+	Message   string     `json:"message"`
+	Icon      string     `json:"icon,omitempty"`
+	FetchedAt *time.Time `json:"fetchedAt,omitempty"`
+	Stale     bool       `json:"stale,omitempty"`    // 是否为已保存的快照
+	Age       *int64     `json:"age,omitempty"`      // 快照距今的秒数，仅在 stale 时返回
+	Degraded  bool       `json:"degraded,omitempty"` // 是否未通过结果检查
+	Problems  []string   `json:"problems,omitempty"` // 结果检查发现的问题
+	Obj       []Item     `json:"obj"`
+}
+
+// NewResult 创建以当前时间为抓取时间的结果
+//
+// 没有设置热度数值的条目会从热度展示文本中解析
+func NewResult(source string, items []Item) *Result {
+	for i := range items {
+		if items[i].HotScore == 0 && items[i].HotValue != "" {
+			items[i].HotScore = ParseHotValue(items[i].HotValue)
+		}
+	}
+	return &Result{
+		Source:    source,
+		FetchedAt: time.Now(),
+		Items:     items,
+	}
+}
+
+// Response 将抓取结果转换为响应格式，图标取自数据源注册表
+func (r *Result) Response() Response {
+	response := Response{
+		Code:    200,
+		Message: r.Source,
+		Obj:     r.Items,
+	}
+	if source, ok := LookupSource(r.Source); ok {
+		response.Icon = source.Icon
+	}
+	if !r.FetchedAt.IsZero() {
+		fetchedAt := r.FetchedAt
+		response.FetchedAt = &fetchedAt
+	}
+	if r.Stale {
+		age := r.Age()
+		response.Stale = true
+		response.Age = &age
+	}
+	if r.Degraded {
+		response.Degraded = true
+		response.Problems = r.Problems
+	}
+	if response.Obj == nil {
+		response.Obj = []Item{}
+	}
+	return response
+}
+
+// Age 获取结果距抓取时间的秒数
+func (r *Result) Age() int64 {
+	if r.FetchedAt.IsZero() {
+		return 0
+	}
+	return int64(time.Since(r.FetchedAt) / time.Second)
+}
+
+// hotValueUnits 热度展示文本中的中文数量单位
+var hotValueUnits = []struct {
+	suffix string
+	scale  float64
+}{
+	{"亿", 1e8},
+	{"万", 1e4},
+	{"千", 1e3},
+}
+
+// ParseHotValue 将热度展示文本解析为数值，如 "123.4万" 解析为 1234000，无法解析时返回 0
+func ParseHotValue(value string) float64 {
+	value = strings.ReplaceAll(strings.TrimSpace(value), ",", "")
+
+	// 去掉数字前后的说明文字，如 "热度 123万"、"123万热度"
+	start := strings.IndexAny(value, "0123456789")
+	if start < 0 {
+		return 0
+	}
+	value = value[start:]
+	end := 0
+	for end < len(value) && (value[end] == '.' || value[end] >= '0' && value[end] <= '9') {
+		end++
+	}
+
+	number, err := strconv.ParseFloat(value[:end], 64)
+	if err != nil {
+		return 0
+	}
+	rest := strings.TrimSpace(value[end:])
+	for _, unit := range hotValueUnits {
+		if strings.HasPrefix(rest, unit.suffix) {
+			return number * unit.scale
+		}
+	}
+	return number
+}
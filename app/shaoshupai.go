@@ -1,11 +1,12 @@
 package app
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"time"
 )
 
 type sspResponse struct {
@@ -16,6 +17,22 @@ type sspData struct {
 	ID    int    `json:"id"`
 }
 
+func init() {
+	Register(Source{
+		RouteName: "shaoshupai",
+		Name:      "少数派",
+		Icon:      "https://cdn-static.sspai.com/favicon/sspai.ico",
+		Category:  CategoryTech,
+		Fetch:     Shaoshupai,
+		// 接口一次返回所有热门文章，不限制条目数上限
+		Expect: &Expectations{
+			MinItems:          DefaultExpectations.MinItems,
+			MaxInvalidRatio:   DefaultExpectations.MaxInvalidRatio,
+			MaxDuplicateRatio: DefaultExpectations.MaxDuplicateRatio,
+		},
+	})
+}
+
 // Shaoshupai 获取少数派热搜数据
 //
 //	@Summary		获取少数派热搜数据
@@ -26,13 +43,9 @@ type sspData struct {
 //	@Success		200	{object}	map[string]interface{}
 //	@Failure		500	{object}	map[string]interface{}
 //	@Router			/shaoshupai [get]
-func Shaoshupai() (map[string]interface{}, error) {
-	// 创建带超时的 HTTP 客户端
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
+func Shaoshupai(ctx context.Context, f *Fetcher) (*Result, error) {
 	url := "https://sspai.com/api/v1/article/tag/page/get?limit=100000&tag=%E7%83%AD%E9%97%A8%E6%96%87%E7%AB%A0"
-	resp, err := client.Get(url)
+	resp, err := f.Get(ctx, url)
 	if err != nil {
 		return nil, fmt.Errorf("http.Get error: %w", err)
 	}
@@ -53,28 +66,17 @@ func Shaoshupai() (map[string]interface{}, error) {
 	}
 	// 检查数据是否为空
 	if len(resultMap.Data) == 0 {
-		return map[string]interface{}{
-			"code":    500,
-			"message": "API返回数据为空",
-			"icon":    "https://cdn-static.sspai.com/favicon/sspai.ico",
-			"obj":     []map[string]interface{}{},
-		}, nil
+		return nil, errors.New("API返回数据为空")
 	}
 
 	data := resultMap.Data
-	var obj []map[string]interface{}
+	var items []Item
 	for index, item := range data {
-		obj = append(obj, map[string]interface{}{
-			"index": index + 1,
-			"title": item.Title,
-			"url":   "https://sspai.com/post/" + fmt.Sprint(item.ID),
+		items = append(items, Item{
+			Index: index + 1,
+			Title: item.Title,
+			URL:   "https://sspai.com/post/" + fmt.Sprint(item.ID),
 		})
 	}
-	api := map[string]interface{}{
-		"code":    200,
-		"message": "shaoshupai",
-		"icon":    "https://cdn-static.sspai.com/favicon/sspai.ico", // 64 x 64
-		"obj":     obj,
-	}
-	return api, nil
+	return NewResult("shaoshupai", items), nil
 }
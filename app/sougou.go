@@ -2,12 +2,23 @@ package app
 
 import (
 	"api/utils"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"time"
 )
 
+func init() {
+	Register(Source{
+		RouteName: "sougou",
+		Name:      "搜狗",
+		Icon:      "https://www.sogou.com/favicon.ico",
+		Category:  CategorySearch,
+		Fetch:     Sougou,
+	})
+}
+
 // Sougou 获取搜狗热搜数据
 //
 //	@Summary		获取搜狗热搜数据
@@ -18,22 +29,17 @@ import (
 //	@Success		200	{object}	map[string]interface{}
 //	@Failure		500	{object}	map[string]interface{}
 //	@Router			/sougou [get]
-func Sougou() (map[string]interface{}, error) {
-	// 创建带超时的 HTTP 客户端
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
+func Sougou(ctx context.Context, f *Fetcher) (*Result, error) {
 	url := "https://www.sogou.com/web?query=%E6%90%9C%E7%8B%97%E7%83%AD%E6%90%9C"
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := f.NewRequest(ctx, url)
 	if err != nil {
 		return nil, fmt.Errorf("http.NewRequest error: %w", err)
 	}
 	// 设置请求头
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/121.0.0.0 Safari/537.36")
 	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/webp,*/*;q=0.8")
 	req.Header.Set("Accept-Language", "zh-CN,zh;q=0.9,en;q=0.8")
 
-	resp, err := client.Do(req)
+	resp, err := f.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("http.Client.Do error: %w", err)
 	}
@@ -52,27 +58,16 @@ func Sougou() (map[string]interface{}, error) {
 	matched := utils.ExtractMatches(string(pageBytes), pattern)
 	// 检查是否匹配到数据
 	if len(matched) == 0 {
-		return map[string]interface{}{
-			"code":    500,
-			"message": "未匹配到数据，可能页面结构已变更",
-			"icon":    "https://www.sogou.com/favicon.ico",
-			"obj":     []map[string]interface{}{},
-		}, nil
+		return nil, errors.New("未匹配到数据，可能页面结构已变更")
 	}
-	var obj []map[string]interface{}
+	var items []Item
 	for index, item := range matched {
-		obj = append(obj, map[string]interface{}{
-			"index":    index + 1,
-			"title":    item[2],
-			"url":      item[1],
-			"hotValue": item[3],
+		items = append(items, Item{
+			Index:    index + 1,
+			Title:    item[2],
+			URL:      item[1],
+			HotValue: item[3],
 		})
 	}
-	api := map[string]interface{}{
-		"code":    200,
-		"message": "sougou",
-		"icon":    "https://www.sogou.com/favicon.ico", // 32 x 32
-		"obj":     obj,
-	}
-	return api, nil
+	return NewResult("sougou", items), nil
 }
@@ -1,15 +1,15 @@
 package app
 
 import (
+	"api/logging"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strconv"
 	"sync"
-	"time"
-
-	"github.com/gofiber/fiber/v2/log"
 )
 
 type souhuResponse struct {
@@ -21,14 +21,9 @@ type newsArticles struct {
 	Hot   string `json:"score"`
 }
 
-func fetchSouhuPage(page int) ([]newsArticles, error) {
-	// 创建带超时的 HTTP 客户端
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-
+func fetchSouhuPage(ctx context.Context, f *Fetcher, page int) ([]newsArticles, error) {
 	url := fmt.Sprintf("https://3g.k.sohu.com/api/channel/hotchart/hotnews.go?p1=NjY2NjY2&page=%d", page)
-	resp, err := client.Get(url)
+	resp, err := f.Get(ctx, url)
 	if err != nil {
 		return nil, fmt.Errorf("fetch page %d: http.Get error: %w", page, err)
 	}
@@ -53,6 +48,16 @@ func fetchSouhuPage(page int) ([]newsArticles, error) {
 	return resultMap.Data, nil
 }
 
+func init() {
+	Register(Source{
+		RouteName: "souhu",
+		Name:      "搜狐",
+		Icon:      "https://m.sohu.com/favicon.ico",
+		Category:  CategoryNews,
+		Fetch:     Souhu,
+	})
+}
+
 // Souhu 获取搜狐热搜数据
 //
 //	@Summary		获取搜狐热搜数据
@@ -63,69 +68,61 @@ func fetchSouhuPage(page int) ([]newsArticles, error) {
 //	@Success		200	{object}	map[string]interface{}
 //	@Failure		500	{object}	map[string]interface{}
 //	@Router			/souhu [get]
-func Souhu() (map[string]interface{}, error) {
-	var wordList []newsArticles
+func Souhu(ctx context.Context, f *Fetcher) (*Result, error) {
+	const pageCount = 2
 	var wg sync.WaitGroup
 	var mutex sync.Mutex
 	var fetchErrors []error
+	// 按页保存结果，保证合并后的顺序与页码一致
+	pages := make([][]newsArticles, pageCount)
 
-	// 并发获取两个页面的数据
-	for i := 1; i <= 2; i++ {
+	for i := 1; i <= pageCount; i++ {
 		wg.Add(1)
 		go func(page int) {
 			defer wg.Done()
-			data, err := fetchSouhuPage(page)
+			data, err := fetchSouhuPage(ctx, f, page)
 			if err != nil {
 				mutex.Lock()
 				fetchErrors = append(fetchErrors, err)
 				mutex.Unlock()
 				return
 			}
-			mutex.Lock()
-			wordList = append(wordList, data...)
-			mutex.Unlock()
+			pages[page-1] = data
 		}(i)
 	}
 
 	wg.Wait()
 
-	// 如果有错误发生，检查是否至少获取到一些数据
+	var wordList []newsArticles
+	for _, data := range pages {
+		wordList = append(wordList, data...)
+	}
+
 	if len(fetchErrors) > 0 {
 		// 如果完全没有获取到数据，返回错误
 		if len(wordList) == 0 {
 			return nil, fmt.Errorf("获取数据失败: %v", fetchErrors)
 		}
 		// 如果获取到部分数据，继续处理但记录错误
-		log.Info("部分页面获取失败，但继续处理已获取的数据: %v", fetchErrors)
+		logging.FromContext(ctx).Warn("部分页面获取失败，但继续处理已获取的数据", "errors", fetchErrors)
 	}
 	// 检查数据是否为空
 	if len(wordList) == 0 {
-		return map[string]interface{}{
-			"code":    500,
-			"message": "API返回数据为空",
-			"icon":    "https://3g.k.sohu.com/favicon.ico",
-			"obj":     []map[string]interface{}{},
-		}, nil
+		return nil, errors.New("API返回数据为空")
 	}
-	var obj []map[string]interface{}
+	var items []Item
 	for index, item := range wordList {
 		hotValue, err := strconv.ParseFloat(item.Hot, 64)
 		if err != nil {
 			hotValue = 0 // 如果解析失败，设置为0
 		}
-		obj = append(obj, map[string]interface{}{
-			"index":    index + 1,
-			"title":    item.Title,
-			"url":      item.URL,
-			"hotValue": fmt.Sprintf("%.2f万", hotValue),
+		items = append(items, Item{
+			Index:    index + 1,
+			Title:    item.Title,
+			URL:      item.URL,
+			HotValue: fmt.Sprintf("%.2f万", hotValue),
 		})
 	}
 
-	api := map[string]interface{}{
-		"code":    200,
-		"message": "souhu",
-		"icon":    "https://3g.k.sohu.com/favicon.ico", // 48 x 48
-		"obj":     obj,
-	}
-	return api, nil
+	return NewResult("souhu", items), nil
 }
@@ -1,12 +1,13 @@
 package app
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strconv"
-	"time"
 )
 
 type ttResponse struct {
@@ -18,6 +19,18 @@ type ttData struct {
 	HotValue string `json:"HotValue"`
 }
 
+func init() {
+	Register(Source{
+		RouteName: "toutiao",
+		Name:      "今日头条",
+		Icon:      "https://sf3-cdn-tos.douyinstatic.com/obj/eden-cn/uhbfnupkbps/toutiao_favicon.ico",
+		Category:  CategoryNews,
+		Fetch:     Toutiao,
+		// 热榜 50 条
+		Expect: itemRange(30, 60),
+	})
+}
+
 // Toutiao 获取今日头条热搜数据
 //
 //	@Summary		获取今日头条热搜数据
@@ -28,13 +41,9 @@ type ttData struct {
 //	@Success		200	{object}	map[string]interface{}
 //	@Failure		500	{object}	map[string]interface{}
 //	@Router			/toutiao [get]
-func Toutiao() (map[string]interface{}, error) {
-	// 创建带超时的 HTTP 客户端
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
+func Toutiao(ctx context.Context, f *Fetcher) (*Result, error) {
 	url := "https://www.toutiao.com/hot-event/hot-board/?origin=toutiao_pc"
-	resp, err := client.Get(url)
+	resp, err := f.Get(ctx, url)
 	if err != nil {
 		return nil, fmt.Errorf("http.Get error: %w", err)
 	}
@@ -55,16 +64,11 @@ func Toutiao() (map[string]interface{}, error) {
 	}
 	// 检查数据是否为空
 	if len(resultMap.Data) == 0 {
-		return map[string]interface{}{
-			"code":    500,
-			"message": "API返回数据为空",
-			"icon":    "https://lf3-static.bytednsdoc.com/obj/eden-cn/pipieh7nupabozups/toutiao_web_pc/tt-icon.png",
-			"obj":     []map[string]interface{}{},
-		}, nil
+		return nil, errors.New("API返回数据为空")
 	}
 
 	data := resultMap.Data
-	var obj []map[string]interface{}
+	var items []Item
 	for index, item := range data {
 		parsedHot, err := strconv.ParseFloat(item.HotValue, 64)
 		hot := 0.0
@@ -74,18 +78,13 @@ func Toutiao() (map[string]interface{}, error) {
 		} else {
 			hot = parsedHot
 		}
-		obj = append(obj, map[string]interface{}{
-			"index":    index + 1,
-			"title":    item.Title,
-			"url":      item.URL,
-			"hotValue": fmt.Sprintf("%.1f万", hot/10000),
+		items = append(items, Item{
+			Index:    index + 1,
+			Title:    item.Title,
+			URL:      item.URL,
+			HotValue: fmt.Sprintf("%.1f万", hot/10000),
+			HotScore: hot,
 		})
 	}
-	api := map[string]interface{}{
-		"code":    200,
-		"message": "toutiao",
-		"icon":    "https://lf3-static.bytednsdoc.com/obj/eden-cn/pipieh7nupabozups/toutiao_web_pc/tt-icon.png", // 144 x 144
-		"obj":     obj,
-	}
-	return api, nil
+	return NewResult("toutiao", items), nil
 }
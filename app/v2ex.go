@@ -2,12 +2,23 @@ package app
 
 import (
 	"api/utils"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"time"
 )
 
+func init() {
+	Register(Source{
+		RouteName: "v2ex",
+		Name:      "V2EX",
+		Icon:      "https://www.v2ex.com/static/favicon.ico",
+		Category:  CategoryTech,
+		Fetch:     V2ex,
+	})
+}
+
 // V2ex 获取V2EX热搜数据
 //
 //	@Summary		获取V2EX热搜数据
@@ -18,13 +29,9 @@ import (
 //	@Success		200	{object}	map[string]interface{}
 //	@Failure		500	{object}	map[string]interface{}
 //	@Router			/v2ex [get]
-func V2ex() (map[string]interface{}, error) {
-	// 创建带超时的 HTTP 客户端
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
+func V2ex(ctx context.Context, f *Fetcher) (*Result, error) {
 	url := "https://www.v2ex.com"
-	resp, err := client.Get(url)
+	resp, err := f.Get(ctx, url)
 	if err != nil {
 		return nil, fmt.Errorf("http.Get error: %w", err)
 	}
@@ -41,27 +48,16 @@ func V2ex() (map[string]interface{}, error) {
 	matched := utils.ExtractMatches(string(pageBytes), pattern)
 	// 检查是否匹配到数据
 	if len(matched) == 0 {
-		return map[string]interface{}{
-			"code":    500,
-			"message": "未匹配到数据，可能页面结构已变更",
-			"icon":    "https://www.v2ex.com/static/img/icon_rayps_64.png",
-			"obj":     []map[string]interface{}{},
-		}, nil
+		return nil, errors.New("未匹配到数据，可能页面结构已变更")
 	}
 
-	var obj []map[string]interface{}
+	var items []Item
 	for index, item := range matched {
-		result := make(map[string]interface{})
-		result["index"] = index + 1
-		result["title"] = item[2]
-		result["url"] = url + item[1]
-		obj = append(obj, result)
-	}
-	api := map[string]interface{}{
-		"code":    200,
-		"message": "V2EX",
-		"icon":    "https://www.v2ex.com/static/img/icon_rayps_64.png", // 64 x 64
-		"obj":     obj,
+		items = append(items, Item{
+			Index: index + 1,
+			Title: item[2],
+			URL:   url + item[1],
+		})
 	}
-	return api, nil
+	return NewResult("v2ex", items), nil
 }
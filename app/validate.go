@@ -0,0 +1,107 @@
+package app
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Expectations 数据源抓取结果的预期，用于发现页面结构变化导致的解析错误
+type Expectations struct {
+	MinItems          int     // 最少条目数
+	MaxItems          int     // 最多条目数，0 表示不限制
+	MaxInvalidRatio   float64 // 标题为空、标题含有HTML或链接无效的条目的最大比例
+	MaxDuplicateRatio float64 // 标题或链接重复的条目的最大比例
+}
+
+// DefaultExpectations 未单独设置预期的数据源使用的默认值
+var DefaultExpectations = Expectations{
+	MinItems:          5,
+	MaxItems:          500,
+	MaxInvalidRatio:   0.1,
+	MaxDuplicateRatio: 0.2,
+}
+
+// itemRange 返回条目数在 minItems 到 maxItems 之间、其他检查使用默认值的预期
+//
+// 榜单长度固定的数据源应该设置较窄的范围，正则只匹配到一部分条目时也能发现
+func itemRange(minItems, maxItems int) *Expectations {
+	expect := DefaultExpectations
+	expect.MinItems = minItems
+	expect.MaxItems = maxItems
+	return &expect
+}
+
+// htmlTagPattern 匹配标题中残留的HTML标签，正则解析的页面结构变化时容易截取到标签片段
+var htmlTagPattern = regexp.MustCompile(`</?[a-zA-Z][a-zA-Z0-9]*(\s[^>]*)?>|</[a-zA-Z]`)
+
+// expectations 获取数据源的预期，未设置时使用默认值
+func (s Source) expectations() Expectations {
+	if s.Expect != nil {
+		return *s.Expect
+	}
+	return DefaultExpectations
+}
+
+// Validate 按预期检查抓取结果，返回发现的问题，没有问题时返回 nil
+func Validate(result *Result, expect Expectations) []string {
+	var problems []string
+	count := len(result.Items)
+	if count < expect.MinItems {
+		problems = append(problems, fmt.Sprintf("条目数 %d 少于预期的 %d", count, expect.MinItems))
+	}
+	if expect.MaxItems > 0 && count > expect.MaxItems {
+		problems = append(problems, fmt.Sprintf("条目数 %d 多于预期的 %d", count, expect.MaxItems))
+	}
+	if count == 0 {
+		return problems
+	}
+
+	var emptyTitles, htmlTitles, invalidURLs, duplicates int
+	titles := make(map[string]bool, count)
+	urls := make(map[string]bool, count)
+	for _, item := range result.Items {
+		title := strings.TrimSpace(item.Title)
+		switch {
+		case title == "":
+			emptyTitles++
+		case htmlTagPattern.MatchString(title):
+			htmlTitles++
+		}
+		if !isAbsoluteURL(item.URL) {
+			invalidURLs++
+		}
+
+		// 标题或链接与前面的条目相同都算重复
+		duplicated := title != "" && titles[title] || item.URL != "" && urls[item.URL]
+		if duplicated {
+			duplicates++
+		}
+		titles[title] = true
+		urls[item.URL] = true
+	}
+
+	ratio := func(n int) float64 {
+		return float64(n) / float64(count)
+	}
+	if n := emptyTitles + htmlTitles; ratio(n) > expect.MaxInvalidRatio {
+		problems = append(problems, fmt.Sprintf("%d 条标题为空、%d 条标题含有HTML", emptyTitles, htmlTitles))
+	}
+	if ratio(invalidURLs) > expect.MaxInvalidRatio {
+		problems = append(problems, fmt.Sprintf("%d 条链接不是有效的绝对地址", invalidURLs))
+	}
+	if ratio(duplicates) > expect.MaxDuplicateRatio {
+		problems = append(problems, fmt.Sprintf("%d 条标题或链接重复", duplicates))
+	}
+	return problems
+}
+
+// isAbsoluteURL 判断链接是否为有效的 http 或 https 绝对地址
+func isAbsoluteURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
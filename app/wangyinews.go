@@ -2,13 +2,24 @@ package app
 
 import (
 	"api/utils"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strconv"
-	"time"
 )
 
+func init() {
+	Register(Source{
+		RouteName: "wangyinews",
+		Name:      "网易新闻",
+		Icon:      "https://news.163.com/favicon.ico",
+		Category:  CategoryNews,
+		Fetch:     WangyiNews,
+	})
+}
+
 // WangyiNews 获取网易新闻热搜数据
 //
 //	@Summary		获取网易新闻热搜数据
@@ -19,13 +30,9 @@ import (
 //	@Success		200	{object}	map[string]interface{}
 //	@Failure		500	{object}	map[string]interface{}
 //	@Router			/wangyinews [get]
-func WangyiNews() (map[string]interface{}, error) {
-	// 创建带超时的 HTTP 客户端
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
+func WangyiNews(ctx context.Context, f *Fetcher) (*Result, error) {
 	url := "https://news.163.com/"
-	resp, err := client.Get(url)
+	resp, err := f.Get(ctx, url)
 	if err != nil {
 		return nil, fmt.Errorf("http.Get error: %w", err)
 	}
@@ -45,15 +52,10 @@ func WangyiNews() (map[string]interface{}, error) {
 	matched := utils.ExtractMatches(string(pageBytes), pattern)
 	// 检查是否匹配到数据
 	if len(matched) == 0 {
-		return map[string]interface{}{
-			"code":    500,
-			"message": "未匹配到数据，可能页面结构已变更",
-			"icon":    "https://news.163.com/favicon.ico",
-			"obj":     []map[string]interface{}{},
-		}, nil
+		return nil, errors.New("未匹配到数据，可能页面结构已变更")
 	}
 
-	var obj []map[string]interface{}
+	var items []Item
 	for index, item := range matched {
 		hot, err := strconv.ParseFloat(item[3], 64)
 		if err != nil {
@@ -62,18 +64,13 @@ func WangyiNews() (map[string]interface{}, error) {
 			// 或者使用日志记录错误但不中断程序
 			// log.Printf("parse hot value error for item %s: %v", title, err)
 		}
-		obj = append(obj, map[string]interface{}{
-			"index":    index + 1,
-			"title":    item[2],
-			"url":      item[1],
-			"hotValue": fmt.Sprintf("%.1f万", hot/10000),
+		items = append(items, Item{
+			Index:    index + 1,
+			Title:    item[2],
+			URL:      item[1],
+			HotValue: fmt.Sprintf("%.1f万", hot/10000),
+			HotScore: hot,
 		})
 	}
-	api := map[string]interface{}{
-		"code":    200,
-		"message": "wangyinews",
-		"icon":    "https://news.163.com/favicon.ico", // 16 x 16
-		"obj":     obj,
-	}
-	return api, nil
+	return NewResult("wangyinews", items), nil
 }
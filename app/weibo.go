@@ -2,14 +2,27 @@ package app
 
 import (
 	"api/utils"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"regexp"
 	"strings"
-	"time"
 )
 
+func init() {
+	Register(Source{
+		RouteName: "weibo",
+		Name:      "微博",
+		Icon:      "https://weibo.com/favicon.ico",
+		Category:  CategorySocial,
+		Fetch:     WeiboHot,
+		// 热搜榜 50 条，加上置顶和推广条目
+		Expect: itemRange(30, 60),
+	})
+}
+
 // WeiboHot 获取微博热搜数据
 //
 //	@Summary		获取微博热搜数据
@@ -20,24 +33,19 @@ import (
 //	@Success		200	{object}	map[string]interface{}
 //	@Failure		500	{object}	map[string]interface{}
 //	@Router			/weibo [get]
-func WeiboHot() (map[string]interface{}, error) {
-	// 创建带超时的 HTTP 客户端
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
+func WeiboHot(ctx context.Context, f *Fetcher) (*Result, error) {
 	url := "https://s.weibo.com/top/summary"
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := f.NewRequest(ctx, url)
 	if err != nil {
 		return nil, fmt.Errorf("http.NewRequest error: %w", err)
 	}
 	// 设置请求头
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/121.0.0.0 Safari/537.36")
 	req.Header.Set("Cookie", "SUB=_2AkMasdasdqadTy2Pna4Rl77p7cJZAXC")
 	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/webp,*/*;q=0.8")
 	req.Header.Set("Accept-Language", "zh-CN,zh;q=0.9,en;q=0.8")
 	req.Header.Set("Referer", "https://s.weibo.com/")
 
-	resp, err := client.Do(req)
+	resp, err := f.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("http.Client.Do error: %w", err)
 	}
@@ -54,7 +62,7 @@ func WeiboHot() (map[string]interface{}, error) {
 	pageContent := string(pageBytes)
 
 	// 方法1：使用正则表达式提取热搜数据
-	var obj []map[string]interface{}
+	var items []Item
 
 	// 正则表达式匹配热搜条目
 	pattern := `<a href="(/weibo\?q=[^"]+)"[^>]*target="_blank">([^<]+)</a>\s*<span>([^<]*)?</span>`
@@ -75,40 +83,29 @@ func WeiboHot() (map[string]interface{}, error) {
 				hotValue = strings.TrimSpace(nonDigitRegexp.ReplaceAllString(hotValue, ""))
 			}
 
-			obj = append(obj, map[string]interface{}{
-				"index":    index + 1,
-				"title":    title,
-				"url":      url,
-				"hotValue": hotValue,
+			items = append(items, Item{
+				Index:    index + 1,
+				Title:    title,
+				URL:      url,
+				HotValue: hotValue,
 			})
 		}
 	}
 
 	// 如果正则匹配失败，尝试备用方法
-	if len(obj) == 0 {
-		obj = extractWeiboHotSearchFallback(pageContent)
+	if len(items) == 0 {
+		items = extractWeiboHotSearchFallback(pageContent)
 	}
 	// 检查是否获取到数据
-	if len(obj) == 0 {
-		return map[string]interface{}{
-			"code":    500,
-			"message": "无法提取热搜数据，页面结构可能已变更",
-			"icon":    "https://weibo.com/favicon.ico",
-			"obj":     []map[string]interface{}{},
-		}, nil
-	}
-	api := map[string]interface{}{
-		"code":    200,
-		"message": "weibo",
-		"icon":    "https://weibo.com/favicon.ico",
-		"obj":     obj,
+	if len(items) == 0 {
+		return nil, errors.New("无法提取热搜数据，页面结构可能已变更")
 	}
-	return api, nil
+	return NewResult("weibo", items), nil
 }
 
 // 备用提取方法
-func extractWeiboHotSearchFallback(content string) []map[string]interface{} {
-	var obj []map[string]interface{}
+func extractWeiboHotSearchFallback(content string) []Item {
+	var items []Item
 
 	// 尝试匹配更简单的模式
 	patterns := []string{
@@ -123,18 +120,18 @@ func extractWeiboHotSearchFallback(content string) []map[string]interface{} {
 				title := strings.TrimSpace(item[2])
 				url := "https://s.weibo.com" + item[1]
 
-				obj = append(obj, map[string]interface{}{
-					"index":    index + 1,
-					"title":    title,
-					"url":      url,
-					"hotValue": "", // 备用方法可能无法获取热度值
+				// 备用方法可能无法获取热度值
+				items = append(items, Item{
+					Index: index + 1,
+					Title: title,
+					URL:   url,
 				})
 			}
 		}
-		if len(obj) > 0 {
+		if len(items) > 0 {
 			break
 		}
 	}
 
-	return obj
+	return items
 }
@@ -2,12 +2,23 @@ package app
 
 import (
 	"api/utils"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"time"
 )
 
+func init() {
+	Register(Source{
+		RouteName: "xinjingbao",
+		Name:      "新京报",
+		Icon:      "https://www.bjnews.com.cn/favicon.ico",
+		Category:  CategoryNews,
+		Fetch:     Xinjingbao,
+	})
+}
+
 // Xinjingbao 获取新京报热搜数据
 //
 //	@Summary		获取新京报热搜数据
@@ -18,14 +29,9 @@ import (
 //	@Success		200	{object}	map[string]interface{}
 //	@Failure		500	{object}	map[string]interface{}
 //	@Router			/xinjingbao [get]
-func Xinjingbao() (map[string]interface{}, error) {
-	// 创建带超时的 HTTP 客户端
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-
+func Xinjingbao(ctx context.Context, f *Fetcher) (*Result, error) {
 	url := "https://www.bjnews.com.cn/"
-	resp, err := client.Get(url)
+	resp, err := f.Get(ctx, url)
 	if err != nil {
 		return nil, fmt.Errorf("http.Get error: %w", err)
 	}
@@ -42,28 +48,17 @@ func Xinjingbao() (map[string]interface{}, error) {
 	matched := utils.ExtractMatches(string(pageBytes), pattern)
 	// 检查是否匹配到数据
 	if len(matched) == 0 {
-		return map[string]interface{}{
-			"code":    500,
-			"message": "未匹配到数据，可能页面结构已变更",
-			"icon":    "https://www.bjnews.com.cn/favicon.ico",
-			"obj":     []map[string]interface{}{},
-		}, nil
+		return nil, errors.New("未匹配到数据，可能页面结构已变更")
 	}
 
-	var obj []map[string]interface{}
+	var items []Item
 	for index, item := range matched {
-		obj = append(obj, map[string]interface{}{
-			"index":    index + 1,
-			"title":    item[2],
-			"url":      item[1],
-			"hotValue": item[3],
+		items = append(items, Item{
+			Index:    index + 1,
+			Title:    item[2],
+			URL:      item[1],
+			HotValue: item[3],
 		})
 	}
-	api := map[string]interface{}{
-		"code":    200,
-		"message": "xinjingbao",
-		"icon":    "https://www.bjnews.com.cn/favicon.ico", // 20 x 20
-		"obj":     obj,
-	}
-	return api, nil
+	return NewResult("xinjingbao", items), nil
 }
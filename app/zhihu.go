@@ -1,11 +1,12 @@
 package app
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"time"
 )
 
 type zhResponse struct {
@@ -18,6 +19,16 @@ type zhData struct {
 	Title string `json:"query"`
 }
 
+func init() {
+	Register(Source{
+		RouteName: "zhihu",
+		Name:      "知乎",
+		Icon:      "https://static.zhihu.com/static/favicon.ico",
+		Category:  CategorySocial,
+		Fetch:     Zhihu,
+	})
+}
+
 // Zhihu 获取知乎热搜数据
 //
 //	@Summary		获取知乎热搜数据
@@ -28,25 +39,19 @@ type zhData struct {
 //	@Success		200	{object}	map[string]interface{}
 //	@Failure		500	{object}	map[string]interface{}
 //	@Router			/zhihu [get]
-func Zhihu() (map[string]interface{}, error) {
-	// 创建带超时的 HTTP 客户端
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-
+func Zhihu(ctx context.Context, f *Fetcher) (*Result, error) {
 	urlStr := "https://www.zhihu.com/api/v4/search/recommend_query/v2"
-	req, err := http.NewRequest("GET", urlStr, nil)
+	req, err := f.NewRequest(ctx, urlStr)
 	if err != nil {
 		return nil, fmt.Errorf("http.NewRequest error: %w", err)
 	}
 
 	// 设置请求头，模拟正常浏览器访问
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/121.0.0.0 Safari/537.36")
 	req.Header.Set("Accept", "application/json, text/plain, */*")
 	req.Header.Set("Accept-Language", "zh-CN,zh;q=0.9,en;q=0.8")
 	req.Header.Set("Referer", "https://www.zhihu.com/")
 
-	resp, err := client.Do(req)
+	resp, err := f.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("http.Client.Do error: %w", err)
 	}
@@ -69,28 +74,17 @@ func Zhihu() (map[string]interface{}, error) {
 	}
 	// 检查数据是否为空
 	if len(resultMap.Response.Data) == 0 {
-		return map[string]interface{}{
-			"code":    500,
-			"message": "API返回数据为空",
-			"icon":    "https://static.zhihu.com/static/favicon.ico",
-			"obj":     []map[string]interface{}{},
-		}, nil
+		return nil, errors.New("API返回数据为空")
 	}
 
 	data := resultMap.Response.Data
-	var obj []map[string]interface{}
+	var items []Item
 	for index, item := range data {
-		obj = append(obj, map[string]interface{}{
-			"index": index + 1,
-			"title": item.Title,
-			"url":   "https://www.zhihu.com/search?q=" + item.Title,
+		items = append(items, Item{
+			Index: index + 1,
+			Title: item.Title,
+			URL:   "https://www.zhihu.com/search?q=" + item.Title,
 		})
 	}
-	api := map[string]interface{}{
-		"code":    200,
-		"message": "zhihu",
-		"icon":    "https://static.zhihu.com/static/favicon.ico", // 32 x 32
-		"obj":     obj,
-	}
-	return api, nil
+	return NewResult("zhihu", items), nil
 }
@@ -2,93 +2,285 @@ package config
 
 import (
 	"fmt"
+	"maps"
 	"os"
-
-	"github.com/joho/godotenv"
+	"slices"
+	"time"
 )
 
 // CORSConfig CORS配置
 type CORSConfig struct {
-	AllowOrigins string // 允许的跨域请求来源
+	AllowOrigins string `yaml:"allow_origins"` // 允许的跨域请求来源
 }
 
 // Config 应用程序配置结构体
+//
+// 配置文件中的键名为字段的 yaml 标签，如 server.shutdown_timeout，时长使用 30s、5m 等格式
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	MCP      *MCPConfig
-	CORS     CORSConfig
-	Debug    bool
+	Server    ServerConfig    `yaml:"server"`
+	Database  DatabaseConfig  `yaml:"database"`
+	MCP       *MCPConfig      `yaml:"mcp"`
+	CORS      CORSConfig      `yaml:"cors"`
+	RateLimit RateLimitConfig `yaml:"rate_limit"`
+	Sources   SourcesConfig   `yaml:"sources"`
+	Retention RetentionConfig `yaml:"retention"`
+	Fetcher   FetcherConfig   `yaml:"fetcher"`
+	Schedule  ScheduleConfig  `yaml:"schedule"`
+	Live      LiveConfig      `yaml:"live"`
+	Cache     CacheConfig     `yaml:"cache"`
+	Log       LogConfig       `yaml:"log"`
+	Debug     bool            `yaml:"debug"`
 }
 
 // ServerConfig 服务器配置
 type ServerConfig struct {
-	Host        string
-	Port        string
-	TLSEnabled  bool   // 是否启用TLS/HTTPS
-	TLSCertFile string // TLS证书文件路径
-	TLSKeyFile  string // TLS私钥文件路径
+	Host        string `yaml:"host"`
+	Port        string `yaml:"port"`
+	TLSEnabled  bool   `yaml:"tls_enabled"`   // 是否启用TLS/HTTPS
+	TLSCertFile string `yaml:"tls_cert_file"` // TLS证书文件路径
+	TLSKeyFile  string `yaml:"tls_key_file"`  // TLS私钥文件路径
+
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"` // 收到退出信号后等待连接断开、定时抓取和数据库写入完成的最长时间
 }
 
 // DatabaseConfig 数据库配置
 type DatabaseConfig struct {
-	Type string // "sqlite" 或 "mysql"
-	DSN  string // 数据库连接字符串
+	Type string `yaml:"type"` // "sqlite" 或 "mysql"
+	DSN  string `yaml:"dsn"`  // 数据库连接字符串
+}
+
+// RateLimitConfig 接口限流配置，按客户端IP统计，调试模式下不限流
+type RateLimitConfig struct {
+	Enabled    bool          `yaml:"enabled"`    // 是否启用限流
+	Max        int           `yaml:"max"`        // 时间窗口内允许的最大请求数
+	Expiration time.Duration `yaml:"expiration"` // 时间窗口长度
+}
+
+// SourcesConfig 数据源配置
+type SourcesConfig struct {
+	Disabled []string `yaml:"disabled"` // 停用的数据源路由名称，停用后不再抓取
+}
+
+// Enabled 数据源是否启用
+func (c SourcesConfig) Enabled(source string) bool {
+	return !slices.Contains(c.Disabled, source)
+}
+
+// RetentionConfig 历史快照保留策略配置
+//
+// 默认策略：7天内每小时保留一次快照，7至90天内每天保留一次，超过90天的快照被删除
+type RetentionConfig struct {
+	Enabled    bool          `yaml:"enabled"`     // 是否启用快照清理任务
+	Interval   time.Duration `yaml:"interval"`    // 清理任务执行间隔
+	HourlyDays int           `yaml:"hourly_days"` // 按小时保留快照的天数，0 表示不设小时级保留
+	DailyDays  int           `yaml:"daily_days"`  // 按天保留快照的天数（从当前时间算起），0 表示永久保留
+}
+
+// ScheduleConfig 定时抓取配置
+type ScheduleConfig struct {
+	Interval time.Duration             `yaml:"interval"` // 默认抓取间隔
+	Jitter   time.Duration             `yaml:"jitter"`   // 默认随机延迟的上限，避免所有数据源同时请求
+	Sources  map[string]SourceSchedule `yaml:"sources"`  // 按数据源路由名称覆盖的抓取计划
+}
+
+// SourceSchedule 单个数据源的抓取计划，设置了 Cron 时忽略 Interval
+type SourceSchedule struct {
+	Interval time.Duration `yaml:"interval"` // 抓取间隔
+	Jitter   time.Duration `yaml:"jitter"`   // 每次抓取前随机延迟的上限
+	Cron     string        `yaml:"cron"`     // cron 表达式（分 时 日 月 周），如 "5 0 * * *"
+}
+
+// defaultSourceSchedules 内置的数据源抓取计划，变化快的榜单缩短间隔，历史上的今天每天抓取一次
+var defaultSourceSchedules = map[string]SourceSchedule{
+	"weibo":        {Interval: 10 * time.Minute, Jitter: 30 * time.Second},
+	"douyin":       {Interval: 10 * time.Minute, Jitter: 30 * time.Second},
+	"baidu":        {Interval: 15 * time.Minute, Jitter: time.Minute},
+	"zhihu":        {Interval: 15 * time.Minute, Jitter: time.Minute},
+	"toutiao":      {Interval: 15 * time.Minute, Jitter: time.Minute},
+	"historytoday": {Cron: "5 0 * * *"},
+}
+
+// For 获取指定数据源的抓取计划，未单独配置的项使用默认值
+func (c ScheduleConfig) For(source string) SourceSchedule {
+	schedule := c.Sources[source]
+	if schedule.Cron == "" && schedule.Interval <= 0 {
+		schedule.Interval = c.Interval
+	}
+	if schedule.Jitter <= 0 {
+		schedule.Jitter = c.Jitter
+	}
+	return schedule
+}
+
+// DefaultUserAgent 抓取数据时默认使用的 User-Agent
+const DefaultUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/121.0.0.0 Safari/537.36"
+
+// FetcherConfig 抓取数据源时使用的HTTP客户端配置
+type FetcherConfig struct {
+	Timeout             time.Duration            `yaml:"timeout"`                 // 单个数据源的默认超时时间
+	SourceTimeouts      map[string]time.Duration `yaml:"source_timeouts"`         // 按数据源路由名称覆盖的超时时间
+	AllTimeout          time.Duration            `yaml:"all_timeout"`             // 一次抓取所有数据源的总超时时间，超时后返回已完成的部分结果
+	UserAgent           string                   `yaml:"user_agent"`              // 请求未指定 User-Agent 时使用的默认值
+	Proxy               string                   `yaml:"proxy"`                   // 代理地址，如 http://127.0.0.1:7890，为空时使用 HTTP_PROXY 等环境变量
+	MaxIdleConnsPerHost int                      `yaml:"max_idle_conns_per_host"` // 每个主机保持的最大空闲连接数
+	BaseURL             string                   `yaml:"-"`                       // 将所有请求转发到该地址，路径为 /原始主机名/原始路径，用于测试时回放录制的数据
+	Retries             int                      `yaml:"retries"`                 // 请求遇到临时错误时的最大重试次数，0 表示不重试
+	RetryBackoff        time.Duration            `yaml:"retry_backoff"`           // 第一次重试前的等待时间，之后每次翻倍
+	RetryMaxBackoff     time.Duration            `yaml:"retry_max_backoff"`       // 重试等待时间的上限
+	BreakerThreshold    int                      `yaml:"breaker_threshold"`       // 数据源连续失败多少次后熔断，0 表示不熔断
+	BreakerCooldown     time.Duration            `yaml:"breaker_cooldown"`        // 熔断后等待多久再尝试探测数据源是否恢复
+	AlertWebhook        string                   `yaml:"alert_webhook"`           // 抓取结果开始或停止未通过检查时，以 JSON 格式 POST 告警的地址
+}
+
+// LiveConfig 实时接口的配置
+type LiveConfig struct {
+	StaleEnabled bool          `yaml:"stale_enabled"` // 上游失败或超时时是否返回数据库中保存的快照
+	StaleTimeout time.Duration `yaml:"stale_timeout"` // 等待上游返回的时间，超时后返回快照并在后台完成抓取
+	StaleMaxAge  time.Duration `yaml:"stale_max_age"` // 可以返回的快照的最大年龄，0 表示不限制
+}
+
+// CacheConfig 实时接口的内存缓存配置
+type CacheConfig struct {
+	Enabled    bool                     `yaml:"enabled"`     // 是否启用缓存
+	TTL        time.Duration            `yaml:"ttl"`         // 默认缓存时间
+	SourceTTLs map[string]time.Duration `yaml:"source_ttls"` // 按数据源路由名称覆盖的缓存时间，all 表示 /all 接口
+}
+
+// defaultCacheTTLs 内置的缓存时间，历史上的今天每天才变化一次
+var defaultCacheTTLs = map[string]time.Duration{
+	"historytoday": time.Hour,
+}
+
+// TTLFor 获取指定数据源的缓存时间，未启用缓存时为 0
+func (c CacheConfig) TTLFor(source string) time.Duration {
+	if !c.Enabled {
+		return 0
+	}
+	if ttl, ok := c.SourceTTLs[source]; ok && ttl > 0 {
+		return ttl
+	}
+	return c.TTL
+}
+
+// LogConfig 日志配置
+type LogConfig struct {
+	Level  string `yaml:"level"`  // 日志级别：debug、info、warn 或 error
+	Format string `yaml:"format"` // 日志格式：text 或 json
 }
 
 // MCPConfig MCP服务器配置
 type MCPConfig struct {
-	STDIOEnabled bool   // 是否启用STDIO MCP服务器
-	HTTPEnabled  bool   // 是否启用HTTP MCP服务器
-	Port         string // HTTP MCP服务器端口
+	STDIOEnabled bool   `yaml:"stdio_enabled"` // 是否启用STDIO MCP服务器
+	HTTPEnabled  bool   `yaml:"http_enabled"`  // 是否启用HTTP MCP服务器
+	Port         string `yaml:"port"`          // HTTP MCP服务器端口
 }
 
-// LoadConfig 从环境变量或.env文件加载配置
+// DefaultConfigFile 未设置 CONFIG_FILE 时尝试读取的配置文件，文件不存在时只使用环境变量
+const DefaultConfigFile = "config.yaml"
+
+// LoadConfig 加载配置
+//
+// 依次应用内置默认值、配置文件（CONFIG_FILE 指定，默认为 config.yaml）和环境变量（包括 .env 文件），
+// 环境变量优先于配置文件。配置文件或环境变量格式错误、配置项无效时返回错误
 func LoadConfig() (*Config, error) {
-	// 加载 .env 文件（如果存在）
-	godotenv.Load()
-
-	// 根据数据库类型设置默认DSN
-	dbType := getEnvOrDefault("DB_TYPE", "sqlite")
-	var defaultDSN string
-	if dbType == "sqlite" {
-		defaultDSN = getEnvOrDefault("SQLITE_DSN", "hot_search.db")
-	} else {
-		defaultDSN = getEnvOrDefault("MYSQL_DSN", "root:password@tcp(127.0.0.1:3306)/hot_search?charset=utf8mb4&parseTime=True&loc=Local")
+	// 加载 .env 文件（如果存在），重新加载配置时也会应用文件的修改
+	loadDotEnv()
+
+	config := Default()
+
+	path, required := os.Getenv("CONFIG_FILE"), true
+	if path == "" {
+		path, required = DefaultConfigFile, false
+	}
+	if err := config.loadFile(path, required); err != nil {
+		return nil, err
 	}
 
-	config := &Config{
+	if err := config.loadEnv(); err != nil {
+		return nil, err
+	}
+
+	// 未指定DSN时根据数据库类型使用默认值
+	if config.Database.DSN == "" {
+		if config.Database.Type == "mysql" {
+			config.Database.DSN = defaultMySQLDSN
+		} else {
+			config.Database.DSN = defaultSQLiteDSN
+		}
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// 默认的数据库连接字符串
+const (
+	defaultSQLiteDSN = "hot_search.db"
+	defaultMySQLDSN  = "root:password@tcp(127.0.0.1:3306)/hot_search?charset=utf8mb4&parseTime=True&loc=Local"
+)
+
+// Default 获取内置的默认配置，数据库连接字符串在加载时根据数据库类型确定
+func Default() *Config {
+	return &Config{
 		Server: ServerConfig{
-			Host:        getEnvOrDefault("SERVER_HOST", "localhost"),
-			Port:        getEnvOrDefault("SERVER_PORT", "8080"),
-			TLSEnabled:  getEnvOrDefault("TLS_ENABLED", "false") == "true",
-			TLSCertFile: getEnvOrDefault("TLS_CERT_FILE", ""),
-			TLSKeyFile:  getEnvOrDefault("TLS_KEY_FILE", ""),
+			Host:            "localhost",
+			Port:            "8080",
+			ShutdownTimeout: 15 * time.Second,
 		},
 		Database: DatabaseConfig{
-			Type: dbType,
-			DSN:  defaultDSN,
+			Type: "sqlite",
 		},
 		CORS: CORSConfig{
-			AllowOrigins: getEnvOrDefault("CORS_ALLOW_ORIGINS", "*"),
+			AllowOrigins: "*",
+		},
+		RateLimit: RateLimitConfig{
+			Enabled:    true,
+			Max:        20,
+			Expiration: 30 * time.Second,
+		},
+		Retention: RetentionConfig{
+			Enabled:    true,
+			Interval:   time.Hour,
+			HourlyDays: 7,
+			DailyDays:  90,
+		},
+		Fetcher: FetcherConfig{
+			Timeout:             10 * time.Second,
+			SourceTimeouts:      make(map[string]time.Duration),
+			AllTimeout:          30 * time.Second,
+			UserAgent:           DefaultUserAgent,
+			MaxIdleConnsPerHost: 10,
+			Retries:             2,
+			RetryBackoff:        500 * time.Millisecond,
+			RetryMaxBackoff:     5 * time.Second,
+			BreakerThreshold:    5,
+			BreakerCooldown:     5 * time.Minute,
+		},
+		Schedule: ScheduleConfig{
+			Interval: time.Hour,
+			Jitter:   time.Minute,
+			Sources:  maps.Clone(defaultSourceSchedules),
+		},
+		Live: LiveConfig{
+			StaleEnabled: true,
+			StaleTimeout: 3 * time.Second,
+			StaleMaxAge:  24 * time.Hour,
+		},
+		Cache: CacheConfig{
+			Enabled:    true,
+			TTL:        time.Minute,
+			SourceTTLs: maps.Clone(defaultCacheTTLs),
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "text",
 		},
 		MCP: &MCPConfig{
-			STDIOEnabled: getEnvOrDefault("MCP_STDIO_ENABLED", "false") == "true",
-			HTTPEnabled:  getEnvOrDefault("MCP_HTTP_ENABLED", "false") == "true",
-			Port:         getEnvOrDefault("MCP_PORT", "8081"),
+			Port: "8081",
 		},
-		Debug: getEnvOrDefault("DEBUG", "false") == "true",
-	}
-
-	return config, nil
-}
-
-// getEnvOrDefault 获取环境变量，如果不存在则返回默认值
-func getEnvOrDefault(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
 	}
-	return defaultValue
 }
 
 // GetServerAddress 获取服务器完整地址
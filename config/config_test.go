@@ -2,7 +2,9 @@ package config
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -14,8 +16,142 @@ func TestLoadConfig(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, "localhost", config.Server.Host)
 		assert.Equal(t, "8080", config.Server.Port)
+		assert.Equal(t, 15*time.Second, config.Server.ShutdownTimeout)
 		assert.Equal(t, "sqlite", config.Database.Type)
 		assert.Equal(t, "hot_search.db", config.Database.DSN)
+		assert.True(t, config.Retention.Enabled)
+		assert.Equal(t, time.Hour, config.Retention.Interval)
+		assert.Equal(t, 7, config.Retention.HourlyDays)
+		assert.Equal(t, 90, config.Retention.DailyDays)
+		assert.Equal(t, 10*time.Second, config.Fetcher.Timeout)
+		assert.Equal(t, 30*time.Second, config.Fetcher.AllTimeout)
+		assert.Equal(t, DefaultUserAgent, config.Fetcher.UserAgent)
+		assert.Empty(t, config.Fetcher.Proxy)
+		assert.Empty(t, config.Fetcher.SourceTimeouts)
+		assert.Equal(t, 2, config.Fetcher.Retries)
+		assert.Equal(t, 500*time.Millisecond, config.Fetcher.RetryBackoff)
+		assert.Equal(t, 5*time.Second, config.Fetcher.RetryMaxBackoff)
+		assert.Equal(t, 5, config.Fetcher.BreakerThreshold)
+		assert.Equal(t, 5*time.Minute, config.Fetcher.BreakerCooldown)
+		assert.Equal(t, time.Hour, config.Schedule.Interval)
+		assert.Equal(t, time.Minute, config.Schedule.Jitter)
+		assert.Equal(t, "5 0 * * *", config.Schedule.For("historytoday").Cron)
+		assert.True(t, config.Live.StaleEnabled)
+		assert.Equal(t, 3*time.Second, config.Live.StaleTimeout)
+		assert.Equal(t, 24*time.Hour, config.Live.StaleMaxAge)
+		assert.True(t, config.Cache.Enabled)
+		assert.Equal(t, time.Minute, config.Cache.TTLFor("weibo"))
+		assert.Equal(t, time.Hour, config.Cache.TTLFor("historytoday"))
+		assert.Equal(t, "info", config.Log.Level)
+		assert.Equal(t, "text", config.Log.Format)
+		assert.True(t, config.RateLimit.Enabled)
+		assert.Equal(t, 20, config.RateLimit.Max)
+		assert.Equal(t, 30*time.Second, config.RateLimit.Expiration)
+		assert.Empty(t, config.Sources.Disabled)
+	})
+
+	// 测试缓存配置环境变量
+	t.Run("CacheEnvConfig", func(t *testing.T) {
+		os.Setenv("CACHE_TTL", "30s")
+		os.Setenv("CACHE_SOURCE_TTLS", "all=2m,weibo=10s")
+		defer func() {
+			os.Unsetenv("CACHE_TTL")
+			os.Unsetenv("CACHE_SOURCE_TTLS")
+		}()
+
+		config, err := LoadConfig()
+		assert.NoError(t, err)
+		assert.Equal(t, 30*time.Second, config.Cache.TTLFor("zhihu"))
+		assert.Equal(t, 10*time.Second, config.Cache.TTLFor("weibo"))
+		assert.Equal(t, 2*time.Minute, config.Cache.TTLFor("all"))
+		assert.Equal(t, time.Hour, config.Cache.TTLFor("historytoday"))
+
+		// 未启用时不缓存
+		config.Cache.Enabled = false
+		assert.Equal(t, time.Duration(0), config.Cache.TTLFor("zhihu"))
+	})
+
+	// 测试定时抓取配置环境变量
+	t.Run("ScheduleEnvConfig", func(t *testing.T) {
+		os.Setenv("SCHEDULE_INTERVAL", "30m")
+		os.Setenv("SCHEDULE_SOURCES", "weibo=5m/10s; zhihu=2m;historytoday=cron:0 6 * * *;")
+		defer func() {
+			os.Unsetenv("SCHEDULE_INTERVAL")
+			os.Unsetenv("SCHEDULE_SOURCES")
+		}()
+
+		config, err := LoadConfig()
+		assert.NoError(t, err)
+		assert.Equal(t, SourceSchedule{Interval: 5 * time.Minute, Jitter: 10 * time.Second}, config.Schedule.For("weibo"))
+		// 未设置随机延迟时使用默认值
+		assert.Equal(t, SourceSchedule{Interval: 2 * time.Minute, Jitter: time.Minute}, config.Schedule.For("zhihu"))
+		assert.Equal(t, SourceSchedule{Cron: "0 6 * * *", Jitter: time.Minute}, config.Schedule.For("historytoday"))
+		// 内置计划仍然生效
+		assert.Equal(t, 10*time.Minute, config.Schedule.For("douyin").Interval)
+		// 未配置的数据源使用默认间隔
+		assert.Equal(t, SourceSchedule{Interval: 30 * time.Minute, Jitter: time.Minute}, config.Schedule.For("v2ex"))
+
+		// 格式错误的计划返回错误
+		os.Setenv("SCHEDULE_SOURCES", "weibo=5m;invalid;bad=abc")
+		_, err = LoadConfig()
+		assert.ErrorContains(t, err, `SCHEDULE_SOURCES="invalid"`)
+		assert.ErrorContains(t, err, `SCHEDULE_SOURCES="bad=abc"`)
+	})
+
+	// 测试抓取配置环境变量
+	t.Run("FetcherEnvConfig", func(t *testing.T) {
+		os.Setenv("FETCH_TIMEOUT", "5s")
+		os.Setenv("FETCH_SOURCE_TIMEOUTS", "weibo=3s, zhihu = 20s")
+		os.Setenv("FETCH_USER_AGENT", "azhot-test")
+		os.Setenv("FETCH_PROXY", "http://127.0.0.1:7890")
+		os.Setenv("FETCH_RETRIES", "0")
+		os.Setenv("FETCH_BREAKER_THRESHOLD", "3")
+		os.Setenv("FETCH_BREAKER_COOLDOWN", "1m")
+		defer func() {
+			os.Unsetenv("FETCH_TIMEOUT")
+			os.Unsetenv("FETCH_SOURCE_TIMEOUTS")
+			os.Unsetenv("FETCH_USER_AGENT")
+			os.Unsetenv("FETCH_PROXY")
+			os.Unsetenv("FETCH_RETRIES")
+			os.Unsetenv("FETCH_BREAKER_THRESHOLD")
+			os.Unsetenv("FETCH_BREAKER_COOLDOWN")
+		}()
+
+		config, err := LoadConfig()
+		assert.NoError(t, err)
+		assert.Equal(t, 5*time.Second, config.Fetcher.Timeout)
+		assert.Equal(t, map[string]time.Duration{"weibo": 3 * time.Second, "zhihu": 20 * time.Second}, config.Fetcher.SourceTimeouts)
+		assert.Equal(t, "azhot-test", config.Fetcher.UserAgent)
+		assert.Equal(t, "http://127.0.0.1:7890", config.Fetcher.Proxy)
+		assert.Equal(t, 0, config.Fetcher.Retries)
+		assert.Equal(t, 3, config.Fetcher.BreakerThreshold)
+		assert.Equal(t, time.Minute, config.Fetcher.BreakerCooldown)
+	})
+
+	// 测试保留策略环境变量
+	t.Run("RetentionEnvConfig", func(t *testing.T) {
+		os.Setenv("RETENTION_ENABLED", "false")
+		os.Setenv("RETENTION_INTERVAL", "30m")
+		os.Setenv("RETENTION_HOURLY_DAYS", "3")
+		os.Setenv("RETENTION_DAILY_DAYS", "30")
+		defer func() {
+			os.Unsetenv("RETENTION_ENABLED")
+			os.Unsetenv("RETENTION_INTERVAL")
+			os.Unsetenv("RETENTION_HOURLY_DAYS")
+			os.Unsetenv("RETENTION_DAILY_DAYS")
+		}()
+
+		config, err := LoadConfig()
+		assert.NoError(t, err)
+		assert.False(t, config.Retention.Enabled)
+		assert.Equal(t, 30*time.Minute, config.Retention.Interval)
+		assert.Equal(t, 3, config.Retention.HourlyDays)
+		assert.Equal(t, 30, config.Retention.DailyDays)
+
+		// 格式错误时返回错误
+		os.Setenv("RETENTION_DAILY_DAYS", "invalid")
+		_, err = LoadConfig()
+		assert.ErrorContains(t, err, `RETENTION_DAILY_DAYS="invalid" 不是有效的整数`)
 	})
 
 	// 测试环境变量配置
@@ -49,6 +185,256 @@ func TestLoadConfig(t *testing.T) {
 	})
 }
 
+// writeConfigFile 写入临时配置文件并通过 CONFIG_FILE 指定
+func writeConfigFile(t *testing.T, name, content string) {
+	path := filepath.Join(t.TempDir(), name)
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	t.Setenv("CONFIG_FILE", path)
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	// 测试从配置文件读取，未配置的项使用默认值
+	t.Run("FileConfig", func(t *testing.T) {
+		writeConfigFile(t, "config.yaml", `
+server:
+  port: 9090
+  shutdown_timeout: 30s
+database:
+  type: mysql
+rate_limit:
+  max: 100
+  expiration: 1m
+sources:
+  disabled: [weibo, zhihu]
+fetcher:
+  timeout: 5s
+  source_timeouts:
+    weibo: 3s
+  proxy: socks5://127.0.0.1:1080
+schedule:
+  interval: 30m
+  sources:
+    weibo:
+      interval: 2m
+    historytoday:
+      cron: "0 6 * * *"
+mcp:
+  http_enabled: true
+  port: "9091"
+`)
+		config, err := LoadConfig()
+		assert.NoError(t, err)
+		assert.Equal(t, "localhost", config.Server.Host)
+		assert.Equal(t, "9090", config.Server.Port)
+		assert.Equal(t, 30*time.Second, config.Server.ShutdownTimeout)
+		assert.Equal(t, "mysql", config.Database.Type)
+		assert.Equal(t, defaultMySQLDSN, config.Database.DSN)
+		assert.True(t, config.RateLimit.Enabled)
+		assert.Equal(t, 100, config.RateLimit.Max)
+		assert.Equal(t, time.Minute, config.RateLimit.Expiration)
+		assert.Equal(t, []string{"weibo", "zhihu"}, config.Sources.Disabled)
+		assert.False(t, config.Sources.Enabled("weibo"))
+		assert.True(t, config.Sources.Enabled("baidu"))
+		assert.Equal(t, 5*time.Second, config.Fetcher.Timeout)
+		assert.Equal(t, map[string]time.Duration{"weibo": 3 * time.Second}, config.Fetcher.SourceTimeouts)
+		assert.Equal(t, "socks5://127.0.0.1:1080", config.Fetcher.Proxy)
+		assert.Equal(t, 2, config.Fetcher.Retries)
+		assert.Equal(t, SourceSchedule{Interval: 2 * time.Minute, Jitter: time.Minute}, config.Schedule.For("weibo"))
+		assert.Equal(t, "0 6 * * *", config.Schedule.For("historytoday").Cron)
+		// 内置计划仍然生效
+		assert.Equal(t, 10*time.Minute, config.Schedule.For("douyin").Interval)
+		assert.True(t, config.MCP.HTTPEnabled)
+		assert.Equal(t, "9091", config.MCP.Port)
+		// 修改配置不影响内置的默认值
+		assert.Equal(t, 10*time.Minute, Default().Schedule.For("weibo").Interval)
+	})
+
+	// 测试 TOML 配置文件，键名和时长格式与 YAML 相同
+	t.Run("TOMLFile", func(t *testing.T) {
+		writeConfigFile(t, "config.toml", `
+debug = true
+
+[server]
+port = 9090
+shutdown_timeout = "30s"
+
+[sources]
+disabled = ["weibo"]
+
+[fetcher.source_timeouts]
+weibo = "3s"
+
+[schedule.sources.historytoday]
+cron = "0 6 * * *"
+`)
+		config, err := LoadConfig()
+		assert.NoError(t, err)
+		assert.True(t, config.Debug)
+		assert.Equal(t, "9090", config.Server.Port)
+		assert.Equal(t, 30*time.Second, config.Server.ShutdownTimeout)
+		assert.Equal(t, []string{"weibo"}, config.Sources.Disabled)
+		assert.Equal(t, map[string]time.Duration{"weibo": 3 * time.Second}, config.Fetcher.SourceTimeouts)
+		assert.Equal(t, "0 6 * * *", config.Schedule.For("historytoday").Cron)
+
+		// 空的 TOML 文件只使用默认值
+		writeConfigFile(t, "config.toml", "")
+		config, err = LoadConfig()
+		assert.NoError(t, err)
+		assert.Equal(t, "8080", config.Server.Port)
+	})
+
+	// 测试环境变量优先于配置文件
+	t.Run("EnvOverridesFile", func(t *testing.T) {
+		writeConfigFile(t, "config.yml", `
+server:
+  port: "9090"
+database:
+  type: mysql
+  dsn: file:dsn
+sources:
+  disabled: [weibo]
+`)
+		t.Setenv("SERVER_PORT", "7070")
+		t.Setenv("DB_TYPE", "sqlite")
+		t.Setenv("SOURCES_DISABLED", "zhihu, baidu")
+
+		config, err := LoadConfig()
+		assert.NoError(t, err)
+		assert.Equal(t, "7070", config.Server.Port)
+		// 更换数据库类型后不使用配置文件中的连接字符串
+		assert.Equal(t, "sqlite", config.Database.Type)
+		assert.Equal(t, defaultSQLiteDSN, config.Database.DSN)
+		assert.Equal(t, []string{"zhihu", "baidu"}, config.Sources.Disabled)
+	})
+
+	// 测试配置文件格式错误
+	t.Run("InvalidFile", func(t *testing.T) {
+		// 未知的配置项
+		writeConfigFile(t, "config.yaml", "server:\n  prot: 9090\n")
+		_, err := LoadConfig()
+		assert.ErrorContains(t, err, "field prot not found")
+
+		// 时长格式错误
+		writeConfigFile(t, "config.yaml", "fetcher:\n  timeout: soon\n")
+		_, err = LoadConfig()
+		assert.ErrorContains(t, err, "解析配置文件")
+
+		// 不支持的文件格式
+		writeConfigFile(t, "config.ini", "[server]\nport = 9090\n")
+		_, err = LoadConfig()
+		assert.ErrorContains(t, err, "格式不支持")
+
+		// TOML 语法错误和未知的配置项
+		writeConfigFile(t, "config.toml", "[server\nport = 9090\n")
+		_, err = LoadConfig()
+		assert.ErrorContains(t, err, "解析配置文件")
+		writeConfigFile(t, "config.toml", "[server]\nprot = 9090\n")
+		_, err = LoadConfig()
+		assert.ErrorContains(t, err, "field prot not found")
+
+		// 指定的文件不存在
+		t.Setenv("CONFIG_FILE", filepath.Join(t.TempDir(), "missing.yaml"))
+		_, err = LoadConfig()
+		assert.ErrorContains(t, err, "读取配置文件失败")
+	})
+
+	// 测试示例配置文件与默认配置一致
+	t.Run("ExampleFile", func(t *testing.T) {
+		t.Setenv("CONFIG_FILE", filepath.Join("..", "config.example.yaml"))
+		config, err := LoadConfig()
+		assert.NoError(t, err)
+
+		expected := Default()
+		expected.Database.DSN = defaultSQLiteDSN
+		expected.Fetcher.SourceTimeouts["zhihu"] = 20 * time.Second
+		expected.Sources.Disabled = []string{}
+		assert.Equal(t, expected, config)
+	})
+
+	// 测试空的配置文件
+	t.Run("EmptyFile", func(t *testing.T) {
+		writeConfigFile(t, "config.yaml", "")
+		config, err := LoadConfig()
+		assert.NoError(t, err)
+		assert.Equal(t, "8080", config.Server.Port)
+	})
+}
+
+// 测试每次加载配置都重新读取 .env 文件，进程本身的环境变量优先
+func TestLoadConfigDotEnv(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("SERVER_HOST", "0.0.0.0")
+	writeDotEnv := func(content string) {
+		assert.NoError(t, os.WriteFile(".env", []byte(content), 0o644))
+	}
+	t.Cleanup(func() {
+		os.Unsetenv("SERVER_PORT")
+		os.Unsetenv("RATE_LIMIT_MAX")
+	})
+
+	writeDotEnv("SERVER_PORT=7000\nSERVER_HOST=127.0.0.1\nRATE_LIMIT_MAX=10\n")
+	config, err := LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, "7000", config.Server.Port)
+	assert.Equal(t, "0.0.0.0", config.Server.Host)
+	assert.Equal(t, 10, config.RateLimit.Max)
+
+	// 修改后重新加载时使用新的值，删除的变量恢复默认值
+	writeDotEnv("SERVER_PORT=7001\nSERVER_HOST=127.0.0.1\n")
+	config, err = LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, "7001", config.Server.Port)
+	assert.Equal(t, "0.0.0.0", config.Server.Host)
+	assert.Equal(t, Default().RateLimit.Max, config.RateLimit.Max)
+
+	// 删除文件后不再使用其中的值
+	assert.NoError(t, os.Remove(".env"))
+	config, err = LoadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, "8080", config.Server.Port)
+}
+
+func TestValidate(t *testing.T) {
+	config := Default()
+	config.Database.DSN = defaultSQLiteDSN
+	assert.NoError(t, config.Validate())
+
+	config.Server.Port = "http"
+	config.Server.TLSEnabled = true
+	config.Database.Type = "postgres"
+	config.RateLimit.Max = 0
+	config.Fetcher.Timeout = 0
+	config.Fetcher.Proxy = "127.0.0.1:7890"
+	config.Schedule.Sources["weibo"] = SourceSchedule{Cron: "5 0 * *"}
+	config.Log.Level = "verbose"
+	err := config.Validate()
+	assert.ErrorContains(t, err, `server.port: "http" 不是有效的端口号`)
+	assert.ErrorContains(t, err, "server.tls_cert_file")
+	assert.ErrorContains(t, err, `database.type: "postgres" 无效`)
+	assert.ErrorContains(t, err, "rate_limit.max: 必须大于0")
+	assert.ErrorContains(t, err, "fetcher.timeout: 必须大于0")
+	assert.ErrorContains(t, err, "fetcher.proxy")
+	assert.ErrorContains(t, err, "schedule.sources.weibo.cron")
+	assert.ErrorContains(t, err, "应包含 5 段")
+	assert.ErrorContains(t, err, "log.level")
+
+	// 关闭限流时不检查限流参数
+	config = Default()
+	config.Database.DSN = defaultSQLiteDSN
+	config.RateLimit = RateLimitConfig{Enabled: false}
+	assert.NoError(t, config.Validate())
+
+	// cron 表达式使用定时抓取的解析器检查，支持简写，拒绝超出范围的取值
+	config = Default()
+	config.Database.DSN = defaultSQLiteDSN
+	config.Schedule.Sources["weibo"] = SourceSchedule{Cron: "@hourly"}
+	assert.NoError(t, config.Validate())
+	for _, expr := range []string{"99 * * * *", "a b c d e"} {
+		config.Schedule.Sources["weibo"] = SourceSchedule{Cron: expr}
+		assert.ErrorContains(t, config.Validate(), "schedule.sources.weibo.cron", expr)
+	}
+}
+
 func TestGetServerAddress(t *testing.T) {
 	config := &Config{
 		Server: ServerConfig{
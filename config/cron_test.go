@@ -0,0 +1,46 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCron(t *testing.T) {
+	base := time.Date(2025, 10, 18, 12, 34, 56, 0, time.Local) // 周六
+	tests := []struct {
+		expr     string
+		expected time.Time
+	}{
+		{"* * * * *", time.Date(2025, 10, 18, 12, 35, 0, 0, time.Local)},
+		{"*/15 * * * *", time.Date(2025, 10, 18, 12, 45, 0, 0, time.Local)},
+		{"5 0 * * *", time.Date(2025, 10, 19, 0, 5, 0, 0, time.Local)},
+		{"@hourly", time.Date(2025, 10, 18, 13, 0, 0, 0, time.Local)},
+		{"0 9-18/3 * * *", time.Date(2025, 10, 18, 15, 0, 0, 0, time.Local)},
+		{"0 8 * * 1-5", time.Date(2025, 10, 20, 8, 0, 0, 0, time.Local)},
+		{"0 0 1,15 * *", time.Date(2025, 11, 1, 0, 0, 0, 0, time.Local)},
+		// 日和周都有限制时满足其一即可
+		{"0 0 1 * 0", time.Date(2025, 10, 19, 0, 0, 0, 0, time.Local)},
+		// 7 也表示周日
+		{"30 6 * * 7", time.Date(2025, 10, 19, 6, 30, 0, 0, time.Local)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.Local)},
+	}
+	for _, test := range tests {
+		schedule, err := ParseCron(test.expr)
+		if !assert.NoError(t, err, test.expr) {
+			continue
+		}
+		assert.True(t, test.expected.Equal(schedule.Next(base)), "%s: expected %v, got %v", test.expr, test.expected, schedule.Next(base))
+	}
+
+	// 不存在的日期没有下一次执行时间
+	schedule, err := ParseCron("0 0 31 2 *")
+	assert.NoError(t, err)
+	assert.True(t, schedule.Next(base).IsZero())
+
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "*/0 * * * *", "a * * * *", "5-1 * * * *"} {
+		_, err := ParseCron(expr)
+		assert.Error(t, err, expr)
+	}
+}
@@ -0,0 +1,286 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+var (
+	dotEnvMu     sync.Mutex
+	dotEnvValues map[string]string // 上一次从 .env 文件设置的环境变量
+)
+
+// loadDotEnv 将 .env 文件中的变量设置到环境变量，每次加载配置时都重新读取
+//
+// 进程启动时已经设置的环境变量优先，不会被覆盖；之前从 .env 设置的变量按文件的新内容更新，
+// 从文件中删除的变量也被清除。文件不存在时视为空文件，格式错误时保持原来的环境变量
+func loadDotEnv() {
+	values, err := godotenv.Read()
+	if errors.Is(err, fs.ErrNotExist) {
+		values, err = map[string]string{}, nil
+	}
+	if err != nil {
+		slog.Warn("读取 .env 文件失败", "error", err)
+		return
+	}
+
+	dotEnvMu.Lock()
+	defer dotEnvMu.Unlock()
+
+	for key, previous := range dotEnvValues {
+		if _, ok := values[key]; !ok && os.Getenv(key) == previous {
+			os.Unsetenv(key)
+		}
+	}
+	applied := make(map[string]string, len(values))
+	for key, value := range values {
+		current, exists := os.LookupEnv(key)
+		previous, owned := dotEnvValues[key]
+		if exists && !(owned && current == previous) {
+			continue
+		}
+		os.Setenv(key, value)
+		applied[key] = value
+	}
+	dotEnvValues = applied
+}
+
+// loadEnv 使用环境变量覆盖配置，未设置或为空的环境变量不覆盖，格式错误时返回所有错误
+func (c *Config) loadEnv() error {
+	e := &envLoader{}
+
+	e.string("SERVER_HOST", &c.Server.Host)
+	e.string("SERVER_PORT", &c.Server.Port)
+	e.bool("TLS_ENABLED", &c.Server.TLSEnabled)
+	e.string("TLS_CERT_FILE", &c.Server.TLSCertFile)
+	e.string("TLS_KEY_FILE", &c.Server.TLSKeyFile)
+	e.duration("SHUTDOWN_TIMEOUT", &c.Server.ShutdownTimeout)
+
+	// 更换数据库类型时不再使用配置文件中的连接字符串
+	if dbType, ok := lookupEnv("DB_TYPE"); ok && dbType != c.Database.Type {
+		c.Database.Type = dbType
+		c.Database.DSN = ""
+	}
+	if c.Database.Type == "mysql" {
+		e.string("MYSQL_DSN", &c.Database.DSN)
+	} else {
+		e.string("SQLITE_DSN", &c.Database.DSN)
+	}
+
+	e.string("CORS_ALLOW_ORIGINS", &c.CORS.AllowOrigins)
+
+	e.bool("RATE_LIMIT_ENABLED", &c.RateLimit.Enabled)
+	e.int("RATE_LIMIT_MAX", &c.RateLimit.Max)
+	e.duration("RATE_LIMIT_EXPIRATION", &c.RateLimit.Expiration)
+
+	e.list("SOURCES_DISABLED", &c.Sources.Disabled)
+
+	e.bool("RETENTION_ENABLED", &c.Retention.Enabled)
+	e.duration("RETENTION_INTERVAL", &c.Retention.Interval)
+	e.int("RETENTION_HOURLY_DAYS", &c.Retention.HourlyDays)
+	e.int("RETENTION_DAILY_DAYS", &c.Retention.DailyDays)
+
+	e.duration("FETCH_TIMEOUT", &c.Fetcher.Timeout)
+	e.durationMap("FETCH_SOURCE_TIMEOUTS", &c.Fetcher.SourceTimeouts)
+	e.duration("FETCH_ALL_TIMEOUT", &c.Fetcher.AllTimeout)
+	e.string("FETCH_USER_AGENT", &c.Fetcher.UserAgent)
+	e.string("FETCH_PROXY", &c.Fetcher.Proxy)
+	e.int("FETCH_MAX_IDLE_CONNS_PER_HOST", &c.Fetcher.MaxIdleConnsPerHost)
+	e.int("FETCH_RETRIES", &c.Fetcher.Retries)
+	e.duration("FETCH_RETRY_BACKOFF", &c.Fetcher.RetryBackoff)
+	e.duration("FETCH_RETRY_MAX_BACKOFF", &c.Fetcher.RetryMaxBackoff)
+	e.int("FETCH_BREAKER_THRESHOLD", &c.Fetcher.BreakerThreshold)
+	e.duration("FETCH_BREAKER_COOLDOWN", &c.Fetcher.BreakerCooldown)
+	e.string("FETCH_ALERT_WEBHOOK", &c.Fetcher.AlertWebhook)
+
+	e.duration("SCHEDULE_INTERVAL", &c.Schedule.Interval)
+	e.duration("SCHEDULE_JITTER", &c.Schedule.Jitter)
+	e.schedules("SCHEDULE_SOURCES", &c.Schedule.Sources)
+
+	e.bool("LIVE_STALE_ENABLED", &c.Live.StaleEnabled)
+	e.duration("LIVE_STALE_TIMEOUT", &c.Live.StaleTimeout)
+	e.duration("LIVE_STALE_MAX_AGE", &c.Live.StaleMaxAge)
+
+	e.bool("CACHE_ENABLED", &c.Cache.Enabled)
+	e.duration("CACHE_TTL", &c.Cache.TTL)
+	e.durationMap("CACHE_SOURCE_TTLS", &c.Cache.SourceTTLs)
+
+	e.string("LOG_LEVEL", &c.Log.Level)
+	e.string("LOG_FORMAT", &c.Log.Format)
+
+	if c.MCP == nil {
+		c.MCP = &MCPConfig{}
+	}
+	e.bool("MCP_STDIO_ENABLED", &c.MCP.STDIOEnabled)
+	e.bool("MCP_HTTP_ENABLED", &c.MCP.HTTPEnabled)
+	e.string("MCP_PORT", &c.MCP.Port)
+
+	e.bool("DEBUG", &c.Debug)
+
+	if len(e.errs) > 0 {
+		return fmt.Errorf("环境变量格式错误: %w", errors.Join(e.errs...))
+	}
+	return nil
+}
+
+// lookupEnv 获取去掉首尾空白的环境变量，未设置或为空时返回 false
+func lookupEnv(key string) (string, bool) {
+	value := strings.TrimSpace(os.Getenv(key))
+	return value, value != ""
+}
+
+// envLoader 读取环境变量并记录格式错误
+type envLoader struct {
+	errs []error
+}
+
+// fail 记录一个格式错误
+func (e *envLoader) fail(key, value, format string) {
+	e.errs = append(e.errs, fmt.Errorf("%s=%q 不是有效的%s", key, value, format))
+}
+
+// string 读取字符串
+func (e *envLoader) string(key string, target *string) {
+	if value, ok := lookupEnv(key); ok {
+		*target = value
+	}
+}
+
+// bool 读取布尔值，支持 true/false、1/0 等写法
+func (e *envLoader) bool(key string, target *bool) {
+	value, ok := lookupEnv(key)
+	if !ok {
+		return
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		e.fail(key, value, "布尔值")
+		return
+	}
+	*target = b
+}
+
+// int 读取整数
+func (e *envLoader) int(key string, target *int) {
+	value, ok := lookupEnv(key)
+	if !ok {
+		return
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		e.fail(key, value, "整数")
+		return
+	}
+	*target = n
+}
+
+// duration 读取时长（如 30m、1h）
+func (e *envLoader) duration(key string, target *time.Duration) {
+	value, ok := lookupEnv(key)
+	if !ok {
+		return
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		e.fail(key, value, "时长")
+		return
+	}
+	*target = d
+}
+
+// list 读取以逗号分隔的列表，替换原来的值
+func (e *envLoader) list(key string, target *[]string) {
+	value, ok := lookupEnv(key)
+	if !ok {
+		return
+	}
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	*target = items
+}
+
+// durationMap 读取 key=时长 形式、以逗号分隔的列表（如 weibo=5s,zhihu=20s），覆盖已有的同名项
+func (e *envLoader) durationMap(key string, target *map[string]time.Duration) {
+	value, ok := lookupEnv(key)
+	if !ok {
+		return
+	}
+	if *target == nil {
+		*target = make(map[string]time.Duration)
+	}
+	for _, pair := range strings.Split(value, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		name = strings.TrimSpace(name)
+		duration, err := time.ParseDuration(strings.TrimSpace(value))
+		if !ok || name == "" || err != nil {
+			e.fail(key, pair, "数据源=时长")
+			continue
+		}
+		(*target)[name] = duration
+	}
+}
+
+// schedules 读取以分号分隔的数据源抓取计划，覆盖已有的同名项
+//
+// 格式为 数据源=间隔[/随机延迟] 或 数据源=cron:表达式，如 weibo=5m/30s;historytoday=cron:5 0 * * *
+func (e *envLoader) schedules(key string, target *map[string]SourceSchedule) {
+	value, ok := lookupEnv(key)
+	if !ok {
+		return
+	}
+	if *target == nil {
+		*target = make(map[string]SourceSchedule)
+	}
+	for _, pair := range strings.Split(value, ";") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		schedule, name, ok := parseSchedule(pair)
+		if !ok {
+			e.fail(key, pair, "抓取计划")
+			continue
+		}
+		(*target)[name] = schedule
+	}
+}
+
+// parseSchedule 解析单个数据源的抓取计划
+func parseSchedule(pair string) (SourceSchedule, string, bool) {
+	name, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+	name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+	if !ok || name == "" {
+		return SourceSchedule{}, "", false
+	}
+
+	if expr, ok := strings.CutPrefix(value, "cron:"); ok {
+		return SourceSchedule{Cron: strings.TrimSpace(expr)}, name, true
+	}
+
+	intervalValue, jitterValue, hasJitter := strings.Cut(value, "/")
+	interval, err := time.ParseDuration(strings.TrimSpace(intervalValue))
+	if err != nil {
+		return SourceSchedule{}, "", false
+	}
+	schedule := SourceSchedule{Interval: interval}
+	if hasJitter {
+		jitter, err := time.ParseDuration(strings.TrimSpace(jitterValue))
+		if err != nil {
+			return SourceSchedule{}, "", false
+		}
+		schedule.Jitter = jitter
+	}
+	return schedule, name, true
+}
@@ -0,0 +1,57 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"go.yaml.in/yaml/v3"
+)
+
+// loadFile 从 YAML 或 TOML 配置文件读取配置，按扩展名选择格式，文件中没有的项保留原来的值
+//
+// required 为 false 时文件不存在不算错误。文件中出现未知的键时返回错误，避免拼写错误的配置项被忽略
+func (c *Config) loadFile(path string, required bool) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) && !required {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("读取配置文件失败: %w", err)
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+	case ".toml":
+		if data, err = tomlToYAML(data); err != nil {
+			return fmt.Errorf("解析配置文件 %s 失败: %w", path, err)
+		}
+	default:
+		return fmt.Errorf("配置文件 %s 格式不支持，请使用 .yaml、.yml 或 .toml 文件", path)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("解析配置文件 %s 失败: %w", path, err)
+	}
+	return nil
+}
+
+// tomlToYAML 将 TOML 文档转换为 YAML，两种格式共用字段的 yaml 标签、时长格式和未知键检查
+func tomlToYAML(data []byte) ([]byte, error) {
+	var values map[string]interface{}
+	if err := toml.Unmarshal(data, &values); err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return nil, nil
+	}
+	return yaml.Marshal(values)
+}
@@ -0,0 +1,132 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Validate 检查配置项是否有效，返回所有无效的配置项
+//
+// 错误信息中的配置项使用配置文件中的键名，如 fetcher.timeout
+func (c *Config) Validate() error {
+	v := &validator{}
+
+	v.port("server.port", c.Server.Port)
+	if c.Server.TLSEnabled {
+		v.check(c.Server.TLSCertFile != "" && c.Server.TLSKeyFile != "", "server.tls_cert_file", "启用TLS时必须提供证书和私钥文件")
+	}
+	v.positive("server.shutdown_timeout", c.Server.ShutdownTimeout)
+
+	v.oneOf("database.type", c.Database.Type, "sqlite", "mysql")
+	v.check(c.Database.DSN != "", "database.dsn", "不能为空")
+
+	if c.RateLimit.Enabled {
+		v.check(c.RateLimit.Max > 0, "rate_limit.max", "必须大于0")
+		v.positive("rate_limit.expiration", c.RateLimit.Expiration)
+	}
+
+	for _, source := range c.Sources.Disabled {
+		v.check(strings.TrimSpace(source) != "", "sources.disabled", "数据源名称不能为空")
+	}
+
+	v.positive("retention.interval", c.Retention.Interval)
+	v.check(c.Retention.HourlyDays >= 0, "retention.hourly_days", "不能为负数")
+	v.check(c.Retention.DailyDays >= 0, "retention.daily_days", "不能为负数")
+
+	v.positive("fetcher.timeout", c.Fetcher.Timeout)
+	for source, timeout := range c.Fetcher.SourceTimeouts {
+		v.positive("fetcher.source_timeouts."+source, timeout)
+	}
+	v.nonNegative("fetcher.all_timeout", c.Fetcher.AllTimeout)
+	v.url("fetcher.proxy", c.Fetcher.Proxy, "http", "https", "socks5")
+	v.check(c.Fetcher.MaxIdleConnsPerHost >= 0, "fetcher.max_idle_conns_per_host", "不能为负数")
+	v.check(c.Fetcher.Retries >= 0, "fetcher.retries", "不能为负数")
+	v.nonNegative("fetcher.retry_backoff", c.Fetcher.RetryBackoff)
+	v.nonNegative("fetcher.retry_max_backoff", c.Fetcher.RetryMaxBackoff)
+	v.check(c.Fetcher.BreakerThreshold >= 0, "fetcher.breaker_threshold", "不能为负数")
+	if c.Fetcher.BreakerThreshold > 0 {
+		v.positive("fetcher.breaker_cooldown", c.Fetcher.BreakerCooldown)
+	}
+	v.url("fetcher.alert_webhook", c.Fetcher.AlertWebhook, "http", "https")
+
+	v.positive("schedule.interval", c.Schedule.Interval)
+	v.nonNegative("schedule.jitter", c.Schedule.Jitter)
+	for source, schedule := range c.Schedule.Sources {
+		key := "schedule.sources." + source
+		v.nonNegative(key+".interval", schedule.Interval)
+		v.nonNegative(key+".jitter", schedule.Jitter)
+		if schedule.Cron != "" {
+			_, err := ParseCron(schedule.Cron)
+			v.check(err == nil, key+".cron", fmt.Sprintf("%q 不是有效的 cron 表达式: %v", schedule.Cron, err))
+		}
+	}
+
+	v.nonNegative("live.stale_timeout", c.Live.StaleTimeout)
+	v.nonNegative("live.stale_max_age", c.Live.StaleMaxAge)
+
+	if c.Cache.Enabled {
+		v.positive("cache.ttl", c.Cache.TTL)
+	}
+	for source, ttl := range c.Cache.SourceTTLs {
+		v.positive("cache.source_ttls."+source, ttl)
+	}
+
+	v.oneOf("log.level", strings.ToLower(c.Log.Level), "debug", "info", "warn", "error")
+	v.oneOf("log.format", strings.ToLower(c.Log.Format), "text", "json")
+
+	if c.MCP != nil && c.MCP.HTTPEnabled {
+		v.port("mcp.port", c.MCP.Port)
+	}
+
+	if len(v.errs) > 0 {
+		return fmt.Errorf("配置无效: %w", errors.Join(v.errs...))
+	}
+	return nil
+}
+
+// validator 记录无效的配置项
+type validator struct {
+	errs []error
+}
+
+// check ok 为 false 时记录错误
+func (v *validator) check(ok bool, key, message string) {
+	if !ok {
+		v.errs = append(v.errs, fmt.Errorf("%s: %s", key, message))
+	}
+}
+
+// positive 检查时长大于0
+func (v *validator) positive(key string, d time.Duration) {
+	v.check(d > 0, key, "必须大于0")
+}
+
+// nonNegative 检查时长不为负数
+func (v *validator) nonNegative(key string, d time.Duration) {
+	v.check(d >= 0, key, "不能为负数")
+}
+
+// oneOf 检查取值是否在允许的范围内
+func (v *validator) oneOf(key, value string, allowed ...string) {
+	v.check(slices.Contains(allowed, value), key, fmt.Sprintf("%q 无效，可选值为 %s", value, strings.Join(allowed, "、")))
+}
+
+// port 检查端口号
+func (v *validator) port(key, value string) {
+	port, err := strconv.Atoi(value)
+	v.check(err == nil && port > 0 && port <= 65535, key, fmt.Sprintf("%q 不是有效的端口号", value))
+}
+
+// url 检查可选的地址，为空时不检查
+func (v *validator) url(key, value string, schemes ...string) {
+	if value == "" {
+		return
+	}
+	u, err := url.Parse(value)
+	v.check(err == nil && slices.Contains(schemes, u.Scheme) && u.Host != "", key, fmt.Sprintf("%q 不是有效的地址，协议需要为 %s", value, strings.Join(schemes, "、")))
+}
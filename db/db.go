@@ -2,15 +2,21 @@ package db
 
 import (
 	"api/config"
+	"api/logging"
+	"api/metrics"
 	"api/model"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
 	"os"
+	"strings"
 	"time"
 
-	"github.com/gofiber/fiber/v2/log"
-
 	"gorm.io/driver/mysql"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
 )
 
 var DB *gorm.DB
@@ -35,12 +41,34 @@ func InitDBWithConfig(cfg *config.Config) {
 	}
 }
 
+// fatal 记录数据库无法使用的错误并退出
+func fatal(msg string, err error) {
+	slog.Error(msg, logging.KeyError, err)
+	os.Exit(1)
+}
+
+// gormWriter 将 GORM 的日志写入服务统一的日志
+type gormWriter struct{}
+
+func (gormWriter) Printf(format string, args ...interface{}) {
+	slog.Warn(fmt.Sprintf(format, args...), "component", "gorm")
+}
+
+// gormLogger GORM 使用的日志，只记录错误和慢查询
+func gormLogger() gormlogger.Interface {
+	return gormlogger.New(gormWriter{}, gormlogger.Config{
+		SlowThreshold:             200 * time.Millisecond,
+		LogLevel:                  gormlogger.Warn,
+		IgnoreRecordNotFoundError: true,
+	})
+}
+
 // InitSQLite 初始化SQLite数据库
 func InitSQLite() {
 
 	DB = initSQLite("hot_search.db")
 
-	log.Info("SQLite database initialized successfully")
+	slog.Info("SQLite database initialized successfully")
 }
 
 // InitSQLiteWithDSN 使用DSN初始化SQLite数据库
@@ -48,20 +76,20 @@ func InitSQLiteWithDSN(dsn string) {
 
 	DB = initSQLite(dsn)
 
-	log.Info("SQLite database initialized successfully")
+	slog.Info("SQLite database initialized successfully")
 }
 
 // initSQLite 初始化SQLite数据库的内部函数
 func initSQLite(dsn string) *gorm.DB {
-	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: gormLogger()})
 	if err != nil {
-		log.Fatal("failed to connect database: " + err.Error())
+		fatal("failed to connect database", err)
 	}
 
 	// 自动迁移模式
-	err = db.AutoMigrate(&model.HotSearchItem{}, &model.HotSearchData{})
+	err = migrate(db)
 	if err != nil {
-		panic("failed to migrate database: " + err.Error())
+		fatal("failed to migrate database", err)
 	}
 
 	return db
@@ -75,45 +103,174 @@ func InitMySQL() {
 		dsn = "root:password@tcp(127.0.0.1:3306)/hot_search?charset=utf8mb4&parseTime=True&loc=Local"
 	}
 
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{Logger: gormLogger()})
 	if err != nil {
-		log.Fatal("failed to connect database: " + err.Error())
+		fatal("failed to connect database", err)
 	}
 
 	DB = db
 
 	// 自动迁移模式
-	err = DB.AutoMigrate(&model.HotSearchItem{}, &model.HotSearchData{})
+	err = migrate(DB)
 	if err != nil {
-		log.Fatal("failed to migrate database: " + err.Error())
+		fatal("failed to migrate database", err)
 	}
 
-	log.Info("MySQL database initialized successfully")
+	slog.Info("MySQL database initialized successfully")
 }
 
 // InitMySQLWithConfig 使用配置初始化MySQL数据库
 func InitMySQLWithConfig(dsn string) {
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{Logger: gormLogger()})
 	if err != nil {
-		log.Fatal("failed to connect database: " + err.Error())
+		fatal("failed to connect database", err)
 	}
 
 	DB = db
 
 	// 自动迁移模式
-	err = DB.AutoMigrate(&model.HotSearchItem{}, &model.HotSearchData{})
+	err = migrate(DB)
 	if err != nil {
-		log.Fatal("failed to migrate database: " + err.Error())
+		fatal("failed to migrate database", err)
+	}
+
+	slog.Info("MySQL database initialized successfully")
+}
+
+// migrate 执行自动迁移，并将旧版本遗留的无快照条目归并为快照
+//
+// 热度、描述、封面图片等字段由自动迁移新增，旧数据中这些字段为空值
+func migrate(db *gorm.DB) error {
+	if err := db.AutoMigrate(&model.HotSearchItem{}, &model.HotSearchData{}); err != nil {
+		return err
+	}
+	return migrateLegacyItems(db)
+}
+
+// migrateLegacyItems 将旧版本写入的条目（snapshot_id 为空）按来源和写入时间归并为快照
+func migrateLegacyItems(db *gorm.DB) error {
+	var legacy []model.HotSearchItem
+	result := db.Where("snapshot_id = 0 OR snapshot_id IS NULL").
+		Order("source, created_at, item_index ASC").
+		Find(&legacy)
+	if result.Error != nil {
+		return result.Error
+	}
+	if len(legacy) == 0 {
+		return nil
 	}
 
-	log.Info("MySQL database initialized successfully")
+	return db.Transaction(func(tx *gorm.DB) error {
+		for start := 0; start < len(legacy); {
+			first := legacy[start]
+			end := start
+			var ids []uint
+			for end < len(legacy) && legacy[end].Source == first.Source && legacy[end].CreatedAt.Equal(first.CreatedAt) {
+				ids = append(ids, legacy[end].ID)
+				end++
+			}
+
+			date, hour := first.Date, first.Hour
+			if date == "" {
+				date, hour = first.CreatedAt.Format("2006-01-02"), first.CreatedAt.Hour()
+			}
+			snapshot := model.HotSearchData{
+				Source:    first.Source,
+				Date:      date,
+				Hour:      hour,
+				CreatedAt: first.CreatedAt,
+			}
+			if err := tx.Omit("Items").Create(&snapshot).Error; err != nil {
+				return err
+			}
+			err := tx.Model(&model.HotSearchItem{}).
+				Where("id IN ?", ids).
+				Updates(map[string]interface{}{"snapshot_id": snapshot.ID, "date": date, "hour": hour}).Error
+			if err != nil {
+				return err
+			}
+			start = end
+		}
+		slog.Info("已将旧数据归并为快照", "items", len(legacy))
+		return nil
+	})
+}
+
+// Ping 检查数据库连接是否可用
+func Ping(ctx context.Context) error {
+	if DB == nil {
+		return errors.New("数据库未初始化")
+	}
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// Close 关闭数据库连接，未初始化时什么也不做
+func Close() error {
+	if DB == nil {
+		return nil
+	}
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
+// GetLatestSnapshot 获取指定来源最新的一次快照（不含条目），没有数据时返回 nil
+func GetLatestSnapshot(source string) (*model.HotSearchData, error) {
+	var snapshots []model.HotSearchData
+	result := DB.Where("source = ?", source).
+		Order("created_at DESC, id DESC").
+		Limit(1).
+		Find(&snapshots)
+	if result.Error != nil || len(snapshots) == 0 {
+		return nil, result.Error
+	}
+	return &snapshots[0], nil
+}
+
+// GetSnapshotAt 获取指定来源在指定时间及之前最新的一次快照（含条目），at 为零值时获取最新的快照，没有数据时返回 nil
+func GetSnapshotAt(source string, at time.Time) (*model.HotSearchData, error) {
+	if at.IsZero() {
+		return findSnapshotWithItems(DB.Where("source = ?", source))
+	}
+	return findSnapshotWithItems(DB.Where("source = ? AND created_at <= ?", source, at))
+}
+
+// GetPreviousSnapshot 获取指定快照的前一次快照（含条目），没有数据时返回 nil
+func GetPreviousSnapshot(snapshot *model.HotSearchData) (*model.HotSearchData, error) {
+	return findSnapshotWithItems(DB.Where("source = ? AND (created_at < ? OR (created_at = ? AND id < ?))",
+		snapshot.Source, snapshot.CreatedAt, snapshot.CreatedAt, snapshot.ID))
+}
+
+// findSnapshotWithItems 按条件查找最新的一次快照并加载其条目
+func findSnapshotWithItems(query *gorm.DB) (*model.HotSearchData, error) {
+	var snapshots []model.HotSearchData
+	result := query.Order("created_at DESC, id DESC").Limit(1).Find(&snapshots)
+	if result.Error != nil || len(snapshots) == 0 {
+		return nil, result.Error
+	}
+
+	snapshot := &snapshots[0]
+	items, err := getSnapshotItems(snapshot.ID)
+	if err != nil {
+		return nil, err
+	}
+	snapshot.Items = items
+	return snapshot, nil
 }
 
 // GetLatestData 获取最新数据
 func GetLatestData(source string) ([]model.HotSearchItem, error) {
-	var items []model.HotSearchItem
-	result := DB.Where("source = ?", source).Order("item_index ASC").Find(&items)
-	return items, result.Error
+	snapshot, err := GetLatestSnapshot(source)
+	if err != nil || snapshot == nil {
+		return nil, err
+	}
+	return getSnapshotItems(snapshot.ID)
 }
 
 // GetAllLatestData 获取所有最新数据
@@ -123,124 +280,221 @@ func GetAllLatestData() (map[string][]model.HotSearchItem, error) {
 
 	// 获取所有不同的来源
 	var sources []string
-	result := DB.Model(&model.HotSearchItem{}).Distinct("source").Pluck("source", &sources)
+	result := DB.Model(&model.HotSearchData{}).Distinct("source").Pluck("source", &sources)
 	if result.Error != nil {
 		return nil, result.Error
 	}
 
-	// 对于每个来源，获取最新创建时间的数据
+	// 对于每个来源，读取其最新快照的条目
 	for _, source := range sources {
-		var latestCreatedAt time.Time
-		// 获取此来源的最新创建时间
-		result = DB.Model(&model.HotSearchItem{}).
-			Where("source = ?", source).
-			Order("created_at DESC").
-			Limit(1).
-			Pluck("created_at", &latestCreatedAt)
-		if result.Error != nil {
-			return nil, result.Error
-		}
-
-		// 获取此来源在最新时间的所有数据
-		var items []model.HotSearchItem
-		result = DB.Where("source = ? AND created_at = ?", source, latestCreatedAt).
-			Order("item_index ASC").
-			Find(&items)
-		if result.Error != nil {
-			return nil, result.Error
+		items, err := GetLatestData(source)
+		if err != nil {
+			return nil, err
 		}
-
 		data[source] = items
 	}
 
 	return data, nil
 }
 
-// SaveData 保存数据到数据库
+// SaveData 保存数据到数据库，每次调用都会追加一次新的快照
 func SaveData(source string, items []model.HotSearchItem) error {
-	// 删除旧数据
-	if err := DB.Where("source = ?", source).Delete(&model.HotSearchItem{}).Error; err != nil {
-		return err
-	}
-
-	// 保存新数据
-	for i := range items {
-		items[i].Source = source
-	}
+	return SaveDataAt(source, items, time.Now())
+}
 
-	return DB.Create(&items).Error
+// SaveDataAt 以指定的抓取时间追加一次快照
+func SaveDataAt(source string, items []model.HotSearchItem, fetchedAt time.Time) error {
+	defer observeWrite("save", time.Now())
+	_, err := saveSnapshot(DB, source, items, fetchedAt)
+	return err
 }
 
 // SaveAllData 保存所有数据
 func SaveAllData(allData map[string][]model.HotSearchItem) error {
-	// 开启事务
-	tx := DB.Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-		}
-	}()
-
-	// 逐个保存每个来源的数据
-	for source, items := range allData {
-		// 删除旧数据
-		if err := tx.Where("source = ?", source).Delete(&model.HotSearchItem{}).Error; err != nil {
-			tx.Rollback()
-			return err
-		}
+	return SaveAllDataAt(allData, time.Now())
+}
 
-		for i := range items {
-			items[i].Source = source
-		}
-		if err := tx.Create(&items).Error; err != nil {
-			tx.Rollback()
-			return err
+// SaveAllDataAt 以指定的抓取时间为每个来源追加一次快照，所有来源在同一事务中写入
+func SaveAllDataAt(allData map[string][]model.HotSearchItem, fetchedAt time.Time) error {
+	defer observeWrite("save_all", time.Now())
+	return DB.Transaction(func(tx *gorm.DB) error {
+		for source, items := range allData {
+			if _, err := saveSnapshot(tx, source, items, fetchedAt); err != nil {
+				return err
+			}
 		}
+		return nil
+	})
+}
+
+// observeWrite 记录一次数据库写入的耗时
+func observeWrite(operation string, start time.Time) {
+	metrics.DBWriteDuration.Observe(metrics.Since(start), operation)
+}
+
+// saveSnapshot 写入一次快照及其条目，条目为空时不写入
+func saveSnapshot(tx *gorm.DB, source string, items []model.HotSearchItem, fetchedAt time.Time) (*model.HotSearchData, error) {
+	if len(items) == 0 {
+		return nil, nil
 	}
 
-	return tx.Commit().Error
+	date := fetchedAt.Format("2006-01-02")
+	hour := fetchedAt.Hour()
+
+	snapshot := &model.HotSearchData{
+		Source:    source,
+		Date:      date,
+		Hour:      hour,
+		CreatedAt: fetchedAt,
+		Items:     make([]model.HotSearchItem, len(items)),
+	}
+	for i, item := range items {
+		item.ID = 0
+		item.Source = source
+		item.Date = date
+		item.Hour = hour
+		item.CreatedAt = fetchedAt
+		snapshot.Items[i] = item
+	}
+
+	if err := tx.Create(snapshot).Error; err != nil {
+		return nil, err
+	}
+	return snapshot, nil
 }
 
-// GetHistoricalData 获取指定日期和小时的数据
-func GetHistoricalData(source, date string, hour int) ([]model.HotSearchItem, error) {
+// getSnapshotItems 获取指定快照的所有条目
+func getSnapshotItems(snapshotID uint) ([]model.HotSearchItem, error) {
 	var items []model.HotSearchItem
-	result := DB.Where("source = ? AND date = ? AND hour = ?", source, date, hour).Order("item_index ASC").Find(&items)
+	result := DB.Where("snapshot_id = ?", snapshotID).Order("item_index ASC").Find(&items)
 	return items, result.Error
 }
 
+// getItemsBySnapshots 批量获取多个快照的条目，按快照ID分组
+func getItemsBySnapshots(snapshotIDs []uint) (map[uint][]model.HotSearchItem, error) {
+	data := make(map[uint][]model.HotSearchItem)
+	if len(snapshotIDs) == 0 {
+		return data, nil
+	}
+
+	var items []model.HotSearchItem
+	result := DB.Where("snapshot_id IN ?", snapshotIDs).Order("item_index ASC").Find(&items)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	for _, item := range items {
+		data[item.SnapshotID] = append(data[item.SnapshotID], item)
+	}
+	return data, nil
+}
+
+// latestPerHour 从按时间升序排列的快照中，为每个日期和小时保留最新的一次
+func latestPerHour(snapshots []model.HotSearchData) map[string]map[int]model.HotSearchData {
+	latest := make(map[string]map[int]model.HotSearchData)
+	for _, snapshot := range snapshots {
+		if latest[snapshot.Date] == nil {
+			latest[snapshot.Date] = make(map[int]model.HotSearchData)
+		}
+		latest[snapshot.Date][snapshot.Hour] = snapshot
+	}
+	return latest
+}
+
+// GetHistoricalData 获取指定日期和小时的数据
+//
+// 同一小时内存在多次快照时（例如按需抓取），返回该小时内最新的一次
+func GetHistoricalData(source, date string, hour int) ([]model.HotSearchItem, error) {
+	var snapshots []model.HotSearchData
+	result := DB.Where("source = ? AND date = ? AND hour = ?", source, date, hour).
+		Order("created_at DESC, id DESC").
+		Limit(1).
+		Find(&snapshots)
+	if result.Error != nil || len(snapshots) == 0 {
+		return nil, result.Error
+	}
+	return getSnapshotItems(snapshots[0].ID)
+}
+
 // GetHistoricalDataByDate 获取指定日期的所有小时数据
 func GetHistoricalDataByDate(source, date string) (map[int][]model.HotSearchItem, error) {
-	var items []model.HotSearchItem
-	result := DB.Where("source = ? AND date = ?", source, date).Order("hour, item_index ASC").Find(&items)
+	var snapshots []model.HotSearchData
+	result := DB.Where("source = ? AND date = ?", source, date).Order("created_at ASC, id ASC").Find(&snapshots)
 	if result.Error != nil {
 		return nil, result.Error
 	}
 
+	perHour := latestPerHour(snapshots)[date]
+	ids := make([]uint, 0, len(perHour))
+	for _, snapshot := range perHour {
+		ids = append(ids, snapshot.ID)
+	}
+	itemsBySnapshot, err := getItemsBySnapshots(ids)
+	if err != nil {
+		return nil, err
+	}
+
 	// 按小时分组
 	data := make(map[int][]model.HotSearchItem)
-	for _, item := range items {
-		data[item.Hour] = append(data[item.Hour], item)
+	for hour, snapshot := range perHour {
+		data[hour] = itemsBySnapshot[snapshot.ID]
 	}
 
 	return data, nil
 }
 
-// GetHistoricalDataBySource 获取指定来源的最新数据
+// GetHistoricalDataBySource 获取指定来源的所有历史数据，每个小时取最新的一次快照
 func GetHistoricalDataBySource(source string) (map[string]map[int][]model.HotSearchItem, error) {
-	var items []model.HotSearchItem
-	result := DB.Where("source = ?", source).Order("date DESC, hour DESC, item_index ASC").Find(&items)
+	var snapshots []model.HotSearchData
+	result := DB.Where("source = ?", source).Order("created_at ASC, id ASC").Find(&snapshots)
 	if result.Error != nil {
 		return nil, result.Error
 	}
 
+	perHour := latestPerHour(snapshots)
+	var ids []uint
+	for _, hours := range perHour {
+		for _, snapshot := range hours {
+			ids = append(ids, snapshot.ID)
+		}
+	}
+	itemsBySnapshot, err := getItemsBySnapshots(ids)
+	if err != nil {
+		return nil, err
+	}
+
 	// 按日期和小时分组
 	data := make(map[string]map[int][]model.HotSearchItem)
-	for _, item := range items {
-		if data[item.Date] == nil {
-			data[item.Date] = make(map[int][]model.HotSearchItem)
+	for date, hours := range perHour {
+		data[date] = make(map[int][]model.HotSearchItem)
+		for hour, snapshot := range hours {
+			data[date][hour] = itemsBySnapshot[snapshot.ID]
 		}
-		data[item.Date][item.Hour] = append(data[item.Date][item.Hour], item)
 	}
 
 	return data, nil
 }
+
+// SearchItems 查找标题包含关键词的条目，按抓取时间从新到旧排列，最多返回 limit 条
+//
+// sources 为空时查找所有来源；from、to 为零值时不限制对应一侧的时间
+func SearchItems(keyword string, sources []string, from, to time.Time, limit int) ([]model.HotSearchItem, error) {
+	query := DB.Where("title LIKE ? ESCAPE '!'", "%"+escapeLike(keyword)+"%")
+	if len(sources) > 0 {
+		query = query.Where("source IN ?", sources)
+	}
+	if !from.IsZero() {
+		query = query.Where("created_at >= ?", from)
+	}
+	if !to.IsZero() {
+		query = query.Where("created_at <= ?", to)
+	}
+
+	var items []model.HotSearchItem
+	result := query.Order("created_at DESC, id DESC").Limit(limit).Find(&items)
+	return items, result.Error
+}
+
+// escapeLike 转义 LIKE 模式中的通配符，转义字符使用 !，MySQL 和 SQLite 对反斜杠的处理不同
+func escapeLike(s string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(s)
+}
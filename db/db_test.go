@@ -3,8 +3,10 @@ package db
 import (
 	"api/config"
 	"api/model"
+	"context"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -26,6 +28,7 @@ func TestInitDBWithConfig(t *testing.T) {
 
 	// 检查数据库连接是否成功
 	assert.NotNil(t, DB)
+	assert.NoError(t, Ping(context.Background()))
 }
 
 func TestSaveAndGetData(t *testing.T) {
@@ -124,7 +127,7 @@ func TestGetLatestDataNotFound(t *testing.T) {
 	assert.Equal(t, 0, len(items))
 }
 
-func TestSaveDataOverwrites(t *testing.T) {
+func TestSaveDataAppendsSnapshot(t *testing.T) {
 	// 创建临时SQLite数据库文件
 	tempDB := "test_hot_search_5.db"
 	defer os.Remove(tempDB) // 测试结束后清理
@@ -139,7 +142,7 @@ func TestSaveDataOverwrites(t *testing.T) {
 	InitDBWithConfig(cfg)
 
 	// 首次保存数据
-	source := "overwrite_test"
+	source := "append_test"
 	firstItems := []model.HotSearchItem{
 		{Title: "First Title", URL: "http://first.com", Index: 1},
 	}
@@ -152,7 +155,7 @@ func TestSaveDataOverwrites(t *testing.T) {
 	assert.Equal(t, 1, len(items))
 	assert.Equal(t, "First Title", items[0].Title)
 
-	// 保存新数据（应该覆盖旧数据）
+	// 保存新数据（应该追加新的快照，而不是覆盖旧数据）
 	secondItems := []model.HotSearchItem{
 		{Title: "Second Title", URL: "http://second.com", Index: 1},
 		{Title: "Second Title 2", URL: "http://second2.com", Index: 2},
@@ -160,12 +163,109 @@ func TestSaveDataOverwrites(t *testing.T) {
 	err = SaveData(source, secondItems)
 	assert.NoError(t, err)
 
-	// 验证新数据
+	// 最新数据来自最新的快照
 	items, err = GetLatestData(source)
 	assert.NoError(t, err)
 	assert.Equal(t, 2, len(items))
 	assert.Equal(t, "Second Title", items[0].Title)
 	assert.Equal(t, "Second Title 2", items[1].Title)
+
+	// 旧快照仍然保留
+	var snapshotCount int64
+	DB.Model(&model.HotSearchData{}).Where("source = ?", source).Count(&snapshotCount)
+	assert.Equal(t, int64(2), snapshotCount)
+
+	var itemCount int64
+	DB.Model(&model.HotSearchItem{}).Where("source = ?", source).Count(&itemCount)
+	assert.Equal(t, int64(3), itemCount)
+}
+
+func TestHistoricalSnapshots(t *testing.T) {
+	// 创建临时SQLite数据库文件
+	tempDB := "test_hot_search_snapshots.db"
+	defer os.Remove(tempDB) // 测试结束后清理
+
+	cfg := &config.Config{
+		Database: config.DatabaseConfig{
+			Type: "sqlite",
+			DSN:  tempDB,
+		},
+	}
+
+	InitDBWithConfig(cfg)
+
+	source := "snapshot_test"
+	base := time.Date(2099, 12, 31, 10, 5, 0, 0, time.Local)
+
+	// 模拟三次定时任务：10点、10点半（同一小时的按需抓取）和11点
+	runs := []struct {
+		at    time.Time
+		title string
+	}{
+		{base, "Ten"},
+		{base.Add(25 * time.Minute), "Ten Thirty"},
+		{base.Add(time.Hour), "Eleven"},
+	}
+	for _, run := range runs {
+		err := SaveAllDataAt(map[string][]model.HotSearchItem{
+			source: {{Title: run.title, URL: "http://example.com", Index: 1}},
+		}, run.at)
+		assert.NoError(t, err)
+	}
+
+	// 最新数据来自11点的快照
+	latest, err := GetLatestData(source)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(latest))
+	assert.Equal(t, "Eleven", latest[0].Title)
+
+	// 10点的历史数据取该小时内最新的一次快照
+	items, err := GetHistoricalData(source, "2099-12-31", 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(items))
+	assert.Equal(t, "Ten Thirty", items[0].Title)
+
+	byDate, err := GetHistoricalDataByDate(source, "2099-12-31")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(byDate))
+	assert.Equal(t, "Ten Thirty", byDate[10][0].Title)
+	assert.Equal(t, "Eleven", byDate[11][0].Title)
+
+	bySource, err := GetHistoricalDataBySource(source)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(bySource["2099-12-31"]))
+}
+
+func TestMigrateLegacyItems(t *testing.T) {
+	// 创建临时SQLite数据库文件
+	tempDB := "test_hot_search_legacy.db"
+	defer os.Remove(tempDB) // 测试结束后清理
+
+	cfg := &config.Config{
+		Database: config.DatabaseConfig{
+			Type: "sqlite",
+			DSN:  tempDB,
+		},
+	}
+
+	InitDBWithConfig(cfg)
+
+	// 模拟旧版本直接写入、没有快照的条目
+	createdAt := time.Date(2099, 1, 2, 8, 0, 0, 0, time.Local)
+	legacy := []model.HotSearchItem{
+		{Source: "legacy", Title: "Legacy 1", Index: 1, CreatedAt: createdAt},
+		{Source: "legacy", Title: "Legacy 2", Index: 2, CreatedAt: createdAt},
+	}
+	assert.NoError(t, DB.Create(&legacy).Error)
+
+	assert.NoError(t, migrateLegacyItems(DB))
+
+	items, err := GetLatestData("legacy")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(items))
+	assert.NotZero(t, items[0].SnapshotID)
+	assert.Equal(t, "2099-01-02", items[0].Date)
+	assert.Equal(t, 8, items[0].Hour)
 }
 
 // 测试InitDB函数
@@ -268,3 +368,101 @@ func TestGetHistoricalDataBySource(t *testing.T) {
 	assert.NoError(t, err)
 	// 可能没有历史数据，这很正常
 }
+
+func TestMigrateAddsItemFields(t *testing.T) {
+	initMemoryDB(t)
+
+	// 模拟旧版本的表结构，只有标题、链接和排名
+	assert.NoError(t, DB.Migrator().DropTable(&model.HotSearchItem{}))
+	assert.NoError(t, DB.Exec(`CREATE TABLE hot_search_items (
+		id integer PRIMARY KEY AUTOINCREMENT,
+		snapshot_id integer,
+		source text,
+		title text,
+		url text,
+		item_index integer,
+		created_at datetime,
+		date text,
+		hour integer
+	)`).Error)
+	createdAt := time.Date(2020, 1, 2, 8, 0, 0, 0, time.Local)
+	assert.NoError(t, DB.Exec(`INSERT INTO hot_search_items (source, title, url, item_index, created_at, date, hour)
+		VALUES ('legacy', 'Legacy', 'http://example.com', 1, ?, '2020-01-02', 8)`, createdAt).Error)
+
+	assert.NoError(t, migrate(DB))
+
+	// 旧数据的新字段为空值
+	items, err := GetLatestData("legacy")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(items))
+	assert.Equal(t, "Legacy", items[0].Title)
+	assert.Zero(t, items[0].HotScore)
+	assert.Empty(t, items[0].HotValue)
+	assert.Nil(t, items[0].Extra)
+
+	// 新写入的数据完整保存附加字段
+	err = SaveData("legacy", []model.HotSearchItem{{
+		Title:    "New",
+		URL:      "http://example.com/new",
+		Index:    1,
+		HotScore: 1234000,
+		HotValue: "123.4万",
+		Desc:     "Description",
+		Image:    "http://example.com/cover.png",
+		Extra:    model.Extra{"time": "08:00"},
+	}})
+	assert.NoError(t, err)
+
+	items, err = GetLatestData("legacy")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(items))
+	assert.Equal(t, 1234000.0, items[0].HotScore)
+	assert.Equal(t, "123.4万", items[0].HotValue)
+	assert.Equal(t, "Description", items[0].Desc)
+	assert.Equal(t, "http://example.com/cover.png", items[0].Image)
+	assert.Equal(t, model.Extra{"time": "08:00"}, items[0].Extra)
+}
+
+func TestSearchItems(t *testing.T) {
+	InitSQLiteWithDSN("file:" + t.Name() + "?mode=memory&cache=shared")
+
+	base := time.Date(2099, 12, 31, 10, 0, 0, 0, time.Local)
+	save := func(source string, at time.Time, titles ...string) {
+		items := make([]model.HotSearchItem, len(titles))
+		for i, title := range titles {
+			items[i] = model.HotSearchItem{Title: title, URL: "http://example.com", Index: i + 1}
+		}
+		assert.NoError(t, SaveAllDataAt(map[string][]model.HotSearchItem{source: items}, at))
+	}
+	save("weibo", base, "Go 1.30 发布", "天气")
+	save("weibo", base.Add(time.Hour), "天气", "Go 1.30 发布")
+	save("zhihu", base.Add(2*time.Hour), "如何评价 go 1.30", "100% 完成")
+
+	// 按时间从新到旧排列，SQLite 的 LIKE 对 ASCII 字母不区分大小写
+	items, err := SearchItems("go 1.30", nil, time.Time{}, time.Time{}, 10)
+	assert.NoError(t, err)
+	if assert.Len(t, items, 3) {
+		assert.Equal(t, "如何评价 go 1.30", items[0].Title)
+		assert.Equal(t, 2, items[1].Index)
+		assert.Equal(t, 1, items[2].Index)
+	}
+
+	// 按来源、时间和数量限制
+	items, err = SearchItems("Go", []string{"weibo"}, base.Add(30*time.Minute), time.Time{}, 10)
+	assert.NoError(t, err)
+	assert.Len(t, items, 1)
+	items, err = SearchItems("Go", nil, time.Time{}, base, 10)
+	assert.NoError(t, err)
+	assert.Len(t, items, 1)
+	items, err = SearchItems("Go", nil, time.Time{}, time.Time{}, 2)
+	assert.NoError(t, err)
+	assert.Len(t, items, 2)
+
+	// 通配符按字面匹配
+	items, err = SearchItems("100%", nil, time.Time{}, time.Time{}, 10)
+	assert.NoError(t, err)
+	assert.Len(t, items, 1)
+	items, err = SearchItems("1_30", nil, time.Time{}, time.Time{}, 10)
+	assert.NoError(t, err)
+	assert.Empty(t, items)
+}
@@ -0,0 +1,160 @@
+package db
+
+import (
+	"api/config"
+	"api/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// pruneBatchSize 每次删除的快照数量上限，避免超出数据库参数个数限制
+const pruneBatchSize = 500
+
+// RetentionTier 保留层级：年龄小于 MaxAge 的快照，在每个 Resolution 时间窗口内只保留最新的一次
+//
+// Resolution 为 0 表示该层级内的快照全部保留；MaxAge 为 0 表示该层级没有年龄上限
+type RetentionTier struct {
+	MaxAge     time.Duration
+	Resolution time.Duration
+}
+
+// RetentionPolicy 快照保留策略，Tiers 按 MaxAge 从小到大排列
+//
+// 超过最后一个层级 MaxAge 的快照会被删除；每个来源最新的一次快照总是保留
+type RetentionPolicy struct {
+	Tiers []RetentionTier
+}
+
+// PruneResult 一次清理的结果
+type PruneResult struct {
+	SnapshotsDeleted int            // 删除的快照数量
+	ItemsDeleted     int64          // 删除的条目数量
+	BySource         map[string]int // 每个来源删除的快照数量
+}
+
+// NewRetentionPolicy 根据配置构建保留策略
+func NewRetentionPolicy(cfg config.RetentionConfig) RetentionPolicy {
+	var policy RetentionPolicy
+	if cfg.HourlyDays > 0 {
+		policy.Tiers = append(policy.Tiers, RetentionTier{
+			MaxAge:     time.Duration(cfg.HourlyDays) * 24 * time.Hour,
+			Resolution: time.Hour,
+		})
+	}
+	if cfg.DailyDays <= 0 || cfg.DailyDays > cfg.HourlyDays {
+		policy.Tiers = append(policy.Tiers, RetentionTier{
+			MaxAge:     time.Duration(max(cfg.DailyDays, 0)) * 24 * time.Hour,
+			Resolution: 24 * time.Hour,
+		})
+	}
+	return policy
+}
+
+// PruneSnapshots 按保留策略压缩和删除历史快照
+func PruneSnapshots(policy RetentionPolicy, now time.Time) (PruneResult, error) {
+	defer observeWrite("prune", time.Now())
+	result := PruneResult{BySource: make(map[string]int)}
+
+	var sources []string
+	if err := DB.Model(&model.HotSearchData{}).Distinct("source").Pluck("source", &sources).Error; err != nil {
+		return result, err
+	}
+
+	for _, source := range sources {
+		var snapshots []model.HotSearchData
+		err := DB.Select("id", "source", "created_at").
+			Where("source = ?", source).
+			Order("created_at DESC, id DESC").
+			Find(&snapshots).Error
+		if err != nil {
+			return result, err
+		}
+
+		expired := policy.expiredSnapshots(snapshots, now)
+		if len(expired) == 0 {
+			continue
+		}
+
+		items, err := deleteSnapshots(expired)
+		if err != nil {
+			return result, err
+		}
+		result.SnapshotsDeleted += len(expired)
+		result.ItemsDeleted += items
+		result.BySource[source] = len(expired)
+	}
+
+	return result, nil
+}
+
+// expiredSnapshots 返回按策略应被删除的快照ID，snapshots 需按时间从新到旧排列
+func (p RetentionPolicy) expiredSnapshots(snapshots []model.HotSearchData, now time.Time) []uint {
+	type bucketKey struct {
+		tier   int
+		bucket int64
+	}
+	seen := make(map[bucketKey]bool)
+
+	var expired []uint
+	for i, snapshot := range snapshots {
+		// 最新的快照总是保留，保证即使抓取长期失败也能返回最后一次数据
+		if i == 0 {
+			continue
+		}
+
+		tier, ok := p.tierFor(now.Sub(snapshot.CreatedAt))
+		if !ok {
+			expired = append(expired, snapshot.ID)
+			continue
+		}
+
+		resolution := p.Tiers[tier].Resolution
+		if resolution < time.Second {
+			continue
+		}
+		key := bucketKey{tier: tier, bucket: bucketOf(snapshot.CreatedAt, resolution)}
+		if seen[key] {
+			expired = append(expired, snapshot.ID)
+			continue
+		}
+		seen[key] = true
+	}
+	return expired
+}
+
+// tierFor 返回指定年龄所属的层级
+func (p RetentionPolicy) tierFor(age time.Duration) (int, bool) {
+	for i, tier := range p.Tiers {
+		if tier.MaxAge == 0 || age < tier.MaxAge {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// bucketOf 计算时间所在的时间窗口编号，窗口按本地时区对齐，使按天保留以自然日为单位
+func bucketOf(t time.Time, resolution time.Duration) int64 {
+	_, offset := t.Zone()
+	return (t.Unix() + int64(offset)) / int64(resolution/time.Second)
+}
+
+// deleteSnapshots 分批删除快照及其条目，返回删除的条目数量
+func deleteSnapshots(ids []uint) (int64, error) {
+	var itemsDeleted int64
+	for start := 0; start < len(ids); start += pruneBatchSize {
+		batch := ids[start:min(start+pruneBatchSize, len(ids))]
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			result := tx.Where("snapshot_id IN ?", batch).Delete(&model.HotSearchItem{})
+			if result.Error != nil {
+				return result.Error
+			}
+			itemsDeleted += result.RowsAffected
+			return tx.Where("id IN ?", batch).Delete(&model.HotSearchData{}).Error
+		})
+		if err != nil {
+			return itemsDeleted, err
+		}
+	}
+	return itemsDeleted, nil
+}
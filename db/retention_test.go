@@ -0,0 +1,123 @@
+package db
+
+import (
+	"api/config"
+	"api/model"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// initMemoryDB 初始化一个独立的内存SQLite数据库
+func initMemoryDB(t *testing.T) {
+	InitSQLiteWithDSN("file:" + t.Name() + "?mode=memory&cache=shared")
+}
+
+// saveSnapshotAt 在指定时间写入一个只包含一条数据的快照
+func saveSnapshotAt(t *testing.T, source string, at time.Time) {
+	err := SaveAllDataAt(map[string][]model.HotSearchItem{
+		source: {{Title: at.Format(time.RFC3339), URL: "http://example.com", Index: 1}},
+	}, at)
+	assert.NoError(t, err)
+}
+
+func countSnapshots(t *testing.T, source string) int64 {
+	var count int64
+	assert.NoError(t, DB.Model(&model.HotSearchData{}).Where("source = ?", source).Count(&count).Error)
+	return count
+}
+
+func TestNewRetentionPolicy(t *testing.T) {
+	policy := NewRetentionPolicy(config.RetentionConfig{HourlyDays: 7, DailyDays: 90})
+	assert.Equal(t, []RetentionTier{
+		{MaxAge: 7 * 24 * time.Hour, Resolution: time.Hour},
+		{MaxAge: 90 * 24 * time.Hour, Resolution: 24 * time.Hour},
+	}, policy.Tiers)
+
+	// 按天保留的天数为 0 表示永久保留
+	policy = NewRetentionPolicy(config.RetentionConfig{HourlyDays: 7, DailyDays: 0})
+	assert.Equal(t, 2, len(policy.Tiers))
+	assert.Equal(t, time.Duration(0), policy.Tiers[1].MaxAge)
+
+	// 按天保留的天数不超过按小时保留的天数时，只保留小时级快照
+	policy = NewRetentionPolicy(config.RetentionConfig{HourlyDays: 7, DailyDays: 3})
+	assert.Equal(t, []RetentionTier{{MaxAge: 7 * 24 * time.Hour, Resolution: time.Hour}}, policy.Tiers)
+}
+
+func TestPruneSnapshotsCompactsHourly(t *testing.T) {
+	initMemoryDB(t)
+
+	now := time.Date(2099, 6, 15, 12, 0, 0, 0, time.Local)
+	source := "weibo"
+
+	// 两小时前的同一小时内有三次快照，应只保留最新的一次
+	saveSnapshotAt(t, source, now.Add(-2*time.Hour))
+	saveSnapshotAt(t, source, now.Add(-2*time.Hour+10*time.Minute))
+	saveSnapshotAt(t, source, now.Add(-2*time.Hour+20*time.Minute))
+	// 当前小时的快照
+	saveSnapshotAt(t, source, now.Add(-time.Minute))
+
+	policy := NewRetentionPolicy(config.RetentionConfig{HourlyDays: 7, DailyDays: 90})
+	result, err := PruneSnapshots(policy, now)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, result.SnapshotsDeleted)
+	assert.Equal(t, int64(2), result.ItemsDeleted)
+	assert.Equal(t, 2, result.BySource[source])
+	assert.Equal(t, int64(2), countSnapshots(t, source))
+
+	// 保留下来的是该小时内最新的快照
+	items, err := GetHistoricalData(source, now.Format("2006-01-02"), now.Add(-2*time.Hour).Hour())
+	assert.NoError(t, err)
+	assert.Equal(t, now.Add(-2*time.Hour+20*time.Minute).Format(time.RFC3339), items[0].Title)
+
+	// 再次执行不会删除更多快照
+	result, err = PruneSnapshots(policy, now)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, result.SnapshotsDeleted)
+}
+
+func TestPruneSnapshotsCompactsDailyAndExpires(t *testing.T) {
+	initMemoryDB(t)
+
+	now := time.Date(2099, 6, 15, 12, 0, 0, 0, time.Local)
+	source := "zhihu"
+
+	// 10天前的同一天内有两次快照，应压缩为一次
+	tenDaysAgo := time.Date(2099, 6, 5, 8, 0, 0, 0, time.Local)
+	saveSnapshotAt(t, source, tenDaysAgo)
+	saveSnapshotAt(t, source, tenDaysAgo.Add(5*time.Hour))
+	// 100天前的快照超过保留期限，应被删除
+	saveSnapshotAt(t, source, now.AddDate(0, 0, -100))
+	// 最新快照
+	saveSnapshotAt(t, source, now)
+
+	policy := NewRetentionPolicy(config.RetentionConfig{HourlyDays: 7, DailyDays: 90})
+	result, err := PruneSnapshots(policy, now)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, result.SnapshotsDeleted)
+	assert.Equal(t, int64(2), countSnapshots(t, source))
+
+	byDate, err := GetHistoricalDataByDate(source, "2099-06-05")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(byDate))
+	assert.NotNil(t, byDate[13])
+}
+
+func TestPruneSnapshotsKeepsLatest(t *testing.T) {
+	initMemoryDB(t)
+
+	now := time.Date(2099, 6, 15, 12, 0, 0, 0, time.Local)
+
+	// 即使唯一的快照已经超过保留期限，也应该保留
+	saveSnapshotAt(t, "cctv", now.AddDate(-1, 0, 0))
+
+	policy := NewRetentionPolicy(config.RetentionConfig{HourlyDays: 7, DailyDays: 90})
+	result, err := PruneSnapshots(policy, now)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, result.SnapshotsDeleted)
+
+	items, err := GetLatestData("cctv")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(items))
+}
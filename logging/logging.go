@@ -0,0 +1,84 @@
+// Package logging 提供服务统一使用的结构化日志
+//
+// 日志基于 log/slog，请求ID、数据源、定时抓取的运行ID等字段通过 context 传递，
+// 使用 FromContext 获取的日志实例会自动带上这些字段
+package logging
+
+import (
+	"api/config"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// 日志中通用的字段名
+const (
+	KeySource    = "source"     // 数据源路由名称
+	KeyRequestID = "request_id" // HTTP 请求ID
+	KeyRunID     = "run_id"     // 定时抓取的运行ID
+	KeyDuration  = "duration"   // 耗时
+	KeyError     = "error"      // 错误信息
+)
+
+// New 按配置创建日志实例，日志写入 w
+func New(cfg config.LogConfig, w io.Writer) (*slog.Logger, error) {
+	var level slog.Level
+	if cfg.Level != "" {
+		if err := level.UnmarshalText([]byte(cfg.Level)); err != nil {
+			return nil, fmt.Errorf("无效的日志级别 %q", cfg.Level)
+		}
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+	switch strings.ToLower(cfg.Format) {
+	case "", "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("无效的日志格式 %q，可选 text 或 json", cfg.Format)
+	}
+}
+
+// Setup 按配置创建日志实例并设置为默认实例
+func Setup(cfg config.LogConfig, w io.Writer) (*slog.Logger, error) {
+	logger, err := New(cfg, w)
+	if err != nil {
+		return nil, err
+	}
+	slog.SetDefault(logger)
+	return logger, nil
+}
+
+type contextKey struct{}
+
+// NewContext 返回带有指定日志实例的 context
+func NewContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext 获取 context 中的日志实例，没有时返回默认实例
+func FromContext(ctx context.Context) *slog.Logger {
+	if ctx != nil {
+		if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+			return logger
+		}
+	}
+	return slog.Default()
+}
+
+// With 返回日志实例附加了指定字段的 context，之后从该 context 获取的日志都会带上这些字段
+func With(ctx context.Context, args ...any) context.Context {
+	return NewContext(ctx, FromContext(ctx).With(args...))
+}
+
+// NewID 生成用于请求ID和运行ID的随机字符串
+func NewID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
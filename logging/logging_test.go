@@ -0,0 +1,91 @@
+package logging
+
+import (
+	"api/config"
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// 测试按配置创建日志实例
+func TestNew(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(config.LogConfig{Level: "warn", Format: "json"}, &buf)
+	assert.NoError(t, err)
+
+	logger.Info("不输出")
+	logger.Warn("输出", KeySource, "zhihu", KeyDuration, time.Second)
+
+	var line map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	assert.Equal(t, "WARN", line["level"])
+	assert.Equal(t, "输出", line["msg"])
+	assert.Equal(t, "zhihu", line[KeySource])
+
+	buf.Reset()
+	logger, err = New(config.LogConfig{}, &buf)
+	assert.NoError(t, err)
+	logger.Debug("不输出")
+	logger.Info("输出", KeySource, "zhihu")
+	assert.Contains(t, buf.String(), "level=INFO")
+	assert.Contains(t, buf.String(), "source=zhihu")
+	assert.NotContains(t, buf.String(), "不输出")
+
+	_, err = New(config.LogConfig{Level: "verbose"}, &buf)
+	assert.Error(t, err)
+	_, err = New(config.LogConfig{Format: "xml"}, &buf)
+	assert.Error(t, err)
+}
+
+// 测试通过 context 传递日志字段
+func TestContext(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+
+	// 没有日志实例时返回默认实例
+	assert.Equal(t, slog.Default(), FromContext(context.Background()))
+
+	ctx := NewContext(context.Background(), logger)
+	ctx = With(ctx, KeyRunID, "run-1")
+	ctx = With(ctx, KeySource, "weibo")
+	FromContext(ctx).Info("抓取完成")
+	assert.Contains(t, buf.String(), "run_id=run-1 source=weibo")
+}
+
+// 测试请求日志中间件
+func TestMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+	defer slog.SetDefault(defaultLogger)
+
+	app := fiber.New()
+	app.Use(Middleware())
+	app.Get("/test", func(c *fiber.Ctx) error {
+		FromContext(c.UserContext()).Info("处理请求")
+		return c.SendString(c.Locals(KeyRequestID).(string))
+	})
+
+	// 没有请求ID时生成一个
+	resp, err := app.Test(httptest.NewRequest("GET", "/test", nil))
+	assert.NoError(t, err)
+	requestID := resp.Header.Get(fiber.HeaderXRequestID)
+	assert.Len(t, requestID, 16)
+	assert.Contains(t, buf.String(), "msg=处理请求 request_id="+requestID)
+	assert.Contains(t, buf.String(), "msg=请求完成 request_id="+requestID+" method=GET path=/test status=200")
+
+	// 使用请求头中的请求ID
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set(fiber.HeaderXRequestID, "abc")
+	resp, err = app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, "abc", resp.Header.Get(fiber.HeaderXRequestID))
+	assert.Contains(t, buf.String(), "request_id=abc")
+}
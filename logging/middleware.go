@@ -0,0 +1,46 @@
+package logging
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Middleware 记录请求日志的中间件
+//
+// 使用请求头中的 X-Request-ID 作为请求ID，没有时生成一个，并写入响应头和 Locals。
+// 带有请求ID的日志实例保存在请求的 UserContext 中，处理函数通过 FromContext(c.UserContext()) 获取
+func Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		requestID := c.Get(fiber.HeaderXRequestID)
+		if requestID == "" || len(requestID) > 64 {
+			requestID = NewID()
+		}
+		c.Set(fiber.HeaderXRequestID, requestID)
+		// 同时保存在 Locals 中，WebSocket 连接升级后只能通过 Locals 获取
+		c.Locals(KeyRequestID, requestID)
+
+		logger := FromContext(c.UserContext()).With(KeyRequestID, requestID)
+		c.SetUserContext(NewContext(c.UserContext(), logger))
+
+		err := c.Next()
+
+		// 错误由后面的错误处理函数写入响应，这里按错误推算状态码
+		status := c.Response().StatusCode()
+		if err != nil {
+			status = fiber.StatusInternalServerError
+			if e, ok := err.(*fiber.Error); ok {
+				status = e.Code
+			}
+		}
+		logger.Info("请求完成",
+			"method", c.Method(),
+			"path", c.Path(),
+			"status", status,
+			"ip", c.IP(),
+			KeyDuration, time.Since(start),
+		)
+		return err
+	}
+}
@@ -18,39 +18,72 @@
 package main
 
 import (
+	"api/app"
 	"api/config"
 	"api/db"
+	"api/logging"
 	"api/mcp"
 	"api/router"
 	"api/service"
+	"api/websocket"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"api/docs" // docs is generated by Swag CLI, you have to import it.
 
 	"github.com/goccy/go-json"
 	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/log"
 	_ "github.com/swaggo/files" // swagger embed files
 )
 
 func main() {
-	// 全局日志实例
-	log.SetOutput(os.Stdout)
-
 	// 加载配置
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		log.Fatal("Failed to load config: ", err)
+		fatal("Failed to load config", err)
+	}
+
+	// 全局日志实例，启用 MCP STDIO 时标准输出用于协议通信，日志写入标准错误
+	logOutput := os.Stdout
+	if cfg.MCP != nil && cfg.MCP.STDIOEnabled {
+		logOutput = os.Stderr
+	}
+	if _, err := logging.Setup(cfg.Log, logOutput); err != nil {
+		fatal("Failed to set up logger", err)
 	}
 
 	// 初始化数据库
 	db.InitDBWithConfig(cfg)
 
+	// 初始化共享的HTTP抓取器
+	fetcher, err := app.NewFetcher(cfg.Fetcher)
+	if err != nil {
+		fatal("Failed to create fetcher", err)
+	}
+	app.SetDefaultFetcher(fetcher)
+	if err := app.SetDisabledSources(cfg.Sources.Disabled); err != nil {
+		fatal("Invalid source config", err)
+	}
+
 	// 初始化服务
 	hotSearchService := &service.HotSearchService{}
+	hotSearchService.SetLiveConfig(cfg.Live)
+	hotSearchService.SetCacheConfig(cfg.Cache)
+
+	// 收到 SIGINT 或 SIGTERM 后开始关闭服务
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	// 启动定时任务
-	hotSearchService.StartScheduler()
+	// 启动定时任务，关闭服务时通过 Shutdown 停止
+	hotSearchService.StartScheduler(context.Background(), cfg.Schedule)
+
+	// 启动历史快照清理任务
+	hotSearchService.StartRetention(cfg.Retention)
 
 	// 创建Fiber应用实例
 	appInstance := fiber.New(fiber.Config{
@@ -61,23 +94,106 @@ func main() {
 	})
 
 	// 设置路由
-	router.SetupRoutes(appInstance, hotSearchService, cfg)
+	wsManager := router.SetupRoutes(appInstance, hotSearchService, cfg)
 
 	// 设置MCP路由
-	mcp.SetupMCPRoutes(appInstance, hotSearchService, cfg)
+	mcpHandler := mcp.SetupMCPRoutes(appInstance, hotSearchService, cfg)
 
 	// 动态更新Swagger文档中的Host
 	updateSwaggerHost(cfg)
 
 	// 根据配置启动服务器（HTTP或HTTPS）
-	if cfg.Server.TLSEnabled && cfg.Server.TLSCertFile != "" && cfg.Server.TLSKeyFile != "" {
-		log.Info("Starting HTTPS server on: ", cfg.GetServerAddress())
-		log.Fatal(appInstance.ListenTLS(cfg.GetServerAddress(), cfg.Server.TLSCertFile, cfg.Server.TLSKeyFile))
-	} else {
+	serverErr := make(chan error, 1)
+	go func() {
 		serverAddress := cfg.GetServerAddress()
-		log.Info("Starting HTTP server on: ", serverAddress)
-		log.Fatal(appInstance.Listen(serverAddress))
+		if cfg.Server.TLSEnabled && cfg.Server.TLSCertFile != "" && cfg.Server.TLSKeyFile != "" {
+			slog.Info("Starting HTTPS server", "address", serverAddress)
+			serverErr <- appInstance.ListenTLS(serverAddress, cfg.Server.TLSCertFile, cfg.Server.TLSKeyFile)
+		} else {
+			slog.Info("Starting HTTP server", "address", serverAddress)
+			serverErr <- appInstance.Listen(serverAddress)
+		}
+	}()
+
+	// 收到 SIGHUP 时重新加载配置
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	defer signal.Stop(reload)
+
+	// 服务器无法启动（如端口被占用）时同样需要停止后台任务，关闭后以非零状态退出
+	var listenErr error
+wait:
+	for {
+		select {
+		case listenErr = <-serverErr:
+			break wait
+		case <-ctx.Done():
+			slog.Info("Shutting down", "timeout", cfg.Server.ShutdownTimeout)
+			break wait
+		case <-reload:
+			reloadConfig(hotSearchService)
+		}
+	}
+	stop()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := shutdown(shutdownCtx, appInstance, wsManager, mcpHandler, hotSearchService); err != nil {
+		fatal("Shutdown did not complete", err)
+	}
+	if listenErr != nil {
+		fatal("Server stopped", listenErr)
+	}
+	slog.Info("Shutdown complete")
+}
+
+// reloadConfig 重新加载配置文件和 .env 文件，只应用不需要重启的配置项：停用的数据源和定时抓取计划
+//
+// 配置无效时继续使用原来的配置
+func reloadConfig(hotSearchService *service.HotSearchService) {
+	cfg, err := config.LoadConfig()
+	if err == nil {
+		err = app.SetDisabledSources(cfg.Sources.Disabled)
+	}
+	if err != nil {
+		slog.Error("Failed to reload config, keeping the current config", logging.KeyError, err)
+		return
+	}
+	hotSearchService.UpdateSchedule(cfg.Schedule)
+	slog.Info("Config reloaded", "disabled_sources", cfg.Sources.Disabled)
+}
+
+// shutdown 按顺序关闭服务，所有步骤共用 ctx 的截止时间
+//
+// 先向 WebSocket 客户端发送关闭帧、关闭 MCP 服务器的 SSE 流，长连接断开后停止接收新的HTTP请求，
+// 等待进行中的请求完成，再停止定时任务（等待进行中的抓取和数据库写入完成），最后关闭数据库。
+// 某个步骤失败时仍然继续后面的步骤，返回遇到的所有错误
+func shutdown(ctx context.Context, appInstance *fiber.App, wsManager *websocket.WsManager, mcpHandler *mcp.MCPHandler, hotSearchService *service.HotSearchService) error {
+	steps := []struct {
+		name string
+		stop func(context.Context) error
+	}{
+		{"websocket", wsManager.Shutdown},
+		{"mcp", mcpHandler.Shutdown},
+		{"http", appInstance.ShutdownWithContext},
+		{"scheduler", hotSearchService.Shutdown},
+		{"database", func(context.Context) error { return db.Close() }},
 	}
+
+	var errs []error
+	for _, step := range steps {
+		if err := step.stop(ctx); err != nil {
+			slog.Error("Shutdown step failed", "step", step.name, logging.KeyError, err)
+			errs = append(errs, fmt.Errorf("%s: %w", step.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// fatal 记录错误并退出
+func fatal(msg string, err error) {
+	slog.Error(msg, logging.KeyError, err)
+	os.Exit(1)
 }
 
 // updateSwaggerHost 更新Swagger文档中的Host
@@ -0,0 +1,204 @@
+package mcp
+
+import (
+	"api/logging"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// JSON-RPC 2.0 的错误码
+const (
+	CodeParseError     = -32700 // 无法解析JSON
+	CodeInvalidRequest = -32600 // 不是有效的请求对象
+	CodeMethodNotFound = -32601 // 方法不存在
+	CodeInvalidParams  = -32602 // 参数无效
+	CodeInternalError  = -32603 // 服务器内部错误
+)
+
+// jsonrpcVersion 请求和响应中 jsonrpc 字段的值
+const jsonrpcVersion = "2.0"
+
+// ID JSON-RPC 请求ID，可以是字符串、数字或 null，响应中原样返回
+//
+// 请求中没有 id 字段时为空，表示这是一个通知，不需要响应
+type ID json.RawMessage
+
+// StringID 创建字符串ID
+func StringID(s string) ID {
+	data, _ := json.Marshal(s)
+	return ID(data)
+}
+
+// NumberID 创建数字ID
+func NumberID(n int64) ID {
+	return ID(strconv.FormatInt(n, 10))
+}
+
+// String 返回ID的字面值，字符串ID不带引号，用于日志
+func (id ID) String() string {
+	var s string
+	if json.Unmarshal(id, &s) == nil {
+		return s
+	}
+	return string(id)
+}
+
+// MarshalJSON 输出ID，为空时输出 null
+func (id ID) MarshalJSON() ([]byte, error) {
+	if len(id) == 0 {
+		return []byte("null"), nil
+	}
+	return id, nil
+}
+
+// UnmarshalJSON 读取ID，只接受字符串、数字或 null
+func (id *ID) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return errors.New("id 为空")
+	}
+	switch data[0] {
+	case '"', 'n', '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		// 字符串、数字和 null 本身是有效的JSON，这里只检查类型
+	default:
+		return fmt.Errorf("id 必须是字符串、数字或 null: %s", data)
+	}
+	*id = append((*id)[:0], data...)
+	return nil
+}
+
+// Request 表示MCP请求，没有ID的请求是通知
+type Request struct {
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      ID              `json:"id,omitempty"`
+	Version string          `json:"jsonrpc"`
+}
+
+// IsNotification 请求是否为通知
+func (r *Request) IsNotification() bool {
+	return len(r.ID) == 0
+}
+
+// Response 表示MCP响应
+type Response struct {
+	ID      ID          `json:"id"`
+	Result  interface{} `json:"result,omitempty"`
+	Error   *Error      `json:"error,omitempty"`
+	Version string      `json:"jsonrpc"`
+}
+
+// Error 表示MCP错误，同时实现 error 接口，方法处理函数返回它时作为 JSON-RPC 错误响应
+type Error struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d: %s", e.Code, e.Message)
+}
+
+// invalidParams 创建参数无效的错误
+func invalidParams(format string, args ...interface{}) *Error {
+	return &Error{Code: CodeInvalidParams, Message: fmt.Sprintf(format, args...)}
+}
+
+// decodeParams 将请求参数解析到 v，参数为空时不修改 v
+func decodeParams(params json.RawMessage, v interface{}) error {
+	if len(params) == 0 || bytes.Equal(params, []byte("null")) {
+		return nil
+	}
+	if err := json.Unmarshal(params, v); err != nil {
+		return invalidParams("Invalid params: %v", err)
+	}
+	return nil
+}
+
+// HandleRequest 处理MCP请求
+func (m *MCPHandler) HandleRequest(requestBytes []byte) ([]byte, error) {
+	return m.HandleRequestContext(context.Background(), requestBytes)
+}
+
+// HandleRequestContext 处理单个请求或批量请求，ctx 中的日志字段（如请求ID）会带到工具调用的日志中
+//
+// 通知和只包含通知的批量请求没有响应，返回 nil
+func (m *MCPHandler) HandleRequestContext(ctx context.Context, requestBytes []byte) ([]byte, error) {
+	ctx = m.withLogger(ctx)
+	requestBytes = bytes.TrimSpace(requestBytes)
+	if !json.Valid(requestBytes) {
+		return json.Marshal(errorResponse(nil, CodeParseError, "Parse error: unable to parse JSON"))
+	}
+
+	// 批量请求按顺序处理，只返回非通知请求的响应
+	if len(requestBytes) > 0 && requestBytes[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(requestBytes, &batch); err != nil {
+			return json.Marshal(errorResponse(nil, CodeParseError, "Parse error: unable to parse JSON"))
+		}
+		if len(batch) == 0 {
+			return json.Marshal(errorResponse(nil, CodeInvalidRequest, "Invalid Request: empty batch"))
+		}
+		responses := make([]*Response, 0, len(batch))
+		for _, raw := range batch {
+			if response := m.handleMessage(ctx, raw); response != nil {
+				responses = append(responses, response)
+			}
+		}
+		if len(responses) == 0 {
+			return nil, nil
+		}
+		return json.Marshal(responses)
+	}
+
+	response := m.handleMessage(ctx, requestBytes)
+	if response == nil {
+		return nil, nil
+	}
+	return json.Marshal(response)
+}
+
+// handleMessage 处理单个JSON-RPC消息，通知返回 nil
+func (m *MCPHandler) handleMessage(ctx context.Context, raw json.RawMessage) *Response {
+	var req Request
+	if err := json.Unmarshal(raw, &req); err != nil {
+		return errorResponse(nil, CodeInvalidRequest, "Invalid Request: "+err.Error())
+	}
+	if req.Version != jsonrpcVersion || req.Method == "" {
+		// 无法判断是否为通知，按规范返回错误
+		return errorResponse(req.ID, CodeInvalidRequest, `Invalid Request: jsonrpc must be "2.0" and method is required`)
+	}
+
+	result, err := m.dispatch(ctx, &req)
+	if req.IsNotification() {
+		if err != nil {
+			m.logger.Debug("处理通知失败", "method", req.Method, logging.KeyError, err)
+		}
+		return nil
+	}
+	if err != nil {
+		var rpcErr *Error
+		if !errors.As(err, &rpcErr) {
+			rpcErr = &Error{Code: CodeInternalError, Message: err.Error()}
+		}
+		return &Response{ID: req.ID, Error: rpcErr, Version: jsonrpcVersion}
+	}
+	// 结果为空时返回空对象，result 和 error 必须有一个
+	if result == nil {
+		result = struct{}{}
+	}
+	return &Response{ID: req.ID, Result: result, Version: jsonrpcVersion}
+}
+
+// errorResponse 创建错误响应
+func errorResponse(id ID, code int, message string) *Response {
+	return &Response{
+		ID:      id,
+		Error:   &Error{Code: code, Message: message},
+		Version: jsonrpcVersion,
+	}
+}
@@ -0,0 +1,125 @@
+package mcp
+
+import (
+	"api/config"
+	"api/service"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestID(t *testing.T) {
+	// 字符串和数字ID原样返回
+	for _, id := range []ID{StringID("abc"), NumberID(42), ID("null")} {
+		data, err := json.Marshal(Response{ID: id, Result: struct{}{}, Version: "2.0"})
+		assert.NoError(t, err)
+
+		var response Response
+		assert.NoError(t, json.Unmarshal(data, &response))
+		assert.Equal(t, id, response.ID)
+	}
+	assert.Equal(t, "abc", StringID("abc").String())
+	assert.Equal(t, "42", NumberID(42).String())
+
+	// 空ID输出 null
+	data, err := json.Marshal(Response{Version: "2.0"})
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"id":null`)
+
+	// 对象和数组不是有效的ID
+	var req Request
+	assert.Error(t, json.Unmarshal([]byte(`{"id":{"a":1}}`), &req))
+	assert.Error(t, json.Unmarshal([]byte(`{"id":[1]}`), &req))
+}
+
+func TestHandleRequestNumericID(t *testing.T) {
+	handler := NewMCPHandler(&service.HotSearchService{}, &config.Config{})
+
+	responseBytes, err := handler.HandleRequest([]byte(`{"jsonrpc":"2.0","id":7,"method":"ping"}`))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":7,"result":{"message":"pong"}}`, string(responseBytes))
+}
+
+func TestHandleRequestInvalidRequest(t *testing.T) {
+	handler := NewMCPHandler(&service.HotSearchService{}, &config.Config{})
+
+	tests := []struct {
+		name    string
+		request string
+		id      ID
+	}{
+		{"InvalidID", `{"jsonrpc":"2.0","id":{"a":1},"method":"ping"}`, nil},
+		{"MissingVersion", `{"id":1,"method":"ping"}`, NumberID(1)},
+		{"MissingMethod", `{"jsonrpc":"2.0","id":"x"}`, StringID("x")},
+		{"NotObject", `"ping"`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			responseBytes, err := handler.HandleRequest([]byte(tt.request))
+			assert.NoError(t, err)
+
+			var response Response
+			assert.NoError(t, json.Unmarshal(responseBytes, &response))
+			if tt.id == nil {
+				assert.Contains(t, string(responseBytes), `"id":null`)
+			} else {
+				assert.Equal(t, tt.id, response.ID)
+			}
+			if assert.NotNil(t, response.Error) {
+				assert.Equal(t, CodeInvalidRequest, response.Error.Code)
+			}
+		})
+	}
+}
+
+func TestHandleRequestNotification(t *testing.T) {
+	handler := NewMCPHandler(&service.HotSearchService{}, &config.Config{})
+
+	// 通知没有响应，包括未知方法的通知
+	for _, request := range []string{
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":1}}`,
+		`{"jsonrpc":"2.0","method":"ping"}`,
+		`{"jsonrpc":"2.0","method":"unknown/method"}`,
+	} {
+		responseBytes, err := handler.HandleRequest([]byte(request))
+		assert.NoError(t, err)
+		assert.Nil(t, responseBytes, request)
+	}
+}
+
+func TestHandleRequestBatch(t *testing.T) {
+	handler := NewMCPHandler(&service.HotSearchService{}, &config.Config{})
+
+	// 按顺序返回非通知请求的响应
+	responseBytes, err := handler.HandleRequest([]byte(`[
+		{"jsonrpc":"2.0","id":1,"method":"ping"},
+		{"jsonrpc":"2.0","method":"notifications/initialized"},
+		{"jsonrpc":"2.0","id":"2","method":"unknown/method"},
+		42
+	]`))
+	assert.NoError(t, err)
+
+	var responses []Response
+	assert.NoError(t, json.Unmarshal(responseBytes, &responses))
+	if assert.Len(t, responses, 3) {
+		assert.Equal(t, NumberID(1), responses[0].ID)
+		assert.NotNil(t, responses[0].Result)
+		assert.Equal(t, StringID("2"), responses[1].ID)
+		assert.Equal(t, CodeMethodNotFound, responses[1].Error.Code)
+		assert.Equal(t, CodeInvalidRequest, responses[2].Error.Code)
+	}
+
+	// 只包含通知时没有响应
+	responseBytes, err = handler.HandleRequest([]byte(`[{"jsonrpc":"2.0","method":"notifications/initialized"}]`))
+	assert.NoError(t, err)
+	assert.Nil(t, responseBytes)
+
+	// 空的批量请求无效
+	var response Response
+	responseBytes, err = handler.HandleRequest([]byte(`[]`))
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(responseBytes, &response))
+	assert.Equal(t, CodeInvalidRequest, response.Error.Code)
+}
@@ -1,68 +1,68 @@
 package mcp
 
 import (
-	"api/all"
-	"api/app"
 	"api/config"
+	"api/logging"
 	"api/service"
 	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
+	"net"
 	"os"
-	"reflect"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/gofiber/fiber/v2"
 )
 
-// MCP协议基于JSON-RPC 2.0，用于AI模型与外部工具交互
+// MCP协议基于JSON-RPC 2.0，用于AI模型与外部工具交互，协议规范见 https://modelcontextprotocol.io/specification
 
-// Request 表示MCP请求
-type Request struct {
-	Method  string      `json:"method"`
-	Params  interface{} `json:"params,omitempty"`
-	ID      string      `json:"id,omitempty"`
-	Version string      `json:"jsonrpc"`
-}
+// ProtocolVersion 服务器支持的最新MCP协议版本
+const ProtocolVersion = "2025-06-18"
 
-// Response 表示MCP响应
-type Response struct {
-	ID      string      `json:"id,omitempty"`
-	Result  interface{} `json:"result,omitempty"`
-	Error   *Error      `json:"error,omitempty"`
-	Version string      `json:"jsonrpc"`
-}
+// supportedProtocolVersions 服务器支持的协议版本，从新到旧排列
+var supportedProtocolVersions = []string{ProtocolVersion, "2025-03-26", "2024-11-05"}
 
-// Error 表示MCP错误
-type Error struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
+// serverInfo 服务器的名称和版本
+var serverInfo = Implementation{Name: "azhot", Title: "azhot 热搜聚合", Version: "1.0.0"}
+
+// Implementation 客户端或服务器的名称和版本
+type Implementation struct {
+	Name    string `json:"name"`
+	Title   string `json:"title,omitempty"`
+	Version string `json:"version"`
 }
 
-// Tool 定义MCP工具
-type Tool struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	InputSchema Schema `json:"inputSchema"`
+// InitializeParams initialize 请求的参数
+type InitializeParams struct {
+	ProtocolVersion string                 `json:"protocolVersion"`
+	Capabilities    map[string]interface{} `json:"capabilities"`
+	ClientInfo      Implementation         `json:"clientInfo"`
 }
 
-// Schema 定义工具输入模式
-type Schema struct {
-	Type       string                 `json:"type"`
-	Properties map[string]interface{} `json:"properties"`
-	Required   []string               `json:"required,omitempty"`
+// InitializeResult initialize 请求的结果
+type InitializeResult struct {
+	ProtocolVersion string             `json:"protocolVersion"`
+	Capabilities    ServerCapabilities `json:"capabilities"`
+	ServerInfo      Implementation     `json:"serverInfo"`
+	Instructions    string             `json:"instructions,omitempty"`
 }
 
-// ListPromptsResponse 响应结构
-type ListPromptsResponse struct {
-	Prompts []Prompt `json:"prompts"`
+// ServerCapabilities 服务器支持的功能
+type ServerCapabilities struct {
+	Tools     *ListChangedCapability `json:"tools,omitempty"`
+	Prompts   *ListChangedCapability `json:"prompts,omitempty"`
+	Resources *ResourcesCapability   `json:"resources,omitempty"`
 }
 
-// Prompt 定义提示
-type Prompt struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
+// ListChangedCapability 列表类功能，listChanged 表示列表变化时是否发送通知
+type ListChangedCapability struct {
+	ListChanged bool `json:"listChanged"`
 }
 
 // MCPHandler 处理MCP请求
@@ -70,6 +70,21 @@ type MCPHandler struct {
 	service *service.HotSearchService
 	config  *config.Config
 	tools   map[string]Tool
+	prompts map[string]Prompt
+	logger  *slog.Logger
+
+	// 接收服务器通知的会话，httpSessions 为其中的 Streamable HTTP 会话
+	sessionsMu   sync.Mutex
+	sessions     map[string]*session
+	httpSessions map[string]*httpSession
+	unsubscribe  func()
+
+	// 关闭服务器时使用，wg 等待 STDIO 服务器和正在处理的 HTTP 请求
+	mu         sync.Mutex
+	stopCtx    context.Context
+	stop       context.CancelFunc
+	httpServer *fiber.App
+	wg         sync.WaitGroup
 }
 
 // NewMCPHandler 创建新的MCP处理器
@@ -78,440 +93,288 @@ func NewMCPHandler(service *service.HotSearchService, config *config.Config) *MC
 		service: service,
 		config:  config,
 		tools:   make(map[string]Tool),
+		prompts: make(map[string]Prompt),
+		logger:  slog.Default().With("component", "mcp"),
+
+		sessions:     make(map[string]*session),
+		httpSessions: make(map[string]*httpSession),
+		unsubscribe:  func() {},
 	}
+	handler.stopCtx, handler.stop = context.WithCancel(context.Background())
 
 	// 注册可用的工具
 	handler.registerTools()
+	handler.registerPrompts()
 
-	return handler
-}
-
-// registerTools 注册可用的工具
-func (m *MCPHandler) registerTools() {
-	// 注册获取热搜数据的工具
-	m.tools["get_hot_search"] = Tool{
-		Name:        "get_hot_search",
-		Description: "获取各大平台的热搜数据，支持的平台包括baidu, bilibili, zhihu, weibo等",
-		InputSchema: Schema{
-			Type: "object",
-			Properties: map[string]interface{}{
-				"platform": map[string]interface{}{
-					"type":        "string",
-					"description": "平台名称，如baidu, bilibili, zhihu, weibo等",
-				},
-			},
-			Required: []string{"platform"},
-		},
-	}
-
-	// 注册获取所有平台热搜的工具
-	m.tools["get_all_hot_search"] = Tool{
-		Name:        "get_all_hot_search",
-		Description: "获取所有平台的热搜数据聚合",
-		InputSchema: Schema{
-			Type:       "object",
-			Properties: map[string]interface{}{},
-		},
+	// 数据源抓取到新数据后通知所有会话
+	if service != nil {
+		handler.unsubscribe = service.Subscribe(handler.handleFetchEvent)
 	}
 
-	// 注册获取历史热搜数据的工具
-	m.tools["get_history_data"] = Tool{
-		Name:        "get_history_data",
-		Description: "获取指定平台的历史热搜数据",
-		InputSchema: Schema{
-			Type: "object",
-			Properties: map[string]interface{}{
-				"platform": map[string]interface{}{
-					"type":        "string",
-					"description": "平台名称",
-				},
-				"date": map[string]interface{}{
-					"type":        "string",
-					"description": "日期，格式为YYYY-MM-DD",
-				},
-				"hour": map[string]interface{}{
-					"type":        "string",
-					"description": "小时，格式为HH",
-				},
-			},
-			Required: []string{"platform", "date"},
-		},
-	}
+	return handler
 }
 
-// HandleRequest 处理MCP请求
-func (m *MCPHandler) HandleRequest(requestBytes []byte) ([]byte, error) {
-	var req Request
-	if err := json.Unmarshal(requestBytes, &req); err != nil {
-		return m.createErrorResponse("", -32700, "Parse error: unable to parse JSON")
-	}
+// withLogger 在 ctx 的日志中加上组件名称
+func (m *MCPHandler) withLogger(ctx context.Context) context.Context {
+	return logging.NewContext(ctx, logging.FromContext(ctx).With("component", "mcp"))
+}
 
+// dispatch 根据方法名调用处理函数，返回 *Error 时作为 JSON-RPC 错误响应
+func (m *MCPHandler) dispatch(ctx context.Context, req *Request) (interface{}, error) {
 	switch req.Method {
+	case "initialize":
+		return m.handleInitialize(ctx, req.Params)
+	case "ping":
+		return m.handlePing()
 	case "tools/list":
-		return m.handleListTools(req.ID)
+		return m.handleListTools()
+	case "tools/call":
+		return m.handleCallTool(ctx, req.Params)
 	case "tool/execute":
-		return m.handleToolExecute(req)
+		// 兼容旧版本的工具调用方法，直接返回工具的数据
+		return m.handleToolExecute(ctx, req.Params)
 	case "prompts/list":
-		return m.handleListPrompts(req.ID)
-	case "ping":
-		return m.handlePing(req.ID)
-	default:
-		return m.createErrorResponse(req.ID, -32601, "Method not found")
-	}
-}
-
-// handleListTools 处理工具列表请求
-func (m *MCPHandler) handleListTools(id string) ([]byte, error) {
-	tools := make([]Tool, 0, len(m.tools))
-	for _, tool := range m.tools {
-		tools = append(tools, tool)
+		return m.handleListPrompts()
+	case "prompts/get":
+		return m.handleGetPrompt(req.Params)
+	case "resources/list":
+		return m.handleListResources()
+	case "resources/templates/list":
+		return m.handleListResourceTemplates()
+	case "resources/read":
+		return m.handleReadResource(req.Params)
+	case "resources/subscribe":
+		return m.handleSubscribeResource(ctx, req.Params)
+	case "resources/unsubscribe":
+		return m.handleUnsubscribeResource(ctx, req.Params)
 	}
 
-	response := Response{
-		ID:      id,
-		Result:  map[string]interface{}{"tools": tools},
-		Version: "2.0",
+	// 客户端发送的通知（如 notifications/initialized、notifications/cancelled）不需要处理
+	if req.IsNotification() && strings.HasPrefix(req.Method, "notifications/") {
+		return nil, nil
 	}
-
-	return json.Marshal(response)
+	return nil, &Error{Code: CodeMethodNotFound, Message: "Method not found: " + req.Method}
 }
 
-// handleToolExecute 处理工具执行请求
-func (m *MCPHandler) handleToolExecute(req Request) ([]byte, error) {
-	params, ok := req.Params.(map[string]interface{})
-	if !ok {
-		return m.createErrorResponse(req.ID, -32602, "Invalid params")
+// handleInitialize 处理初始化请求，协商协议版本并返回服务器支持的功能
+//
+// 客户端请求的版本受支持时使用该版本，否则返回服务器支持的最新版本，由客户端决定是否断开
+func (m *MCPHandler) handleInitialize(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p InitializeParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
 	}
-
-	toolName, ok := params["name"].(string)
-	if !ok {
-		return m.createErrorResponse(req.ID, -32602, "Missing tool name")
+	if p.ProtocolVersion == "" {
+		return nil, invalidParams("Missing protocolVersion")
 	}
 
-	_, exists := m.tools[toolName]
-	if !exists {
-		return m.createErrorResponse(req.ID, -32601, "Tool not found: "+toolName)
+	version := ProtocolVersion
+	if slices.Contains(supportedProtocolVersions, p.ProtocolVersion) {
+		version = p.ProtocolVersion
 	}
-
-	// 根据工具名称执行相应的操作
-	switch toolName {
-	case "get_hot_search":
-		platform, ok := params["arguments"].(map[string]interface{})["platform"].(string)
-		if !ok {
-			return m.createErrorResponse(req.ID, -32602, "Missing platform argument")
-		}
-		return m.executeGetHotSearch(req.ID, platform)
-	case "get_all_hot_search":
-		return m.executeGetAllHotSearch(req.ID)
-	case "get_history_data":
-		args, ok := params["arguments"].(map[string]interface{})
-		if !ok {
-			return m.createErrorResponse(req.ID, -32602, "Missing arguments")
-		}
-		platform, platformOk := args["platform"].(string)
-		date, dateOk := args["date"].(string)
-		if !platformOk || !dateOk {
-			return m.createErrorResponse(req.ID, -32602, "Missing required arguments: platform and date")
-		}
-		hour, _ := args["hour"].(string) // hour is optional
-		return m.executeGetHistoryData(req.ID, platform, date, hour)
-	default:
-		return m.createErrorResponse(req.ID, -32601, "Unknown tool: "+toolName)
+	if s := sessionFromContext(ctx); s != nil {
+		s.initialize(version, p.ClientInfo)
 	}
+	logging.FromContext(ctx).Info("MCP 客户端初始化", "client", p.ClientInfo.Name, "client_version", p.ClientInfo.Version,
+		"requested_version", p.ProtocolVersion, "protocol_version", version)
+
+	return InitializeResult{
+		ProtocolVersion: version,
+		Capabilities: ServerCapabilities{
+			Tools:     &ListChangedCapability{},
+			Prompts:   &ListChangedCapability{},
+			Resources: &ResourcesCapability{Subscribe: true},
+		},
+		ServerInfo: serverInfo,
+		Instructions: "azhot 聚合了国内各大平台的热搜榜单，可以获取单个或所有平台的实时热搜，以及按日期查询历史热搜。" +
+			"get_all_hot_search 返回的数据量较大，概览多个平台时优先使用 get_top_hot_search；" +
+			"search_history、trending_across_platforms 和 diff_rankings 可以搜索历史、查找跨平台话题和比较排名变化。" +
+			"资源 azhot://latest/{source} 是各平台最近一次保存的快照，订阅后定时抓取保存新快照时会收到通知。",
+	}, nil
 }
 
-// executeGetHotSearch 执行获取热搜数据的工具
-func (m *MCPHandler) executeGetHotSearch(id, platform string) ([]byte, error) {
-	// 导入app包来调用实际的API函数
-	var result interface{}
-	var err error
-
-	// 使用反射或映射来调用相应的函数
-	switch platform {
-	case "baidu":
-		result, err = callAppFunction("Baidu")
-	case "bilibili":
-		result, err = callAppFunction("Bilibili")
-	case "zhihu":
-		result, err = callAppFunction("Zhihu")
-	case "weibo":
-		result, err = callAppFunction("WeiboHot")
-	case "360search":
-		result, err = callAppFunction("Search360")
-	case "acfun":
-		result, err = callAppFunction("Acfun")
-	case "csdn":
-		result, err = callAppFunction("CSDN")
-	case "dongqiudi":
-		result, err = callAppFunction("Dongqiudi")
-	case "douban":
-		result, err = callAppFunction("Douban")
-	case "douyin":
-		result, err = callAppFunction("Douyin")
-	case "github":
-		result, err = callAppFunction("Github")
-	case "guojiadili":
-		result, err = callAppFunction("GuoJiadili")
-	case "hupu":
-		result, err = callAppFunction("Hupu")
-	case "ithome":
-		result, err = callAppFunction("Ithome")
-	case "lishipin":
-		result, err = callAppFunction("Lishipin")
-	case "pengpai":
-		result, err = callAppFunction("Pengpai")
-	case "qqnews":
-		result, err = callAppFunction("Qqnews")
-	case "sougou":
-		result, err = callAppFunction("Sougou")
-	case "souhu":
-		result, err = callAppFunction("Souhu")
-	case "toutiao":
-		result, err = callAppFunction("Toutiao")
-	case "v2ex":
-		result, err = callAppFunction("V2ex")
-	case "wangyinews":
-		result, err = callAppFunction("WangyiNews")
-	case "xinjingbao":
-		result, err = callAppFunction("Xinjingbao")
-	case "renmin":
-		result, err = callAppFunction("Renminwang")
-	case "nanfang":
-		result, err = callAppFunction("Nanfangzhoumo")
-	case "360doc":
-		result, err = callAppFunction("Doc360")
-	case "cctv":
-		result, err = callAppFunction("CCTV")
-	case "quark":
-		result, err = callAppFunction("Quark")
-	default:
-		return m.createErrorResponse(id, -32602, "Unsupported platform: "+platform)
-	}
-
-	if err != nil {
-		return m.createErrorResponse(id, -32603, "Error calling API: "+err.Error())
-	}
-
-	response := Response{
-		ID:      id,
-		Result:  result,
-		Version: "2.0",
+// handleListTools 处理工具列表请求，按名称排序
+func (m *MCPHandler) handleListTools() (interface{}, error) {
+	tools := make([]Tool, 0, len(m.tools))
+	for _, tool := range m.tools {
+		tools = append(tools, tool)
 	}
-
-	return json.Marshal(response)
+	sort.Slice(tools, func(i, j int) bool {
+		return tools[i].Name < tools[j].Name
+	})
+	return map[string]interface{}{"tools": tools}, nil
 }
 
-// executeGetAllHotSearch 执行获取所有平台热搜的工具
-func (m *MCPHandler) executeGetAllHotSearch(id string) ([]byte, error) {
-	result := all.All()
+// handlePing 处理ping请求
+func (m *MCPHandler) handlePing() (interface{}, error) {
+	return map[string]interface{}{"message": "pong"}, nil
+}
 
-	response := Response{
-		ID:      id,
-		Result:  result,
-		Version: "2.0",
+// RunMCPServerSTDIO 运行MCP服务器通过STDIO，调用 Shutdown 后停止
+func (m *MCPHandler) RunMCPServerSTDIO() {
+	if err := m.ServeSTDIO(m.stopCtx, os.Stdin, os.Stdout); err != nil {
+		m.logger.Error("读取标准输入失败", logging.KeyError, err)
 	}
-
-	return json.Marshal(response)
 }
 
-// callAppFunction 通过反射调用app包中的函数
-func callAppFunction(funcName string) (interface{}, error) {
-	// 获取app包中函数的反射值
-	fn := reflect.ValueOf(getAppFunctionByName(funcName))
-	if !fn.IsValid() || fn.Kind() != reflect.Func {
-		return nil, fmt.Errorf("function %s not found", funcName)
+// ServeSTDIO 从 r 逐行读取JSON-RPC请求，响应逐行写入 w
+//
+// r 读取完毕、ctx 取消或调用 Shutdown 后返回，正在处理的请求会先处理完
+func (m *MCPHandler) ServeSTDIO(ctx context.Context, r io.Reader, w io.Writer) error {
+	// 已经关闭时不再启动
+	if !m.track() {
+		return nil
 	}
-
-	// 调用函数
-	results := fn.Call(nil)
-	if len(results) != 2 { // 期望返回值, error
-		return nil, fmt.Errorf("function %s does not return (interface{}, error)", funcName)
+	defer m.wg.Done()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stopWatching := context.AfterFunc(m.stopCtx, cancel)
+	defer stopWatching()
+
+	// 响应和服务器发起的通知都逐行写入 w
+	var writeMu sync.Mutex
+	writeLine := func(message []byte) {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		fmt.Fprintln(w, string(message))
 	}
-
-	// 检查第二个返回值（error）
-	err := results[1].Interface()
-	if err != nil {
-		if e, ok := err.(error); ok {
-			return nil, e
+	s := &session{id: newSessionID(), transport: "stdio", send: writeLine}
+	m.addSession(s)
+	defer m.removeSession(s.id)
+	ctx = withSession(ctx, s)
+
+	// 读取会一直阻塞，放在单独的协程中，以便 ctx 取消后可以直接返回
+	lines := make(chan string)
+	readErr := make(chan error, 1)
+	go func() {
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			select {
+			case lines <- scanner.Text():
+			case <-ctx.Done():
+				return
+			}
+		}
+		readErr <- scanner.Err()
+	}()
+
+	for {
+		var line string
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-readErr:
+			return err
+		case line = <-lines:
 		}
-	}
 
-	// 返回第一个返回值
-	return results[0].Interface(), nil
-}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
 
-// appFunctionMap 将函数名称映射到app包中的函数
-var appFunctionMap = map[string]interface{}{
-	"Baidu":         app.Baidu,
-	"Bilibili":      app.Bilibili,
-	"Zhihu":         app.Zhihu,
-	"WeiboHot":      app.WeiboHot,
-	"Search360":     app.Search360,
-	"Acfun":         app.Acfun,
-	"CSDN":          app.CSDN,
-	"Dongqiudi":     app.Dongqiudi,
-	"Douban":        app.Douban,
-	"Douyin":        app.Douyin,
-	"Github":        app.Github,
-	"GuoJiadili":    app.Guojiadili,
-	"Hupu":          app.Hupu,
-	"Ithome":        app.Ithome,
-	"Lishipin":      app.Lishipin,
-	"Pengpai":       app.Pengpai,
-	"Qqnews":        app.Qqnews,
-	"Sougou":        app.Sougou,
-	"Souhu":         app.Souhu,
-	"Toutiao":       app.Toutiao,
-	"V2ex":          app.V2ex,
-	"WangyiNews":    app.WangyiNews,
-	"Xinjingbao":    app.Xinjingbao,
-	"Renminwang":    app.Renminwang,
-	"Nanfangzhoumo": app.Nanfangzhoumo,
-	"Doc360":        app.Doc360,
-	"CCTV":          app.CCTV,
-	"Quark":         app.Quark,
-}
+		// 处理输入的JSON-RPC请求，通知没有响应
+		response, err := m.HandleRequestContext(ctx, []byte(line))
+		if err != nil {
+			m.logger.Error("处理请求失败", logging.KeyError, err)
+			continue
+		}
+		if response == nil {
+			continue
+		}
 
-// getAppFunctionByName 获取app包中指定名称的函数
-func getAppFunctionByName(name string) interface{} {
-	if fn, exists := appFunctionMap[name]; exists {
-		return fn
+		// 将响应输出到STDOUT
+		writeLine(response)
 	}
-	return nil
 }
 
-// executeGetHistoryData 执行获取历史数据的工具
-func (m *MCPHandler) executeGetHistoryData(id, platform, date, hour string) ([]byte, error) {
-	var result interface{}
-	var err error
-
-	if hour != "" {
-		// 获取指定日期和小时的历史数据
-		result, err = m.service.GetHistoricalDataForWS(platform, date, hour)
-	} else {
-		// 获取指定日期的所有小时数据
-		result, err = m.service.GetHistoricalDataByDateForWS(platform, date)
-	}
-
+// RunMCPServerHTTP 运行MCP服务器通过HTTP
+func (m *MCPHandler) RunMCPServerHTTP(port string) error {
+	ln, err := net.Listen("tcp", ":"+port)
 	if err != nil {
-		return m.createErrorResponse(id, -32603, "Error getting historical data: "+err.Error())
+		return err
 	}
-
-	response := Response{
-		ID:      id,
-		Result:  result,
-		Version: "2.0",
-	}
-
-	return json.Marshal(response)
+	m.logger.Info("MCP HTTP 服务器启动", "port", port)
+	return m.serveHTTP(ln)
 }
 
-// handleListPrompts 处理提示列表请求
-func (m *MCPHandler) handleListPrompts(id string) ([]byte, error) {
-	prompts := []Prompt{
-		{
-			Name:        "analyze_hot_search_trends",
-			Description: "分析当前热搜趋势，识别热门话题和用户兴趣",
-		},
-		{
-			Name:        "compare_platform_topics",
-			Description: "比较不同平台的热门话题，分析差异和共同点",
-		},
-	}
-
-	response := ListPromptsResponse{
-		Prompts: prompts,
-	}
-
-	result := map[string]interface{}{
-		"prompts": response.Prompts,
-	}
+// serveHTTP 在 ln 上运行独立的MCP HTTP服务器，Streamable HTTP 端点为 / 和 /mcp
+func (m *MCPHandler) serveHTTP(ln net.Listener) error {
+	// 创建一个独立的Fiber应用作为MCP服务器
+	app := fiber.New(fiber.Config{
+		AppName:               "azhot MCP Server",
+		ServerHeader:          "azhot-mcp",
+		DisableStartupMessage: true,
+	})
 
-	resp := Response{
-		ID:      id,
-		Result:  result,
-		Version: "2.0",
-	}
+	// 记录请求日志并生成请求ID
+	app.Use(logging.Middleware())
 
-	return json.Marshal(resp)
-}
+	// 请求体可以是单个请求或批量请求
+	m.mountStreamable(app, "/")
+	m.mountStreamable(app, "/mcp")
 
-// handlePing 处理ping请求
-func (m *MCPHandler) handlePing(id string) ([]byte, error) {
-	response := Response{
-		ID:      id,
-		Result:  map[string]interface{}{"message": "pong"},
-		Version: "2.0",
+	// 已经关闭时不再启动
+	m.mu.Lock()
+	if m.stopCtx.Err() != nil {
+		m.mu.Unlock()
+		return ln.Close()
 	}
+	m.httpServer = app
+	m.mu.Unlock()
 
-	return json.Marshal(response)
+	return app.Listener(ln)
 }
 
-// createErrorResponse 创建错误响应
-func (m *MCPHandler) createErrorResponse(id string, code int, message string) ([]byte, error) {
-	response := Response{
-		ID:      id,
-		Error:   &Error{Code: code, Message: message},
-		Version: "2.0",
+// track 登记一个关闭时需要等待的任务，已经关闭时返回 false
+//
+// 加锁保证 Shutdown 等待时不会再有新的任务
+func (m *MCPHandler) track() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stopCtx.Err() != nil {
+		return false
 	}
-
-	return json.Marshal(response)
+	m.wg.Add(1)
+	return true
 }
 
-// RunMCPServerSTDIO 运行MCP服务器通过STDIO
-func (m *MCPHandler) RunMCPServerSTDIO() {
-	scanner := bufio.NewScanner(os.Stdin)
-
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" {
-			continue
-		}
-
-		// 处理输入的JSON-RPC请求
-		response, err := m.HandleRequest([]byte(line))
-		if err != nil {
-			log.Printf("Error handling request: %v", err)
-			continue
+// Shutdown 停止 STDIO 和 HTTP 服务器，关闭接收通知的 SSE 流，等待正在处理的请求完成后结束所有会话
+//
+// ctx 到期时不再等待，返回 ctx 的错误
+func (m *MCPHandler) Shutdown(ctx context.Context) error {
+	m.mu.Lock()
+	m.stop()
+	server := m.httpServer
+	m.mu.Unlock()
+	m.unsubscribe()
+
+	if server != nil {
+		if err := server.ShutdownWithContext(ctx); err != nil {
+			return err
 		}
-
-		// 将响应输出到STDOUT
-		fmt.Println(string(response))
 	}
 
-	if err := scanner.Err(); err != nil {
-		log.Printf("Error reading stdin: %v", err)
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
 	}
-}
 
-// RunMCPServerHTTP 运行MCP服务器通过HTTP
-func (m *MCPHandler) RunMCPServerHTTP(port string) error {
-	// 创建一个独立的Fiber应用作为MCP服务器
-	app := fiber.New(fiber.Config{
-		AppName:      "azhot MCP Server",
-		ServerHeader: "azhot-mcp",
-	})
-
-	// 添加基本路由
-	app.Post("/", func(c *fiber.Ctx) error {
-		var req Request
-		if err := c.BodyParser(&req); err != nil {
-			return c.Status(400).JSON(fiber.Map{
-				"error": "Invalid request format",
-			})
-		}
-
-		requestBytes, _ := json.Marshal(req)
-		response, err := m.HandleRequest(requestBytes)
-		if err != nil {
-			return c.Status(500).JSON(fiber.Map{
-				"error": err.Error(),
-			})
-		}
-
-		return c.Send(response)
-	})
-
-	log.Printf("Starting MCP HTTP server on port %s", port)
-	return app.Listen(":" + port)
+	m.sessionsMu.Lock()
+	sessions := make([]*httpSession, 0, len(m.httpSessions))
+	for _, s := range m.httpSessions {
+		sessions = append(sessions, s)
+	}
+	m.sessionsMu.Unlock()
+	for _, s := range sessions {
+		m.closeHTTPSession(s)
+	}
+	return nil
 }
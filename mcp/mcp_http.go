@@ -2,17 +2,22 @@ package mcp
 
 import (
 	"api/config"
+	"api/logging"
 	"api/service"
 	"encoding/json"
+	"sort"
+
 	"github.com/gofiber/fiber/v2"
-	"log"
 )
 
-// SetupMCPRoutes 设置MCP相关路由
-func SetupMCPRoutes(app *fiber.App, service *service.HotSearchService, cfg *config.Config) {
+// SetupMCPRoutes 设置MCP相关路由，并按配置启动 STDIO 和 HTTP 服务器，返回的处理器用于在关闭服务时停止它们
+func SetupMCPRoutes(app *fiber.App, service *service.HotSearchService, cfg *config.Config) *MCPHandler {
 	// 创建MCP处理器
 	mcpHandler := NewMCPHandler(service, cfg)
 
+	// Streamable HTTP 端点，MCP 客户端通过它连接
+	mcpHandler.mountStreamable(app, "/mcp")
+
 	// MCP HTTP端点 - 用于调试和直接访问
 	mcpGroup := app.Group("/mcp")
 
@@ -20,11 +25,11 @@ func SetupMCPRoutes(app *fiber.App, service *service.HotSearchService, cfg *conf
 	mcpGroup.Get("/tools", func(c *fiber.Ctx) error {
 		request := Request{
 			Method:  "tools/list",
-			ID:      "http-request",
+			ID:      StringID("http-request"),
 			Version: "2.0",
 		}
 		requestBytes, _ := json.Marshal(request)
-		response, err := mcpHandler.HandleRequest(requestBytes)
+		response, err := mcpHandler.HandleRequestContext(c.UserContext(), requestBytes)
 		if err != nil {
 			return c.Status(500).JSON(fiber.Map{
 				"error": err.Error(),
@@ -36,7 +41,7 @@ func SetupMCPRoutes(app *fiber.App, service *service.HotSearchService, cfg *conf
 	// 工具执行端点
 	mcpGroup.Post("/tool/execute", func(c *fiber.Ctx) error {
 		var req Request
-		if err := c.BodyParser(&req); err != nil {
+		if err := json.Unmarshal(c.Body(), &req); err != nil {
 			return c.Status(400).JSON(fiber.Map{
 				"error": "Invalid request format",
 			})
@@ -47,9 +52,12 @@ func SetupMCPRoutes(app *fiber.App, service *service.HotSearchService, cfg *conf
 		if req.Version == "" {
 			req.Version = "2.0"
 		}
+		if req.IsNotification() {
+			req.ID = StringID("http-request")
+		}
 
 		requestBytes, _ := json.Marshal(req)
-		response, err := mcpHandler.HandleRequest(requestBytes)
+		response, err := mcpHandler.HandleRequestContext(c.UserContext(), requestBytes)
 		if err != nil {
 			return c.Status(500).JSON(fiber.Map{
 				"error": err.Error(),
@@ -63,11 +71,11 @@ func SetupMCPRoutes(app *fiber.App, service *service.HotSearchService, cfg *conf
 	mcpGroup.Get("/prompts", func(c *fiber.Ctx) error {
 		request := Request{
 			Method:  "prompts/list",
-			ID:      "http-request",
+			ID:      StringID("http-request"),
 			Version: "2.0",
 		}
 		requestBytes, _ := json.Marshal(request)
-		response, err := mcpHandler.HandleRequest(requestBytes)
+		response, err := mcpHandler.HandleRequestContext(c.UserContext(), requestBytes)
 		if err != nil {
 			return c.Status(500).JSON(fiber.Map{
 				"error": err.Error(),
@@ -80,11 +88,11 @@ func SetupMCPRoutes(app *fiber.App, service *service.HotSearchService, cfg *conf
 	mcpGroup.Get("/ping", func(c *fiber.Ctx) error {
 		request := Request{
 			Method:  "ping",
-			ID:      "http-request",
+			ID:      StringID("http-request"),
 			Version: "2.0",
 		}
 		requestBytes, _ := json.Marshal(request)
-		response, err := mcpHandler.HandleRequest(requestBytes)
+		response, err := mcpHandler.HandleRequestContext(c.UserContext(), requestBytes)
 		if err != nil {
 			return c.Status(500).JSON(fiber.Map{
 				"error": err.Error(),
@@ -95,12 +103,25 @@ func SetupMCPRoutes(app *fiber.App, service *service.HotSearchService, cfg *conf
 
 	// MCP发现端点 - 提供MCP服务器元数据
 	mcpGroup.Get("/.well-known/mcp-info", func(c *fiber.Ctx) error {
+		tools := make([]string, 0, len(mcpHandler.tools))
+		for name := range mcpHandler.tools {
+			tools = append(tools, name)
+		}
+		sort.Strings(tools)
+		prompts := make([]string, 0, len(mcpHandler.prompts))
+		for name := range mcpHandler.prompts {
+			prompts = append(prompts, name)
+		}
+		sort.Strings(prompts)
 		info := map[string]interface{}{
-			"version":     "1.0",
-			"name":        "azhot MCP Server",
-			"description": "MCP server for azhot - Hot Search API Aggregation Service",
-			"tools":       []string{"get_hot_search", "get_all_hot_search", "get_history_data"},
-			"prompts":     []string{"analyze_hot_search_trends", "compare_platform_topics"},
+			"version":         serverInfo.Version,
+			"name":            "azhot MCP Server",
+			"description":     "MCP server for azhot - Hot Search API Aggregation Service",
+			"protocolVersion": ProtocolVersion,
+			"endpoint":        "/mcp",
+			"tools":           tools,
+			"prompts":         prompts,
+			"resources":       []string{sourcesURI, latestURITemplate, historyURITemplate},
 		}
 		return c.JSON(info)
 	})
@@ -108,7 +129,7 @@ func SetupMCPRoutes(app *fiber.App, service *service.HotSearchService, cfg *conf
 	// 如果配置中启用了MCP STDIO服务器，则启动它
 	if cfg.MCP != nil && cfg.MCP.STDIOEnabled {
 		go func() {
-			log.Println("Starting MCP STDIO server...")
+			mcpHandler.logger.Info("MCP STDIO 服务器启动")
 			mcpHandler.RunMCPServerSTDIO()
 		}()
 	}
@@ -116,11 +137,12 @@ func SetupMCPRoutes(app *fiber.App, service *service.HotSearchService, cfg *conf
 	// 如果配置中启用了MCP HTTP服务器，则启动它
 	if cfg.MCP != nil && cfg.MCP.HTTPEnabled {
 		go func() {
-			log.Println("Starting MCP HTTP server on port", cfg.MCP.Port)
 			err := mcpHandler.RunMCPServerHTTP(cfg.MCP.Port)
 			if err != nil {
-				log.Printf("Error starting MCP HTTP server: %v", err)
+				mcpHandler.logger.Error("MCP HTTP 服务器启动失败", logging.KeyError, err)
 			}
 		}()
 	}
+
+	return mcpHandler
 }
@@ -1,14 +1,38 @@
 package mcp
 
 import (
+	"api/app"
 	"api/config"
+	"api/db"
+	"api/model"
 	"api/service"
+	"bufio"
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
+// handle 处理请求并解析响应
+func handle(t *testing.T, handler *MCPHandler, request string) Response {
+	t.Helper()
+	responseBytes, err := handler.HandleRequest([]byte(request))
+	assert.NoError(t, err)
+
+	var response Response
+	assert.NoError(t, json.Unmarshal(responseBytes, &response))
+	assert.Equal(t, "2.0", response.Version)
+	return response
+}
+
 func TestNewMCPHandler(t *testing.T) {
 	// 创建服务和配置
 	service := &service.HotSearchService{}
@@ -34,7 +58,7 @@ func TestRegisterTools(t *testing.T) {
 	handler := NewMCPHandler(service, config)
 
 	// 验证工具是否已注册
-	expectedTools := []string{"get_hot_search", "get_all_hot_search", "get_history_data"}
+	expectedTools := []string{"get_hot_search", "get_all_hot_search", "get_history_data", "search_history", "trending_across_platforms", "diff_rankings", "get_top_hot_search"}
 	for _, expectedTool := range expectedTools {
 		_, exists := handler.tools[expectedTool]
 		assert.True(t, exists, "Tool %s should be registered", expectedTool)
@@ -47,6 +71,95 @@ func TestRegisterTools(t *testing.T) {
 	assert.Contains(t, getHotSearchTool.Description, "获取各大平台的热搜数据")
 }
 
+func TestHandleInitialize(t *testing.T) {
+	handler := NewMCPHandler(&service.HotSearchService{}, &config.Config{})
+
+	tests := []struct {
+		name      string
+		requested string
+		expected  string
+	}{
+		{"Latest", ProtocolVersion, ProtocolVersion},
+		{"Supported", "2024-11-05", "2024-11-05"},
+		{"Unsupported", "1999-01-01", ProtocolVersion},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := handle(t, handler, `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"`+
+				tt.requested+`","capabilities":{},"clientInfo":{"name":"test","version":"1.0"}}}`)
+			assert.Nil(t, response.Error)
+
+			var result InitializeResult
+			data, _ := json.Marshal(response.Result)
+			assert.NoError(t, json.Unmarshal(data, &result))
+			assert.Equal(t, tt.expected, result.ProtocolVersion)
+			assert.NotNil(t, result.Capabilities.Tools)
+			assert.NotNil(t, result.Capabilities.Prompts)
+			assert.Equal(t, "azhot", result.ServerInfo.Name)
+		})
+	}
+
+	// 缺少协议版本
+	response := handle(t, handler, `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`)
+	if assert.NotNil(t, response.Error) {
+		assert.Equal(t, CodeInvalidParams, response.Error.Code)
+	}
+}
+
+func TestHandleCallTool(t *testing.T) {
+	handler := NewMCPHandler(&service.HotSearchService{}, &config.Config{})
+	handler.tools["test_echo"] = Tool{Name: "test_echo", call: func(ctx context.Context, args json.RawMessage) (interface{}, error) {
+		return map[string]string{"echo": string(args)}, nil
+	}}
+	handler.tools["test_fail"] = Tool{Name: "test_fail", call: func(ctx context.Context, args json.RawMessage) (interface{}, error) {
+		return nil, errors.New("upstream unavailable")
+	}}
+
+	callResult := func(response Response) CallToolResult {
+		var result CallToolResult
+		data, _ := json.Marshal(response.Result)
+		assert.NoError(t, json.Unmarshal(data, &result))
+		return result
+	}
+
+	// 成功时以文本内容返回JSON数据
+	response := handle(t, handler, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"test_echo","arguments":{"a":1}}}`)
+	assert.Nil(t, response.Error)
+	result := callResult(response)
+	assert.False(t, result.IsError)
+	if assert.Len(t, result.Content, 1) {
+		assert.Equal(t, "text", result.Content[0].Type)
+		assert.JSONEq(t, `{"echo":"{\"a\":1}"}`, result.Content[0].Text)
+	}
+
+	// 执行失败时返回 isError 结果
+	response = handle(t, handler, `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"test_fail"}}`)
+	assert.Nil(t, response.Error)
+	result = callResult(response)
+	assert.True(t, result.IsError)
+	assert.Equal(t, "upstream unavailable", result.Content[0].Text)
+
+	// 旧的 tool/execute 方法返回错误响应
+	response = handle(t, handler, `{"jsonrpc":"2.0","id":3,"method":"tool/execute","params":{"name":"test_fail"}}`)
+	if assert.NotNil(t, response.Error) {
+		assert.Equal(t, CodeInternalError, response.Error.Code)
+	}
+
+	// 参数无效时返回 JSON-RPC 错误
+	for _, params := range []string{
+		`{}`,
+		`{"name":"nonexistent"}`,
+		`{"name":"get_hot_search"}`,
+		`{"name":"get_hot_search","arguments":{"platform":1}}`,
+		`{"name":"get_history_data","arguments":{"platform":"baidu"}}`,
+	} {
+		response = handle(t, handler, `{"jsonrpc":"2.0","id":4,"method":"tools/call","params":`+params+`}`)
+		if assert.NotNil(t, response.Error, params) {
+			assert.Equal(t, CodeInvalidParams, response.Error.Code, params)
+		}
+	}
+}
+
 func TestHandleListTools(t *testing.T) {
 	// 创建服务和配置
 	service := &service.HotSearchService{}
@@ -55,16 +168,10 @@ func TestHandleListTools(t *testing.T) {
 	// 创建MCP处理器
 	handler := NewMCPHandler(service, config)
 
-	// 调用handleListTools
-	responseBytes, err := handler.handleListTools("test-id")
-	assert.NoError(t, err)
-
-	// 解析响应
-	var response Response
-	err = json.Unmarshal(responseBytes, &response)
-	assert.NoError(t, err)
+	// 请求工具列表
+	response := handle(t, handler, `{"jsonrpc":"2.0","id":"test-id","method":"tools/list"}`)
 
-	assert.Equal(t, "test-id", response.ID)
+	assert.Equal(t, StringID("test-id"), response.ID)
 	assert.Equal(t, "2.0", response.Version)
 	assert.NotNil(t, response.Result)
 
@@ -86,16 +193,10 @@ func TestHandlePing(t *testing.T) {
 	// 创建MCP处理器
 	handler := NewMCPHandler(service, config)
 
-	// 调用handlePing
-	responseBytes, err := handler.handlePing("ping-id")
-	assert.NoError(t, err)
-
-	// 解析响应
-	var response Response
-	err = json.Unmarshal(responseBytes, &response)
-	assert.NoError(t, err)
+	// 发送ping请求
+	response := handle(t, handler, `{"jsonrpc":"2.0","id":"ping-id","method":"ping"}`)
 
-	assert.Equal(t, "ping-id", response.ID)
+	assert.Equal(t, StringID("ping-id"), response.ID)
 	assert.Equal(t, "2.0", response.Version)
 	assert.NotNil(t, response.Result)
 
@@ -114,16 +215,10 @@ func TestHandleListPrompts(t *testing.T) {
 	// 创建MCP处理器
 	handler := NewMCPHandler(service, config)
 
-	// 调用handleListPrompts
-	responseBytes, err := handler.handleListPrompts("prompts-id")
-	assert.NoError(t, err)
-
-	// 解析响应
-	var response Response
-	err = json.Unmarshal(responseBytes, &response)
-	assert.NoError(t, err)
+	// 请求提示列表
+	response := handle(t, handler, `{"jsonrpc":"2.0","id":"prompts-id","method":"prompts/list"}`)
 
-	assert.Equal(t, "prompts-id", response.ID)
+	assert.Equal(t, StringID("prompts-id"), response.ID)
 	assert.Equal(t, "2.0", response.Version)
 	assert.NotNil(t, response.Result)
 
@@ -137,14 +232,28 @@ func TestHandleListPrompts(t *testing.T) {
 	assert.NotEmpty(t, prompts)
 }
 
-func TestGetAppFunctionByName(t *testing.T) {
-	// 测试获取存在的函数
-	fn := getAppFunctionByName("Baidu")
-	assert.NotNil(t, fn, "Should be able to get Baidu function")
+func TestExecuteGetHotSearchUnsupportedPlatform(t *testing.T) {
+	// 创建服务和配置
+	service := &service.HotSearchService{}
+	config := &config.Config{}
+
+	// 创建MCP处理器
+	handler := NewMCPHandler(service, config)
 
-	// 测试获取不存在的函数
-	nilFn := getAppFunctionByName("NonExistentFunction")
-	assert.Nil(t, nilFn, "Should return nil for non-existent function")
+	// 不在注册表中的平台应返回参数错误
+	response := handle(t, handler, `{"jsonrpc":"2.0","id":"unsupported-id","method":"tool/execute","params":{"name":"get_hot_search","arguments":{"platform":"nonexistent"}}}`)
+
+	assert.Equal(t, StringID("unsupported-id"), response.ID)
+	assert.NotNil(t, response.Error)
+	assert.Equal(t, -32602, response.Error.Code)
+	assert.Contains(t, response.Error.Message, "Unsupported platform")
+
+	// 缺少参数时返回参数错误
+	response = handle(t, handler, `{"jsonrpc":"2.0","id":"missing-id","method":"tool/execute","params":{"name":"get_hot_search"}}`)
+	if assert.NotNil(t, response.Error) {
+		assert.Equal(t, -32602, response.Error.Code)
+		assert.Contains(t, response.Error.Message, "Missing platform argument")
+	}
 }
 
 func TestCreateErrorResponse(t *testing.T) {
@@ -155,16 +264,13 @@ func TestCreateErrorResponse(t *testing.T) {
 	// 创建MCP处理器
 	handler := NewMCPHandler(service, config)
 
-	// 调用createErrorResponse
-	responseBytes, err := handler.createErrorResponse("error-id", 500, "Test error message")
-	assert.NoError(t, err)
-
-	// 解析响应
-	var response Response
-	err = json.Unmarshal(responseBytes, &response)
-	assert.NoError(t, err)
+	// 处理函数返回的 *Error 原样作为错误响应
+	handler.tools["test_error"] = Tool{Name: "test_error", call: func(ctx context.Context, args json.RawMessage) (interface{}, error) {
+		return nil, &Error{Code: 500, Message: "Test error message"}
+	}}
+	response := handle(t, handler, `{"jsonrpc":"2.0","id":"error-id","method":"tools/call","params":{"name":"test_error"}}`)
 
-	assert.Equal(t, "error-id", response.ID)
+	assert.Equal(t, StringID("error-id"), response.ID)
 	assert.Equal(t, "2.0", response.Version)
 	assert.NotNil(t, response.Error)
 	assert.Equal(t, 500, response.Error.Code)
@@ -189,7 +295,8 @@ func TestHandleRequestWithInvalidJSON(t *testing.T) {
 	err = json.Unmarshal(responseBytes, &response)
 	assert.NoError(t, err)
 
-	assert.Equal(t, "", response.ID)
+	assert.Equal(t, ID("null"), response.ID)
+	assert.Contains(t, string(responseBytes), `"id":null`)
 	assert.NotNil(t, response.Error)
 	assert.Equal(t, -32700, response.Error.Code)
 	assert.Contains(t, response.Error.Message, "Parse error")
@@ -206,7 +313,7 @@ func TestHandleRequestWithUnknownMethod(t *testing.T) {
 	// 创建未知方法的请求
 	request := Request{
 		Method:  "unknown/method",
-		ID:      "unknown-id",
+		ID:      StringID("unknown-id"),
 		Version: "2.0",
 	}
 	requestBytes, _ := json.Marshal(request)
@@ -219,7 +326,7 @@ func TestHandleRequestWithUnknownMethod(t *testing.T) {
 	err = json.Unmarshal(responseBytes, &response)
 	assert.NoError(t, err)
 
-	assert.Equal(t, "unknown-id", response.ID)
+	assert.Equal(t, StringID("unknown-id"), response.ID)
 	assert.NotNil(t, response.Error)
 	assert.Equal(t, -32601, response.Error.Code)
 	assert.Contains(t, response.Error.Message, "Method not found")
@@ -233,16 +340,124 @@ func TestExecuteGetAllHotSearch(t *testing.T) {
 	// 创建MCP处理器
 	handler := NewMCPHandler(service, config)
 
-	// 调用executeGetAllHotSearch
-	responseBytes, err := handler.executeGetAllHotSearch("all-id")
+	// 通过兼容的 tool/execute 方法调用，不需要参数
+	response := handle(t, handler, `{"jsonrpc":"2.0","id":"all-id","method":"tool/execute","params":{"name":"get_all_hot_search"}}`)
+
+	assert.Equal(t, StringID("all-id"), response.ID)
+	assert.Equal(t, "2.0", response.Version)
+	assert.NotNil(t, response.Result)
+}
+
+func init() {
+	// 上游一直失败的数据源，用于测试工具返回已保存的快照
+	app.Register(app.Source{
+		RouteName: "mcp_test_down",
+		Fetch: func(ctx context.Context, f *app.Fetcher) (*app.Result, error) {
+			return nil, errors.New("upstream unavailable")
+		},
+	})
+}
+
+// 测试实时数据工具与 HTTP 接口一样在上游失败时返回已保存的快照
+func TestExecuteGetHotSearchStale(t *testing.T) {
+	db.InitSQLiteWithDSN("file:" + t.Name() + "?mode=memory&cache=shared")
+	require.NoError(t, db.SaveData("mcp_test_down", []model.HotSearchItem{{Title: "快照", URL: "https://example.com/1", Index: 1}}))
+
+	hotSearchService := &service.HotSearchService{}
+	handler := NewMCPHandler(hotSearchService, &config.Config{})
+
+	// 未启用 stale 模式时返回错误
+	text, rpcErr := callTool(t, handler, "get_hot_search", `{"platform":"mcp_test_down"}`, nil)
+	require.Nil(t, rpcErr)
+	assert.Contains(t, text, "upstream unavailable")
+
+	hotSearchService.SetLiveConfig(config.LiveConfig{StaleEnabled: true})
+	var response app.Response
+	text, rpcErr = callTool(t, handler, "get_hot_search", `{"platform":"mcp_test_down"}`, &response)
+	require.Nil(t, rpcErr)
+	require.Empty(t, text)
+	assert.True(t, response.Stale)
+	if assert.Len(t, response.Obj, 1) {
+		assert.Equal(t, "快照", response.Obj[0].Title)
+	}
+}
+
+func TestServeSTDIO(t *testing.T) {
+	handler := NewMCPHandler(&service.HotSearchService{}, &config.Config{})
+
+	// 逐行处理请求，空行被忽略，通知没有响应，输入结束后返回
+	input := strings.NewReader("{\"jsonrpc\":\"2.0\",\"id\":1,\"method\":\"ping\"}\n\n" +
+		"{\"jsonrpc\":\"2.0\",\"method\":\"notifications/initialized\"}\n" +
+		"{\"jsonrpc\":\"2.0\",\"id\":2,\"method\":\"ping\"}\n")
+	var output bytes.Buffer
+	assert.NoError(t, handler.ServeSTDIO(context.Background(), input, &output))
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	assert.Len(t, lines, 2)
+	for i, line := range lines {
+		var response Response
+		assert.NoError(t, json.Unmarshal([]byte(line), &response))
+		assert.Equal(t, ID(strconv.Itoa(i+1)), response.ID)
+	}
+}
+
+func TestServeSTDIONotifications(t *testing.T) {
+	handler := NewMCPHandler(&service.HotSearchService{}, &config.Config{})
+
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- handler.ServeSTDIO(context.Background(), inR, outW)
+	}()
+	lines := bufio.NewScanner(outR)
+
+	// 收到响应说明会话已经登记
+	_, err := io.WriteString(inW, `{"jsonrpc":"2.0","id":1,"method":"ping"}`+"\n")
 	assert.NoError(t, err)
+	assert.True(t, lines.Scan())
+
+	// 抓取到新数据后推送通知
+	go handler.handleFetchEvent(service.FetchEvent{Source: "baidu", Result: app.NewResult("baidu", nil), Scheduled: true, Saved: true})
+	assert.True(t, lines.Scan())
+	var notification Request
+	assert.NoError(t, json.Unmarshal(lines.Bytes(), &notification))
+	assert.Equal(t, "notifications/hot_search/updated", notification.Method)
+	assert.JSONEq(t, `{"source":"baidu","total":0,"scheduled":true}`, string(removeField(t, notification.Params, "fetchedAt")))
+
+	// 输入结束后会话移除
+	inW.Close()
+	assert.NoError(t, <-done)
+	assert.Empty(t, handler.sessions)
+}
 
-	// 解析响应
-	var response Response
-	err = json.Unmarshal(responseBytes, &response)
+// removeField 删除JSON对象中的字段
+func removeField(t *testing.T, data json.RawMessage, field string) []byte {
+	t.Helper()
+	var m map[string]interface{}
+	assert.NoError(t, json.Unmarshal(data, &m))
+	delete(m, field)
+	result, err := json.Marshal(m)
 	assert.NoError(t, err)
+	return result
+}
 
-	assert.Equal(t, "all-id", response.ID)
-	assert.Equal(t, "2.0", response.Version)
-	assert.NotNil(t, response.Result)
+func TestShutdownStopsSTDIO(t *testing.T) {
+	handler := NewMCPHandler(&service.HotSearchService{}, &config.Config{})
+
+	// 输入一直没有数据，Shutdown 后 ServeSTDIO 返回（Shutdown 先执行时直接返回）
+	r, w := io.Pipe()
+	defer w.Close()
+	done := make(chan error, 1)
+	go func() {
+		done <- handler.ServeSTDIO(context.Background(), r, io.Discard)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.NoError(t, handler.Shutdown(ctx))
+	assert.NoError(t, <-done)
+
+	// 关闭后不再启动
+	assert.NoError(t, handler.ServeSTDIO(context.Background(), strings.NewReader("{}\n"), io.Discard))
 }
@@ -0,0 +1,372 @@
+package mcp
+
+import (
+	"api/app"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// promptItemLimit 提示中每个平台最多列出的条目数
+const promptItemLimit = 20
+
+// Prompt 提示模板，prompts/get 时按参数填入服务中的热搜数据，展开为发给模型的消息
+type Prompt struct {
+	Name        string           `json:"name"`
+	Title       string           `json:"title,omitempty"`
+	Description string           `json:"description"`
+	Arguments   []PromptArgument `json:"arguments,omitempty"`
+
+	// render 按参数生成提示的正文，参数无效时返回 *Error
+	render func(req promptRequest) (string, error)
+}
+
+// PromptArgument 提示模板的参数，参数值都是字符串
+type PromptArgument struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+}
+
+// ListPromptsResponse 响应结构
+type ListPromptsResponse struct {
+	Prompts []Prompt `json:"prompts"`
+}
+
+// GetPromptParams prompts/get 请求的参数
+type GetPromptParams struct {
+	Name      string            `json:"name"`
+	Arguments map[string]string `json:"arguments,omitempty"`
+}
+
+// PromptMessage 提示展开后的消息
+type PromptMessage struct {
+	Role    string  `json:"role"`
+	Content Content `json:"content"`
+}
+
+// GetPromptResult prompts/get 请求的结果
+type GetPromptResult struct {
+	Description string          `json:"description,omitempty"`
+	Messages    []PromptMessage `json:"messages"`
+}
+
+// 各提示模板共用的参数
+var (
+	platformsArgument = PromptArgument{
+		Name:        "platforms",
+		Description: "逗号分隔的平台路由名称或别名，如 weibo,zhihu",
+	}
+	rangeArgument = PromptArgument{
+		Name:        "range",
+		Description: "数据范围：latest（默认，最新快照）、today、yesterday、YYYY-MM-DD（某一天）或 YYYY-MM-DD HH（某一小时）",
+	}
+	languageArgument = PromptArgument{
+		Name:        "language",
+		Description: "回答使用的语言：zh（默认）或 en",
+	}
+)
+
+// registerPrompts 注册可用的提示模板
+func (m *MCPHandler) registerPrompts() {
+	platforms := platformsArgument
+	platforms.Description += "，为空时使用所有启用的平台"
+	m.prompts["analyze_hot_search_trends"] = Prompt{
+		Name:        "analyze_hot_search_trends",
+		Title:       "分析热搜趋势",
+		Description: "分析当前热搜趋势，识别热门话题和用户兴趣",
+		Arguments:   []PromptArgument{platforms, rangeArgument, languageArgument},
+		render:      m.renderAnalyzeTrends,
+	}
+
+	platforms = platformsArgument
+	platforms.Description += "，至少两个"
+	platforms.Required = true
+	m.prompts["compare_platform_topics"] = Prompt{
+		Name:        "compare_platform_topics",
+		Title:       "比较平台话题",
+		Description: "比较不同平台的热门话题，分析差异和共同点",
+		Arguments:   []PromptArgument{platforms, rangeArgument, languageArgument},
+		render:      m.renderComparePlatforms,
+	}
+}
+
+// promptText 提示中随语言变化的文字
+type promptText struct {
+	latest   string // 最新快照的范围说明
+	allDay   string // 整天的范围说明，参数为日期
+	noData   string // 没有数据时的说明
+	hotValue string // 热度的格式，参数为热度
+	daily    string // 整天数据中一个话题的格式，参数为排名、标题、上榜小时数和最高排名
+	analyze  string // analyze_hot_search_trends 的说明，参数为范围
+	compare  string // compare_platform_topics 的说明，参数为范围
+}
+
+// promptTexts 支持的语言
+var promptTexts = map[string]*promptText{
+	"zh": {
+		latest:   "最新快照",
+		allDay:   "%s 全天，按上榜小时数排序",
+		noData:   "暂无数据",
+		hotValue: "（热度 %s）",
+		daily:    "%d. %s（上榜 %d 小时，最高第 %d 名）",
+		analyze:  "请分析以下热搜榜单（%s）中的热点趋势：归纳主要话题及其所属领域，指出在多个平台同时出现的话题，并总结用户关注的方向。请使用中文回答。",
+		compare:  "请比较以下平台的热搜榜单（%s）：找出各平台共同关注的话题和各自独有的话题，分析不同平台的用户群体和内容偏好有什么差异。请使用中文回答。",
+	},
+	"en": {
+		latest:   "latest snapshot",
+		allDay:   "all day on %s, sorted by hours on the list",
+		noData:   "No data",
+		hotValue: " (hot: %s)",
+		daily:    "%d. %s (hours on the list: %d, best rank: #%d)",
+		analyze:  "Analyze the trends in the following trending lists (%s): summarize the main topics and their fields, point out topics that appear on multiple platforms, and describe what users are paying attention to. Please answer in English.",
+		compare:  "Compare the trending lists of the following platforms (%s): identify the topics they share and the topics unique to each, and analyze how their audiences and content preferences differ. Please answer in English.",
+	},
+}
+
+// promptSpan 提示使用的数据范围
+type promptSpan struct {
+	date string // 为空时使用最新快照
+	hour int    // 小于 0 时使用整天的数据
+}
+
+// parsePromptSpan 解析 range 参数，today 和 yesterday 相对于 now
+func parsePromptSpan(value string, now time.Time) (promptSpan, error) {
+	value = strings.TrimSpace(value)
+	switch value {
+	case "", "latest":
+		return promptSpan{hour: -1}, nil
+	case "today":
+		return promptSpan{date: now.Format(time.DateOnly), hour: -1}, nil
+	case "yesterday":
+		return promptSpan{date: now.AddDate(0, 0, -1).Format(time.DateOnly), hour: -1}, nil
+	}
+
+	if t, err := time.ParseInLocation(time.DateOnly, value, time.Local); err == nil {
+		return promptSpan{date: t.Format(time.DateOnly), hour: -1}, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02 15", value, time.Local); err == nil {
+		return promptSpan{date: t.Format(time.DateOnly), hour: t.Hour()}, nil
+	}
+	return promptSpan{}, invalidParams("Invalid range %q, expected latest, today, yesterday, YYYY-MM-DD or YYYY-MM-DD HH", value)
+}
+
+// describe 返回范围的说明
+func (s promptSpan) describe(text *promptText) string {
+	switch {
+	case s.date == "":
+		return text.latest
+	case s.hour < 0:
+		return fmt.Sprintf(text.allDay, s.date)
+	}
+	return fmt.Sprintf("%s %02d:00", s.date, s.hour)
+}
+
+// promptRequest 解析后的提示参数
+type promptRequest struct {
+	sources []app.Source
+	span    promptSpan
+	text    *promptText
+}
+
+// parsePromptRequest 解析提示参数，platforms 为空时使用所有启用的平台
+func parsePromptRequest(args map[string]string, now time.Time) (promptRequest, error) {
+	var req promptRequest
+
+	language := strings.ToLower(strings.TrimSpace(args["language"]))
+	if language == "" {
+		language = "zh"
+	}
+	req.text = promptTexts[language]
+	if req.text == nil {
+		return req, invalidParams("Unsupported language %q, expected zh or en", language)
+	}
+
+	span, err := parsePromptSpan(args["range"], now)
+	if err != nil {
+		return req, err
+	}
+	req.span = span
+
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(args["platforms"], ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		source, exists := app.LookupSource(name)
+		if !exists {
+			return req, invalidParams("Unsupported platform: %s", name)
+		}
+		if !seen[source.RouteName] {
+			seen[source.RouteName] = true
+			req.sources = append(req.sources, source)
+		}
+	}
+	if len(req.sources) == 0 {
+		for _, source := range app.Sources() {
+			if app.Enabled(source.RouteName) {
+				req.sources = append(req.sources, source)
+			}
+		}
+	}
+	return req, nil
+}
+
+// handleListPrompts 处理提示列表请求，按名称排序
+func (m *MCPHandler) handleListPrompts() (interface{}, error) {
+	prompts := make([]Prompt, 0, len(m.prompts))
+	for _, prompt := range m.prompts {
+		prompts = append(prompts, prompt)
+	}
+	sort.Slice(prompts, func(i, j int) bool {
+		return prompts[i].Name < prompts[j].Name
+	})
+	return ListPromptsResponse{Prompts: prompts}, nil
+}
+
+// handleGetPrompt 处理 prompts/get 请求，返回填入热搜数据后的用户消息
+func (m *MCPHandler) handleGetPrompt(params json.RawMessage) (interface{}, error) {
+	var p GetPromptParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.Name == "" {
+		return nil, invalidParams("Missing prompt name")
+	}
+	prompt, exists := m.prompts[p.Name]
+	if !exists {
+		return nil, invalidParams("Unknown prompt: %s", p.Name)
+	}
+	for _, arg := range prompt.Arguments {
+		if arg.Required && strings.TrimSpace(p.Arguments[arg.Name]) == "" {
+			return nil, invalidParams("Missing required argument: %s", arg.Name)
+		}
+	}
+
+	req, err := parsePromptRequest(p.Arguments, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	text, err := prompt.render(req)
+	if err != nil {
+		return nil, err
+	}
+	return GetPromptResult{
+		Description: prompt.Description,
+		Messages:    []PromptMessage{{Role: "user", Content: textContent(text)}},
+	}, nil
+}
+
+// renderAnalyzeTrends 生成 analyze_hot_search_trends 提示
+func (m *MCPHandler) renderAnalyzeTrends(req promptRequest) (string, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, req.text.analyze, req.span.describe(req.text))
+	if err := m.writePromptData(&b, req); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// renderComparePlatforms 生成 compare_platform_topics 提示
+func (m *MCPHandler) renderComparePlatforms(req promptRequest) (string, error) {
+	if len(req.sources) < 2 {
+		return "", invalidParams("At least two platforms are required")
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, req.text.compare, req.span.describe(req.text))
+	if err := m.writePromptData(&b, req); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// writePromptData 按范围读取各平台的热搜，每个平台一节，最多列出 promptItemLimit 条
+func (m *MCPHandler) writePromptData(b *strings.Builder, req promptRequest) error {
+	for _, source := range req.sources {
+		if req.span.date != "" && req.span.hour < 0 {
+			snapshots, err := m.service.GetDailySnapshots(source.RouteName, req.span.date)
+			if err != nil {
+				return fmt.Errorf("Error getting historical data: %w", err)
+			}
+			fmt.Fprintf(b, "\n\n## %s (%s) · %s\n", source.Name, source.RouteName, req.span.date)
+			topics := dailyTopics(snapshots)
+			if len(topics) == 0 {
+				b.WriteString(req.text.noData)
+			}
+			for i, topic := range topics[:min(len(topics), promptItemLimit)] {
+				if i > 0 {
+					b.WriteString("\n")
+				}
+				fmt.Fprintf(b, req.text.daily, i+1, topic.title, topic.hours, topic.bestRank)
+			}
+			continue
+		}
+
+		var result *app.Result
+		var err error
+		if req.span.date == "" {
+			result, err = m.service.GetLatestSnapshot(source.RouteName)
+		} else {
+			result, err = m.service.GetHourlySnapshot(source.RouteName, req.span.date, req.span.hour)
+		}
+		if err != nil {
+			return fmt.Errorf("Error getting snapshot: %w", err)
+		}
+		if result == nil || len(result.Items) == 0 {
+			fmt.Fprintf(b, "\n\n## %s (%s)\n%s", source.Name, source.RouteName, req.text.noData)
+			continue
+		}
+		fmt.Fprintf(b, "\n\n## %s (%s) · %s\n", source.Name, source.RouteName, result.FetchedAt.Format("2006-01-02 15:04"))
+		for i, item := range result.Items[:min(len(result.Items), promptItemLimit)] {
+			if i > 0 {
+				b.WriteString("\n")
+			}
+			fmt.Fprintf(b, "%d. %s", item.Index, item.Title)
+			if item.HotValue != "" {
+				fmt.Fprintf(b, req.text.hotValue, item.HotValue)
+			}
+		}
+	}
+	return nil
+}
+
+// dailyTopic 一天中在榜单上出现过的话题
+type dailyTopic struct {
+	title    string
+	hours    int // 上榜小时数
+	bestRank int // 最高排名
+}
+
+// dailyTopics 汇总一天中每个小时的榜单，按上榜小时数从多到少、最高排名从高到低排序
+func dailyTopics(snapshots map[int]*app.Result) []dailyTopic {
+	topics := make(map[string]*dailyTopic)
+	for _, result := range snapshots {
+		for _, item := range result.Items {
+			topic, exists := topics[item.Title]
+			if !exists {
+				topic = &dailyTopic{title: item.Title, bestRank: item.Index}
+				topics[item.Title] = topic
+			}
+			topic.hours++
+			topic.bestRank = min(topic.bestRank, item.Index)
+		}
+	}
+
+	sorted := make([]dailyTopic, 0, len(topics))
+	for _, topic := range topics {
+		sorted = append(sorted, *topic)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].hours != sorted[j].hours {
+			return sorted[i].hours > sorted[j].hours
+		}
+		if sorted[i].bestRank != sorted[j].bestRank {
+			return sorted[i].bestRank < sorted[j].bestRank
+		}
+		return sorted[i].title < sorted[j].title
+	})
+	return sorted
+}
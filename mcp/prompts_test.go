@@ -0,0 +1,180 @@
+package mcp
+
+import (
+	"api/app"
+	"api/config"
+	"api/db"
+	"api/model"
+	"api/service"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePromptSpan(t *testing.T) {
+	now := time.Date(2024, 5, 2, 10, 0, 0, 0, time.Local)
+	tests := []struct {
+		value    string
+		expected promptSpan
+	}{
+		{"", promptSpan{hour: -1}},
+		{"latest", promptSpan{hour: -1}},
+		{"today", promptSpan{date: "2024-05-02", hour: -1}},
+		{"yesterday", promptSpan{date: "2024-05-01", hour: -1}},
+		{"2024-04-30", promptSpan{date: "2024-04-30", hour: -1}},
+		{" 2024-04-30 08 ", promptSpan{date: "2024-04-30", hour: 8}},
+	}
+	for _, tt := range tests {
+		span, err := parsePromptSpan(tt.value, now)
+		assert.NoError(t, err, tt.value)
+		assert.Equal(t, tt.expected, span, tt.value)
+	}
+
+	for _, value := range []string{"tomorrow", "2024/04/30", "2024-04-30 24", "2024-04-30T08"} {
+		_, err := parsePromptSpan(value, now)
+		assert.Error(t, err, value)
+	}
+}
+
+// setupPromptData 保存 2024-05-01 8 点和 9 点的微博、知乎快照
+func setupPromptData(t *testing.T) *MCPHandler {
+	t.Helper()
+	db.InitSQLiteWithDSN("file:" + t.Name() + "?mode=memory&cache=shared")
+
+	save := func(source string, hour int, titles ...string) {
+		items := make([]model.HotSearchItem, len(titles))
+		for i, title := range titles {
+			items[i] = model.HotSearchItem{Title: title, URL: "https://example.com/" + title, Index: i + 1}
+		}
+		items[0].HotValue = "100万"
+		at := time.Date(2024, 5, 1, hour, 30, 0, 0, time.Local)
+		require.NoError(t, db.SaveAllDataAt(map[string][]model.HotSearchItem{source: items}, at))
+	}
+	save("weibo", 8, "早高峰", "天气")
+	save("weibo", 9, "天气", "新品发布", "早高峰")
+	save("zhihu", 9, "如何评价新品发布")
+
+	return NewMCPHandler(&service.HotSearchService{}, &config.Config{})
+}
+
+// getPrompt 调用 prompts/get，返回展开后的文本
+func getPrompt(t *testing.T, handler *MCPHandler, name string, args map[string]string) (string, *Error) {
+	t.Helper()
+	params, err := json.Marshal(GetPromptParams{Name: name, Arguments: args})
+	require.NoError(t, err)
+	request, err := json.Marshal(Request{Method: "prompts/get", Params: params, ID: NumberID(1), Version: "2.0"})
+	require.NoError(t, err)
+
+	response := handle(t, handler, string(request))
+	if response.Error != nil {
+		return "", response.Error
+	}
+	var result GetPromptResult
+	remarshal(t, response.Result, &result)
+	require.Len(t, result.Messages, 1)
+	assert.Equal(t, "user", result.Messages[0].Role)
+	assert.Equal(t, "text", result.Messages[0].Content.Type)
+	assert.Equal(t, handler.prompts[name].Description, result.Description)
+	return result.Messages[0].Content.Text, nil
+}
+
+func TestHandleListPromptsArguments(t *testing.T) {
+	handler := NewMCPHandler(&service.HotSearchService{}, &config.Config{})
+
+	response := handle(t, handler, `{"jsonrpc":"2.0","id":1,"method":"prompts/list"}`)
+	require.Nil(t, response.Error)
+	var result ListPromptsResponse
+	remarshal(t, response.Result, &result)
+	require.Len(t, result.Prompts, 2)
+	assert.Equal(t, "analyze_hot_search_trends", result.Prompts[0].Name)
+	assert.Equal(t, "compare_platform_topics", result.Prompts[1].Name)
+
+	for _, prompt := range result.Prompts {
+		names := make([]string, len(prompt.Arguments))
+		for i, arg := range prompt.Arguments {
+			names[i] = arg.Name
+		}
+		assert.Equal(t, []string{"platforms", "range", "language"}, names)
+	}
+	assert.False(t, result.Prompts[0].Arguments[0].Required)
+	assert.True(t, result.Prompts[1].Arguments[0].Required)
+}
+
+func TestGetPromptAnalyzeTrends(t *testing.T) {
+	handler := setupPromptData(t)
+
+	// 最新快照，默认使用中文
+	text, rpcErr := getPrompt(t, handler, "analyze_hot_search_trends", map[string]string{"platforms": "weibo, zhihu"})
+	require.Nil(t, rpcErr)
+	assert.Equal(t, "请分析以下热搜榜单（最新快照）中的热点趋势：归纳主要话题及其所属领域，指出在多个平台同时出现的话题，并总结用户关注的方向。请使用中文回答。"+
+		"\n\n## 微博 (weibo) · 2024-05-01 09:30\n1. 天气（热度 100万）\n2. 新品发布\n3. 早高峰"+
+		"\n\n## 知乎 (zhihu) · 2024-05-01 09:30\n1. 如何评价新品发布（热度 100万）", text)
+
+	// 整天的数据按上榜小时数汇总，相同时按最高排名和标题排序，英文说明
+	text, rpcErr = getPrompt(t, handler, "analyze_hot_search_trends", map[string]string{
+		"platforms": "weibo,baidu",
+		"range":     "2024-05-01",
+		"language":  "en",
+	})
+	require.Nil(t, rpcErr)
+	assert.Equal(t, "Analyze the trends in the following trending lists (all day on 2024-05-01, sorted by hours on the list): "+
+		"summarize the main topics and their fields, point out topics that appear on multiple platforms, and describe what users are paying attention to. Please answer in English."+
+		"\n\n## 微博 (weibo) · 2024-05-01\n1. 天气 (hours on the list: 2, best rank: #1)\n2. 早高峰 (hours on the list: 2, best rank: #1)\n3. 新品发布 (hours on the list: 1, best rank: #2)"+
+		"\n\n## 百度 (baidu) · 2024-05-01\nNo data", text)
+
+	// 不指定平台时使用所有启用的平台
+	text, rpcErr = getPrompt(t, handler, "analyze_hot_search_trends", nil)
+	require.Nil(t, rpcErr)
+	for _, source := range app.Sources() {
+		assert.Contains(t, text, "("+source.RouteName+")")
+	}
+
+	// 无效的参数
+	_, rpcErr = getPrompt(t, handler, "analyze_hot_search_trends", map[string]string{"language": "fr"})
+	require.NotNil(t, rpcErr)
+	assert.Equal(t, CodeInvalidParams, rpcErr.Code)
+	_, rpcErr = getPrompt(t, handler, "analyze_hot_search_trends", map[string]string{"platforms": "unknown"})
+	require.NotNil(t, rpcErr)
+	assert.Equal(t, CodeInvalidParams, rpcErr.Code)
+	_, rpcErr = getPrompt(t, handler, "analyze_hot_search_trends", map[string]string{"range": "last week"})
+	require.NotNil(t, rpcErr)
+	assert.Equal(t, CodeInvalidParams, rpcErr.Code)
+}
+
+func TestGetPromptComparePlatforms(t *testing.T) {
+	handler := setupPromptData(t)
+
+	// 指定小时，重复的平台只出现一次
+	text, rpcErr := getPrompt(t, handler, "compare_platform_topics", map[string]string{
+		"platforms": "weibo,zhihu,weibo",
+		"range":     "2024-05-01 08",
+	})
+	require.Nil(t, rpcErr)
+	assert.Equal(t, "请比较以下平台的热搜榜单（2024-05-01 08:00）：找出各平台共同关注的话题和各自独有的话题，分析不同平台的用户群体和内容偏好有什么差异。请使用中文回答。"+
+		"\n\n## 微博 (weibo) · 2024-05-01 08:30\n1. 早高峰（热度 100万）\n2. 天气"+
+		"\n\n## 知乎 (zhihu)\n暂无数据", text)
+
+	text, rpcErr = getPrompt(t, handler, "compare_platform_topics", map[string]string{"platforms": "weibo,zhihu", "language": "EN"})
+	require.Nil(t, rpcErr)
+	assert.Equal(t, "Compare the trending lists of the following platforms (latest snapshot): "+
+		"identify the topics they share and the topics unique to each, and analyze how their audiences and content preferences differ. Please answer in English."+
+		"\n\n## 微博 (weibo) · 2024-05-01 09:30\n1. 天气 (hot: 100万)\n2. 新品发布\n3. 早高峰"+
+		"\n\n## 知乎 (zhihu) · 2024-05-01 09:30\n1. 如何评价新品发布 (hot: 100万)", text)
+
+	// 缺少平台或只有一个平台
+	_, rpcErr = getPrompt(t, handler, "compare_platform_topics", nil)
+	require.NotNil(t, rpcErr)
+	assert.Equal(t, CodeInvalidParams, rpcErr.Code)
+	assert.Contains(t, rpcErr.Message, "platforms")
+	_, rpcErr = getPrompt(t, handler, "compare_platform_topics", map[string]string{"platforms": "weibo"})
+	require.NotNil(t, rpcErr)
+	assert.Equal(t, CodeInvalidParams, rpcErr.Code)
+
+	// 未知的提示
+	_, rpcErr = getPrompt(t, handler, "unknown", nil)
+	require.NotNil(t, rpcErr)
+	assert.Equal(t, CodeInvalidParams, rpcErr.Code)
+}
@@ -0,0 +1,420 @@
+package mcp
+
+import (
+	"api/app"
+	"api/service"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	// maxListLimit 列表类参数 limit 的最大值
+	maxListLimit = 100
+	// defaultQueryLimit 查询类工具默认返回的条目数
+	defaultQueryLimit = 20
+	// defaultSearchDays 搜索历史时不指定起始时间默认搜索的天数
+	defaultSearchDays = 7
+	// defaultTopK、maxTopK get_top_hot_search 每个平台默认和最多返回的条目数
+	defaultTopK = 5
+	maxTopK     = 50
+)
+
+// itemFields get_top_hot_search 可以选择的条目字段，与 app.Item 的 JSON 字段名一致
+var itemFields = []string{"index", "title", "url", "hotValue", "hotScore", "desc", "image", "extra"}
+
+// defaultItemFields get_top_hot_search 默认返回的条目字段
+var defaultItemFields = []string{"index", "title", "hotValue"}
+
+// SearchHistoryArgs search_history 工具的参数
+type SearchHistoryArgs struct {
+	Keyword   string   `json:"keyword"`
+	Platforms []string `json:"platforms,omitempty"`
+	From      string   `json:"from,omitempty"`
+	To        string   `json:"to,omitempty"`
+	Limit     int      `json:"limit,omitempty"`
+}
+
+// SearchHistoryResult search_history 工具的结果
+type SearchHistoryResult struct {
+	Keyword string                `json:"keyword"`
+	From    time.Time             `json:"from"`
+	To      *time.Time            `json:"to,omitempty"`
+	Matches []service.SearchMatch `json:"matches"`
+}
+
+// TrendingArgs trending_across_platforms 工具的参数
+type TrendingArgs struct {
+	MinPlatforms int      `json:"min_platforms,omitempty"`
+	Platforms    []string `json:"platforms,omitempty"`
+	Limit        int      `json:"limit,omitempty"`
+}
+
+// TrendingResult trending_across_platforms 工具的结果
+type TrendingResult struct {
+	MinPlatforms int                          `json:"minPlatforms"`
+	Total        int                          `json:"total"` // 截断前的话题数
+	Topics       []service.CrossPlatformTopic `json:"topics"`
+}
+
+// DiffRankingsArgs diff_rankings 工具的参数
+type DiffRankingsArgs struct {
+	Platform string `json:"platform"`
+	From     string `json:"from,omitempty"`
+	To       string `json:"to,omitempty"`
+	Limit    int    `json:"limit,omitempty"`
+}
+
+// DiffTotals 截断前各类变化的条目数
+type DiffTotals struct {
+	Added   int `json:"added"`
+	Removed int `json:"removed"`
+	Moved   int `json:"moved"`
+}
+
+// DiffRankingsResult diff_rankings 工具的结果，每类变化最多返回 limit 条，排名变化按变化幅度从大到小排列
+type DiffRankingsResult struct {
+	*service.Diff
+	Totals DiffTotals `json:"totals"`
+}
+
+// TopHotSearchArgs get_top_hot_search 工具的参数
+type TopHotSearchArgs struct {
+	Platforms []string `json:"platforms,omitempty"`
+	K         int      `json:"k,omitempty"`
+	Fields    []string `json:"fields,omitempty"`
+}
+
+// PlatformTop 一个平台排名前 K 的热搜
+type PlatformTop struct {
+	Source    string                   `json:"source"`
+	Name      string                   `json:"name"`
+	FetchedAt time.Time                `json:"fetchedAt"`
+	Items     []map[string]interface{} `json:"items"`
+}
+
+// TopHotSearchResult get_top_hot_search 工具的结果
+type TopHotSearchResult struct {
+	Platforms []PlatformTop `json:"platforms"`
+	Missing   []string      `json:"missing,omitempty"` // 没有已保存快照的平台
+}
+
+// registerQueryTools 注册查询已保存数据的工具，这些工具不会临时抓取，返回的数据量可以通过参数控制
+func (m *MCPHandler) registerQueryTools(platforms []string) {
+	platformList := Property{
+		Type:        "array",
+		Description: "平台名称列表，为空时使用所有启用的平台",
+		Items:       &Property{Type: "string", Enum: platforms},
+	}
+	timeDescription := "，支持 RFC3339、YYYY-MM-DD HH:MM、YYYY-MM-DD 或 Unix 时间戳（秒）"
+
+	m.tools["search_history"] = Tool{
+		Name:        "search_history",
+		Description: "在已保存的历史热搜中搜索标题包含关键词的条目，同一平台同一标题的多次上榜合并为一条，按最近一次上榜时间从新到旧排列",
+		InputSchema: Schema{
+			Type: "object",
+			Properties: map[string]Property{
+				"keyword":   {Type: "string", Description: "标题中包含的关键词"},
+				"platforms": platformList,
+				"from":      {Type: "string", Description: fmt.Sprintf("起始时间，默认为 %d 天前", defaultSearchDays) + timeDescription},
+				"to":        {Type: "string", Description: "结束时间，默认不限制" + timeDescription},
+				"limit":     limitProperty("最多返回的条目数", maxListLimit, defaultQueryLimit),
+			},
+			Required: []string{"keyword"},
+		},
+		call: m.executeSearchHistory,
+	}
+
+	m.tools["trending_across_platforms"] = Tool{
+		Name:        "trending_across_platforms",
+		Description: "根据各平台最新保存的榜单，找出同时出现在多个平台上的话题，按平台数和平均排名排列",
+		InputSchema: Schema{
+			Type: "object",
+			Properties: map[string]Property{
+				"min_platforms": {Type: "integer", Description: "话题至少出现的平台数", Minimum: intValue(2), Default: 2},
+				"platforms":     platformList,
+				"limit":         limitProperty("最多返回的话题数", maxListLimit, defaultQueryLimit),
+			},
+		},
+		call: m.executeTrending,
+	}
+
+	m.tools["diff_rankings"] = Tool{
+		Name:        "diff_rankings",
+		Description: "比较指定平台两个时间点的榜单，返回新上榜、掉出榜单和排名变化的条目。不指定 to 时取最新快照，不指定 from 时取 to 对应快照的前一次快照",
+		InputSchema: Schema{
+			Type: "object",
+			Properties: map[string]Property{
+				"platform": {Type: "string", Description: "平台名称", Enum: platforms},
+				"from":     {Type: "string", Description: "起始时间" + timeDescription},
+				"to":       {Type: "string", Description: "结束时间" + timeDescription},
+				"limit":    limitProperty("每类变化最多返回的条目数", maxListLimit, defaultQueryLimit),
+			},
+			Required: []string{"platform"},
+		},
+		call: m.executeDiffRankings,
+	}
+
+	m.tools["get_top_hot_search"] = Tool{
+		Name:        "get_top_hot_search",
+		Description: "获取各平台最新保存的榜单中排名前 K 的热搜，可以选择返回的字段，适合快速了解多个平台的热点",
+		InputSchema: Schema{
+			Type: "object",
+			Properties: map[string]Property{
+				"platforms": platformList,
+				"k":         limitProperty("每个平台返回的条目数", maxTopK, defaultTopK),
+				"fields": {
+					Type:        "array",
+					Description: "返回的条目字段",
+					Items:       &Property{Type: "string", Enum: itemFields},
+					Default:     defaultItemFields,
+				},
+			},
+		},
+		call: m.executeTopHotSearch,
+	}
+}
+
+// resolvePlatforms 将平台名称或别名转换为路由名称并去重，为空时返回所有启用的平台
+func resolvePlatforms(names []string) ([]app.Source, error) {
+	var sources []app.Source
+	seen := make(map[string]bool)
+	for _, name := range names {
+		source, exists := app.LookupSource(name)
+		if !exists {
+			return nil, invalidParams("Unsupported platform: %s", name)
+		}
+		if !seen[source.RouteName] {
+			seen[source.RouteName] = true
+			sources = append(sources, source)
+		}
+	}
+	if len(sources) == 0 {
+		for _, source := range app.Sources() {
+			if app.Enabled(source.RouteName) {
+				sources = append(sources, source)
+			}
+		}
+	}
+	return sources, nil
+}
+
+// routeNames 返回数据源的路由名称
+func routeNames(sources []app.Source) []string {
+	names := make([]string, len(sources))
+	for i, source := range sources {
+		names[i] = source.RouteName
+	}
+	return names
+}
+
+// parseToolTime 解析工具的时间参数，start 为 true 时只有日期的时间取当天开始，否则取当天结束
+func parseToolTime(name, value string, start bool) (time.Time, error) {
+	if start {
+		if t, err := time.ParseInLocation(time.DateOnly, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	t, err := service.ParseDiffTime(value)
+	if err != nil {
+		return time.Time{}, invalidParams("Invalid %s argument: %v", name, err)
+	}
+	return t, nil
+}
+
+// parseSearchRange 解析 search_history 的时间范围，不指定起始时间时搜索 now 之前 defaultSearchDays 天
+func parseSearchRange(fromValue, toValue string, now time.Time) (from, to time.Time, err error) {
+	if from, err = parseToolTime("from", fromValue, true); err != nil {
+		return
+	}
+	if to, err = parseToolTime("to", toValue, false); err != nil {
+		return
+	}
+	if from.IsZero() {
+		from = now.AddDate(0, 0, -defaultSearchDays)
+	}
+	if !to.IsZero() && to.Before(from) {
+		err = invalidParams("Invalid time range: from is after to")
+	}
+	return
+}
+
+// executeSearchHistory 执行搜索历史热搜的工具
+func (m *MCPHandler) executeSearchHistory(ctx context.Context, arguments json.RawMessage) (interface{}, error) {
+	args := SearchHistoryArgs{Limit: defaultQueryLimit}
+	if err := decodeParams(arguments, &args); err != nil {
+		return nil, err
+	}
+	var sources []string
+	if len(args.Platforms) > 0 {
+		resolved, err := resolvePlatforms(args.Platforms)
+		if err != nil {
+			return nil, err
+		}
+		sources = routeNames(resolved)
+	}
+	from, to, err := parseSearchRange(args.From, args.To, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	matches, err := m.service.SearchHistory(args.Keyword, sources, from, to, args.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("Error searching history: %w", err)
+	}
+	result := SearchHistoryResult{Keyword: args.Keyword, From: from, Matches: matches}
+	if !to.IsZero() {
+		result.To = &to
+	}
+	return result, nil
+}
+
+// executeTrending 执行查找跨平台话题的工具
+func (m *MCPHandler) executeTrending(ctx context.Context, arguments json.RawMessage) (interface{}, error) {
+	args := TrendingArgs{MinPlatforms: 2, Limit: defaultQueryLimit}
+	if err := decodeParams(arguments, &args); err != nil {
+		return nil, err
+	}
+	sources, err := resolvePlatforms(args.Platforms)
+	if err != nil {
+		return nil, err
+	}
+
+	topics, err := m.service.GetCrossPlatformTopics(routeNames(sources), args.MinPlatforms)
+	if err != nil {
+		return nil, fmt.Errorf("Error finding cross-platform topics: %w", err)
+	}
+	result := TrendingResult{MinPlatforms: args.MinPlatforms, Total: len(topics), Topics: topics}
+	if len(result.Topics) > args.Limit {
+		result.Topics = result.Topics[:args.Limit]
+	}
+	return result, nil
+}
+
+// executeDiffRankings 执行比较榜单排名的工具
+func (m *MCPHandler) executeDiffRankings(ctx context.Context, arguments json.RawMessage) (interface{}, error) {
+	args := DiffRankingsArgs{Limit: defaultQueryLimit}
+	if err := decodeParams(arguments, &args); err != nil {
+		return nil, err
+	}
+	source, exists := app.LookupSource(args.Platform)
+	if !exists {
+		return nil, invalidParams("Unsupported platform: %s", args.Platform)
+	}
+	from, err := parseToolTime("from", args.From, false)
+	if err != nil {
+		return nil, err
+	}
+	to, err := parseToolTime("to", args.To, false)
+	if err != nil {
+		return nil, err
+	}
+
+	diff, err := m.service.GetDiff(source.RouteName, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("Error getting diff: %w", err)
+	}
+	if diff == nil {
+		return nil, fmt.Errorf("未找到 %s 在指定时间的两次快照", source.RouteName)
+	}
+
+	result := DiffRankingsResult{
+		Diff:   diff,
+		Totals: DiffTotals{Added: len(diff.Added), Removed: len(diff.Removed), Moved: len(diff.Moved)},
+	}
+	sort.SliceStable(diff.Moved, func(i, j int) bool {
+		return abs(diff.Moved[i].Delta) > abs(diff.Moved[j].Delta)
+	})
+	diff.Added = truncateChanges(diff.Added, args.Limit)
+	diff.Removed = truncateChanges(diff.Removed, args.Limit)
+	diff.Moved = truncateChanges(diff.Moved, args.Limit)
+	return result, nil
+}
+
+// truncateChanges 最多保留前 limit 条变化
+func truncateChanges(changes []service.RankChange, limit int) []service.RankChange {
+	if len(changes) > limit {
+		return changes[:limit]
+	}
+	return changes
+}
+
+// abs 返回整数的绝对值
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// executeTopHotSearch 执行获取各平台前 K 条热搜的工具
+func (m *MCPHandler) executeTopHotSearch(ctx context.Context, arguments json.RawMessage) (interface{}, error) {
+	args := TopHotSearchArgs{K: defaultTopK}
+	if err := decodeParams(arguments, &args); err != nil {
+		return nil, err
+	}
+	if len(args.Fields) == 0 {
+		args.Fields = defaultItemFields
+	}
+	sources, err := resolvePlatforms(args.Platforms)
+	if err != nil {
+		return nil, err
+	}
+
+	result := TopHotSearchResult{Platforms: []PlatformTop{}}
+	for _, source := range sources {
+		snapshot, err := m.service.GetLatestSnapshot(source.RouteName)
+		if err != nil {
+			return nil, fmt.Errorf("Error getting latest snapshot: %w", err)
+		}
+		if snapshot == nil || len(snapshot.Items) == 0 {
+			result.Missing = append(result.Missing, source.RouteName)
+			continue
+		}
+
+		top := PlatformTop{Source: source.RouteName, Name: source.Name, FetchedAt: snapshot.FetchedAt}
+		for _, item := range truncateItems(snapshot.Items, args.K) {
+			top.Items = append(top.Items, selectFields(item, args.Fields))
+		}
+		result.Platforms = append(result.Platforms, top)
+	}
+	return result, nil
+}
+
+// selectFields 只保留条目中选择的字段，省略空值
+func selectFields(item app.Item, fields []string) map[string]interface{} {
+	selected := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		switch field {
+		case "index":
+			selected[field] = item.Index
+		case "title":
+			selected[field] = item.Title
+		case "url":
+			selected[field] = item.URL
+		case "hotValue":
+			if item.HotValue != "" {
+				selected[field] = item.HotValue
+			}
+		case "hotScore":
+			if item.HotScore != 0 {
+				selected[field] = item.HotScore
+			}
+		case "desc":
+			if item.Desc != "" {
+				selected[field] = item.Desc
+			}
+		case "image":
+			if item.Image != "" {
+				selected[field] = item.Image
+			}
+		case "extra":
+			if len(item.Extra) > 0 {
+				selected[field] = item.Extra
+			}
+		}
+	}
+	return selected
+}
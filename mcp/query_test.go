@@ -0,0 +1,240 @@
+package mcp
+
+import (
+	"api/db"
+	"api/model"
+	"api/service"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// callTool 通过 tools/call 调用工具，成功时将结果解析到 v，执行失败时返回错误文本
+func callTool(t *testing.T, handler *MCPHandler, name, args string, v interface{}) (string, *Error) {
+	t.Helper()
+	response := handle(t, handler, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"`+name+`","arguments":`+args+`}}`)
+	if response.Error != nil {
+		return "", response.Error
+	}
+	var result CallToolResult
+	remarshal(t, response.Result, &result)
+	require.Len(t, result.Content, 1)
+	if result.IsError {
+		return result.Content[0].Text, nil
+	}
+	require.NoError(t, json.Unmarshal([]byte(result.Content[0].Text), v))
+	return "", nil
+}
+
+func TestListToolsSchemas(t *testing.T) {
+	handler := setupPromptData(t)
+
+	response := handle(t, handler, `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)
+	require.Nil(t, response.Error)
+	var result struct {
+		Tools []Tool `json:"tools"`
+	}
+	remarshal(t, response.Result, &result)
+
+	tools := make(map[string]Tool)
+	for _, tool := range result.Tools {
+		tools[tool.Name] = tool
+	}
+	for _, name := range []string{"search_history", "trending_across_platforms", "diff_rankings", "get_top_hot_search"} {
+		assert.Contains(t, tools, name)
+	}
+
+	// 平台参数的枚举值由数据源列表生成，包含别名
+	assert.Equal(t, platformNames(), tools["get_hot_search"].InputSchema.Properties["platform"].Enum)
+	assert.Contains(t, tools["diff_rankings"].InputSchema.Properties["platform"].Enum, "weibo")
+	assert.Equal(t, platformNames(), tools["search_history"].InputSchema.Properties["platforms"].Items.Enum)
+	assert.Equal(t, itemFields, tools["get_top_hot_search"].InputSchema.Properties["fields"].Items.Enum)
+
+	// 数量限制的范围和默认值
+	limit := tools["search_history"].InputSchema.Properties["limit"]
+	assert.Equal(t, maxListLimit, *limit.Maximum)
+	assert.EqualValues(t, defaultQueryLimit, limit.Default)
+}
+
+func TestParseSearchRange(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.Local)
+
+	from, to, err := parseSearchRange("", "", now)
+	assert.NoError(t, err)
+	assert.Equal(t, now.AddDate(0, 0, -defaultSearchDays), from)
+	assert.True(t, to.IsZero())
+
+	// 只有日期时起始时间取当天开始，结束时间取当天结束
+	from, to, err = parseSearchRange("2024-05-01", "2024-05-01", now)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 5, 1, 0, 0, 0, 0, time.Local), from)
+	assert.Equal(t, time.Date(2024, 5, 2, 0, 0, 0, 0, time.Local).Add(-time.Nanosecond), to)
+
+	_, _, err = parseSearchRange("2024-05-02", "2024-05-01", now)
+	assert.Error(t, err)
+	_, _, err = parseSearchRange("last week", "", now)
+	assert.Error(t, err)
+}
+
+func TestSearchHistoryTool(t *testing.T) {
+	handler := setupPromptData(t)
+
+	var result SearchHistoryResult
+	text, rpcErr := callTool(t, handler, "search_history", `{"keyword":"新品","from":"2024-05-01"}`, &result)
+	require.Nil(t, rpcErr)
+	require.Empty(t, text)
+	assert.Equal(t, "新品", result.Keyword)
+	if assert.Len(t, result.Matches, 2) {
+		assert.Equal(t, "如何评价新品发布", result.Matches[0].Title)
+		assert.Equal(t, "新品发布", result.Matches[1].Title)
+		assert.Equal(t, 2, result.Matches[1].BestRank)
+	}
+
+	// 按平台和数量限制
+	result = SearchHistoryResult{}
+	_, rpcErr = callTool(t, handler, "search_history", `{"keyword":"早高峰","platforms":["weibo"],"from":"2024-05-01","to":"2024-05-01 09:00","limit":1}`, &result)
+	require.Nil(t, rpcErr)
+	if assert.Len(t, result.Matches, 1) {
+		assert.Equal(t, 1, result.Matches[0].Appearances)
+		assert.Equal(t, "weibo", result.Matches[0].Source)
+	}
+
+	for _, args := range []string{`{}`, `{"keyword":"a","limit":0}`, `{"keyword":"a","platforms":["unknown"]}`, `{"keyword":"a","from":"soon"}`} {
+		_, rpcErr = callTool(t, handler, "search_history", args, nil)
+		if assert.NotNil(t, rpcErr, args) {
+			assert.Equal(t, CodeInvalidParams, rpcErr.Code, args)
+		}
+	}
+}
+
+func TestTrendingAcrossPlatformsTool(t *testing.T) {
+	handler := setupPromptData(t)
+
+	var result TrendingResult
+	_, rpcErr := callTool(t, handler, "trending_across_platforms", `{"platforms":["weibo","zhihu"]}`, &result)
+	require.Nil(t, rpcErr)
+	assert.Equal(t, 2, result.MinPlatforms)
+	assert.Equal(t, 1, result.Total)
+	if assert.Len(t, result.Topics, 1) {
+		assert.Equal(t, "新品发布", result.Topics[0].Title)
+		assert.Equal(t, []service.PlatformRank{
+			{Source: "zhihu", Title: "如何评价新品发布", URL: "https://example.com/如何评价新品发布", Rank: 1},
+			{Source: "weibo", Title: "新品发布", URL: "https://example.com/新品发布", Rank: 2},
+		}, result.Topics[0].Platforms)
+	}
+
+	result = TrendingResult{}
+	_, rpcErr = callTool(t, handler, "trending_across_platforms", `{"platforms":["weibo","zhihu"],"min_platforms":3}`, &result)
+	require.Nil(t, rpcErr)
+	assert.Equal(t, 0, result.Total)
+	assert.Empty(t, result.Topics)
+
+	_, rpcErr = callTool(t, handler, "trending_across_platforms", `{"min_platforms":1}`, nil)
+	if assert.NotNil(t, rpcErr) {
+		assert.Equal(t, CodeInvalidParams, rpcErr.Code)
+	}
+}
+
+func TestDiffRankingsTool(t *testing.T) {
+	handler := setupPromptData(t)
+
+	// 排名变化按变化幅度排列，截断后返回总数
+	var result DiffRankingsResult
+	_, rpcErr := callTool(t, handler, "diff_rankings", `{"platform":"weibo","limit":1}`, &result)
+	require.Nil(t, rpcErr)
+	assert.Equal(t, DiffTotals{Added: 1, Removed: 0, Moved: 2}, result.Totals)
+	assert.Equal(t, []service.RankChange{{Title: "新品发布", URL: "https://example.com/新品发布", Rank: 2}}, result.Added)
+	assert.Equal(t, []service.RankChange{{Title: "早高峰", URL: "https://example.com/早高峰", Rank: 3, PreviousRank: 1, Delta: -2}}, result.Moved)
+	assert.Empty(t, result.Removed)
+
+	// 找不到快照时返回工具错误
+	text, rpcErr := callTool(t, handler, "diff_rankings", `{"platform":"zhihu"}`, nil)
+	require.Nil(t, rpcErr)
+	assert.Contains(t, text, "未找到 zhihu")
+
+	for _, args := range []string{`{}`, `{"platform":"unknown"}`, `{"platform":"weibo","to":"tomorrow"}`} {
+		_, rpcErr = callTool(t, handler, "diff_rankings", args, nil)
+		if assert.NotNil(t, rpcErr, args) {
+			assert.Equal(t, CodeInvalidParams, rpcErr.Code, args)
+		}
+	}
+}
+
+func TestGetTopHotSearchTool(t *testing.T) {
+	handler := setupPromptData(t)
+
+	var result TopHotSearchResult
+	_, rpcErr := callTool(t, handler, "get_top_hot_search", `{"platforms":["weibo","zhihu","baidu"],"k":2,"fields":["title","hotValue"]}`, &result)
+	require.Nil(t, rpcErr)
+	assert.Equal(t, []string{"baidu"}, result.Missing)
+	if assert.Len(t, result.Platforms, 2) {
+		assert.Equal(t, "weibo", result.Platforms[0].Source)
+		assert.Equal(t, "微博", result.Platforms[0].Name)
+		assert.Equal(t, []map[string]interface{}{
+			{"title": "天气", "hotValue": "100万"},
+			{"title": "新品发布"},
+		}, result.Platforms[0].Items)
+		assert.Equal(t, "zhihu", result.Platforms[1].Source)
+	}
+
+	// 默认字段
+	result = TopHotSearchResult{}
+	_, rpcErr = callTool(t, handler, "get_top_hot_search", `{"platforms":["zhihu"]}`, &result)
+	require.Nil(t, rpcErr)
+	if assert.Len(t, result.Platforms, 1) {
+		assert.Equal(t, []map[string]interface{}{
+			{"index": float64(1), "title": "如何评价新品发布", "hotValue": "100万"},
+		}, result.Platforms[0].Items)
+	}
+
+	for _, args := range []string{`{"k":51}`, `{"fields":["rank"]}`} {
+		_, rpcErr = callTool(t, handler, "get_top_hot_search", args, nil)
+		if assert.NotNil(t, rpcErr, args) {
+			assert.Equal(t, CodeInvalidParams, rpcErr.Code, args)
+		}
+	}
+}
+
+func TestGetHistoryDataTool(t *testing.T) {
+	handler := setupPromptData(t)
+	at := time.Date(2024, 5, 1, 9, 30, 0, 0, time.Local)
+	require.NoError(t, db.SaveAllDataAt(map[string][]model.HotSearchItem{
+		"quark": {{Title: "夸克热搜", URL: "https://example.com/quark", Index: 1}},
+	}, at))
+
+	var result struct {
+		Code int                         `json:"code"`
+		Obj  map[string][]map[string]any `json:"obj"`
+	}
+	text, rpcErr := callTool(t, handler, "get_history_data", `{"platform":"weibo","date":"2024-05-01"}`, &result)
+	require.Nil(t, rpcErr)
+	require.Empty(t, text)
+	assert.Equal(t, 200, result.Code)
+	assert.Len(t, result.Obj["09:00"], 3)
+
+	// 别名按数据源的路由名称查询
+	var hourly struct {
+		Obj []map[string]any `json:"obj"`
+	}
+	text, rpcErr = callTool(t, handler, "get_history_data", `{"platform":"kuake","date":"2024-05-01","hour":"9"}`, &hourly)
+	require.Nil(t, rpcErr)
+	require.Empty(t, text)
+	if assert.Len(t, hourly.Obj, 1) {
+		assert.Equal(t, "夸克热搜", hourly.Obj[0]["title"])
+	}
+
+	// 没有数据或参数格式错误时返回 isError 结果
+	for _, args := range []string{`{"platform":"weibo","date":"2024-05-01","hour":"3"}`, `{"platform":"weibo","date":"2024-05-01","hour":"soon"}`} {
+		text, rpcErr = callTool(t, handler, "get_history_data", args, nil)
+		require.Nil(t, rpcErr, args)
+		assert.Contains(t, text, "Error getting historical data", args)
+	}
+
+	_, rpcErr = callTool(t, handler, "get_history_data", `{"platform":"unknown","date":"2024-05-01"}`, nil)
+	if assert.NotNil(t, rpcErr) {
+		assert.Equal(t, CodeInvalidParams, rpcErr.Code)
+	}
+}
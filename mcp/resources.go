@@ -0,0 +1,293 @@
+package mcp
+
+import (
+	"api/app"
+	"api/logging"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CodeResourceNotFound MCP 规定的资源不存在错误码
+const CodeResourceNotFound = -32002
+
+// 资源URI，数据源名称使用路由名称，读取和订阅时也接受别名
+const (
+	sourcesURI         = "azhot://sources"
+	latestURIPrefix    = "azhot://latest/"
+	historyURIPrefix   = "azhot://history/"
+	latestURITemplate  = latestURIPrefix + "{source}"
+	historyURITemplate = historyURIPrefix + "{source}/{date}/{hour}"
+	resourceMimeType   = "application/json"
+)
+
+// ResourcesCapability 资源功能，subscribe 表示支持订阅单个资源的变化
+type ResourcesCapability struct {
+	Subscribe   bool `json:"subscribe"`
+	ListChanged bool `json:"listChanged"`
+}
+
+// Resource resources/list 返回的资源
+type Resource struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+}
+
+// ResourceTemplate resources/templates/list 返回的资源模板，URI 模板的格式见 RFC 6570
+type ResourceTemplate struct {
+	URITemplate string `json:"uriTemplate"`
+	Name        string `json:"name"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+}
+
+// ResourceContents resources/read 返回的资源内容
+type ResourceContents struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType,omitempty"`
+	Text     string `json:"text"`
+}
+
+// ResourceParams resources/read、resources/subscribe 和 resources/unsubscribe 请求的参数
+type ResourceParams struct {
+	URI string `json:"uri"`
+}
+
+// ResourceUpdatedParams 订阅的资源变化后推送的 notifications/resources/updated 通知的参数
+type ResourceUpdatedParams struct {
+	URI string `json:"uri"`
+}
+
+// SourceResource azhot://sources 资源中的数据源信息
+type SourceResource struct {
+	app.PlatformInfo
+	Aliases []string `json:"aliases,omitempty"` // 路由别名
+	Enabled bool     `json:"enabled"`           // 是否启用，停用的数据源不再定时抓取，历史数据仍可读取
+}
+
+// resourceRef 解析后的资源URI
+type resourceRef struct {
+	kind   string // sources、latest 或 history
+	source string // 路由名称
+	date   string // YYYY-MM-DD，仅 history
+	hour   int    // 0-23，仅 history
+}
+
+// URI 返回资源的规范URI，别名会替换为路由名称
+func (r resourceRef) URI() string {
+	switch r.kind {
+	case "latest":
+		return latestURIPrefix + r.source
+	case "history":
+		return fmt.Sprintf("%s%s/%s/%02d", historyURIPrefix, r.source, r.date, r.hour)
+	}
+	return sourcesURI
+}
+
+// resourceNotFound 创建资源不存在的错误
+func resourceNotFound(uri string) *Error {
+	return &Error{Code: CodeResourceNotFound, Message: "Resource not found", Data: map[string]string{"uri": uri}}
+}
+
+// parseResourceURI 解析资源URI，URI 不是本服务器的资源或数据源不存在时返回资源不存在的错误，日期或小时格式错误时返回参数无效的错误
+func parseResourceURI(uri string) (resourceRef, error) {
+	if uri == "" {
+		return resourceRef{}, invalidParams("Missing uri")
+	}
+	if uri == sourcesURI {
+		return resourceRef{kind: "sources"}, nil
+	}
+
+	var kind, rest string
+	switch {
+	case strings.HasPrefix(uri, latestURIPrefix):
+		kind, rest = "latest", strings.TrimPrefix(uri, latestURIPrefix)
+	case strings.HasPrefix(uri, historyURIPrefix):
+		kind, rest = "history", strings.TrimPrefix(uri, historyURIPrefix)
+	default:
+		return resourceRef{}, resourceNotFound(uri)
+	}
+
+	parts := strings.Split(rest, "/")
+	if (kind == "latest" && len(parts) != 1) || (kind == "history" && len(parts) != 3) {
+		return resourceRef{}, resourceNotFound(uri)
+	}
+	source, exists := app.LookupSource(parts[0])
+	if !exists {
+		return resourceRef{}, resourceNotFound(uri)
+	}
+	ref := resourceRef{kind: kind, source: source.RouteName}
+	if kind == "latest" {
+		return ref, nil
+	}
+
+	if _, err := time.Parse(time.DateOnly, parts[1]); err != nil {
+		return resourceRef{}, invalidParams("Invalid date %q, expected YYYY-MM-DD", parts[1])
+	}
+	hour, err := strconv.Atoi(parts[2])
+	if err != nil || hour < 0 || hour > 23 {
+		return resourceRef{}, invalidParams("Invalid hour %q, expected 0-23", parts[2])
+	}
+	ref.date, ref.hour = parts[1], hour
+	return ref, nil
+}
+
+// handleListResources 处理资源列表请求，返回数据源列表和每个启用的数据源的最新快照
+func (m *MCPHandler) handleListResources() (interface{}, error) {
+	resources := []Resource{{
+		URI:         sourcesURI,
+		Name:        "sources",
+		Title:       "数据源列表",
+		Description: "所有数据源的路由名称、中文名称、分类、别名和启用状态",
+		MimeType:    resourceMimeType,
+	}}
+	for _, source := range app.Sources() {
+		if !app.Enabled(source.RouteName) {
+			continue
+		}
+		resources = append(resources, Resource{
+			URI:         latestURIPrefix + source.RouteName,
+			Name:        "latest/" + source.RouteName,
+			Title:       source.Name + "最新热搜",
+			Description: source.Name + "最近一次保存的热搜快照，定时抓取保存新快照后通知订阅者",
+			MimeType:    resourceMimeType,
+		})
+	}
+	return map[string]interface{}{"resources": resources}, nil
+}
+
+// handleListResourceTemplates 处理资源模板列表请求
+func (m *MCPHandler) handleListResourceTemplates() (interface{}, error) {
+	templates := []ResourceTemplate{
+		{
+			URITemplate: latestURITemplate,
+			Name:        "latest",
+			Title:       "最新热搜",
+			Description: "指定平台最近一次保存的热搜快照，source 为路由名称或别名，如 weibo",
+			MimeType:    resourceMimeType,
+		},
+		{
+			URITemplate: historyURITemplate,
+			Name:        "history",
+			Title:       "历史热搜",
+			Description: "指定平台某一小时的热搜快照，date 格式为 YYYY-MM-DD，hour 为 0-23，同一小时有多次快照时取最新的一次",
+			MimeType:    resourceMimeType,
+		},
+	}
+	return map[string]interface{}{"resourceTemplates": templates}, nil
+}
+
+// handleReadResource 处理资源读取请求，内容为JSON文本，快照的格式与 HTTP 接口相同
+func (m *MCPHandler) handleReadResource(params json.RawMessage) (interface{}, error) {
+	var p ResourceParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	ref, err := parseResourceURI(p.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	var data interface{}
+	switch ref.kind {
+	case "sources":
+		data = sourceResources()
+	case "latest":
+		result, err := m.service.GetLatestSnapshot(ref.source)
+		if err != nil {
+			return nil, fmt.Errorf("Error getting latest snapshot: %w", err)
+		}
+		if result == nil {
+			return nil, resourceNotFound(p.URI)
+		}
+		data = result.Response()
+	case "history":
+		result, err := m.service.GetHourlySnapshot(ref.source, ref.date, ref.hour)
+		if err != nil {
+			return nil, fmt.Errorf("Error getting historical data: %w", err)
+		}
+		if result == nil {
+			return nil, resourceNotFound(p.URI)
+		}
+		data = result.Response()
+	}
+
+	text, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"contents": []ResourceContents{{URI: p.URI, MimeType: resourceMimeType, Text: string(text)}},
+	}, nil
+}
+
+// sourceResources 返回所有数据源的信息，按路由名称排序
+func sourceResources() []SourceResource {
+	sources := app.Sources()
+	resources := make([]SourceResource, len(sources))
+	for i, source := range sources {
+		resources[i] = SourceResource{
+			PlatformInfo: app.PlatformInfo{
+				RouteName: source.RouteName,
+				Name:      source.Name,
+				Icon:      source.Icon,
+				Category:  source.Category,
+			},
+			Aliases: source.Aliases,
+			Enabled: app.Enabled(source.RouteName),
+		}
+	}
+	return resources
+}
+
+// handleSubscribeResource 处理资源订阅请求，目前只有 azhot://latest/{source} 会变化
+//
+// 订阅属于当前会话，定时任务保存该数据源的新快照后向会话推送 notifications/resources/updated，
+// 通知中的 URI 为规范URI（别名替换为路由名称）
+func (m *MCPHandler) handleSubscribeResource(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	s, ref, err := m.subscriptionTarget(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	if ref.kind != "latest" {
+		return nil, invalidParams("Resource %s does not support subscriptions", ref.URI())
+	}
+	s.subscribe(ref.URI())
+	logging.FromContext(ctx).Info("订阅资源", "uri", ref.URI())
+	return nil, nil
+}
+
+// handleUnsubscribeResource 处理取消资源订阅请求，没有订阅过时也返回成功
+func (m *MCPHandler) handleUnsubscribeResource(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	s, ref, err := m.subscriptionTarget(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	s.unsubscribe(ref.URI())
+	return nil, nil
+}
+
+// subscriptionTarget 解析订阅请求的参数，返回当前会话和资源
+func (m *MCPHandler) subscriptionTarget(ctx context.Context, params json.RawMessage) (*session, resourceRef, error) {
+	var p ResourceParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, resourceRef{}, err
+	}
+	ref, err := parseResourceURI(p.URI)
+	if err != nil {
+		return nil, resourceRef{}, err
+	}
+	// 没有会话时（如调试用的 HTTP 端点）无法推送通知
+	s := sessionFromContext(ctx)
+	if s == nil {
+		return nil, resourceRef{}, &Error{Code: CodeInvalidRequest, Message: "Subscriptions require a session"}
+	}
+	return s, ref, nil
+}
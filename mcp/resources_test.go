@@ -0,0 +1,242 @@
+package mcp
+
+import (
+	"api/app"
+	"api/config"
+	"api/db"
+	"api/model"
+	"api/service"
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseResourceURI(t *testing.T) {
+	tests := []struct {
+		uri       string
+		canonical string
+		code      int
+	}{
+		{"azhot://sources", "azhot://sources", 0},
+		{"azhot://latest/baidu", "azhot://latest/baidu", 0},
+		{"azhot://latest/kuake", "azhot://latest/quark", 0}, // 别名
+		{"azhot://history/weibo/2024-05-01/8", "azhot://history/weibo/2024-05-01/08", 0},
+		{"", "", CodeInvalidParams},
+		{"https://example.com", "", CodeResourceNotFound},
+		{"azhot://latest/unknown", "", CodeResourceNotFound},
+		{"azhot://latest/baidu/extra", "", CodeResourceNotFound},
+		{"azhot://history/weibo/2024-05-01", "", CodeResourceNotFound},
+		{"azhot://history/weibo/20240501/08", "", CodeInvalidParams},
+		{"azhot://history/weibo/2024-05-01/24", "", CodeInvalidParams},
+	}
+	for _, tt := range tests {
+		ref, err := parseResourceURI(tt.uri)
+		if tt.code != 0 {
+			var rpcErr *Error
+			if assert.ErrorAs(t, err, &rpcErr, tt.uri) {
+				assert.Equal(t, tt.code, rpcErr.Code, tt.uri)
+			}
+			continue
+		}
+		assert.NoError(t, err, tt.uri)
+		assert.Equal(t, tt.canonical, ref.URI())
+	}
+}
+
+func TestHandleListResources(t *testing.T) {
+	handler := NewMCPHandler(&service.HotSearchService{}, &config.Config{})
+
+	response := handle(t, handler, `{"jsonrpc":"2.0","id":1,"method":"resources/list"}`)
+	assert.Nil(t, response.Error)
+	var result struct {
+		Resources []Resource `json:"resources"`
+	}
+	remarshal(t, response.Result, &result)
+	require.NotEmpty(t, result.Resources)
+	assert.Equal(t, sourcesURI, result.Resources[0].URI)
+
+	uris := make([]string, 0, len(result.Resources))
+	for _, resource := range result.Resources {
+		assert.Equal(t, resourceMimeType, resource.MimeType)
+		uris = append(uris, resource.URI)
+	}
+	assert.Contains(t, uris, "azhot://latest/baidu")
+	assert.Len(t, uris, len(app.Sources())+1)
+
+	response = handle(t, handler, `{"jsonrpc":"2.0","id":2,"method":"resources/templates/list"}`)
+	assert.Nil(t, response.Error)
+	var templates struct {
+		ResourceTemplates []ResourceTemplate `json:"resourceTemplates"`
+	}
+	remarshal(t, response.Result, &templates)
+	require.Len(t, templates.ResourceTemplates, 2)
+	assert.Equal(t, "azhot://latest/{source}", templates.ResourceTemplates[0].URITemplate)
+	assert.Equal(t, "azhot://history/{source}/{date}/{hour}", templates.ResourceTemplates[1].URITemplate)
+}
+
+func TestHandleReadResource(t *testing.T) {
+	db.InitSQLiteWithDSN("file:" + t.Name() + "?mode=memory&cache=shared")
+	handler := NewMCPHandler(&service.HotSearchService{}, &config.Config{})
+
+	morning := time.Date(2024, 5, 1, 8, 30, 0, 0, time.Local)
+	save := func(source string, at time.Time, title string) {
+		items := []model.HotSearchItem{{Title: title, URL: "https://example.com/" + title, Index: 1}}
+		require.NoError(t, db.SaveAllDataAt(map[string][]model.HotSearchItem{source: items}, at))
+	}
+	save("baidu", morning, "早间")
+	save("baidu", morning.Add(2*time.Hour), "上午")
+	save("quark", morning, "夸克")
+
+	// read 读取资源，返回响应和资源内容
+	read := func(uri string) (Response, string) {
+		t.Helper()
+		request, err := json.Marshal(Request{Method: "resources/read", Params: json.RawMessage(`{"uri":"` + uri + `"}`), ID: NumberID(1), Version: "2.0"})
+		require.NoError(t, err)
+		response := handle(t, handler, string(request))
+		if response.Error != nil {
+			return response, ""
+		}
+		var result struct {
+			Contents []ResourceContents `json:"contents"`
+		}
+		remarshal(t, response.Result, &result)
+		require.Len(t, result.Contents, 1)
+		assert.Equal(t, uri, result.Contents[0].URI)
+		assert.Equal(t, resourceMimeType, result.Contents[0].MimeType)
+		return response, result.Contents[0].Text
+	}
+	// snapshot 读取快照资源
+	snapshot := func(uri string) app.Response {
+		t.Helper()
+		response, text := read(uri)
+		require.Nil(t, response.Error)
+		var data app.Response
+		require.NoError(t, json.Unmarshal([]byte(text), &data))
+		return data
+	}
+
+	// 最新快照
+	data := snapshot("azhot://latest/baidu")
+	require.Len(t, data.Obj, 1)
+	assert.Equal(t, "上午", data.Obj[0].Title)
+	assert.True(t, morning.Add(2*time.Hour).Equal(*data.FetchedAt))
+
+	// 别名
+	assert.Equal(t, "夸克", snapshot("azhot://latest/kuake").Obj[0].Title)
+
+	// 历史快照
+	assert.Equal(t, "早间", snapshot("azhot://history/baidu/2024-05-01/08").Obj[0].Title)
+
+	// 数据源列表
+	response, text := read(sourcesURI)
+	require.Nil(t, response.Error)
+	var sources []SourceResource
+	require.NoError(t, json.Unmarshal([]byte(text), &sources))
+	assert.Len(t, sources, len(app.Sources()))
+	for _, source := range sources {
+		if source.RouteName == "quark" {
+			assert.Equal(t, []string{"kuake"}, source.Aliases)
+			assert.True(t, source.Enabled)
+		}
+	}
+
+	// 没有数据和无效的URI
+	response, _ = read("azhot://latest/zhihu")
+	require.NotNil(t, response.Error)
+	assert.Equal(t, CodeResourceNotFound, response.Error.Code)
+	response, _ = read("azhot://history/baidu/2024-05-01/09")
+	require.NotNil(t, response.Error)
+	assert.Equal(t, CodeResourceNotFound, response.Error.Code)
+	response, _ = read("azhot://history/baidu/2024-05-01/noon")
+	require.NotNil(t, response.Error)
+	assert.Equal(t, CodeInvalidParams, response.Error.Code)
+}
+
+func TestResourceSubscriptions(t *testing.T) {
+	handler := NewMCPHandler(&service.HotSearchService{}, &config.Config{})
+
+	// 没有会话时无法订阅
+	response := handle(t, handler, `{"jsonrpc":"2.0","id":1,"method":"resources/subscribe","params":{"uri":"azhot://latest/baidu"}}`)
+	require.NotNil(t, response.Error)
+	assert.Equal(t, CodeInvalidRequest, response.Error.Code)
+
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- handler.ServeSTDIO(context.Background(), inR, outW)
+	}()
+	lines := bufio.NewScanner(outR)
+	call := func(request string) Response {
+		t.Helper()
+		_, err := io.WriteString(inW, request+"\n")
+		require.NoError(t, err)
+		require.True(t, lines.Scan())
+		var response Response
+		require.NoError(t, json.Unmarshal(lines.Bytes(), &response))
+		return response
+	}
+	// next 读取下一个通知的方法和参数
+	next := func() (string, json.RawMessage) {
+		t.Helper()
+		require.True(t, lines.Scan())
+		var notification Request
+		require.NoError(t, json.Unmarshal(lines.Bytes(), &notification))
+		assert.True(t, notification.IsNotification())
+		return notification.Method, notification.Params
+	}
+	fetched := func(source string, scheduled bool) {
+		go handler.handleFetchEvent(service.FetchEvent{Source: source, Result: app.NewResult(source, nil), Scheduled: scheduled, Saved: scheduled})
+	}
+
+	// 通过别名订阅，通知中使用规范URI
+	response = call(`{"jsonrpc":"2.0","id":1,"method":"resources/subscribe","params":{"uri":"azhot://latest/kuake"}}`)
+	assert.Nil(t, response.Error)
+	response = call(`{"jsonrpc":"2.0","id":2,"method":"resources/subscribe","params":{"uri":"azhot://history/quark/2024-05-01/08"}}`)
+	require.NotNil(t, response.Error)
+	assert.Equal(t, CodeInvalidParams, response.Error.Code)
+
+	// 定时任务保存新快照后通知订阅者
+	fetched("quark", true)
+	method, _ := next()
+	assert.Equal(t, "notifications/hot_search/updated", method)
+	method, params := next()
+	assert.Equal(t, "notifications/resources/updated", method)
+	assert.JSONEq(t, `{"uri":"azhot://latest/quark"}`, string(params))
+
+	// 按需抓取和未订阅的数据源只有热搜更新通知，之后的 ping 响应说明没有其他通知
+	fetched("quark", false)
+	method, _ = next()
+	assert.Equal(t, "notifications/hot_search/updated", method)
+	fetched("baidu", true)
+	method, _ = next()
+	assert.Equal(t, "notifications/hot_search/updated", method)
+	response = call(`{"jsonrpc":"2.0","id":3,"method":"ping"}`)
+	assert.Equal(t, "3", response.ID.String())
+
+	// 取消订阅后不再通知
+	response = call(`{"jsonrpc":"2.0","id":4,"method":"resources/unsubscribe","params":{"uri":"azhot://latest/quark"}}`)
+	assert.Nil(t, response.Error)
+	fetched("quark", true)
+	method, _ = next()
+	assert.Equal(t, "notifications/hot_search/updated", method)
+	response = call(`{"jsonrpc":"2.0","id":5,"method":"ping"}`)
+	assert.Equal(t, "5", response.ID.String())
+
+	inW.Close()
+	assert.NoError(t, <-done)
+}
+
+// remarshal 将响应结果重新解析到 v
+func remarshal(t *testing.T, result interface{}, v interface{}) {
+	t.Helper()
+	data, err := json.Marshal(result)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, v))
+}
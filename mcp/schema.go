@@ -0,0 +1,125 @@
+package mcp
+
+import (
+	"api/app"
+	"bytes"
+	"encoding/json"
+	"math"
+	"slices"
+	"sort"
+)
+
+// Schema 定义工具输入模式
+type Schema struct {
+	Type       string              `json:"type"`
+	Properties map[string]Property `json:"properties"`
+	Required   []string            `json:"required,omitempty"`
+}
+
+// Property 工具参数的 JSON Schema，只支持工具用到的关键字
+type Property struct {
+	Type        string      `json:"type"`
+	Description string      `json:"description,omitempty"`
+	Enum        []string    `json:"enum,omitempty"`
+	Items       *Property   `json:"items,omitempty"` // 数组元素的模式
+	Minimum     *int        `json:"minimum,omitempty"`
+	Maximum     *int        `json:"maximum,omitempty"`
+	Default     interface{} `json:"default,omitempty"`
+}
+
+// intValue 返回整数的指针，用于设置参数的最小值和最大值
+func intValue(v int) *int {
+	return &v
+}
+
+// platformNames 返回所有平台的路由名称和别名，用作平台参数的枚举值
+func platformNames() []string {
+	var names []string
+	for _, source := range app.Sources() {
+		names = append(names, source.RouteName)
+		names = append(names, source.Aliases...)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// validate 按模式检查工具参数，参数无效时返回 *Error
+//
+// 没有声明类型的模式不做检查；未声明的参数交给工具自行处理
+func (s Schema) validate(args json.RawMessage) error {
+	if s.Type == "" {
+		return nil
+	}
+
+	values := make(map[string]json.RawMessage)
+	if trimmed := bytes.TrimSpace(args); len(trimmed) > 0 && !bytes.Equal(trimmed, []byte("null")) {
+		if err := json.Unmarshal(trimmed, &values); err != nil {
+			return invalidParams("Invalid arguments: expected object")
+		}
+	}
+
+	for _, name := range s.Required {
+		if value, exists := values[name]; !exists || isNull(value) || string(bytes.TrimSpace(value)) == `""` {
+			return invalidParams("Missing %s argument", name)
+		}
+	}
+
+	names := make([]string, 0, len(s.Properties))
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value, exists := values[name]
+		if !exists || isNull(value) {
+			continue
+		}
+		if err := s.Properties[name].validate(name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validate 检查单个参数的类型、枚举值和取值范围
+func (p Property) validate(name string, value json.RawMessage) error {
+	switch p.Type {
+	case "string":
+		var s string
+		if err := json.Unmarshal(value, &s); err != nil {
+			return invalidParams("Invalid %s argument: expected string", name)
+		}
+		if len(p.Enum) > 0 && !slices.Contains(p.Enum, s) {
+			return invalidParams("Unsupported %s: %s", name, s)
+		}
+	case "integer":
+		var n float64
+		if err := json.Unmarshal(value, &n); err != nil || n != math.Trunc(n) {
+			return invalidParams("Invalid %s argument: expected integer", name)
+		}
+		if p.Minimum != nil && n < float64(*p.Minimum) {
+			return invalidParams("Invalid %s argument: must be at least %d", name, *p.Minimum)
+		}
+		if p.Maximum != nil && n > float64(*p.Maximum) {
+			return invalidParams("Invalid %s argument: must be at most %d", name, *p.Maximum)
+		}
+	case "array":
+		var elements []json.RawMessage
+		if err := json.Unmarshal(value, &elements); err != nil {
+			return invalidParams("Invalid %s argument: expected array", name)
+		}
+		if p.Items != nil {
+			for _, element := range elements {
+				if err := p.Items.validate(name, element); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// isNull 判断参数值是否为 null
+func isNull(value json.RawMessage) bool {
+	return bytes.Equal(bytes.TrimSpace(value), []byte("null"))
+}
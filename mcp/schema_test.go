@@ -0,0 +1,55 @@
+package mcp
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchemaValidate(t *testing.T) {
+	schema := Schema{
+		Type: "object",
+		Properties: map[string]Property{
+			"platform":  {Type: "string", Enum: []string{"baidu", "weibo"}},
+			"platforms": {Type: "array", Items: &Property{Type: "string", Enum: []string{"baidu", "weibo"}}},
+			"limit":     {Type: "integer", Minimum: intValue(1), Maximum: intValue(10)},
+		},
+		Required: []string{"platform"},
+	}
+
+	for _, args := range []string{
+		`{"platform":"baidu"}`,
+		`{"platform":"weibo","platforms":["baidu","weibo"],"limit":10}`,
+		`{"platform":"weibo","limit":null,"unknown":true}`,
+		`{"platform":"weibo","limit":5.0}`,
+	} {
+		assert.NoError(t, schema.validate(json.RawMessage(args)), args)
+	}
+
+	tests := []struct {
+		args    string
+		message string
+	}{
+		{``, "Missing platform argument"},
+		{`{"platform":""}`, "Missing platform argument"},
+		{`[1]`, "Invalid arguments: expected object"},
+		{`{"platform":1}`, "Invalid platform argument: expected string"},
+		{`{"platform":"zhihu"}`, "Unsupported platform: zhihu"},
+		{`{"platform":"baidu","platforms":"baidu"}`, "Invalid platforms argument: expected array"},
+		{`{"platform":"baidu","platforms":["zhihu"]}`, "Unsupported platforms: zhihu"},
+		{`{"platform":"baidu","limit":1.5}`, "Invalid limit argument: expected integer"},
+		{`{"platform":"baidu","limit":0}`, "Invalid limit argument: must be at least 1"},
+		{`{"platform":"baidu","limit":11}`, "Invalid limit argument: must be at most 10"},
+	}
+	for _, tt := range tests {
+		err := schema.validate(json.RawMessage(tt.args))
+		if assert.IsType(t, &Error{}, err, tt.args) {
+			assert.Equal(t, CodeInvalidParams, err.(*Error).Code)
+			assert.Equal(t, tt.message, err.(*Error).Message)
+		}
+	}
+
+	// 没有声明类型的模式不做检查
+	assert.NoError(t, Schema{}.validate(json.RawMessage(`[1]`)))
+}
@@ -0,0 +1,167 @@
+package mcp
+
+import (
+	"api/logging"
+	"api/metrics"
+	"api/service"
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// session 一个客户端连接的状态，每个 STDIO 连接和 Streamable HTTP 会话各对应一个
+type session struct {
+	id        string
+	transport string // stdio 或 http
+
+	// send 向客户端推送服务器发起的消息，不能阻塞
+	send func(message []byte)
+
+	mu              sync.Mutex
+	protocolVersion string
+	client          Implementation
+	subscriptions   map[string]bool // 订阅的资源URI
+}
+
+// initialize 记录初始化时协商的协议版本和客户端信息
+func (s *session) initialize(version string, client Implementation) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.protocolVersion = version
+	s.client = client
+}
+
+// ProtocolVersion 返回协商的协议版本，还没有初始化时为空
+func (s *session) ProtocolVersion() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.protocolVersion
+}
+
+// subscribe 订阅资源
+func (s *session) subscribe(uri string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.subscriptions == nil {
+		s.subscriptions = make(map[string]bool)
+	}
+	s.subscriptions[uri] = true
+}
+
+// unsubscribe 取消订阅资源
+func (s *session) unsubscribe(uri string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.subscriptions, uri)
+}
+
+// subscribed 是否订阅了资源
+func (s *session) subscribed(uri string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.subscriptions[uri]
+}
+
+type sessionKey struct{}
+
+// withSession 返回带有会话的 context，方法处理函数通过 sessionFromContext 获取当前会话
+func withSession(ctx context.Context, s *session) context.Context {
+	return context.WithValue(ctx, sessionKey{}, s)
+}
+
+// sessionFromContext 返回 ctx 中的会话，直接调用 HandleRequest 时没有会话，返回 nil
+func sessionFromContext(ctx context.Context) *session {
+	s, _ := ctx.Value(sessionKey{}).(*session)
+	return s
+}
+
+// addSession 登记会话，之后服务器发起的通知会推送给它
+func (m *MCPHandler) addSession(s *session) {
+	m.sessionsMu.Lock()
+	m.sessions[s.id] = s
+	m.sessionsMu.Unlock()
+	metrics.MCPSessions.Inc(s.transport)
+	m.logger.Info("MCP 会话建立", "session", s.id, "transport", s.transport)
+}
+
+// removeSession 移除会话
+func (m *MCPHandler) removeSession(id string) {
+	m.sessionsMu.Lock()
+	s, exists := m.sessions[id]
+	delete(m.sessions, id)
+	m.sessionsMu.Unlock()
+	if exists {
+		metrics.MCPSessions.Dec(s.transport)
+		m.logger.Info("MCP 会话结束", "session", id, "transport", s.transport)
+	}
+}
+
+// notify 向所有会话推送通知
+func (m *MCPHandler) notify(method string, params interface{}) {
+	m.notifyWhere(method, params, nil)
+}
+
+// notifyWhere 向 match 返回 true 的会话推送通知，match 为 nil 时推送给所有会话
+func (m *MCPHandler) notifyWhere(method string, params interface{}, match func(*session) bool) {
+	data, err := json.Marshal(params)
+	if err != nil {
+		m.logger.Error("序列化通知失败", "method", method, logging.KeyError, err)
+		return
+	}
+	message, err := json.Marshal(Request{Method: method, Params: data, Version: jsonrpcVersion})
+	if err != nil {
+		m.logger.Error("序列化通知失败", "method", method, logging.KeyError, err)
+		return
+	}
+
+	m.sessionsMu.Lock()
+	sessions := make([]*session, 0, len(m.sessions))
+	for _, s := range m.sessions {
+		if match == nil || match(s) {
+			sessions = append(sessions, s)
+		}
+	}
+	m.sessionsMu.Unlock()
+
+	for _, s := range sessions {
+		s.send(message)
+	}
+}
+
+// HotSearchUpdatedParams 数据源抓取到新数据后推送的 notifications/hot_search/updated 通知的参数
+type HotSearchUpdatedParams struct {
+	Source    string    `json:"source"`
+	FetchedAt time.Time `json:"fetchedAt"`
+	Total     int       `json:"total"`             // 条目数
+	Scheduled bool      `json:"scheduled"`         // 是否由定时任务触发
+	Changed   *bool     `json:"changed,omitempty"` // 与上一次快照相比是否有变化，没有历史快照时不返回
+}
+
+// handleFetchEvent 数据源抓取完成后通知所有会话，返回已保存快照的抓取不通知
+//
+// 定时任务保存了新快照时，还会向订阅了该数据源最新快照资源的会话推送 notifications/resources/updated
+func (m *MCPHandler) handleFetchEvent(event service.FetchEvent) {
+	if event.Result == nil || event.Result.Stale {
+		return
+	}
+	params := HotSearchUpdatedParams{
+		Source:    event.Source,
+		FetchedAt: event.Result.FetchedAt,
+		Total:     len(event.Result.Items),
+		Scheduled: event.Scheduled,
+	}
+	if event.Diff != nil {
+		changed := !event.Diff.Empty()
+		params.Changed = &changed
+	}
+	m.notify("notifications/hot_search/updated", params)
+
+	// 按需抓取不一定保存，只有保存了新快照时最新快照资源才会变化
+	if event.Saved {
+		uri := latestURIPrefix + event.Source
+		m.notifyWhere("notifications/resources/updated", ResourceUpdatedParams{URI: uri}, func(s *session) bool {
+			return s.subscribed(uri)
+		})
+	}
+}
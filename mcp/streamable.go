@@ -0,0 +1,476 @@
+package mcp
+
+import (
+	"api/logging"
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Streamable HTTP 传输，规范见 https://modelcontextprotocol.io/specification/2025-06-18/basic/transports#streamable-http
+//
+// 同一个端点支持三种请求：
+//   - POST 发送 JSON-RPC 消息。包含请求时，客户端接受 text/event-stream 则以 SSE 流返回响应，否则返回 JSON；
+//     只有通知时返回 202。initialize 请求总是返回 JSON，响应头 Mcp-Session-Id 中为新会话的ID，之后的请求都需要带上它
+//   - GET 打开 SSE 流，接收服务器发起的通知
+//   - DELETE 结束会话
+//
+// SSE 事件的ID格式为 "流编号-事件编号"，GET 流的编号为 0。断线后带上 Last-Event-ID 头发送 GET 请求，
+// 可以继续接收该事件所在的流中错过的事件
+
+const (
+	headerSessionID       = "Mcp-Session-Id"
+	headerProtocolVersion = "Mcp-Protocol-Version"
+	headerLastEventID     = "Last-Event-ID"
+	mimeEventStream       = "text/event-stream"
+
+	// maxSessionEvents 每个会话保留的最近事件数，用于断线重连后重放
+	maxSessionEvents = 256
+	// sessionIdleTimeout 会话超过该时间没有请求且没有打开的流时被清理
+	sessionIdleTimeout = 30 * time.Minute
+	// keepAliveInterval SSE 流没有事件时发送注释的间隔，避免空闲连接被代理断开
+	keepAliveInterval = 25 * time.Second
+)
+
+// newSessionID 生成会话ID
+func newSessionID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// sseEvent SSE 流中的一个事件，data 为空的事件只用于让客户端拿到事件ID
+type sseEvent struct {
+	id     int64
+	stream int64 // 所属的流，0 为 GET 请求打开的流
+	data   []byte
+}
+
+// httpSession Streamable HTTP 会话，保存最近的事件用于断线重连
+type httpSession struct {
+	*session
+
+	// ctx 在会话结束时取消，正在处理的请求随之取消
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu          sync.Mutex
+	events      []sseEvent
+	nextEventID int64
+	nextStream  int64
+	open        map[int64]bool // 响应还没有写入的 POST 流
+	getStream   int64          // 当前 GET 流的序号，打开新的 GET 流后旧的流结束
+	changed     chan struct{}  // 有新事件或流状态变化时关闭并替换
+	writers     int            // 正在写入的 SSE 流的个数
+	lastActive  time.Time
+}
+
+func newHTTPSession() *httpSession {
+	s := &httpSession{
+		open:       make(map[int64]bool),
+		changed:    make(chan struct{}),
+		lastActive: time.Now(),
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.session = &session{id: newSessionID(), transport: "http", send: s.sendNotification}
+	return s
+}
+
+// sendNotification 服务器发起的消息放入 GET 流
+func (s *httpSession) sendNotification(message []byte) {
+	s.append(0, message)
+}
+
+// append 向流中添加事件，只保留最近的 maxSessionEvents 个事件
+func (s *httpSession) append(stream int64, data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextEventID++
+	s.events = append(s.events, sseEvent{id: s.nextEventID, stream: stream, data: data})
+	if len(s.events) > maxSessionEvents {
+		s.events = slices.Clone(s.events[len(s.events)-maxSessionEvents:])
+	}
+	s.broadcast()
+}
+
+// broadcast 唤醒等待新事件的流，调用时需要持有 s.mu
+func (s *httpSession) broadcast() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// openStream 创建 POST 响应的流，先写入一个没有数据的事件，客户端拿到事件ID后断线也可以重连
+func (s *httpSession) openStream() int64 {
+	s.mu.Lock()
+	s.nextStream++
+	stream := s.nextStream
+	s.open[stream] = true
+	s.mu.Unlock()
+
+	s.append(stream, nil)
+	return stream
+}
+
+// closeStream 响应写入后结束 POST 流
+func (s *httpSession) closeStream(stream int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.open, stream)
+	s.broadcast()
+}
+
+// replaceGetStream 打开新的 GET 流并结束旧的流，返回新流的序号和当前的最后一个事件编号
+func (s *httpSession) replaceGetStream() (get, last int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.getStream++
+	s.broadcast()
+	return s.getStream, s.nextEventID
+}
+
+// pending 返回流中编号大于 after 的事件、流是否还会有新的事件，以及等待新事件的通道
+func (s *httpSession) pending(stream, after, get int64) ([]sseEvent, bool, <-chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var events []sseEvent
+	for _, event := range s.events {
+		if event.stream == stream && event.id > after {
+			events = append(events, event)
+		}
+	}
+	open := s.open[stream]
+	if stream == 0 {
+		open = s.getStream == get
+	}
+	return events, open, s.changed
+}
+
+// touch 记录会话的最近一次请求时间
+func (s *httpSession) touch() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastActive = time.Now()
+}
+
+// idle 会话是否超过 sessionIdleTimeout 没有活动
+func (s *httpSession) idle(now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.writers == 0 && now.Sub(s.lastActive) > sessionIdleTimeout
+}
+
+// addWriters 修改正在写入的 SSE 流的个数
+func (s *httpSession) addWriters(delta int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.writers += delta
+	s.lastActive = time.Now()
+}
+
+// formatEventID 生成 SSE 事件ID
+func formatEventID(stream, id int64) string {
+	return strconv.FormatInt(stream, 10) + "-" + strconv.FormatInt(id, 10)
+}
+
+// parseEventID 解析 Last-Event-ID
+func parseEventID(value string) (stream, id int64, ok bool) {
+	streamPart, idPart, found := strings.Cut(value, "-")
+	if !found {
+		return 0, 0, false
+	}
+	stream, err1 := strconv.ParseInt(streamPart, 10, 64)
+	id, err2 := strconv.ParseInt(idPart, 10, 64)
+	if err1 != nil || err2 != nil || stream < 0 || id < 0 {
+		return 0, 0, false
+	}
+	return stream, id, true
+}
+
+// mountStreamable 在 path 上注册 Streamable HTTP 端点
+func (m *MCPHandler) mountStreamable(router fiber.Router, path string) {
+	router.Post(path, m.handleStreamablePost)
+	router.Get(path, m.handleStreamableGet)
+	router.Delete(path, m.handleStreamableDelete)
+}
+
+// newHTTPSession 创建并登记 Streamable HTTP 会话，同时清理空闲的会话
+func (m *MCPHandler) newHTTPSession() *httpSession {
+	now := time.Now()
+	var expired []*httpSession
+	m.sessionsMu.Lock()
+	for _, s := range m.httpSessions {
+		if s.idle(now) {
+			expired = append(expired, s)
+		}
+	}
+	m.sessionsMu.Unlock()
+	for _, s := range expired {
+		m.closeHTTPSession(s)
+	}
+
+	s := newHTTPSession()
+	m.sessionsMu.Lock()
+	m.httpSessions[s.id] = s
+	m.sessionsMu.Unlock()
+	m.addSession(s.session)
+	return s
+}
+
+// closeHTTPSession 结束会话，取消正在处理的请求并关闭打开的流
+func (m *MCPHandler) closeHTTPSession(s *httpSession) {
+	s.cancel()
+	m.sessionsMu.Lock()
+	delete(m.httpSessions, s.id)
+	m.sessionsMu.Unlock()
+	m.removeSession(s.id)
+}
+
+// findSession 查找请求头中的会话，找不到时写入错误响应并返回 nil
+func (m *MCPHandler) findSession(c *fiber.Ctx) *httpSession {
+	id := c.Get(headerSessionID)
+	if id == "" {
+		writeHTTPError(c, fiber.StatusBadRequest, CodeInvalidRequest, "Bad Request: Mcp-Session-Id header is required")
+		return nil
+	}
+	m.sessionsMu.Lock()
+	s := m.httpSessions[id]
+	m.sessionsMu.Unlock()
+	if s == nil {
+		writeHTTPError(c, fiber.StatusNotFound, CodeInvalidRequest, "Session not found")
+		return nil
+	}
+	s.touch()
+	return s
+}
+
+// writeHTTPError 写入HTTP错误响应，响应体为没有ID的 JSON-RPC 错误
+func writeHTTPError(c *fiber.Ctx, status, code int, message string) error {
+	return c.Status(status).JSON(errorResponse(nil, code, message))
+}
+
+// inspectMessages 检查请求体中是否有需要响应的请求，以及是否有 initialize 请求
+func inspectMessages(body []byte) (hasRequests, initialize bool) {
+	body = bytes.TrimSpace(body)
+	messages := []json.RawMessage{body}
+	if len(body) > 0 && body[0] == '[' {
+		if err := json.Unmarshal(body, &messages); err != nil {
+			return false, false
+		}
+	}
+	for _, raw := range messages {
+		var msg struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		// 没有 method 的是客户端对服务器请求的响应，不需要处理
+		if json.Unmarshal(raw, &msg) != nil || msg.Method == "" {
+			continue
+		}
+		if len(msg.ID) > 0 {
+			hasRequests = true
+			initialize = initialize || msg.Method == "initialize"
+		}
+	}
+	return hasRequests, initialize
+}
+
+// handleStreamablePost 处理客户端发送的 JSON-RPC 消息
+func (m *MCPHandler) handleStreamablePost(c *fiber.Ctx) error {
+	if m.stopCtx.Err() != nil {
+		return writeHTTPError(c, fiber.StatusServiceUnavailable, CodeInternalError, "Server is shutting down")
+	}
+	accept := c.Get(fiber.HeaderAccept)
+	acceptSSE := strings.Contains(accept, mimeEventStream)
+	acceptJSON := accept == "" || strings.Contains(accept, fiber.MIMEApplicationJSON) || strings.Contains(accept, "*/*")
+	if !acceptSSE && !acceptJSON {
+		return writeHTTPError(c, fiber.StatusNotAcceptable, CodeInvalidRequest, "Not Acceptable: client must accept application/json or text/event-stream")
+	}
+
+	// 请求体在处理函数返回后会被复用，流式响应在单独的协程中处理，需要复制
+	body := bytes.Clone(c.Body())
+	hasRequests, initialize := inspectMessages(body)
+
+	var s *httpSession
+	if initialize {
+		s = m.newHTTPSession()
+	} else {
+		if s = m.findSession(c); s == nil {
+			return nil
+		}
+		if version := c.Get(headerProtocolVersion); version != "" && !slices.Contains(supportedProtocolVersions, version) {
+			return writeHTTPError(c, fiber.StatusBadRequest, CodeInvalidRequest, "Unsupported protocol version: "+version)
+		}
+	}
+	ctx := withSession(c.UserContext(), s.session)
+
+	// 只有通知时不需要返回内容，消息无效时仍然返回错误
+	if !hasRequests {
+		response, err := m.HandleRequestContext(ctx, body)
+		if err != nil {
+			return writeHTTPError(c, fiber.StatusInternalServerError, CodeInternalError, err.Error())
+		}
+		if response != nil {
+			c.Status(fiber.StatusBadRequest).Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+			return c.Send(response)
+		}
+		return c.SendStatus(fiber.StatusAccepted)
+	}
+
+	// initialize 和不接受 SSE 的请求直接返回 JSON
+	if initialize || !acceptSSE {
+		response, err := m.HandleRequestContext(ctx, body)
+		if err != nil {
+			return writeHTTPError(c, fiber.StatusInternalServerError, CodeInternalError, err.Error())
+		}
+		if initialize {
+			// 初始化失败时不保留会话
+			if s.ProtocolVersion() == "" {
+				m.closeHTTPSession(s)
+			} else {
+				c.Set(headerSessionID, s.id)
+			}
+		}
+		c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		return c.Send(response)
+	}
+
+	// 请求在单独的协程中处理，客户端断开后响应仍然保存在会话中，可以重连后获取
+	if !m.track() {
+		return writeHTTPError(c, fiber.StatusServiceUnavailable, CodeInternalError, "Server is shutting down")
+	}
+	stream := s.openStream()
+	go func() {
+		defer m.wg.Done()
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		defer context.AfterFunc(s.ctx, cancel)()
+
+		response, err := m.HandleRequestContext(ctx, body)
+		if err != nil {
+			logging.FromContext(ctx).Error("处理请求失败", logging.KeyError, err)
+			response, _ = json.Marshal(errorResponse(nil, CodeInternalError, err.Error()))
+		}
+		if response != nil {
+			s.append(stream, response)
+		}
+		s.closeStream(stream)
+	}()
+
+	m.streamEvents(c, s, stream, 0, 0)
+	return nil
+}
+
+// handleStreamableGet 打开接收服务器通知的 SSE 流，带有 Last-Event-ID 时从该事件之后继续
+func (m *MCPHandler) handleStreamableGet(c *fiber.Ctx) error {
+	if m.stopCtx.Err() != nil {
+		return writeHTTPError(c, fiber.StatusServiceUnavailable, CodeInternalError, "Server is shutting down")
+	}
+	if !strings.Contains(c.Get(fiber.HeaderAccept), mimeEventStream) {
+		return writeHTTPError(c, fiber.StatusNotAcceptable, CodeInvalidRequest, "Not Acceptable: client must accept text/event-stream")
+	}
+	s := m.findSession(c)
+	if s == nil {
+		return nil
+	}
+
+	var stream, after int64
+	if lastEventID := c.Get(headerLastEventID); lastEventID != "" {
+		var ok bool
+		if stream, after, ok = parseEventID(lastEventID); !ok {
+			return writeHTTPError(c, fiber.StatusBadRequest, CodeInvalidRequest, "Invalid Last-Event-ID: "+lastEventID)
+		}
+	}
+
+	// 恢复 POST 流时只重放该流的响应，否则打开新的 GET 流
+	var get int64
+	if stream == 0 {
+		var last int64
+		get, last = s.replaceGetStream()
+		if c.Get(headerLastEventID) == "" {
+			after = last
+		}
+	}
+	m.streamEvents(c, s, stream, after, get)
+	return nil
+}
+
+// handleStreamableDelete 结束会话
+func (m *MCPHandler) handleStreamableDelete(c *fiber.Ctx) error {
+	s := m.findSession(c)
+	if s == nil {
+		return nil
+	}
+	m.closeHTTPSession(s)
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// streamEvents 以 SSE 流返回流中编号大于 after 的事件
+func (m *MCPHandler) streamEvents(c *fiber.Ctx, s *httpSession, stream, after, get int64) {
+	c.Set(fiber.HeaderContentType, mimeEventStream)
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(headerSessionID, s.id)
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		m.writeEvents(w, s, stream, after, get)
+	})
+}
+
+// writeEvents 写入流中编号大于 after 的事件，直到流结束、连接断开或会话结束
+//
+// GET 流在服务器关闭时结束；POST 流会等到响应写入，关闭服务器时会等待正在处理的请求完成
+func (m *MCPHandler) writeEvents(w *bufio.Writer, s *httpSession, stream, after, get int64) {
+	s.addWriters(1)
+	defer s.addWriters(-1)
+
+	var stop <-chan struct{}
+	if stream == 0 {
+		stop = m.stopCtx.Done()
+	}
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+
+	// 响应头和第一块数据一起发送，先写入一个注释，客户端可以马上开始读取
+	w.WriteString(": stream opened\n\n")
+	if err := w.Flush(); err != nil {
+		return
+	}
+	for {
+		events, open, changed := s.pending(stream, after, get)
+		for _, event := range events {
+			fmt.Fprintf(w, "id: %s\ndata: %s\n\n", formatEventID(event.stream, event.id), event.data)
+			after = event.id
+		}
+		if len(events) > 0 {
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+		if !open {
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-s.ctx.Done():
+			return
+		case <-stop:
+			return
+		case <-keepAlive.C:
+			w.WriteString(": keep-alive\n\n")
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
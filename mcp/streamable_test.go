@@ -0,0 +1,341 @@
+package mcp
+
+import (
+	"api/app"
+	"api/config"
+	"api/service"
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startHTTPServer 启动独立的MCP HTTP服务器，返回端点地址
+func startHTTPServer(t *testing.T, handler *MCPHandler) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go handler.serveHTTP(ln)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		assert.NoError(t, handler.Shutdown(ctx))
+	})
+
+	// 等待服务器开始接受连接
+	url := "http://" + ln.Addr().String() + "/mcp"
+	require.Eventually(t, func() bool {
+		handler.mu.Lock()
+		defer handler.mu.Unlock()
+		return handler.httpServer != nil
+	}, 5*time.Second, 10*time.Millisecond)
+	return url
+}
+
+// httpClient 测试使用的HTTP客户端，不复用连接，
+// fasthttp 在关闭时不会立即断开刚建立的空闲连接，复用连接会让 Shutdown 等待
+var httpClient = &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+
+// testClient 在测试中模拟 Streamable HTTP 客户端
+type testClient struct {
+	t         *testing.T
+	url       string
+	sessionID string
+}
+
+// do 发送请求，会话建立后带上会话ID
+func (c *testClient) do(method, body string, header map[string]string) *http.Response {
+	c.t.Helper()
+	req, err := http.NewRequest(method, c.url, strings.NewReader(body))
+	require.NoError(c.t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	if c.sessionID != "" {
+		req.Header.Set(headerSessionID, c.sessionID)
+	}
+	for key, value := range header {
+		req.Header.Set(key, value)
+	}
+	resp, err := httpClient.Do(req)
+	require.NoError(c.t, err)
+	return resp
+}
+
+// initialize 完成初始化握手并记录会话ID
+func (c *testClient) initialize() {
+	c.t.Helper()
+	resp := c.do(http.MethodPost, `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18","capabilities":{},"clientInfo":{"name":"test","version":"1.0"}}}`, nil)
+	defer resp.Body.Close()
+	require.Equal(c.t, http.StatusOK, resp.StatusCode)
+	assert.Equal(c.t, "application/json", resp.Header.Get("Content-Type"))
+
+	var response Response
+	require.NoError(c.t, json.NewDecoder(resp.Body).Decode(&response))
+	assert.Nil(c.t, response.Error)
+	c.sessionID = resp.Header.Get(headerSessionID)
+	require.NotEmpty(c.t, c.sessionID)
+
+	resp = c.do(http.MethodPost, `{"jsonrpc":"2.0","method":"notifications/initialized"}`, nil)
+	resp.Body.Close()
+	assert.Equal(c.t, http.StatusAccepted, resp.StatusCode)
+}
+
+// sseReader 读取 SSE 流中的事件
+type sseReader struct {
+	r *bufio.Reader
+}
+
+// next 读取下一个事件，跳过注释，超时或流结束时返回错误
+func (r *sseReader) next(t *testing.T) (id, data string, err error) {
+	t.Helper()
+	for {
+		line, err := r.r.ReadString('\n')
+		if err != nil {
+			return "", "", err
+		}
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case line == "":
+			if id != "" || data != "" {
+				return id, data, nil
+			}
+		case strings.HasPrefix(line, ":"):
+		case strings.HasPrefix(line, "id: "):
+			id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+// readEvents 在协程中读取 SSE 流，便于带超时地等待事件
+func readEvents(t *testing.T, resp *http.Response) <-chan [2]string {
+	events := make(chan [2]string, 16)
+	go func() {
+		defer close(events)
+		reader := &sseReader{r: bufio.NewReader(resp.Body)}
+		for {
+			id, data, err := reader.next(t)
+			if err != nil {
+				return
+			}
+			events <- [2]string{id, data}
+		}
+	}()
+	return events
+}
+
+// nextEvent 等待下一个事件
+func nextEvent(t *testing.T, events <-chan [2]string) (id, data string) {
+	t.Helper()
+	select {
+	case event, ok := <-events:
+		require.True(t, ok, "SSE 流已结束")
+		return event[0], event[1]
+	case <-time.After(5 * time.Second):
+		t.Fatal("等待 SSE 事件超时")
+	}
+	return "", ""
+}
+
+// assertClosed 断言 SSE 流已经结束
+func assertClosed(t *testing.T, events <-chan [2]string) {
+	t.Helper()
+	select {
+	case event, ok := <-events:
+		assert.False(t, ok, "多余的事件: %v", event)
+	case <-time.After(5 * time.Second):
+		t.Fatal("等待 SSE 流结束超时")
+	}
+}
+
+func TestStreamableHTTP(t *testing.T) {
+	handler := NewMCPHandler(&service.HotSearchService{}, &config.Config{})
+	client := &testClient{t: t, url: startHTTPServer(t, handler)}
+	client.initialize()
+	handler.sessionsMu.Lock()
+	assert.Equal(t, ProtocolVersion, handler.sessions[client.sessionID].ProtocolVersion())
+	handler.sessionsMu.Unlock()
+
+	// 接受 SSE 时以流返回响应，先是只有事件ID的事件，然后是响应
+	resp := client.do(http.MethodPost, `{"jsonrpc":"2.0","id":2,"method":"tools/list"}`, nil)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, mimeEventStream, resp.Header.Get("Content-Type"))
+	events := readEvents(t, resp)
+	id, data := nextEvent(t, events)
+	assert.Equal(t, "1-1", id)
+	assert.Empty(t, data)
+	id, data = nextEvent(t, events)
+	assert.Equal(t, "1-2", id)
+	var response Response
+	require.NoError(t, json.Unmarshal([]byte(data), &response))
+	assert.Equal(t, NumberID(2), response.ID)
+	assert.Contains(t, data, "get_hot_search")
+	assertClosed(t, events)
+
+	// 只接受 JSON 时直接返回 JSON
+	resp = client.do(http.MethodPost, `{"jsonrpc":"2.0","id":3,"method":"ping"}`, map[string]string{"Accept": "application/json"})
+	defer resp.Body.Close()
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+	assert.Equal(t, NumberID(3), response.ID)
+
+	// 会话和协议版本检查
+	tests := []struct {
+		name   string
+		header map[string]string
+		status int
+	}{
+		{"MissingSession", map[string]string{headerSessionID: ""}, http.StatusBadRequest},
+		{"UnknownSession", map[string]string{headerSessionID: "unknown"}, http.StatusNotFound},
+		{"UnsupportedVersion", map[string]string{headerProtocolVersion: "1999-01-01"}, http.StatusBadRequest},
+		{"NotAcceptable", map[string]string{"Accept": "text/html"}, http.StatusNotAcceptable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := client.do(http.MethodPost, `{"jsonrpc":"2.0","id":4,"method":"ping"}`, tt.header)
+			defer resp.Body.Close()
+			assert.Equal(t, tt.status, resp.StatusCode)
+		})
+	}
+
+	// 初始化失败时不创建会话
+	resp = (&testClient{t: t, url: client.url}).do(http.MethodPost, `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`, nil)
+	defer resp.Body.Close()
+	assert.Empty(t, resp.Header.Get(headerSessionID))
+	handler.sessionsMu.Lock()
+	assert.Len(t, handler.httpSessions, 1)
+	handler.sessionsMu.Unlock()
+
+	// 结束会话后不能再使用
+	resp = client.do(http.MethodDelete, "", nil)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp = client.do(http.MethodPost, `{"jsonrpc":"2.0","id":5,"method":"ping"}`, nil)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestStreamableNotifications(t *testing.T) {
+	handler := NewMCPHandler(&service.HotSearchService{}, &config.Config{})
+	client := &testClient{t: t, url: startHTTPServer(t, handler)}
+	client.initialize()
+
+	refreshed := func(source string) {
+		handler.handleFetchEvent(service.FetchEvent{
+			Source: source,
+			Result: app.NewResult(source, []app.Item{{Index: 1, Title: "标题", URL: "https://example.com"}}),
+		})
+	}
+
+	// GET 流接收服务器发起的通知
+	resp := client.do(http.MethodGet, "", map[string]string{"Accept": mimeEventStream})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	events := readEvents(t, resp)
+	refreshed("baidu")
+	lastEventID, data := nextEvent(t, events)
+	var notification Request
+	require.NoError(t, json.Unmarshal([]byte(data), &notification))
+	assert.True(t, notification.IsNotification())
+	assert.Equal(t, "notifications/hot_search/updated", notification.Method)
+	var params HotSearchUpdatedParams
+	require.NoError(t, json.Unmarshal(notification.Params, &params))
+	assert.Equal(t, "baidu", params.Source)
+	assert.Equal(t, 1, params.Total)
+
+	// 断线期间的通知在重连后重放
+	resp.Body.Close()
+	refreshed("zhihu")
+	refreshed("weibo")
+	resp = client.do(http.MethodGet, "", map[string]string{"Accept": mimeEventStream, headerLastEventID: lastEventID})
+	defer resp.Body.Close()
+	events = readEvents(t, resp)
+	for _, source := range []string{"zhihu", "weibo"} {
+		_, data = nextEvent(t, events)
+		assert.Contains(t, data, `"source":"`+source+`"`)
+	}
+
+	// 无效的 Last-Event-ID
+	invalid := client.do(http.MethodGet, "", map[string]string{"Accept": mimeEventStream, headerLastEventID: "abc"})
+	invalid.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, invalid.StatusCode)
+
+	// 关闭服务器时 GET 流结束
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.NoError(t, handler.Shutdown(ctx))
+	assertClosed(t, events)
+}
+
+func TestStreamableResume(t *testing.T) {
+	handler := NewMCPHandler(&service.HotSearchService{}, &config.Config{})
+	release := make(chan struct{})
+	handler.tools["test_slow"] = Tool{Name: "test_slow", call: func(ctx context.Context, args json.RawMessage) (interface{}, error) {
+		<-release
+		return "done", nil
+	}}
+	client := &testClient{t: t, url: startHTTPServer(t, handler)}
+	client.initialize()
+
+	// 拿到第一个事件ID后断开
+	resp := client.do(http.MethodPost, `{"jsonrpc":"2.0","id":"slow","method":"tools/call","params":{"name":"test_slow"}}`, nil)
+	lastEventID, _, err := (&sseReader{r: bufio.NewReader(resp.Body)}).next(t)
+	require.NoError(t, err)
+	resp.Body.Close()
+	close(release)
+
+	// 重连后收到响应，之后流结束
+	resp = client.do(http.MethodGet, "", map[string]string{"Accept": mimeEventStream, headerLastEventID: lastEventID})
+	defer resp.Body.Close()
+	events := readEvents(t, resp)
+	_, data := nextEvent(t, events)
+	var response Response
+	require.NoError(t, json.Unmarshal([]byte(data), &response))
+	assert.Equal(t, StringID("slow"), response.ID)
+	assert.Contains(t, data, `\"done\"`)
+	assertClosed(t, events)
+}
+
+func TestParseEventID(t *testing.T) {
+	stream, id, ok := parseEventID(formatEventID(3, 42))
+	assert.True(t, ok)
+	assert.Equal(t, int64(3), stream)
+	assert.Equal(t, int64(42), id)
+
+	for _, value := range []string{"", "1", "a-1", "1-b", "-1-2"} {
+		_, _, ok := parseEventID(value)
+		assert.False(t, ok, value)
+	}
+}
+
+func TestSetupMCPRoutesStreamable(t *testing.T) {
+	app := fiber.New()
+	handler := SetupMCPRoutes(app, &service.HotSearchService{}, &config.Config{})
+	defer handler.Shutdown(context.Background())
+
+	// 主服务的 /mcp 端点同样支持 Streamable HTTP
+	req := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26"}}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	resp, err := app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	sessionID := resp.Header.Get(headerSessionID)
+	assert.NotEmpty(t, sessionID)
+
+	req = httptest.NewRequest(http.MethodDelete, "/mcp", nil)
+	req.Header.Set(headerSessionID, sessionID)
+	resp, err = app.Test(req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+}
@@ -0,0 +1,292 @@
+package mcp
+
+import (
+	"api/all"
+	"api/app"
+	"api/logging"
+	"api/metrics"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Tool 定义MCP工具
+type Tool struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	InputSchema Schema `json:"inputSchema"`
+
+	// call 执行工具，参数无效时返回 *Error，执行失败时返回其他错误
+	call func(ctx context.Context, args json.RawMessage) (interface{}, error)
+}
+
+// CallToolParams tools/call 请求的参数
+type CallToolParams struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+}
+
+// Content 工具返回的内容块
+type Content struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// CallToolResult tools/call 请求的结果，工具执行失败时 IsError 为 true，错误信息放在 Content 中
+type CallToolResult struct {
+	Content []Content `json:"content"`
+	IsError bool      `json:"isError"`
+}
+
+// textContent 创建文本内容块
+func textContent(text string) Content {
+	return Content{Type: "text", Text: text}
+}
+
+// limitProperty 返回数量限制参数的模式，defaultLimit 为 0 时表示默认不限制
+func limitProperty(description string, maximum, defaultLimit int) Property {
+	property := Property{Type: "integer", Description: description, Minimum: intValue(1), Maximum: intValue(maximum)}
+	if defaultLimit > 0 {
+		property.Default = defaultLimit
+	}
+	return property
+}
+
+// registerTools 注册可用的工具
+func (m *MCPHandler) registerTools() {
+	platforms := platformNames()
+
+	// 注册获取热搜数据的工具
+	m.tools["get_hot_search"] = Tool{
+		Name:        "get_hot_search",
+		Description: "获取各大平台的热搜数据，支持的平台包括" + strings.Join(app.GetAllRouteNames(), ", "),
+		InputSchema: Schema{
+			Type: "object",
+			Properties: map[string]Property{
+				"platform": {
+					Type:        "string",
+					Description: "平台名称，如baidu, bilibili, zhihu, weibo等",
+					Enum:        platforms,
+				},
+				"limit": limitProperty("最多返回的条目数，默认返回全部", maxListLimit, 0),
+			},
+			Required: []string{"platform"},
+		},
+		call: m.executeGetHotSearch,
+	}
+
+	// 注册获取所有平台热搜的工具
+	m.tools["get_all_hot_search"] = Tool{
+		Name:        "get_all_hot_search",
+		Description: "获取所有平台的热搜数据聚合，数据量较大，建议设置 limit 或使用 get_top_hot_search",
+		InputSchema: Schema{
+			Type: "object",
+			Properties: map[string]Property{
+				"limit": limitProperty("每个平台最多返回的条目数，默认返回全部", maxListLimit, 0),
+			},
+		},
+		call: m.executeGetAllHotSearch,
+	}
+
+	// 注册获取历史热搜数据的工具
+	m.tools["get_history_data"] = Tool{
+		Name:        "get_history_data",
+		Description: "获取指定平台的历史热搜数据",
+		InputSchema: Schema{
+			Type: "object",
+			Properties: map[string]Property{
+				"platform": {
+					Type:        "string",
+					Description: "平台名称",
+					Enum:        platforms,
+				},
+				"date": {
+					Type:        "string",
+					Description: "日期，格式为YYYY-MM-DD",
+				},
+				"hour": {
+					Type:        "string",
+					Description: "小时，格式为HH",
+				},
+			},
+			Required: []string{"platform", "date"},
+		},
+		call: m.executeGetHistoryData,
+	}
+
+	m.registerQueryTools(platforms)
+}
+
+// callTool 查找并执行工具，记录调用次数和耗时
+func (m *MCPHandler) callTool(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p CallToolParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.Name == "" {
+		return nil, invalidParams("Missing tool name")
+	}
+	tool, exists := m.tools[p.Name]
+	if !exists {
+		return nil, invalidParams("Unknown tool: %s", p.Name)
+	}
+
+	if err := tool.InputSchema.validate(p.Arguments); err != nil {
+		return nil, err
+	}
+
+	ctx = logging.With(ctx, "tool", p.Name)
+	start := time.Now()
+	data, err := tool.call(ctx, p.Arguments)
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	metrics.MCPToolCalls.Inc(p.Name, result)
+	logging.FromContext(ctx).Info("工具调用完成", "result", result, logging.KeyDuration, time.Since(start))
+	return data, err
+}
+
+// handleCallTool 处理 tools/call 请求
+//
+// 工具名称和参数无效时返回 JSON-RPC 错误；工具执行失败时返回 isError 为 true 的结果，
+// 便于模型看到错误信息后调整调用。成功时以JSON文本返回工具的数据
+func (m *MCPHandler) handleCallTool(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	data, err := m.callTool(ctx, params)
+	if err != nil {
+		if _, ok := err.(*Error); ok {
+			return nil, err
+		}
+		return CallToolResult{Content: []Content{textContent(err.Error())}, IsError: true}, nil
+	}
+
+	text, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	return CallToolResult{Content: []Content{textContent(string(text))}}, nil
+}
+
+// handleToolExecute 处理旧版本的 tool/execute 请求，成功时直接返回工具的数据，失败时返回 JSON-RPC 错误
+func (m *MCPHandler) handleToolExecute(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p CallToolParams
+	if err := decodeParams(params, &p); err == nil && p.Name != "" {
+		if _, exists := m.tools[p.Name]; !exists {
+			return nil, &Error{Code: CodeMethodNotFound, Message: "Tool not found: " + p.Name}
+		}
+	}
+
+	data, err := m.callTool(ctx, params)
+	if err != nil {
+		if _, ok := err.(*Error); ok {
+			return nil, err
+		}
+		return nil, &Error{Code: CodeInternalError, Message: err.Error()}
+	}
+	return data, nil
+}
+
+// HotSearchArgs get_hot_search 工具的参数
+type HotSearchArgs struct {
+	Platform string `json:"platform"`
+	Limit    int    `json:"limit,omitempty"`
+}
+
+// AllHotSearchArgs get_all_hot_search 工具的参数
+type AllHotSearchArgs struct {
+	Limit int `json:"limit,omitempty"`
+}
+
+// HistoryDataArgs get_history_data 工具的参数
+type HistoryDataArgs struct {
+	Platform string `json:"platform"`
+	Date     string `json:"date"`
+	Hour     string `json:"hour,omitempty"`
+}
+
+// executeGetHotSearch 执行获取热搜数据的工具
+func (m *MCPHandler) executeGetHotSearch(ctx context.Context, arguments json.RawMessage) (interface{}, error) {
+	var args HotSearchArgs
+	if err := decodeParams(arguments, &args); err != nil {
+		return nil, err
+	}
+	if args.Platform == "" {
+		return nil, invalidParams("Missing platform argument")
+	}
+
+	// 在数据源注册表中查找对应的抓取函数（支持别名）
+	source, exists := app.LookupSource(args.Platform)
+	if !exists {
+		return nil, invalidParams("Unsupported platform: %s", args.Platform)
+	}
+
+	// 与 HTTP 接口共用缓存，上游失败时按配置返回已保存的快照
+	result, _, err := m.service.GetLiveCached(ctx, source.RouteName)
+	if err != nil {
+		return nil, fmt.Errorf("Error calling API: %w", err)
+	}
+	response := result.Response()
+	response.Obj = truncateItems(response.Obj, args.Limit)
+	return response, nil
+}
+
+// executeGetAllHotSearch 执行获取所有平台热搜的工具
+func (m *MCPHandler) executeGetAllHotSearch(ctx context.Context, arguments json.RawMessage) (interface{}, error) {
+	var args AllHotSearchArgs
+	if err := decodeParams(arguments, &args); err != nil {
+		return nil, err
+	}
+
+	results, _, err := m.service.GetAllLiveCached(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Error calling API: %w", err)
+	}
+	response := all.NewResponse(results)
+	for source, items := range response.Obj {
+		response.Obj[source] = truncateItems(items, args.Limit)
+	}
+	return response, nil
+}
+
+// truncateItems 最多保留前 limit 条，limit 不大于 0 时不限制
+func truncateItems(items []app.Item, limit int) []app.Item {
+	if limit > 0 && len(items) > limit {
+		return items[:limit]
+	}
+	return items
+}
+
+// executeGetHistoryData 执行获取历史数据的工具
+func (m *MCPHandler) executeGetHistoryData(ctx context.Context, arguments json.RawMessage) (interface{}, error) {
+	var args HistoryDataArgs
+	if err := decodeParams(arguments, &args); err != nil {
+		return nil, err
+	}
+	if args.Platform == "" || args.Date == "" {
+		return nil, invalidParams("Missing required arguments: platform and date")
+	}
+	source, exists := app.LookupSource(args.Platform)
+	if !exists {
+		return nil, invalidParams("Unsupported platform: %s", args.Platform)
+	}
+
+	var result map[string]interface{}
+	var err error
+	if args.Hour != "" {
+		// 获取指定日期和小时的历史数据
+		result, err = m.service.GetHistoricalDataForWS(source.RouteName, args.Date, args.Hour)
+	} else {
+		// 获取指定日期的所有小时数据
+		result, err = m.service.GetHistoricalDataByDateForWS(source.RouteName, args.Date)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error getting historical data: %w", err)
+	}
+	// 服务层以 code 字段表示失败，转换为工具错误
+	if code, _ := result["code"].(int); code != 200 {
+		return nil, fmt.Errorf("Error getting historical data: %v", result["message"])
+	}
+	return result, nil
+}
@@ -0,0 +1,79 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// 服务导出的指标，各个包在相应的位置记录
+var (
+	// FetchTotal 数据源抓取次数，result 为 success、degraded、error、rejected（熔断中被拒绝）、disabled（数据源已停用）或 canceled（调用方取消）
+	FetchTotal = NewCounterVec("azhot_fetch_total", "数据源抓取次数", "source", "result")
+	// FetchDuration 数据源抓取耗时
+	FetchDuration = NewHistogramVec("azhot_fetch_duration_seconds", "数据源抓取耗时（秒）", nil, "source")
+	// FetchItems 数据源最近一次抓取到的条目数
+	FetchItems = NewGaugeVec("azhot_fetch_items", "数据源最近一次抓取到的条目数", "source")
+
+	// SchedulerRuns 定时抓取次数，result 为 success 或 error
+	SchedulerRuns = NewCounterVec("azhot_scheduler_runs_total", "定时抓取次数", "source", "result")
+	// SchedulerRunDuration 定时抓取耗时，包括抓取和保存
+	SchedulerRunDuration = NewHistogramVec("azhot_scheduler_run_duration_seconds", "定时抓取耗时（秒），包括抓取和保存", nil, "source")
+
+	// DBWriteDuration 数据库写入耗时，operation 为 save、save_all 或 prune
+	DBWriteDuration = NewHistogramVec("azhot_db_write_duration_seconds", "数据库写入耗时（秒）", nil, "operation")
+
+	// WebSocketConnections 当前的 WebSocket 连接数
+	WebSocketConnections = NewGaugeVec("azhot_websocket_connections", "当前的 WebSocket 连接数")
+	// WebSocketSubscriptions 各数据源当前的 WebSocket 订阅数
+	WebSocketSubscriptions = NewGaugeVec("azhot_websocket_subscriptions", "各数据源当前的 WebSocket 订阅数", "source")
+
+	// MCPToolCalls MCP 工具调用次数，result 为 success 或 error
+	MCPToolCalls = NewCounterVec("azhot_mcp_tool_calls_total", "MCP 工具调用次数", "tool", "result")
+	// MCPSessions 当前的 MCP 会话数，transport 为 stdio 或 http
+	MCPSessions = NewGaugeVec("azhot_mcp_sessions", "当前的 MCP 会话数", "transport")
+
+	// HTTPRequests HTTP 请求数，route 为匹配到的路由，未匹配的请求为 unmatched
+	HTTPRequests = NewCounterVec("azhot_http_requests_total", "HTTP 请求数", "method", "route", "status")
+	// HTTPRequestDuration HTTP 请求耗时
+	HTTPRequestDuration = NewHistogramVec("azhot_http_request_duration_seconds", "HTTP 请求耗时（秒）", nil, "method", "route")
+)
+
+// Since 计算从 start 开始经过的秒数
+func Since(start time.Time) float64 {
+	return time.Since(start).Seconds()
+}
+
+// Handler 以文本格式输出默认注册表中的指标
+func Handler(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderContentType, ContentType)
+	_, err := Default.WriteTo(c)
+	return err
+}
+
+// Middleware 记录 HTTP 请求数和耗时的中间件，按路由模板（如 /history/:source）统计，避免标签过多
+func Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		err := c.Next()
+
+		// 错误由后面的错误处理函数写入响应，这里按错误推算状态码
+		status := c.Response().StatusCode()
+		if err != nil {
+			status = fiber.StatusInternalServerError
+			if e, ok := err.(*fiber.Error); ok {
+				status = e.Code
+			}
+		}
+		// 没有匹配到路由时 c.Route() 是最后一个执行的全局中间件，其路径为 /
+		route := c.Route().Path
+		if route == "/" && c.Path() != "/" {
+			route = "unmatched"
+		}
+		method := c.Method()
+		HTTPRequests.Inc(method, route, strconv.Itoa(status))
+		HTTPRequestDuration.Observe(Since(start), method, route)
+		return err
+	}
+}
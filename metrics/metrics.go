@@ -0,0 +1,251 @@
+// Package metrics 以 Prometheus 文本格式导出服务的运行指标
+//
+// 只实现了服务需要的计数器、仪表盘和直方图，输出格式参考
+// https://prometheus.io/docs/instrumenting/exposition_formats/
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType 文本格式的 Content-Type
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultBuckets 耗时直方图默认的桶上限，单位为秒
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// Registry 指标注册表
+type Registry struct {
+	mu      sync.RWMutex
+	metrics map[string]*metric
+}
+
+// NewRegistry 创建空的注册表
+func NewRegistry() *Registry {
+	return &Registry{metrics: make(map[string]*metric)}
+}
+
+// Default 服务使用的默认注册表
+var Default = NewRegistry()
+
+// register 注册指标，重复注册同名指标时 panic
+func (r *Registry) register(m *metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.metrics[m.name]; ok {
+		panic("metrics: 重复注册指标 " + m.name)
+	}
+	r.metrics[m.name] = m
+}
+
+// WriteTo 按名称顺序输出所有指标
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.RLock()
+	metrics := make([]*metric, 0, len(r.metrics))
+	for _, m := range r.metrics {
+		metrics = append(metrics, m)
+	}
+	r.mu.RUnlock()
+	sort.Slice(metrics, func(i, j int) bool {
+		return metrics[i].name < metrics[j].name
+	})
+
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	for _, m := range metrics {
+		m.write(bw)
+	}
+	if err := bw.Flush(); err != nil {
+		return cw.n, err
+	}
+	return cw.n, nil
+}
+
+// metric 一个指标及其按标签值区分的所有序列
+type metric struct {
+	name    string
+	help    string
+	kind    string // counter、gauge 或 histogram
+	labels  []string
+	buckets []float64 // 仅直方图使用
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+// series 一组标签值对应的数据
+type series struct {
+	values []string
+	value  float64  // 计数器和仪表盘的值
+	counts []uint64 // 直方图各个桶的计数，不累加
+	count  uint64
+	sum    float64
+}
+
+// newMetric 创建指标并注册到注册表
+func newMetric(r *Registry, kind, name, help string, labels []string, buckets []float64) *metric {
+	m := &metric{
+		name:    name,
+		help:    help,
+		kind:    kind,
+		labels:  labels,
+		buckets: buckets,
+		series:  make(map[string]*series),
+	}
+	// 没有标签的指标总是输出，即使还没有记录过数据
+	if len(labels) == 0 {
+		m.series[""] = m.newSeries(nil)
+	}
+	r.register(m)
+	return m
+}
+
+// newSeries 创建序列
+func (m *metric) newSeries(values []string) *series {
+	s := &series{values: values}
+	if m.kind == "histogram" {
+		s.counts = make([]uint64, len(m.buckets))
+	}
+	return s
+}
+
+// with 获取标签值对应的序列，调用方需要持有 m.mu
+func (m *metric) with(values []string) *series {
+	if len(values) != len(m.labels) {
+		panic(fmt.Sprintf("metrics: 指标 %s 需要 %d 个标签值，实际为 %d 个", m.name, len(m.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+	s, ok := m.series[key]
+	if !ok {
+		s = m.newSeries(append([]string(nil), values...))
+		m.series[key] = s
+	}
+	return s
+}
+
+// add 增加计数器或仪表盘的值
+func (m *metric) add(delta float64, values []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.with(values).value += delta
+}
+
+// set 设置仪表盘的值
+func (m *metric) set(value float64, values []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.with(values).value = value
+}
+
+// observe 记录一次直方图观测值
+func (m *metric) observe(value float64, values []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := m.with(values)
+	if i := sort.SearchFloat64s(m.buckets, value); i < len(m.buckets) {
+		s.counts[i]++
+	}
+	s.count++
+	s.sum += value
+}
+
+// write 输出指标，序列按标签值排序
+func (m *metric) write(w *bufio.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := make([]string, 0, len(m.series))
+	for key := range m.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	fmt.Fprintf(w, "# HELP %s %s\n", m.name, escapeHelp(m.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", m.name, m.kind)
+	for _, key := range keys {
+		s := m.series[key]
+		if m.kind != "histogram" {
+			writeSample(w, m.name, m.labels, s.values, "", "", s.value)
+			continue
+		}
+		var cumulative uint64
+		for i, upper := range m.buckets {
+			cumulative += s.counts[i]
+			writeSample(w, m.name+"_bucket", m.labels, s.values, "le", formatFloat(upper), float64(cumulative))
+		}
+		writeSample(w, m.name+"_bucket", m.labels, s.values, "le", "+Inf", float64(s.count))
+		writeSample(w, m.name+"_sum", m.labels, s.values, "", "", s.sum)
+		writeSample(w, m.name+"_count", m.labels, s.values, "", "", float64(s.count))
+	}
+}
+
+// writeSample 输出一行样本，extraName 不为空时追加一个标签（直方图的 le）
+func writeSample(w *bufio.Writer, name string, labels, values []string, extraName, extraValue string, value float64) {
+	w.WriteString(name)
+	if len(labels) > 0 || extraName != "" {
+		w.WriteByte('{')
+		for i, label := range labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, "%s=\"%s\"", label, escapeLabel(values[i]))
+		}
+		if extraName != "" {
+			if len(labels) > 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, "%s=\"%s\"", extraName, extraValue)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(value))
+	w.WriteByte('\n')
+}
+
+// formatFloat 按文本格式的要求输出数值
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+// escapeHelp 转义说明文字中的反斜杠和换行
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+// escapeLabel 转义标签值中的反斜杠、换行和双引号
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+// countingWriter 记录已写入的字节数
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
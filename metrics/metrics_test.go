@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 测试以文本格式输出计数器、仪表盘和直方图
+func TestRegistryWriteTo(t *testing.T) {
+	r := NewRegistry()
+	counter := r.NewCounterVec("test_total", "测试计数", "source", "result")
+	gauge := r.NewGaugeVec("test_connections", "测试连接数")
+	histogram := r.NewHistogramVec("test_duration_seconds", "测试耗时", []float64{0.1, 1}, "source")
+
+	counter.Inc("zhihu", "success")
+	counter.Add(2, "baidu", "error")
+	counter.Inc("zhihu", "success")
+	gauge.Inc()
+	gauge.Inc()
+	gauge.Dec()
+	histogram.Observe(0.05, "zhihu")
+	histogram.Observe(0.5, "zhihu")
+	histogram.Observe(3, "zhihu")
+
+	var out strings.Builder
+	_, err := r.WriteTo(&out)
+	assert.NoError(t, err)
+	assert.Equal(t, `# HELP test_connections 测试连接数
+# TYPE test_connections gauge
+test_connections 1
+# HELP test_duration_seconds 测试耗时
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{source="zhihu",le="0.1"} 1
+test_duration_seconds_bucket{source="zhihu",le="1"} 2
+test_duration_seconds_bucket{source="zhihu",le="+Inf"} 3
+test_duration_seconds_sum{source="zhihu"} 3.55
+test_duration_seconds_count{source="zhihu"} 3
+# HELP test_total 测试计数
+# TYPE test_total counter
+test_total{source="baidu",result="error"} 2
+test_total{source="zhihu",result="success"} 2
+`, out.String())
+}
+
+// 测试标签值和说明文字的转义
+func TestEscape(t *testing.T) {
+	r := NewRegistry()
+	counter := r.NewCounterVec("test_escape_total", "第一行\n第二行", "route")
+	counter.Inc(`/a"b\c` + "\n")
+
+	var out strings.Builder
+	_, err := r.WriteTo(&out)
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), `# HELP test_escape_total 第一行\n第二行`)
+	assert.Contains(t, out.String(), `test_escape_total{route="/a\"b\\c\n"} 1`)
+}
+
+// 测试错误的用法
+func TestInvalidUsage(t *testing.T) {
+	r := NewRegistry()
+	counter := r.NewCounterVec("test_invalid_total", "测试", "source")
+
+	assert.Panics(t, func() { counter.Inc() })
+	assert.Panics(t, func() { counter.Add(-1, "zhihu") })
+	assert.Panics(t, func() { r.NewGaugeVec("test_invalid_total", "重复注册") })
+	assert.Panics(t, func() { r.NewHistogramVec("test_buckets", "桶上限未排序", []float64{1, 0.5}) })
+}
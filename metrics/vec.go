@@ -0,0 +1,92 @@
+package metrics
+
+// CounterVec 按标签区分的计数器，只能增加
+type CounterVec struct {
+	m *metric
+}
+
+// NewCounterVec 创建计数器并注册到默认注册表
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return Default.NewCounterVec(name, help, labels...)
+}
+
+// NewCounterVec 创建计数器并注册到注册表
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{m: newMetric(r, "counter", name, help, labels, nil)}
+}
+
+// Inc 计数加一，values 为各个标签的值，顺序与创建时相同
+func (c *CounterVec) Inc(values ...string) {
+	c.m.add(1, values)
+}
+
+// Add 计数增加 delta，delta 不能为负数
+func (c *CounterVec) Add(delta float64, values ...string) {
+	if delta < 0 {
+		panic("metrics: 计数器 " + c.m.name + " 不能减少")
+	}
+	c.m.add(delta, values)
+}
+
+// GaugeVec 按标签区分的仪表盘，可以增加、减少或直接设置
+type GaugeVec struct {
+	m *metric
+}
+
+// NewGaugeVec 创建仪表盘并注册到默认注册表
+func NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	return Default.NewGaugeVec(name, help, labels...)
+}
+
+// NewGaugeVec 创建仪表盘并注册到注册表
+func (r *Registry) NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	return &GaugeVec{m: newMetric(r, "gauge", name, help, labels, nil)}
+}
+
+// Set 设置值
+func (g *GaugeVec) Set(value float64, values ...string) {
+	g.m.set(value, values)
+}
+
+// Add 增加 delta，delta 可以为负数
+func (g *GaugeVec) Add(delta float64, values ...string) {
+	g.m.add(delta, values)
+}
+
+// Inc 加一
+func (g *GaugeVec) Inc(values ...string) {
+	g.m.add(1, values)
+}
+
+// Dec 减一
+func (g *GaugeVec) Dec(values ...string) {
+	g.m.add(-1, values)
+}
+
+// HistogramVec 按标签区分的直方图
+type HistogramVec struct {
+	m *metric
+}
+
+// NewHistogramVec 创建直方图并注册到默认注册表，buckets 为空时使用 DefaultBuckets
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	return Default.NewHistogramVec(name, help, buckets, labels...)
+}
+
+// NewHistogramVec 创建直方图并注册到注册表，buckets 需要按升序排列，为空时使用 DefaultBuckets
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	for i := 1; i < len(buckets); i++ {
+		if buckets[i] <= buckets[i-1] {
+			panic("metrics: 直方图 " + name + " 的桶上限需要按升序排列")
+		}
+	}
+	return &HistogramVec{m: newMetric(r, "histogram", name, help, labels, buckets)}
+}
+
+// Observe 记录一次观测值，耗时使用秒为单位
+func (h *HistogramVec) Observe(value float64, values ...string) {
+	h.m.observe(value, values)
+}
@@ -1,23 +1,74 @@
 package model
 
-import "time"
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
 
 // HotSearchItem 表示单个热搜条目
 type HotSearchItem struct {
-	ID        uint      `json:"-" gorm:"primaryKey"`
-	Source    string    `json:"source" gorm:"index"`
-	Title     string    `json:"title"`
-	URL       string    `json:"url"`
-	Index     int       `json:"index" gorm:"column:item_index"`
-	CreatedAt time.Time `json:"created_at"`
-	Date      string    `json:"date" gorm:"index"` // 格式: YYYY-MM-DD
-	Hour      int       `json:"hour" gorm:"index"` // 0-23
+	ID         uint      `json:"-" gorm:"primaryKey"`
+	SnapshotID uint      `json:"-" gorm:"index"` // 所属快照 HotSearchData.ID
+	Source     string    `json:"source" gorm:"index"`
+	Title      string    `json:"title"`
+	URL        string    `json:"url"`
+	Index      int       `json:"index" gorm:"column:item_index"`
+	HotScore   float64   `json:"hot_score"`              // 热度数值
+	HotValue   string    `json:"hot_value"`              // 热度的原始展示文本，如 "123.4万"
+	Desc       string    `json:"desc"`                   // 描述
+	Image      string    `json:"image"`                  // 封面图片URL
+	Extra      Extra     `json:"extra" gorm:"type:text"` // 平台特有的其他字段，以JSON存储
+	CreatedAt  time.Time `json:"created_at"`
+	Date       string    `json:"date" gorm:"index"` // 格式: YYYY-MM-DD
+	Hour       int       `json:"hour" gorm:"index"` // 0-23
 }
 
-// HotSearchData 表示某个来源的完整热搜数据
+// HotSearchData 表示某个来源在某一时刻抓取到的完整热搜快照
+//
+// 快照只追加不修改，每次抓取都会生成一条新的记录，历史查询基于这些快照进行
 type HotSearchData struct {
 	ID        uint            `json:"-" gorm:"primaryKey"`
 	Source    string          `json:"source" gorm:"index"`
-	Items     []HotSearchItem `json:"items" gorm:"foreignKey:Source;references:Source"`
-	CreatedAt time.Time       `json:"created_at"`
+	Date      string          `json:"date" gorm:"index"` // 格式: YYYY-MM-DD
+	Hour      int             `json:"hour" gorm:"index"` // 0-23
+	Items     []HotSearchItem `json:"items" gorm:"foreignKey:SnapshotID"`
+	CreatedAt time.Time       `json:"created_at" gorm:"index"`
+}
+
+// Extra 以JSON格式存储在单个字段中的附加数据
+type Extra map[string]string
+
+// Value 实现 driver.Valuer，空值存储为 NULL
+func (e Extra) Value() (driver.Value, error) {
+	if len(e) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan 实现 sql.Scanner
+func (e *Extra) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*e = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("unsupported type for Extra: %T", value)
+	}
+	if len(data) == 0 {
+		*e = nil
+		return nil
+	}
+	return json.Unmarshal(data, e)
 }
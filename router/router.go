@@ -4,55 +4,72 @@ import (
 	"api/all"
 	app_pkg "api/app"
 	"api/config"
+	"api/logging"
+	"api/metrics"
 	"api/service"
 	"api/websocket"
+	"context"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/cache"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/etag"
 	"github.com/gofiber/fiber/v2/middleware/favicon"
 	"github.com/gofiber/fiber/v2/middleware/idempotency"
 	"github.com/gofiber/fiber/v2/middleware/limiter"
-	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/monitor"
 	"github.com/gofiber/swagger" // swagger handler
 )
 
-// SetupRoutes 配置所有路由
-func SetupRoutes(app *fiber.App, hotSearchService *service.HotSearchService, cfg *config.Config) {
+// SetupRoutes 配置所有路由，返回的 WebSocket 管理器用于在关闭服务时断开所有连接
+func SetupRoutes(app *fiber.App, hotSearchService *service.HotSearchService, cfg *config.Config) *websocket.WsManager {
 	// 使用CORS中间件
 
-	// 使用日志中间件
-	app.Use(logger.New())
+	// 使用日志中间件，为每个请求生成请求ID
+	app.Use(logging.Middleware())
+
+	// 按路由统计请求数和耗时
+	app.Use(metrics.Middleware())
 
 	if !cfg.Debug {
 		app.Use(cors.New(cors.Config{
 			AllowOrigins: cfg.CORS.AllowOrigins,
+			// 浏览器中的 MCP 客户端需要读取会话ID
+			ExposeHeaders: "Mcp-Session-Id",
 		}))
 
-		app.Use(cache.New())
-
-		app.Use(etag.New())
+		app.Use(etag.New(etag.Config{
+			// SSE 是流式响应，计算 ETag 需要读完整个响应
+			Next: func(c *fiber.Ctx) bool {
+				return strings.Contains(c.Get(fiber.HeaderAccept), "text/event-stream")
+			},
+		}))
 
 		app.Use(favicon.New())
 
 		app.Use(idempotency.New())
 
-		app.Use(limiter.New(limiter.Config{
-			Next: func(c *fiber.Ctx) bool {
-				return c.IP() == "127.0.0.1"
-			},
-			Max:               20,
-			Expiration:        30 * time.Second,
-			LimiterMiddleware: limiter.SlidingWindow{},
-		}))
+		if cfg.RateLimit.Enabled {
+			app.Use(limiter.New(limiter.Config{
+				// 本机请求、探针和指标抓取不限流
+				Next: func(c *fiber.Ctx) bool {
+					return c.IP() == "127.0.0.1" || c.Path() == "/healthz" || c.Path() == "/readyz" || c.Path() == "/metrics"
+				},
+				Max:               cfg.RateLimit.Max,
+				Expiration:        cfg.RateLimit.Expiration,
+				LimiterMiddleware: limiter.SlidingWindow{},
+			}))
+		}
 	}
 
-	// 仅在调试模式下启用 metrics 路由
+	// Prometheus 指标
+	app.Get("/metrics", metrics.Handler)
+
+	// 仅在调试模式下启用 Fiber 的监控页面
 	if cfg.Debug {
-		app.Get("/metrics", monitor.New())
+		app.Get("/monitor", monitor.New())
 	}
 
 	// Swagger API文档
@@ -62,7 +79,7 @@ func SetupRoutes(app *fiber.App, hotSearchService *service.HotSearchService, cfg
 	setupAPIRoutes(app, hotSearchService)
 
 	// 设置WebSocket路由
-	websocket.SetupWebSocketRoutes(app, hotSearchService, cfg)
+	return websocket.SetupWebSocketRoutes(app, hotSearchService, cfg)
 }
 
 // setupAPIRoutes 设置API路由
@@ -72,103 +89,34 @@ func setupAPIRoutes(app *fiber.App, hotSearchService *service.HotSearchService)
 		return c.Redirect("/swagger/index.html")
 	})
 
-	// 实时API - 直接请求接口
-	app.Get("/baidu", createHandler(func() (interface{}, error) {
-		return app_pkg.Baidu()
-	}))
-	app.Get("/bilibili", createHandler(func() (interface{}, error) {
-		return app_pkg.Bilibili()
-	}))
-	app.Get("/list", createHandler(func() (interface{}, error) {
+	// 平台列表
+	app.Get("/list", createHandler(func(ctx context.Context) (interface{}, error) {
 		return app_pkg.ListSources()
 	}))
-	app.Get("/360search", createHandler(func() (interface{}, error) {
-		return app_pkg.Search360()
-	}))
-	app.Get("/acfun", createHandler(func() (interface{}, error) {
-		return app_pkg.Acfun()
-	}))
-	app.Get("/csdn", createHandler(func() (interface{}, error) {
-		return app_pkg.CSDN()
-	}))
-	app.Get("/dongqiudi", createHandler(func() (interface{}, error) {
-		return app_pkg.Dongqiudi()
-	}))
-	app.Get("/douban", createHandler(func() (interface{}, error) {
-		return app_pkg.Douban()
-	}))
-	app.Get("/douyin", createHandler(func() (interface{}, error) {
-		return app_pkg.Douyin()
-	}))
-	app.Get("/github", createHandler(func() (interface{}, error) {
-		return app_pkg.Github()
-	}))
-	app.Get("/guojiadili", createHandler(func() (interface{}, error) {
-		return app_pkg.Guojiadili()
-	}))
-	// 统一历史路由为 historytoday，避免与历史记录查询冲突
-	app.Get("/historytoday", createHandler(func() (interface{}, error) {
-		return app_pkg.HistoryToday()
-	}))
-	app.Get("/hupu", createHandler(func() (interface{}, error) {
-		return app_pkg.Hupu()
-	}))
-	app.Get("/ithome", createHandler(func() (interface{}, error) {
-		return app_pkg.Ithome()
-	}))
-	app.Get("/lishipin", createHandler(func() (interface{}, error) {
-		return app_pkg.Lishipin()
-	}))
-	app.Get("/pengpai", createHandler(func() (interface{}, error) {
-		return app_pkg.Pengpai()
-	}))
-	app.Get("/qqnews", createHandler(func() (interface{}, error) {
-		return app_pkg.Qqnews()
-	}))
-	app.Get("/sougou", createHandler(func() (interface{}, error) {
-		return app_pkg.Sougou()
-	}))
-	app.Get("/souhu", createHandler(func() (interface{}, error) {
-		return app_pkg.Souhu()
-	}))
-	app.Get("/toutiao", createHandler(func() (interface{}, error) {
-		return app_pkg.Toutiao()
-	}))
-	app.Get("/v2ex", createHandler(func() (interface{}, error) {
-		return app_pkg.V2ex()
-	}))
-	app.Get("/wangyinews", createHandler(func() (interface{}, error) {
-		return app_pkg.WangyiNews()
-	}))
-	app.Get("/weibo", createHandler(func() (interface{}, error) {
-		return app_pkg.WeiboHot()
-	}))
-	app.Get("/xinjingbao", createHandler(func() (interface{}, error) {
-		return app_pkg.Xinjingbao()
-	}))
-	app.Get("/zhihu", createHandler(func() (interface{}, error) {
-		return app_pkg.Zhihu()
-	}))
-	app.Get("/renmin", createHandler(func() (interface{}, error) {
-		return app_pkg.Renminwang()
-	}))
-	app.Get("/nanfang", createHandler(func() (interface{}, error) {
-		return app_pkg.Nanfangzhoumo()
-	}))
-	app.Get("/360doc", createHandler(func() (interface{}, error) {
-		return app_pkg.Doc360()
-	}))
-	app.Get("/cctv", createHandler(func() (interface{}, error) {
-		return app_pkg.CCTV()
-	}))
-	app.Get("/quark", createHandler(func() (interface{}, error) {
-		return app_pkg.Quark()
-	}))
+
+	// 实时API - 直接请求接口，上游失败或超时时返回已保存的快照，路由由数据源注册表生成（包括别名）
+	// 历史上的今天使用 historytoday 路由，避免与历史记录查询冲突
+	for _, source := range app_pkg.Sources() {
+		handler := createLiveHandler(hotSearchService, source.RouteName)
+
+		app.Get("/"+source.RouteName, handler)
+		for _, alias := range source.Aliases {
+			app.Get("/"+alias, handler)
+		}
+	}
 
 	// 聚合API
-	app.Get("/all", createHandler(func() (interface{}, error) {
-		return all.All(), nil
-	}))
+	app.Get("/all", func(c *fiber.Ctx) error {
+		results, info, err := hotSearchService.GetAllLiveCached(c.UserContext())
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"code":    500,
+				"message": err.Error(),
+			})
+		}
+		setCacheHeaders(c, info)
+		return c.JSON(all.NewResponse(results))
+	})
 
 	// 历史API - 保留历史记录查询
 	// 获取指定平台、日期和小时的历史数据
@@ -185,12 +133,40 @@ func setupAPIRoutes(app *fiber.App, hotSearchService *service.HotSearchService)
 	app.Get("/history/:source", func(c *fiber.Ctx) error {
 		return hotSearchService.GetHistoricalDataBySourceHandler(c)
 	})
+
+	// 比较指定平台两个时间点的快照
+	app.Get("/diff/:source", func(c *fiber.Ctx) error {
+		return hotSearchService.GetDiffHandler(c)
+	})
+
+	// 管理API - 各数据源的定时抓取状态
+	app.Get("/admin/schedule", func(c *fiber.Ctx) error {
+		return hotSearchService.GetScheduleHandler(c)
+	})
+
+	// 健康检查 - 各数据源最近的抓取情况
+	app.Get("/health", func(c *fiber.Ctx) error {
+		return hotSearchService.GetHealthHandler(c)
+	})
+
+	app.Get("/health/:source", func(c *fiber.Ctx) error {
+		return hotSearchService.GetSourceHealthHandler(c)
+	})
+
+	// 容器编排使用的存活和就绪探针
+	app.Get("/healthz", func(c *fiber.Ctx) error {
+		return hotSearchService.LivenessHandler(c)
+	})
+
+	app.Get("/readyz", func(c *fiber.Ctx) error {
+		return hotSearchService.ReadinessHandler(c)
+	})
 }
 
-// createHandler 创建处理器函数
-func createHandler(f func() (interface{}, error)) func(*fiber.Ctx) error {
+// createHandler 创建处理器函数，请求的上下文会传递给 f
+func createHandler(f func(ctx context.Context) (interface{}, error)) func(*fiber.Ctx) error {
 	return func(c *fiber.Ctx) error {
-		data, err := f()
+		data, err := f(c.UserContext())
 		if err != nil {
 			return c.Status(500).JSON(fiber.Map{
 				"code":    500,
@@ -201,16 +177,27 @@ func createHandler(f func() (interface{}, error)) func(*fiber.Ctx) error {
 	}
 }
 
-// createHandlerWithCache 使用缓存创建处理器
-func createHandlerWithCache(service *service.HotSearchService, source string) func(*fiber.Ctx) error {
+// createLiveHandler 创建实时数据的处理器，缓存时间内返回缓存，上游失败或超时时返回已保存的快照
+func createLiveHandler(hotSearchService *service.HotSearchService, source string) func(*fiber.Ctx) error {
 	return func(c *fiber.Ctx) error {
-		data, err := service.GetFromDBOrFetch(source)
+		result, info, err := hotSearchService.GetLiveCached(c.UserContext(), source)
 		if err != nil {
 			return c.Status(500).JSON(fiber.Map{
 				"code":    500,
 				"message": err.Error(),
 			})
 		}
-		return c.JSON(data)
+		setCacheHeaders(c, info)
+		return c.JSON(result.Response())
+	}
+}
+
+// setCacheHeaders 设置缓存相关的响应头，X-Cache 为 HIT 或 MISS，Age 为缓存数据已保存的秒数
+func setCacheHeaders(c *fiber.Ctx, info service.CacheInfo) {
+	if info.Hit {
+		c.Set("X-Cache", "HIT")
+	} else {
+		c.Set("X-Cache", "MISS")
 	}
+	c.Set(fiber.HeaderAge, strconv.Itoa(int(info.Age/time.Second)))
 }
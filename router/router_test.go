@@ -1,16 +1,30 @@
 package router
 
 import (
+	app_pkg "api/app"
 	"api/config"
 	"api/service"
+	"context"
 	"encoding/json"
+	"io"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/stretchr/testify/assert"
 )
 
+func init() {
+	// 用于测试实时接口缓存的数据源
+	app_pkg.Register(app_pkg.Source{
+		RouteName: "router_test_cached",
+		Fetch: func(ctx context.Context, f *app_pkg.Fetcher) (*app_pkg.Result, error) {
+			return app_pkg.NewResult("router_test_cached", []app_pkg.Item{{Index: 1, Title: "Title", URL: "http://example.com"}}), nil
+		},
+	})
+}
+
 func TestSetupRoutes(t *testing.T) {
 	app := fiber.New()
 
@@ -49,6 +63,23 @@ func TestSetupRoutes(t *testing.T) {
 		})
 	})
 
+	// 测试每个已注册的数据源都有对应的路由
+	t.Run("Test every registered source has a route", func(t *testing.T) {
+		routes := make(map[string]bool)
+		for _, route := range app.GetRoutes() {
+			if route.Method == fiber.MethodGet {
+				routes[route.Path] = true
+			}
+		}
+		for _, source := range app_pkg.Sources() {
+			assert.True(t, routes["/"+source.RouteName], "missing HTTP route for %s", source.RouteName)
+			assert.True(t, routes["/ws/"+source.RouteName], "missing WebSocket route for %s", source.RouteName)
+			for _, alias := range source.Aliases {
+				assert.True(t, routes["/"+alias], "missing HTTP route for alias %s", alias)
+			}
+		}
+	})
+
 	// 测试历史API路由
 	t.Run("Test history routes exist", func(t *testing.T) {
 		// 在测试环境中跳过数据库操作，只需验证路由是否注册
@@ -59,11 +90,61 @@ func TestSetupRoutes(t *testing.T) {
 	})
 }
 
+func TestLiveRouteCacheHeaders(t *testing.T) {
+	app := fiber.New()
+	hotSearchService := &service.HotSearchService{}
+	hotSearchService.SetCacheConfig(config.CacheConfig{Enabled: true, TTL: time.Minute})
+	SetupRoutes(app, hotSearchService, &config.Config{Debug: true})
+
+	// 第一次请求上游，之后命中缓存
+	resp, err := app.Test(httptest.NewRequest("GET", "/router_test_cached", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, "MISS", resp.Header.Get("X-Cache"))
+	assert.Equal(t, "0", resp.Header.Get("Age"))
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/router_test_cached", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, "HIT", resp.Header.Get("X-Cache"))
+	assert.NotEmpty(t, resp.Header.Get("Age"))
+
+	var body app_pkg.Response
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, "router_test_cached", body.Message)
+	assert.Equal(t, "Title", body.Obj[0].Title)
+}
+
+func TestMetricsRoute(t *testing.T) {
+	app := fiber.New()
+	hotSearchService := &service.HotSearchService{}
+	SetupRoutes(app, hotSearchService, &config.Config{})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/router_test_cached", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+	_, err = app.Test(httptest.NewRequest("GET", "/not_exists_route", nil))
+	assert.NoError(t, err)
+
+	// 非调试模式下也提供指标，请求按路由模板统计
+	resp, err = app.Test(httptest.NewRequest("GET", "/metrics", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("Content-Type"), "text/plain; version=0.0.4")
+
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), `azhot_http_requests_total{method="GET",route="/router_test_cached",status="200"}`)
+	assert.Contains(t, string(body), `azhot_http_requests_total{method="GET",route="unmatched",status="404"}`)
+	assert.Contains(t, string(body), `azhot_fetch_total{source="router_test_cached",`)
+	assert.Contains(t, string(body), "# TYPE azhot_fetch_duration_seconds histogram")
+	assert.Contains(t, string(body), "azhot_websocket_connections ")
+}
+
 func TestCreateHandler(t *testing.T) {
 	app := fiber.New()
 
 	// 创建一个简单的处理器函数用于测试
-	simpleFunc := func() (interface{}, error) {
+	simpleFunc := func(ctx context.Context) (interface{}, error) {
 		return map[string]interface{}{"test": "value"}, nil
 	}
 
@@ -87,7 +168,7 @@ func TestCreateHandlerWithError(t *testing.T) {
 	app := fiber.New()
 
 	// 创建一个返回错误的处理器函数用于测试
-	errorFunc := func() (interface{}, error) {
+	errorFunc := func(ctx context.Context) (interface{}, error) {
 		return nil, assert.AnError
 	}
 
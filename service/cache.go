@@ -0,0 +1,151 @@
+package service
+
+import (
+	"api/app"
+	"api/config"
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// allCacheKey /all 接口的缓存键
+const allCacheKey = "all"
+
+// degradedCacheTTL 过期快照或降级结果的最长缓存时间，让上游恢复后能尽快返回最新数据
+const degradedCacheTTL = 10 * time.Second
+
+// CacheInfo 一次读取的缓存信息，用于设置 X-Cache 和 Age 响应头
+type CacheInfo struct {
+	Hit bool          // 是否命中缓存
+	Age time.Duration // 命中时缓存数据已保存的时间
+}
+
+// cacheEntry 缓存的一份数据
+type cacheEntry struct {
+	value     interface{}
+	storedAt  time.Time
+	expiresAt time.Time
+}
+
+// liveCache 实时接口的内存缓存，同一个键的并发请求只会向上游请求一次
+//
+// 零值可以直接使用
+type liveCache struct {
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	group   singleflight.Group
+}
+
+// get 获取未过期的缓存数据
+func (c *liveCache) get(key string, now time.Time) (cacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || !now.Before(entry.expiresAt) {
+		return cacheEntry{}, false
+	}
+	return entry, true
+}
+
+// set 保存缓存数据，now 为数据开始加载的时间；已有更晚保存的数据时不覆盖
+func (c *liveCache) set(key string, value interface{}, now time.Time, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.entries == nil {
+		c.entries = make(map[string]cacheEntry)
+	}
+	if entry, ok := c.entries[key]; ok && entry.storedAt.After(now) {
+		return
+	}
+	c.entries[key] = cacheEntry{value: value, storedAt: now, expiresAt: now.Add(ttl)}
+}
+
+// SetCacheConfig 设置实时接口的缓存配置，需要在处理请求之前调用
+func (s *HotSearchService) SetCacheConfig(cfg config.CacheConfig) {
+	s.cacheConfig = cfg
+}
+
+// cached 从缓存读取数据，缓存不存在或已过期时调用 load 加载
+//
+// 同一个键同时只有一个 load 在执行，其他请求等待它的结果；load 不会随某个请求的取消而中断
+func (s *HotSearchService) cached(ctx context.Context, key string, load func(ctx context.Context) (interface{}, error)) (interface{}, CacheInfo, error) {
+	now := time.Now()
+	if entry, ok := s.cache.get(key, now); ok {
+		return entry.value, CacheInfo{Hit: true, Age: now.Sub(entry.storedAt)}, nil
+	}
+
+	loadCtx := context.WithoutCancel(ctx)
+	ch := s.cache.group.DoChan(key, func() (interface{}, error) {
+		start := time.Now()
+		value, err := load(loadCtx)
+		if err == nil {
+			s.storeCached(key, value, start)
+		}
+		return value, err
+	})
+
+	select {
+	case res := <-ch:
+		return res.Val, CacheInfo{}, res.Err
+	case <-ctx.Done():
+		return nil, CacheInfo{}, ctx.Err()
+	}
+}
+
+// storeCached 按配置的缓存时间保存数据，过期快照或降级结果最多保存 degradedCacheTTL
+//
+// loadedAt 为开始加载的时间，加载期间后台刷新已经保存了更新的数据时不覆盖
+func (s *HotSearchService) storeCached(key string, value interface{}, loadedAt time.Time) {
+	ttl := s.cacheConfig.TTLFor(key)
+	if ttl <= 0 {
+		return
+	}
+	if degraded(value) {
+		ttl = min(ttl, degradedCacheTTL)
+	}
+	s.cache.set(key, value, loadedAt, ttl)
+}
+
+// degraded 判断加载的数据是否包含过期快照或降级结果
+func degraded(value interface{}) bool {
+	switch v := value.(type) {
+	case *app.Result:
+		return v != nil && (v.Stale || v.Degraded)
+	case map[string]*app.Result:
+		for _, result := range v {
+			if degraded(result) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// GetLiveCached 获取指定数据源的实时数据，在缓存时间内直接返回缓存
+func (s *HotSearchService) GetLiveCached(ctx context.Context, source string) (*app.Result, CacheInfo, error) {
+	if src, ok := app.LookupSource(source); ok {
+		source = src.RouteName
+	}
+	value, info, err := s.cached(ctx, source, func(ctx context.Context) (interface{}, error) {
+		return s.GetLive(ctx, source)
+	})
+	if err != nil {
+		return nil, info, err
+	}
+	return value.(*app.Result), info, nil
+}
+
+// GetAllLiveCached 获取所有平台的实时数据，在缓存时间内直接返回缓存
+func (s *HotSearchService) GetAllLiveCached(ctx context.Context) (map[string]*app.Result, CacheInfo, error) {
+	value, info, err := s.cached(ctx, allCacheKey, func(ctx context.Context) (interface{}, error) {
+		return s.GetAllLive(ctx), nil
+	})
+	if err != nil {
+		return nil, info, err
+	}
+	return value.(map[string]*app.Result), info, nil
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"api/app"
+	"api/config"
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 测试并发请求只向上游请求一次
+func TestGetLiveCachedSingleFlight(t *testing.T) {
+	service := &HotSearchService{}
+	service.SetCacheConfig(config.CacheConfig{Enabled: true, TTL: time.Minute})
+
+	var calls atomic.Int32
+	setLiveTestFetch(func(ctx context.Context) (*app.Result, error) {
+		calls.Add(1)
+		time.Sleep(50 * time.Millisecond)
+		return app.NewResult("service_test_live", []app.Item{{Index: 1, Title: "Live", URL: "http://example.com/live"}}), nil
+	})
+
+	var wg sync.WaitGroup
+	var misses atomic.Int32
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, info, err := service.GetLiveCached(context.Background(), "service_test_live")
+			assert.NoError(t, err)
+			assert.Equal(t, "Live", result.Items[0].Title)
+			if !info.Hit {
+				misses.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), calls.Load())
+	assert.Equal(t, int32(100), misses.Load())
+
+	// 缓存时间内直接返回缓存
+	time.Sleep(10 * time.Millisecond)
+	_, info, err := service.GetLiveCached(context.Background(), "service_test_live")
+	assert.NoError(t, err)
+	assert.True(t, info.Hit)
+	assert.Greater(t, info.Age, time.Duration(0))
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+// 测试缓存过期和未启用缓存的情况
+func TestGetLiveCachedExpiry(t *testing.T) {
+	service := &HotSearchService{}
+	service.SetCacheConfig(config.CacheConfig{
+		Enabled:    true,
+		TTL:        time.Minute,
+		SourceTTLs: map[string]time.Duration{"service_test_live": 20 * time.Millisecond},
+	})
+
+	var calls atomic.Int32
+	setLiveTestFetch(func(ctx context.Context) (*app.Result, error) {
+		calls.Add(1)
+		return app.NewResult("service_test_live", nil), nil
+	})
+
+	_, info, err := service.GetLiveCached(context.Background(), "service_test_live")
+	assert.NoError(t, err)
+	assert.False(t, info.Hit)
+	_, info, _ = service.GetLiveCached(context.Background(), "service_test_live")
+	assert.True(t, info.Hit)
+
+	// 过期后重新请求上游
+	time.Sleep(30 * time.Millisecond)
+	_, info, _ = service.GetLiveCached(context.Background(), "service_test_live")
+	assert.False(t, info.Hit)
+	assert.Equal(t, int32(2), calls.Load())
+
+	// 未启用缓存时每次都请求上游
+	service = &HotSearchService{}
+	for i := 0; i < 2; i++ {
+		_, info, err = service.GetLiveCached(context.Background(), "service_test_live")
+		assert.NoError(t, err)
+		assert.False(t, info.Hit)
+	}
+	assert.Equal(t, int32(4), calls.Load())
+}
+
+// 测试降级结果只缓存较短的时间
+func TestGetLiveCachedDegraded(t *testing.T) {
+	service := &HotSearchService{}
+	service.SetCacheConfig(config.CacheConfig{Enabled: true, TTL: time.Hour})
+
+	// 没有条目的结果未通过检查，标记为降级
+	setLiveTestFetch(func(ctx context.Context) (*app.Result, error) {
+		return app.NewResult("service_test_live", nil), nil
+	})
+	result, _, err := service.GetLiveCached(context.Background(), "service_test_live")
+	assert.NoError(t, err)
+	assert.True(t, result.Degraded)
+	entry, ok := service.cache.get("service_test_live", time.Now())
+	assert.True(t, ok)
+	assert.LessOrEqual(t, entry.expiresAt.Sub(entry.storedAt), degradedCacheTTL)
+
+	// 正常结果按配置的缓存时间保存
+	service = &HotSearchService{}
+	service.SetCacheConfig(config.CacheConfig{Enabled: true, TTL: time.Hour})
+	setLiveTestFetch(func(ctx context.Context) (*app.Result, error) {
+		return app.NewResult("service_test_live", []app.Item{{Index: 1, Title: "Live", URL: "http://example.com/live"}}), nil
+	})
+	_, _, err = service.GetLiveCached(context.Background(), "service_test_live")
+	assert.NoError(t, err)
+	entry, ok = service.cache.get("service_test_live", time.Now())
+	assert.True(t, ok)
+	assert.Equal(t, time.Hour, entry.expiresAt.Sub(entry.storedAt))
+
+	// /all 中有一个平台降级时整体只缓存较短的时间
+	assert.True(t, degraded(map[string]*app.Result{
+		"a": app.NewResult("a", nil),
+		"b": {Source: "b", Stale: true},
+	}))
+	assert.False(t, degraded(map[string]*app.Result{"a": app.NewResult("a", nil)}))
+}
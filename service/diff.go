@@ -0,0 +1,273 @@
+package service
+
+import (
+	"api/app"
+	"api/db"
+	"api/logging"
+	"api/model"
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RankChange 单条热搜在两次快照之间的变化
+type RankChange struct {
+	Title        string `json:"title"`
+	URL          string `json:"url"`
+	Rank         int    `json:"rank,omitempty"`         // 新快照中的排名，掉出榜单的条目为 0
+	PreviousRank int    `json:"previousRank,omitempty"` // 旧快照中的排名，新上榜的条目为 0
+	Delta        int    `json:"delta,omitempty"`        // 排名变化，正数表示上升的名次
+}
+
+// Diff 同一数据源两次快照之间的差异
+type Diff struct {
+	Source  string       `json:"source"`
+	From    time.Time    `json:"from"`    // 旧快照的抓取时间
+	To      time.Time    `json:"to"`      // 新快照的抓取时间
+	Added   []RankChange `json:"added"`   // 新上榜的条目
+	Removed []RankChange `json:"removed"` // 掉出榜单的条目
+	Moved   []RankChange `json:"moved"`   // 排名发生变化的条目
+}
+
+// Empty 判断两次快照之间是否没有任何变化
+func (d *Diff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Moved) == 0
+}
+
+// NewDiff 计算两次抓取结果之间的差异
+func NewDiff(source string, from, to *app.Result) *Diff {
+	added, removed, moved := DiffItems(from.Items, to.Items)
+	return &Diff{
+		Source:  source,
+		From:    from.FetchedAt,
+		To:      to.FetchedAt,
+		Added:   added,
+		Removed: removed,
+		Moved:   moved,
+	}
+}
+
+// DiffItems 比较两个榜单，按规范化后的链接或标题匹配条目
+//
+// 优先使用链接匹配，链接在榜单中不唯一或无法匹配时使用标题匹配
+func DiffItems(from, to []app.Item) (added, removed, moved []RankChange) {
+	added, removed, moved = []RankChange{}, []RankChange{}, []RankChange{}
+
+	byURL := uniqueIndex(from, func(item app.Item) string { return normalizeURL(item.URL) })
+	byTitle := uniqueIndex(from, func(item app.Item) string { return normalizeTitle(item.Title) })
+	matched := make([]bool, len(from))
+
+	for _, item := range to {
+		previous := -1
+		if i, ok := byURL[normalizeURL(item.URL)]; ok && !matched[i] {
+			previous = i
+		} else if i, ok := byTitle[normalizeTitle(item.Title)]; ok && !matched[i] {
+			previous = i
+		}
+
+		if previous < 0 {
+			added = append(added, RankChange{Title: item.Title, URL: item.URL, Rank: item.Index})
+			continue
+		}
+		matched[previous] = true
+		if delta := from[previous].Index - item.Index; delta != 0 {
+			moved = append(moved, RankChange{
+				Title:        item.Title,
+				URL:          item.URL,
+				Rank:         item.Index,
+				PreviousRank: from[previous].Index,
+				Delta:        delta,
+			})
+		}
+	}
+
+	for i, item := range from {
+		if !matched[i] {
+			removed = append(removed, RankChange{Title: item.Title, URL: item.URL, PreviousRank: item.Index})
+		}
+	}
+	return added, removed, moved
+}
+
+// uniqueIndex 按规范化后的键建立索引，空键和在榜单中重复的键不参与匹配
+func uniqueIndex(items []app.Item, key func(app.Item) string) map[string]int {
+	index := make(map[string]int, len(items))
+	duplicated := make(map[string]bool)
+	for i, item := range items {
+		k := key(item)
+		if k == "" {
+			continue
+		}
+		if _, exists := index[k]; exists {
+			duplicated[k] = true
+			continue
+		}
+		index[k] = i
+	}
+	for k := range duplicated {
+		delete(index, k)
+	}
+	return index
+}
+
+// normalizeTitle 规范化标题：忽略大小写、空白和标点符号
+func normalizeTitle(title string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(title) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// normalizeURL 规范化链接：忽略协议、www 前缀、末尾斜杠、锚点和查询参数的顺序
+func normalizeURL(rawURL string) string {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+	normalized := host + strings.TrimRight(u.Path, "/")
+	if query := u.Query().Encode(); query != "" {
+		normalized += "?" + query
+	}
+	return normalized
+}
+
+// convertSnapshotToResult 将数据库中的快照转换为抓取结果
+func (s *HotSearchService) convertSnapshotToResult(source string, snapshot *model.HotSearchData) *app.Result {
+	result := s.convertToResult(source, snapshot.Items)
+	result.FetchedAt = snapshot.CreatedAt
+	return result
+}
+
+// diffWithLatest 计算抓取结果与数据库中最新快照之间的差异，没有历史快照时返回 nil
+func (s *HotSearchService) diffWithLatest(ctx context.Context, source string, result *app.Result) *Diff {
+	snapshot, err := db.GetSnapshotAt(s.convertRouteNameToDBSource(source), result.FetchedAt)
+	if err != nil {
+		logging.FromContext(ctx).Error("获取最新快照失败", logging.KeyError, err)
+		return nil
+	}
+	if snapshot == nil {
+		return nil
+	}
+	return NewDiff(source, s.convertSnapshotToResult(source, snapshot), result)
+}
+
+// GetDiff 比较指定来源两个时间点的快照
+//
+// 每个时间点取该时间及之前最新的一次快照；to 为零值时取最新快照，from 为零值时取 to 对应快照的前一次快照。
+// 找不到快照时返回 nil
+func (s *HotSearchService) GetDiff(source string, from, to time.Time) (*Diff, error) {
+	dbSource := s.convertRouteNameToDBSource(source)
+
+	toSnapshot, err := db.GetSnapshotAt(dbSource, to)
+	if err != nil || toSnapshot == nil {
+		return nil, err
+	}
+
+	var fromSnapshot *model.HotSearchData
+	if from.IsZero() {
+		fromSnapshot, err = db.GetPreviousSnapshot(toSnapshot)
+	} else {
+		fromSnapshot, err = db.GetSnapshotAt(dbSource, from)
+	}
+	if err != nil || fromSnapshot == nil {
+		return nil, err
+	}
+
+	return NewDiff(dbSource, s.convertSnapshotToResult(dbSource, fromSnapshot), s.convertSnapshotToResult(dbSource, toSnapshot)), nil
+}
+
+// diffTimeLayouts diff 接口支持的时间格式，按本地时区解析
+var diffTimeLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+}
+
+// ParseDiffTime 解析 diff 接口的时间参数，为空时返回零值，支持 RFC3339、本地时间、日期（取当天结束）和 Unix 时间戳（秒）
+func ParseDiffTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	for _, layout := range diffTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+	return time.Time{}, fmt.Errorf("无法解析时间: %s", value)
+}
+
+// GetDiffHandler 比较指定来源两个时间点的快照
+//
+//	@Summary		比较两个时间点的热搜快照
+//	@Description	比较指定来源两个时间点的热搜快照，返回新上榜、掉出榜单和排名变化的条目。不指定 to 时取最新快照，不指定 from 时取 to 对应快照的前一次快照
+//	@Tags			HistoryAPI
+//	@Accept			json
+//	@Produce		json
+//	@Param			source	path		string	true	"数据源名称"
+//	@Param			from	query		string	false	"起始时间，支持 RFC3339、YYYY-MM-DD HH:MM、YYYY-MM-DD 或 Unix 时间戳"
+//	@Param			to		query		string	false	"结束时间，格式同 from"
+//	@Success		200		{object}	map[string]interface{}
+//	@Failure		500		{object}	map[string]interface{}
+//	@Router			/diff/{source} [get]
+func (s *HotSearchService) GetDiffHandler(c *fiber.Ctx) error {
+	source := c.Params("source")
+
+	from, err := ParseDiffTime(c.Query("from"))
+	if err != nil {
+		return c.JSON(fiber.Map{
+			"code":    500,
+			"message": "from 参数格式错误: " + err.Error(),
+			"obj":     map[string]interface{}{},
+		})
+	}
+	to, err := ParseDiffTime(c.Query("to"))
+	if err != nil {
+		return c.JSON(fiber.Map{
+			"code":    500,
+			"message": "to 参数格式错误: " + err.Error(),
+			"obj":     map[string]interface{}{},
+		})
+	}
+
+	diff, err := s.GetDiff(source, from, to)
+	if err != nil {
+		return c.JSON(fiber.Map{
+			"code":    500,
+			"message": "服务器内部错误: " + err.Error(),
+			"obj":     map[string]interface{}{},
+		})
+	}
+	if diff == nil {
+		return c.JSON(fiber.Map{
+			"code":    500,
+			"message": fmt.Sprintf("未找到 %s 可以比较的两次快照", source),
+			"obj":     map[string]interface{}{},
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"code":    200,
+		"message": source,
+		"obj":     diff,
+	})
+}
@@ -0,0 +1,249 @@
+package service
+
+import (
+	"api/app"
+	"api/db"
+	"api/model"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffItems(t *testing.T) {
+	from := []app.Item{
+		{Index: 1, Title: "Stay", URL: "https://example.com/stay"},
+		{Index: 2, Title: "Drop", URL: "https://example.com/drop"},
+		{Index: 3, Title: "Climb", URL: "https://example.com/climb"},
+		{Index: 4, Title: "Renamed", URL: "https://example.com/same-url"},
+		{Index: 5, Title: "Hello, World", URL: "https://example.com/old-url"},
+	}
+	to := []app.Item{
+		{Index: 1, Title: "Stay", URL: "https://example.com/stay"},
+		{Index: 2, Title: "Climb", URL: "http://www.example.com/climb/"},     // 链接规范化后相同
+		{Index: 3, Title: "New", URL: "https://example.com/new"},             // 新上榜
+		{Index: 4, Title: "Renamed!", URL: "https://example.com/same-url"},   // 标题变化，链接相同
+		{Index: 6, Title: "hello world", URL: "https://example.com/new-url"}, // 链接变化，标题规范化后相同
+	}
+
+	added, removed, moved := DiffItems(from, to)
+	assert.Equal(t, []RankChange{{Title: "New", URL: "https://example.com/new", Rank: 3}}, added)
+	assert.Equal(t, []RankChange{{Title: "Drop", URL: "https://example.com/drop", PreviousRank: 2}}, removed)
+	assert.Equal(t, []RankChange{
+		{Title: "Climb", URL: "http://www.example.com/climb/", Rank: 2, PreviousRank: 3, Delta: 1},
+		{Title: "hello world", URL: "https://example.com/new-url", Rank: 6, PreviousRank: 5, Delta: -1},
+	}, moved)
+
+	// 没有变化时返回空列表而不是 nil
+	added, removed, moved = DiffItems(from, from)
+	assert.Equal(t, []RankChange{}, added)
+	assert.Equal(t, []RankChange{}, removed)
+	assert.Equal(t, []RankChange{}, moved)
+}
+
+func TestDiffItemsDuplicateURL(t *testing.T) {
+	// 所有条目的链接都相同时只能按标题匹配
+	from := []app.Item{
+		{Index: 1, Title: "A", URL: "https://example.com/"},
+		{Index: 2, Title: "B", URL: "https://example.com/"},
+	}
+	to := []app.Item{
+		{Index: 1, Title: "B", URL: "https://example.com/"},
+		{Index: 2, Title: "C", URL: "https://example.com/"},
+	}
+
+	added, removed, moved := DiffItems(from, to)
+	assert.Equal(t, 1, len(added))
+	assert.Equal(t, "C", added[0].Title)
+	assert.Equal(t, 1, len(removed))
+	assert.Equal(t, "A", removed[0].Title)
+	assert.Equal(t, 1, len(moved))
+	assert.Equal(t, 1, moved[0].Delta)
+}
+
+func TestParseDiffTime(t *testing.T) {
+	tm, err := ParseDiffTime("")
+	assert.NoError(t, err)
+	assert.True(t, tm.IsZero())
+
+	tm, err = ParseDiffTime("2099-01-02T08:30:00Z")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2099, 1, 2, 8, 30, 0, 0, time.UTC), tm)
+
+	tm, err = ParseDiffTime("2099-01-02 08:30")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2099, 1, 2, 8, 30, 0, 0, time.Local), tm)
+
+	// 只有日期时取当天结束
+	tm, err = ParseDiffTime("2099-01-02")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2099, 1, 3, 0, 0, 0, 0, time.Local).Add(-time.Nanosecond), tm)
+
+	tm, err = ParseDiffTime("4070908800")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4070908800), tm.Unix())
+
+	_, err = ParseDiffTime("yesterday")
+	assert.Error(t, err)
+}
+
+// saveDiffSnapshot 在指定时间保存一个快照，titles 按顺序作为排名
+func saveDiffSnapshot(t *testing.T, source string, at time.Time, titles ...string) {
+	var items []model.HotSearchItem
+	for i, title := range titles {
+		items = append(items, model.HotSearchItem{Title: title, URL: "https://example.com/" + title, Index: i + 1})
+	}
+	assert.NoError(t, db.SaveAllDataAt(map[string][]model.HotSearchItem{source: items}, at))
+}
+
+func TestGetDiff(t *testing.T) {
+	db.InitSQLiteWithDSN("file:" + t.Name() + "?mode=memory&cache=shared")
+	service := &HotSearchService{}
+
+	base := time.Date(2099, 1, 1, 8, 0, 0, 0, time.Local)
+	saveDiffSnapshot(t, "weibo", base, "A", "B", "C")
+	saveDiffSnapshot(t, "weibo", base.Add(time.Hour), "B", "A", "D")
+	saveDiffSnapshot(t, "weibo", base.Add(2*time.Hour), "D", "B", "E")
+
+	// 默认比较最新的两次快照
+	diff, err := service.GetDiff("weibo", time.Time{}, time.Time{})
+	assert.NoError(t, err)
+	assert.True(t, base.Add(time.Hour).Equal(diff.From))
+	assert.True(t, base.Add(2*time.Hour).Equal(diff.To))
+	assert.Equal(t, []string{"E"}, titles(diff.Added))
+	assert.Equal(t, []string{"A"}, titles(diff.Removed))
+	assert.Equal(t, []string{"D", "B"}, titles(diff.Moved))
+	assert.Equal(t, 2, diff.Moved[0].Delta)
+	assert.Equal(t, -1, diff.Moved[1].Delta)
+
+	// 指定时间点时取该时间及之前最新的快照
+	diff, err = service.GetDiff("weibo", base.Add(30*time.Minute), base.Add(90*time.Minute))
+	assert.NoError(t, err)
+	assert.True(t, base.Equal(diff.From))
+	assert.True(t, base.Add(time.Hour).Equal(diff.To))
+	assert.Equal(t, []string{"D"}, titles(diff.Added))
+	assert.Equal(t, []string{"C"}, titles(diff.Removed))
+	assert.Equal(t, []string{"B", "A"}, titles(diff.Moved))
+
+	// 只有一次快照或没有快照时无法比较
+	diff, err = service.GetDiff("weibo", time.Time{}, base)
+	assert.NoError(t, err)
+	assert.Nil(t, diff)
+	diff, err = service.GetDiff("zhihu", time.Time{}, time.Time{})
+	assert.NoError(t, err)
+	assert.Nil(t, diff)
+}
+
+// 测试通过别名查询快照、变化和历史数据
+func TestSnapshotAliases(t *testing.T) {
+	db.InitSQLiteWithDSN("file:" + t.Name() + "?mode=memory&cache=shared")
+	service := &HotSearchService{}
+
+	base := time.Date(2099, 1, 1, 8, 0, 0, 0, time.Local)
+	saveDiffSnapshot(t, "quark", base, "A", "B")
+	saveDiffSnapshot(t, "quark", base.Add(time.Hour), "B", "C")
+
+	diff, err := service.GetDiff("kuake", time.Time{}, time.Time{})
+	assert.NoError(t, err)
+	if assert.NotNil(t, diff) {
+		assert.Equal(t, "quark", diff.Source)
+		assert.Equal(t, []string{"C"}, titles(diff.Added))
+	}
+
+	snapshot, err := service.GetLatestSnapshot("kuake")
+	assert.NoError(t, err)
+	if assert.NotNil(t, snapshot) {
+		assert.Len(t, snapshot.Items, 2)
+	}
+
+	history, err := service.GetHistoricalDataForWS("kuake", "2099-01-01", "9")
+	assert.NoError(t, err)
+	assert.Equal(t, 200, history["code"])
+
+	matches, err := service.SearchHistory("C", []string{"kuake"}, time.Time{}, time.Time{}, 10)
+	assert.NoError(t, err)
+	assert.Len(t, matches, 1)
+}
+
+func titles(changes []RankChange) []string {
+	var result []string
+	for _, change := range changes {
+		result = append(result, change.Title)
+	}
+	return result
+}
+
+func TestGetDiffHandler(t *testing.T) {
+	db.InitSQLiteWithDSN("file:" + t.Name() + "?mode=memory&cache=shared")
+	service := &HotSearchService{}
+
+	base := time.Date(2099, 1, 1, 8, 0, 0, 0, time.Local)
+	saveDiffSnapshot(t, "weibo", base, "A", "B")
+	saveDiffSnapshot(t, "weibo", base.Add(time.Hour), "B", "C")
+
+	app := fiber.New()
+	app.Get("/diff/:source", service.GetDiffHandler)
+
+	get := func(url string) map[string]interface{} {
+		resp, err := app.Test(httptest.NewRequest("GET", url, nil))
+		assert.NoError(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+		body, err := io.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		var result map[string]interface{}
+		assert.NoError(t, json.Unmarshal(body, &result))
+		return result
+	}
+
+	result := get("/diff/weibo?from=2099-01-01%2008:00&to=2099-01-01")
+	assert.Equal(t, float64(200), result["code"])
+	obj := result["obj"].(map[string]interface{})
+	assert.Equal(t, "weibo", obj["source"])
+	assert.Equal(t, 1, len(obj["added"].([]interface{})))
+	assert.Equal(t, 1, len(obj["removed"].([]interface{})))
+	assert.Equal(t, 1, len(obj["moved"].([]interface{})))
+
+	// 参数格式错误
+	result = get("/diff/weibo?from=yesterday")
+	assert.Equal(t, float64(500), result["code"])
+
+	// 没有可以比较的快照
+	result = get("/diff/zhihu")
+	assert.Equal(t, float64(500), result["code"])
+}
+
+func TestFetchEventDiff(t *testing.T) {
+	db.InitSQLiteWithDSN("file:" + t.Name() + "?mode=memory&cache=shared")
+	app.Register(app.Source{
+		RouteName: "service_test_diff",
+		Expect:    &app.Expectations{MinItems: 1},
+		Fetch: func(ctx context.Context, f *app.Fetcher) (*app.Result, error) {
+			return app.NewResult("service_test_diff", []app.Item{
+				{Index: 1, Title: "B", URL: "https://example.com/B"},
+				{Index: 2, Title: "C", URL: "https://example.com/C"},
+			}), nil
+		},
+	})
+	saveDiffSnapshot(t, "service_test_diff", time.Now().Add(-time.Hour), "A", "B")
+
+	service := &HotSearchService{}
+	var events []FetchEvent
+	service.Subscribe(func(event FetchEvent) {
+		events = append(events, event)
+	})
+
+	// 抓取后与数据库中最新的快照比较
+	source, _ := app.LookupSource("service_test_diff")
+	_, err := service.fetchSource(context.Background(), source)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(events))
+	assert.NotNil(t, events[0].Diff)
+	assert.Equal(t, []string{"C"}, titles(events[0].Diff.Added))
+	assert.Equal(t, []string{"A"}, titles(events[0].Diff.Removed))
+	assert.Equal(t, []string{"B"}, titles(events[0].Diff.Moved))
+}
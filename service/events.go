@@ -0,0 +1,66 @@
+package service
+
+import (
+	"api/app"
+	"context"
+)
+
+// FetchEvent 一次抓取完成后发布的事件
+type FetchEvent struct {
+	Source    string      // 数据源路由名称
+	Result    *app.Result // 抓取结果
+	Scheduled bool        // 是否由定时任务触发
+	Saved     bool        // 是否保存了新快照，只关心新快照的订阅者应忽略 false 的事件
+	Diff      *Diff       // 与数据库中上一次快照相比的变化，没有历史快照时为 nil
+}
+
+// FetchEventHandler 抓取事件的处理函数，在发布事件的协程中同步调用，不应阻塞
+type FetchEventHandler func(event FetchEvent)
+
+// Subscribe 订阅抓取事件，返回取消订阅的函数
+func (s *HotSearchService) Subscribe(handler FetchEventHandler) (unsubscribe func()) {
+	s.eventsMu.Lock()
+	defer s.eventsMu.Unlock()
+
+	if s.handlers == nil {
+		s.handlers = make(map[int]FetchEventHandler)
+	}
+	id := s.nextHandlerID
+	s.nextHandlerID++
+	s.handlers[id] = handler
+
+	return func() {
+		s.eventsMu.Lock()
+		defer s.eventsMu.Unlock()
+		delete(s.handlers, id)
+	}
+}
+
+// publish 向所有订阅者发布抓取事件
+func (s *HotSearchService) publish(event FetchEvent) {
+	s.eventsMu.RLock()
+	handlers := make([]FetchEventHandler, 0, len(s.handlers))
+	for _, handler := range s.handlers {
+		handlers = append(handlers, handler)
+	}
+	s.eventsMu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(event)
+	}
+}
+
+// hasSubscribers 判断是否有订阅者，没有时不必计算事件中的变化
+func (s *HotSearchService) hasSubscribers() bool {
+	s.eventsMu.RLock()
+	defer s.eventsMu.RUnlock()
+	return len(s.handlers) > 0
+}
+
+// publishOnDemand 发布按需抓取的结果，失败、未通过检查或返回快照时不发布
+func (s *HotSearchService) publishOnDemand(ctx context.Context, source string, result *app.Result) {
+	if result == nil || result.Degraded || result.Stale || !s.hasSubscribers() {
+		return
+	}
+	s.publish(FetchEvent{Source: source, Result: result, Diff: s.diffWithLatest(ctx, source, result)})
+}
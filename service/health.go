@@ -0,0 +1,116 @@
+package service
+
+import (
+	"api/app"
+	"api/db"
+	"context"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// HealthStatus 服务的健康状态
+type HealthStatus struct {
+	Status   string             `json:"status"`   // ok 或 degraded，有数据源失败、熔断或结果未通过检查时为 degraded
+	Healthy  int                `json:"healthy"`  // 最近一次抓取成功的数据源数量
+	Degraded int                `json:"degraded"` // 最近一次抓取结果未通过检查的数据源数量
+	Failing  int                `json:"failing"`  // 最近一次抓取失败或已熔断的数据源数量
+	Sources  []app.SourceHealth `json:"sources"`  // 各数据源的抓取情况
+}
+
+// GetHealth 获取服务和各数据源的健康状态
+func (s *HotSearchService) GetHealth() HealthStatus {
+	health := HealthStatus{
+		Status:  "ok",
+		Sources: app.DefaultFetcher().HealthAll(),
+	}
+	for _, source := range health.Sources {
+		switch source.Status {
+		case app.HealthOK:
+			health.Healthy++
+		case app.HealthDegraded:
+			health.Degraded++
+		case app.HealthFailing, app.HealthDown:
+			health.Failing++
+		}
+	}
+	if health.Failing > 0 || health.Degraded > 0 {
+		health.Status = "degraded"
+	}
+	return health
+}
+
+// GetHealthHandler 获取健康状态的HTTP处理器
+//
+//	@Summary		获取健康状态
+//	@Description	获取服务的健康状态和各数据源最近的抓取情况
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	map[string]interface{}
+//	@Router			/health [get]
+func (s *HotSearchService) GetHealthHandler(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"code":    200,
+		"message": "health",
+		"obj":     s.GetHealth(),
+	})
+}
+
+// GetSourceHealthHandler 获取单个数据源健康状态的HTTP处理器
+//
+//	@Summary		获取数据源的健康状态
+//	@Description	获取数据源最近一次成功时间、最近的错误、连续失败次数、平均耗时和条目数
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			source	path		string	true	"数据源名称"
+//	@Success		200		{object}	map[string]interface{}
+//	@Failure		404		{object}	map[string]interface{}
+//	@Router			/health/{source} [get]
+func (s *HotSearchService) GetSourceHealthHandler(c *fiber.Ctx) error {
+	source, ok := app.LookupSource(c.Params("source"))
+	if !ok {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"code":    404,
+			"message": "数据源 " + c.Params("source") + " 不存在",
+			"obj":     map[string]interface{}{},
+		})
+	}
+	return c.JSON(fiber.Map{
+		"code":    200,
+		"message": source.RouteName,
+		"obj":     app.DefaultFetcher().Health(source.RouteName),
+	})
+}
+
+// LivenessHandler 存活探针，进程能处理请求即返回 200
+//
+//	@Summary		存活探针
+//	@Tags			admin
+//	@Produce		json
+//	@Success		200	{object}	map[string]interface{}
+//	@Router			/healthz [get]
+func (s *HotSearchService) LivenessHandler(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{"status": "ok"})
+}
+
+// ReadinessHandler 就绪探针，数据库可用时返回 200，否则返回 503
+//
+//	@Summary		就绪探针
+//	@Tags			admin
+//	@Produce		json
+//	@Success		200	{object}	map[string]interface{}
+//	@Failure		503	{object}	map[string]interface{}
+//	@Router			/readyz [get]
+func (s *HotSearchService) ReadinessHandler(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(c.UserContext(), 2*time.Second)
+	defer cancel()
+	if err := db.Ping(ctx); err != nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"status":  "unavailable",
+			"message": "数据库不可用: " + err.Error(),
+		})
+	}
+	return c.JSON(fiber.Map{"status": "ok"})
+}
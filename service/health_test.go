@@ -0,0 +1,110 @@
+package service
+
+import (
+	"api/app"
+	"api/config"
+	"api/db"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// 测试GetHealthHandler和GetSourceHealthHandler方法
+func TestGetHealthHandler(t *testing.T) {
+	f, err := app.NewFetcher(config.FetcherConfig{BreakerThreshold: 2, BreakerCooldown: time.Minute})
+	assert.NoError(t, err)
+	app.SetDefaultFetcher(f)
+	defer app.SetDefaultFetcher(nil)
+
+	service := &HotSearchService{}
+	handler := fiber.New()
+	handler.Get("/health", service.GetHealthHandler)
+	handler.Get("/health/:source", service.GetSourceHealthHandler)
+
+	var body struct {
+		Code int          `json:"code"`
+		Obj  HealthStatus `json:"obj"`
+	}
+	resp, err := handler.Test(httptest.NewRequest("GET", "/health", nil))
+	assert.NoError(t, err)
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, 200, body.Code)
+	assert.Equal(t, "ok", body.Obj.Status)
+	assert.Equal(t, len(app.Sources()), len(body.Obj.Sources))
+
+	// 数据源失败后健康状态为 degraded
+	failing := app.Source{
+		RouteName: "service_test_scheduled",
+		Fetch: func(ctx context.Context, f *app.Fetcher) (*app.Result, error) {
+			return nil, errors.New("页面结构可能已变更")
+		},
+	}
+	_, err = f.Fetch(context.Background(), failing)
+	assert.Error(t, err)
+	_, err = f.Fetch(context.Background(), app.Source{
+		RouteName: "service_test_live",
+		Fetch: func(ctx context.Context, f *app.Fetcher) (*app.Result, error) {
+			return app.NewResult("service_test_live", []app.Item{{Index: 1, Title: "A", URL: "http://example.com/a"}}), nil
+		},
+		Expect: &app.Expectations{MinItems: 1},
+	})
+	assert.NoError(t, err)
+
+	resp, err = handler.Test(httptest.NewRequest("GET", "/health", nil))
+	assert.NoError(t, err)
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, "degraded", body.Obj.Status)
+	assert.Equal(t, 1, body.Obj.Healthy)
+	assert.Equal(t, 1, body.Obj.Failing)
+
+	// 查询单个数据源，连续失败后熔断
+	_, err = f.Fetch(context.Background(), failing)
+	assert.Error(t, err)
+	var sourceBody struct {
+		Code int              `json:"code"`
+		Obj  app.SourceHealth `json:"obj"`
+	}
+	resp, err = handler.Test(httptest.NewRequest("GET", "/health/service_test_scheduled", nil))
+	assert.NoError(t, err)
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&sourceBody))
+	assert.Equal(t, 200, sourceBody.Code)
+	assert.Equal(t, app.HealthDown, sourceBody.Obj.Status)
+	assert.Equal(t, 2, sourceBody.Obj.ConsecutiveFailures)
+	assert.Equal(t, "页面结构可能已变更", sourceBody.Obj.LastError)
+	assert.Equal(t, app.BreakerOpen, sourceBody.Obj.Breaker)
+
+	resp, err = handler.Test(httptest.NewRequest("GET", "/health/not_exists", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, 404, resp.StatusCode)
+}
+
+// 测试存活和就绪探针
+func TestProbeHandlers(t *testing.T) {
+	service := &HotSearchService{}
+	handler := fiber.New()
+	handler.Get("/healthz", service.LivenessHandler)
+	handler.Get("/readyz", service.ReadinessHandler)
+
+	resp, err := handler.Test(httptest.NewRequest("GET", "/healthz", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+
+	// 数据库不可用时未就绪
+	saved := db.DB
+	db.DB = nil
+	resp, err = handler.Test(httptest.NewRequest("GET", "/readyz", nil))
+	db.DB = saved
+	assert.NoError(t, err)
+	assert.Equal(t, 503, resp.StatusCode)
+
+	db.InitSQLiteWithDSN("file:" + t.Name() + "?mode=memory&cache=shared")
+	resp, err = handler.Test(httptest.NewRequest("GET", "/readyz", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+}
@@ -3,44 +3,70 @@ package service
 import (
 	"api/all"
 	"api/app"
+	"api/config"
 	"api/db"
+	"api/logging"
 	"api/model"
+	"context"
 	"fmt"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/log"
 )
 
 // HotSearchService 热搜服务
-type HotSearchService struct{}
+//
+// 零值可以直接使用
+type HotSearchService struct {
+	// 抓取事件的订阅者
+	eventsMu      sync.RWMutex
+	handlers      map[int]FetchEventHandler
+	nextHandlerID int
+
+	// 定时抓取任务
+	jobsMu        sync.RWMutex
+	jobs          map[string]*scheduledJob
+	schedulerWG   sync.WaitGroup
+	stopScheduler context.CancelFunc
+
+	// 快照清理任务和后台写入数据库的协程，关闭服务时等待它们完成
+	stopRetention context.CancelFunc
+	background    sync.WaitGroup
+
+	// 实时接口的配置和缓存
+	live        config.LiveConfig
+	cacheConfig config.CacheConfig
+	cache       liveCache
+}
 
 // GetFromDBOrFetch 从数据库获取最新数据，如果数据库为空则临时获取并保存
-func (s *HotSearchService) GetFromDBOrFetch(source string) (map[string]interface{}, error) {
+func (s *HotSearchService) GetFromDBOrFetch(ctx context.Context, source string) (*app.Result, error) {
 	// 将路由名称转换为数据库中存储的源名称
 	dbSource := s.convertRouteNameToDBSource(source)
+	logger := logging.FromContext(ctx).With(logging.KeySource, source)
 
 	// 首先尝试从数据库获取最新数据
 	items, err := db.GetLatestData(dbSource)
 	if err != nil {
-		log.Errorf(fmt.Sprintf("从数据库获取 %s 数据失败: %v", source, err))
+		logger.Error("从数据库获取数据失败", logging.KeyError, err)
 	}
 
 	// 如果数据库中没有数据，则临时获取并保存
 	if len(items) == 0 {
-		log.Info("数据库中没有 " + source + " 数据，临时获取并保存...")
-		result, err := s.FetchDataFromAPI(source)
+		logger.Info("数据库中没有数据，临时获取并保存")
+		result, err := s.FetchDataFromAPI(ctx, source)
 		if err != nil {
 			return nil, err
 		}
 
-		// 保存到数据库
-		hotSearchItems := s.convertToHotSearchItems(result)
-		if len(hotSearchItems) > 0 {
+		// 保存到数据库，未通过检查的结果不保存
+		hotSearchItems := s.convertToHotSearchItems(result.Items)
+		if len(hotSearchItems) > 0 && !result.Degraded {
 			err = db.SaveData(dbSource, hotSearchItems)
 			if err != nil {
-				log.Errorf(fmt.Sprintf("保存 %s 数据到数据库失败: %v", source, err))
+				logger.Error("保存数据到数据库失败", logging.KeyError, err)
 			}
 		}
 
@@ -48,265 +74,133 @@ func (s *HotSearchService) GetFromDBOrFetch(source string) (map[string]interface
 	}
 
 	// 从数据库数据构建返回结果
-	return s.convertFromDBItems(items, source), nil
+	return s.convertToResult(source, items), nil
 }
 
 // GetAllFromDBOrFetch 从数据库获取所有数据，如果数据库为空则临时获取并保存
-func (s *HotSearchService) GetAllFromDBOrFetch() (map[string]interface{}, error) {
+func (s *HotSearchService) GetAllFromDBOrFetch(ctx context.Context) (map[string]*app.Result, error) {
 	// 首先尝试从数据库获取
+	logger := logging.FromContext(ctx)
 	data, err := db.GetAllLatestData()
 	if err != nil {
-		log.Errorf("从数据库获取所有数据失败: %v", err)
+		logger.Error("从数据库获取所有数据失败", logging.KeyError, err)
 	}
 
 	// 如果数据库中没有数据，则临时获取并保存
 	if len(data) == 0 {
-		log.Info("数据库中没有数据，临时获取所有数据并保存...")
-		result := all.All()
-
-		// 转换数据并保存到数据库
-		allData := make(map[string][]model.HotSearchItem)
-		if obj, ok := result["obj"].(map[string]interface{}); ok {
-			for source, sourceData := range obj {
-				hotSearchItems := s.convertSourceDataToHotSearchItems(sourceData)
-				// 使用路由名称作为源名称
-				allData[source] = hotSearchItems
+		logger.Info("数据库中没有数据，临时获取所有数据并保存")
+		results := all.All(ctx)
+
+		// 转换数据并保存到数据库，使用数据库源名称作为键
+		dbData := make(map[string][]model.HotSearchItem)
+		for routeName, result := range results {
+			if result.Degraded {
+				continue
 			}
+			dbSource := s.convertRouteNameToDBSource(routeName)
+			dbData[dbSource] = s.convertToHotSearchItems(result.Items)
 		}
 
-		if len(allData) > 0 {
-			// 将路由名称转换为数据库源名称
-			dbData := make(map[string][]model.HotSearchItem)
-			for routeName, items := range allData {
-				dbSource := s.convertRouteNameToDBSource(routeName)
-				dbData[dbSource] = items
-			}
+		if len(dbData) > 0 {
 			err = db.SaveAllData(dbData)
 			if err != nil {
-				log.Errorf(fmt.Sprintf("保存所有数据到数据库失败: %v", err))
+				logger.Error("保存所有数据到数据库失败", logging.KeyError, err)
 			}
 		}
 
-		return result, nil
+		return results, nil
 	}
 
 	// 从数据库数据构建返回结果
-	return s.convertFromAllDBItems(data), nil
-}
-
-// fetchAPIData 定时获取API数据并保存到数据库
-func (s *HotSearchService) fetchAPIData() {
-	log.Info("开始定时获取API数据...")
-
-	currentTime := time.Now()
-	date := currentTime.Format("2006-01-02")
-	hour := currentTime.Hour()
-
-	// 获取所有数据
-	allResult := all.All()
-
-	// 转换数据并保存到数据库
-	allData := make(map[string][]model.HotSearchItem)
-	if obj, ok := allResult["obj"].(map[string]interface{}); ok {
-		for source, sourceData := range obj {
-			hotSearchItems := s.convertSourceDataToHotSearchItems(sourceData)
-			// 添加日期和小时信息
-			for i := range hotSearchItems {
-				hotSearchItems[i].Date = date
-				hotSearchItems[i].Hour = hour
-			}
-			// 使用数据库源名称作为键
-			dbSource := s.convertRouteNameToDBSource(source)
-			allData[dbSource] = hotSearchItems
-		}
+	results := make(map[string]*app.Result, len(data))
+	for source, items := range data {
+		results[source] = s.convertToResult(source, items)
 	}
+	return results, nil
+}
 
-	if len(allData) > 0 {
-		err := db.SaveAllData(allData)
+// FetchDataFromAPI 根据来源获取API数据，超时时间取该数据源的配置
+//
+// 抓取成功且通过检查后发布 Scheduled 为 false 的事件
+func (s *HotSearchService) FetchDataFromAPI(ctx context.Context, source string) (*app.Result, error) {
+	// 在数据源注册表中查找对应的抓取函数（支持别名）
+	if src, exists := app.LookupSource(source); exists {
+		result, err := app.Fetch(ctx, src)
 		if err != nil {
-			log.Errorf(fmt.Sprintf("定时保存所有数据到数据库失败: %v", err))
-		} else {
-			log.Info(fmt.Sprintf("定时获取API数据并保存到数据库完成，共保存 %d 个平台的数据，时间: %s %d:00", len(allData), date, hour))
+			return nil, err
 		}
+		s.publishOnDemand(ctx, src.RouteName, result)
+		return result, nil
 	}
-}
-
-// StartScheduler 启动定时任务
-func (s *HotSearchService) StartScheduler() {
-	// 立即执行一次
-	s.fetchAPIData()
-
-	// 每小时执行一次
-	ticker := time.NewTicker(1 * time.Hour)
-	go func() {
-		for range ticker.C {
-			s.fetchAPIData()
-		}
-	}()
-}
 
-// apiFunctionMap 将路由名称映射到API函数
-var apiFunctionMap = map[string]func() (map[string]interface{}, error){
-	"360search":  app.Search360,
-	"bilibili":   app.Bilibili,
-	"acfun":      app.Acfun,
-	"csdn":       app.CSDN,
-	"dongqiudi":  app.Dongqiudi,
-	"douban":     app.Douban,
-	"douyin":     app.Douyin,
-	"github":     app.Github,
-	"guojiadili": app.Guojiadili,
-	"historytoday": app.HistoryToday,
-	"hupu":       app.Hupu,
-	"ithome":     app.Ithome,
-	"lishipin":   app.Lishipin,
-	"pengpai":    app.Pengpai,
-	"qqnews":     app.Qqnews,
-	"shaoshupai": app.Shaoshupai,
-	"sougou":     app.Sougou,
-	"toutiao":    app.Toutiao,
-	"v2ex":       app.V2ex,
-	"wangyinews": app.WangyiNews,
-	"weibo":      app.WeiboHot,
-	"xinjingbao": app.Xinjingbao,
-	"zhihu":      app.Zhihu,
-	"quark":      app.Quark,
-	"kuake":      app.Quark,
-	"souhu":      app.Souhu,
-	"baidu":      app.Baidu,
-	"renmin":     app.Renminwang,
-	"nanfang":    app.Nanfangzhoumo,
-	"360doc":     app.Doc360,
-	"cctv":       app.CCTV,
-}
-
-// FetchDataFromAPI 根据来源获取API数据
-func (s *HotSearchService) FetchDataFromAPI(source string) (map[string]interface{}, error) {
-	// 检查映射中是否存在对应的函数
-	if fn, exists := apiFunctionMap[source]; exists {
-		return fn()
-	}
-	
 	// 默认返回空结果
-	return map[string]interface{}{
-		"code":    200,
-		"message": source,
-		"obj":     []map[string]interface{}{},
-	}, nil
+	return app.NewResult(source, nil), nil
 }
 
-// convertSourceDataToHotSearchItems 将源数据转换为HotSearchItem
-func (s *HotSearchService) convertSourceDataToHotSearchItems(sourceData interface{}) []model.HotSearchItem {
-	var hotSearchItems []model.HotSearchItem
-
-	// 检查是否为 []interface{} 类型
-	if items, ok := sourceData.([]interface{}); ok {
-		for _, item := range items {
-			if itemMap, ok := item.(map[string]interface{}); ok {
-				title, _ := itemMap["title"].(string)
-				url, _ := itemMap["url"].(string)
-				index, _ := itemMap["index"].(float64)
-
-				hotSearchItems = append(hotSearchItems, model.HotSearchItem{
-					Title: title,
-					URL:   url,
-					Index: int(index),
-				})
-			}
-		}
-	} else if items, ok := sourceData.([]map[string]interface{}); ok {
-		// 检查是否为 []map[string]interface{} 类型
-		for _, item := range items {
-			title, _ := item["title"].(string)
-			url, _ := item["url"].(string)
-			index, _ := item["index"].(float64)
-
-			hotSearchItems = append(hotSearchItems, model.HotSearchItem{
-				Title: title,
-				URL:   url,
-				Index: int(index),
-			})
-		}
+// convertToHotSearchItems 将抓取到的数据转换为HotSearchItem
+func (s *HotSearchService) convertToHotSearchItems(items []app.Item) []model.HotSearchItem {
+	hotSearchItems := make([]model.HotSearchItem, 0, len(items))
+	for _, item := range items {
+		hotSearchItems = append(hotSearchItems, model.HotSearchItem{
+			Title:    item.Title,
+			URL:      item.URL,
+			Index:    item.Index,
+			HotScore: item.HotScore,
+			HotValue: item.HotValue,
+			Desc:     item.Desc,
+			Image:    item.Image,
+			Extra:    model.Extra(item.Extra),
+		})
 	}
-
 	return hotSearchItems
 }
 
-// convertToHotSearchItems 将API返回的数据转换为HotSearchItem
-func (s *HotSearchService) convertToHotSearchItems(apiResult map[string]interface{}) []model.HotSearchItem {
-	var items []model.HotSearchItem
-
-	// 尝试不同类型的 obj 数据格式
-	if obj, ok := apiResult["obj"].([]interface{}); ok {
-		for _, item := range obj {
-			if itemMap, ok := item.(map[string]interface{}); ok {
-				title, _ := itemMap["title"].(string)
-				url, _ := itemMap["url"].(string)
-				index, _ := itemMap["index"].(float64)
-
-				items = append(items, model.HotSearchItem{
-					Title: title,
-					URL:   url,
-					Index: int(index),
-				})
-			}
-		}
-	} else if obj, ok := apiResult["obj"].([]map[string]interface{}); ok {
-		for _, item := range obj {
-			title, _ := item["title"].(string)
-			url, _ := item["url"].(string)
-			index, _ := item["index"].(float64)
-
-			items = append(items, model.HotSearchItem{
-				Title: title,
-				URL:   url,
-				Index: int(index),
-			})
-		}
+// convertFromDBItems 将数据库中的数据转换为API返回的数据项
+func (s *HotSearchService) convertFromDBItems(items []model.HotSearchItem) []app.Item {
+	obj := make([]app.Item, 0, len(items))
+	for _, item := range items {
+		obj = append(obj, app.Item{
+			Index:    item.Index,
+			Title:    item.Title,
+			URL:      item.URL,
+			HotValue: item.HotValue,
+			HotScore: item.HotScore,
+			Desc:     item.Desc,
+			Image:    item.Image,
+			Extra:    item.Extra,
+		})
 	}
-
-	return items
+	return obj
 }
 
-// convertFromDBItems 将数据库中的数据转换为API返回格式
-func (s *HotSearchService) convertFromDBItems(items []model.HotSearchItem, source string) map[string]interface{} {
-	var obj []map[string]interface{}
-
-	for _, item := range items {
-		obj = append(obj, map[string]interface{}{
-			"index": item.Index,
-			"title": item.Title,
-			"url":   item.URL,
-		})
+// convertToResult 将数据库中的一个快照转换为抓取结果，抓取时间取快照的写入时间
+func (s *HotSearchService) convertToResult(source string, items []model.HotSearchItem) *app.Result {
+	result := &app.Result{
+		Source: source,
+		Items:  s.convertFromDBItems(items),
 	}
+	if len(items) > 0 {
+		result.FetchedAt = items[0].CreatedAt
+	}
+	return result
+}
 
+// convertToHistoryResult 将历史数据转换为API返回格式
+func (s *HotSearchService) convertToHistoryResult(items []model.HotSearchItem, source string) map[string]interface{} {
 	return map[string]interface{}{
 		"code":    200,
-		"message": source,
-		"obj":     obj,
+		"message": source + "历史数据",
+		"obj":     s.convertFromDBItems(items),
 	}
 }
 
-// convertFromAllDBItems 将所有数据库数据转换为API返回格式
-func (s *HotSearchService) convertFromAllDBItems(data map[string][]model.HotSearchItem) map[string]interface{} {
-	allObj := make(map[string]interface{})
-
-	for source, items := range data {
-		var obj []map[string]interface{}
-		for _, item := range items {
-			obj = append(obj, map[string]interface{}{
-				"index": item.Index,
-				"title": item.Title,
-				"url":   item.URL,
-			})
-		}
-		allObj[source] = obj
-	}
-
-	return map[string]interface{}{
-		"code": 200,
-		"obj":  allObj,
+// convertHourlyItems 将按小时分组的历史数据转换为以 "HH:00" 为键的格式
+func (s *HotSearchService) convertHourlyItems(data map[int][]model.HotSearchItem) map[string][]app.Item {
+	result := make(map[string][]app.Item, len(data))
+	for hour, items := range data {
+		result[fmt.Sprintf("%02d:00", hour)] = s.convertFromDBItems(items)
 	}
+	return result
 }
 
 // GetHistoricalDataHandler 获取指定日期和小时的历史数据
@@ -356,7 +250,7 @@ func (s *HotSearchService) GetHistoricalDataHandler(c *fiber.Ctx) error {
 	}
 
 	// 从数据库数据构建返回结果
-	result := s.convertFromDBItems(items, source+"历史数据")
+	result := s.convertToHistoryResult(items, source)
 	return c.JSON(result)
 }
 
@@ -389,18 +283,7 @@ func (s *HotSearchService) GetHistoricalDataByDateHandler(c *fiber.Ctx) error {
 	}
 
 	// 转换数据格式
-	result := make(map[string]interface{})
-	for hour, items := range data {
-		var obj []map[string]interface{}
-		for _, item := range items {
-			obj = append(obj, map[string]interface{}{
-				"index": item.Index,
-				"title": item.Title,
-				"url":   item.URL,
-			})
-		}
-		result[fmt.Sprintf("%02d:00", hour)] = obj
-	}
+	result := s.convertHourlyItems(data)
 
 	return c.JSON(fiber.Map{
 		"code": 200,
@@ -435,21 +318,9 @@ func (s *HotSearchService) GetHistoricalDataBySourceHandler(c *fiber.Ctx) error
 	}
 
 	// 转换数据格式
-	result := make(map[string]interface{})
+	result := make(map[string]map[string][]app.Item, len(data))
 	for date, hoursData := range data {
-		hoursResult := make(map[string]interface{})
-		for hour, items := range hoursData {
-			var obj []map[string]interface{}
-			for _, item := range items {
-				obj = append(obj, map[string]interface{}{
-					"index": item.Index,
-					"title": item.Title,
-					"url":   item.URL,
-				})
-			}
-			hoursResult[fmt.Sprintf("%02d:00", hour)] = obj
-		}
-		result[date] = hoursResult
+		result[date] = s.convertHourlyItems(hoursData)
 	}
 
 	return c.JSON(fiber.Map{
@@ -458,46 +329,49 @@ func (s *HotSearchService) GetHistoricalDataBySourceHandler(c *fiber.Ctx) error
 	})
 }
 
-// convertRouteNameToDBSource 将路由名称转换为数据库中存储的源名称
+// convertRouteNameToDBSource 将路由名称或别名转换为数据库中存储的源名称
 func (s *HotSearchService) convertRouteNameToDBSource(routeName string) string {
-	// 现在直接返回路由名称，不再转换为中文名称
+	// 数据库中直接使用路由名称，别名（如 kuake）转换为对应的路由名称
+	if source, ok := app.LookupSource(routeName); ok {
+		return source.RouteName
+	}
 	return routeName
 }
 
 // GetRouteNames 获取所有可用的路由名称列表
 func (s *HotSearchService) GetRouteNames() []string {
-	return []string{
-		"360search",
-		"bilibili",
-		"acfun",
-		"csdn",
-		"dongqiudi",
-		"douban",
-		"douyin",
-		"github",
-		"guojiadili",
-		"history",
-		"hupu",
-		"ithome",
-		"lishipin",
-		"pengpai",
-		"qqnews",
-		"shaoshupai",
-		"sougou",
-		"toutiao",
-		"v2ex",
-		"wangyinews",
-		"weibo",
-		"xinjingbao",
-		"zhihu",
-		"quark",
-		"souhu",
-		"baidu",
-		"renmin",
-		"nanfang",
-		"360doc",
-		"cctv",
+	return app.GetAllRouteNames()
+}
+
+// GetLatestSnapshot 获取指定来源最新保存的快照，不会临时抓取，没有数据时返回 nil
+func (s *HotSearchService) GetLatestSnapshot(source string) (*app.Result, error) {
+	snapshot, err := db.GetSnapshotAt(s.convertRouteNameToDBSource(source), time.Time{})
+	if err != nil || snapshot == nil {
+		return nil, err
 	}
+	return s.convertSnapshotToResult(source, snapshot), nil
+}
+
+// GetHourlySnapshot 获取指定来源在指定日期和小时保存的快照，同一小时有多次快照时取最新的一次，没有数据时返回 nil
+func (s *HotSearchService) GetHourlySnapshot(source, date string, hour int) (*app.Result, error) {
+	items, err := db.GetHistoricalData(s.convertRouteNameToDBSource(source), date, hour)
+	if err != nil || len(items) == 0 {
+		return nil, err
+	}
+	return s.convertToResult(source, items), nil
+}
+
+// GetDailySnapshots 获取指定来源在指定日期每个小时的快照，键为小时，每个小时取最新的一次，没有数据时返回空 map
+func (s *HotSearchService) GetDailySnapshots(source, date string) (map[int]*app.Result, error) {
+	data, err := db.GetHistoricalDataByDate(s.convertRouteNameToDBSource(source), date)
+	if err != nil {
+		return nil, err
+	}
+	results := make(map[int]*app.Result, len(data))
+	for hour, items := range data {
+		results[hour] = s.convertToResult(source, items)
+	}
+	return results, nil
 }
 
 // GetHistoricalDataForWS 获取指定日期和小时的历史数据用于WebSocket
@@ -532,7 +406,7 @@ func (s *HotSearchService) GetHistoricalDataForWS(source, date, hourParam string
 	}
 
 	// 从数据库数据构建返回结果
-	result := s.convertFromDBItems(items, source+"历史数据")
+	result := s.convertToHistoryResult(items, source)
 	return result, nil
 }
 
@@ -551,18 +425,7 @@ func (s *HotSearchService) GetHistoricalDataByDateForWS(source, date string) (ma
 	}
 
 	// 转换数据格式
-	result := make(map[string]interface{})
-	for hour, items := range data {
-		var obj []map[string]interface{}
-		for _, item := range items {
-			obj = append(obj, map[string]interface{}{
-				"index": item.Index,
-				"title": item.Title,
-				"url":   item.URL,
-			})
-		}
-		result[fmt.Sprintf("%02d:00", hour)] = obj
-	}
+	result := s.convertHourlyItems(data)
 
 	return map[string]interface{}{
 		"code": 200,
@@ -585,21 +448,9 @@ func (s *HotSearchService) GetHistoricalDataBySourceForWS(source string) (map[st
 	}
 
 	// 转换数据格式
-	result := make(map[string]interface{})
+	result := make(map[string]map[string][]app.Item, len(data))
 	for date, hoursData := range data {
-		hoursResult := make(map[string]interface{})
-		for hour, items := range hoursData {
-			var obj []map[string]interface{}
-			for _, item := range items {
-				obj = append(obj, map[string]interface{}{
-					"index": item.Index,
-					"title": item.Title,
-					"url":   item.URL,
-				})
-			}
-			hoursResult[fmt.Sprintf("%02d:00", hour)] = obj
-		}
-		result[date] = hoursResult
+		result[date] = s.convertHourlyItems(hoursData)
 	}
 
 	return map[string]interface{}{
@@ -0,0 +1,37 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// Shutdown 停止定时抓取和快照清理任务，并等待正在进行的抓取和后台的数据库写入完成
+//
+// ctx 到期时不再等待，返回 ctx 的错误
+func (s *HotSearchService) Shutdown(ctx context.Context) error {
+	s.jobsMu.RLock()
+	stopScheduler, stopRetention := s.stopScheduler, s.stopRetention
+	s.jobsMu.RUnlock()
+	if stopScheduler != nil {
+		stopScheduler()
+	}
+	if stopRetention != nil {
+		stopRetention()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.schedulerWG.Wait()
+		s.background.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		slog.Info("定时任务已停止，数据库写入已完成")
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("等待定时抓取和数据库写入完成超时: %w", ctx.Err())
+	}
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"api/app"
+	"api/config"
+	"api/db"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 测试Shutdown等待正在进行的定时抓取完成后才返回
+func TestShutdown(t *testing.T) {
+	db.InitSQLiteWithDSN("file:" + t.Name() + "?mode=memory&cache=shared")
+	service := &HotSearchService{}
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	setLiveTestFetch(func(ctx context.Context) (*app.Result, error) {
+		close(started)
+		<-release
+		return app.NewResult("service_test_live", []app.Item{
+			{Index: 1, Title: "A", URL: "http://example.com/a"},
+		}), nil
+	})
+	defer setLiveTestFetch(nil)
+
+	service.StartScheduler(context.Background(), config.ScheduleConfig{Interval: time.Hour})
+	service.StartRetention(config.RetentionConfig{Enabled: true, Interval: time.Hour})
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("定时任务没有在启动后抓取数据")
+	}
+
+	// 抓取还没有完成，等待超时
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := service.Shutdown(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// 抓取完成后保存到数据库，Shutdown 正常返回
+	close(release)
+	ctx, cancel = context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	assert.NoError(t, service.Shutdown(ctx))
+
+	items, err := db.GetLatestData("service_test_live")
+	assert.NoError(t, err)
+	assert.Len(t, items, 1)
+}
+
+// 测试没有启动任何任务时Shutdown立即返回
+func TestShutdownNotStarted(t *testing.T) {
+	service := &HotSearchService{}
+	assert.NoError(t, service.Shutdown(context.Background()))
+}
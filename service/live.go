@@ -0,0 +1,165 @@
+package service
+
+import (
+	"api/all"
+	"api/app"
+	"api/config"
+	"api/db"
+	"api/logging"
+	"context"
+	"fmt"
+	"time"
+)
+
+// SetLiveConfig 设置实时接口的配置，需要在处理请求之前调用
+func (s *HotSearchService) SetLiveConfig(cfg config.LiveConfig) {
+	s.live = cfg
+}
+
+// liveFetch 一次实时抓取的结果
+type liveFetch struct {
+	result *app.Result
+	err    error
+}
+
+// GetLive 获取指定数据源的实时数据
+//
+// 启用 stale 模式时，上游失败、结果未通过检查或在 StaleTimeout 内没有返回，则返回数据库中保存的快照并标记为 stale；
+// 超时的抓取在后台继续完成，成功后保存到数据库，之后的请求可以获取更新的快照。
+// 获取到通过检查的实时数据时发布 Scheduled 为 false 的事件
+func (s *HotSearchService) GetLive(ctx context.Context, source string) (*app.Result, error) {
+	src, ok := app.LookupSource(source)
+	if !ok {
+		return nil, fmt.Errorf("数据源 %s 不存在", source)
+	}
+	ctx = logging.With(ctx, logging.KeySource, src.RouteName)
+	if !s.live.StaleEnabled {
+		result, err := app.Fetch(ctx, src)
+		if err == nil {
+			s.publishOnDemand(ctx, src.RouteName, result)
+		}
+		return result, err
+	}
+
+	// 请求结束后抓取仍然继续，超时时间由抓取器按数据源控制
+	done := make(chan liveFetch, 1)
+	go func() {
+		result, err := app.Fetch(context.WithoutCancel(ctx), src)
+		done <- liveFetch{result: result, err: err}
+	}()
+
+	// 没有设置超时时间时只在上游失败时返回快照
+	var timeout <-chan time.Time
+	if s.live.StaleTimeout > 0 {
+		timer := time.NewTimer(s.live.StaleTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	var fetched *liveFetch
+	select {
+	case f := <-done:
+		if f.err == nil && !f.result.Degraded {
+			s.publishOnDemand(ctx, src.RouteName, f.result)
+			return f.result, nil
+		}
+		fetched = &f
+	case <-timeout:
+	case <-ctx.Done():
+	}
+
+	// 上游失败或结果未通过检查时优先返回快照
+	if stale := s.staleSnapshot(ctx, source); stale != nil {
+		logger := logging.FromContext(ctx)
+		switch {
+		case fetched == nil:
+			logger.Warn("获取数据超时，返回已保存的快照并在后台刷新", "timeout", s.live.StaleTimeout)
+			s.background.Add(1)
+			go func() {
+				defer s.background.Done()
+				s.refreshInBackground(context.WithoutCancel(ctx), src.RouteName, done)
+			}()
+		case fetched.err != nil:
+			logger.Warn("获取数据失败，返回已保存的快照", logging.KeyError, fetched.err)
+		default:
+			logger.Warn("抓取结果未通过检查，返回已保存的快照")
+		}
+		return stale, nil
+	}
+	if fetched != nil {
+		return fetched.result, fetched.err
+	}
+
+	// 没有可用的快照时继续等待上游返回
+	select {
+	case fetched := <-done:
+		if fetched.err == nil {
+			s.publishOnDemand(ctx, src.RouteName, fetched.result)
+		}
+		return fetched.result, fetched.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// GetAllLive 获取所有平台的实时数据，启用 stale 模式时没有获取到的平台使用数据库中保存的快照
+func (s *HotSearchService) GetAllLive(ctx context.Context) map[string]*app.Result {
+	results := all.All(ctx)
+	if !s.live.StaleEnabled {
+		return results
+	}
+
+	for _, source := range app.Sources() {
+		if _, ok := results[source.RouteName]; ok || !app.Enabled(source.RouteName) {
+			continue
+		}
+		if stale := s.staleSnapshot(logging.With(ctx, logging.KeySource, source.RouteName), source.RouteName); stale != nil {
+			results[source.RouteName] = stale
+		}
+	}
+	return results
+}
+
+// staleSnapshot 获取数据源最新保存的快照，没有快照或快照超过 StaleMaxAge 时返回 nil
+func (s *HotSearchService) staleSnapshot(ctx context.Context, source string) *app.Result {
+	items, err := db.GetLatestData(s.convertRouteNameToDBSource(source))
+	if err != nil {
+		logging.FromContext(ctx).Error("从数据库获取快照失败", logging.KeyError, err)
+		return nil
+	}
+	if len(items) == 0 {
+		return nil
+	}
+
+	result := s.convertToResult(source, items)
+	if s.live.StaleMaxAge > 0 && time.Since(result.FetchedAt) > s.live.StaleMaxAge {
+		return nil
+	}
+	result.Stale = true
+	return result
+}
+
+// refreshInBackground 等待后台抓取完成，成功且通过检查后保存到数据库
+//
+// 保存后用新数据替换缓存中的快照，并通知订阅者
+func (s *HotSearchService) refreshInBackground(ctx context.Context, source string, done <-chan liveFetch) {
+	fetched := <-done
+	if fetched.err != nil {
+		logging.FromContext(ctx).Error("后台刷新数据失败", logging.KeyError, fetched.err)
+		return
+	}
+	result := fetched.result
+	if result.Degraded {
+		return
+	}
+
+	diff := s.diffWithLatest(ctx, source, result)
+	dbSource := s.convertRouteNameToDBSource(source)
+	if err := db.SaveDataAt(dbSource, s.convertToHotSearchItems(result.Items), result.FetchedAt); err != nil {
+		logging.FromContext(ctx).Error("后台刷新后保存数据到数据库失败", logging.KeyError, err)
+		return
+	}
+
+	s.storeCached(source, result, time.Now())
+	s.publish(FetchEvent{Source: source, Result: result, Saved: true, Diff: diff})
+}
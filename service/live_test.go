@@ -0,0 +1,163 @@
+package service
+
+import (
+	"api/app"
+	"api/config"
+	"api/db"
+	"api/model"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	liveTestMu    sync.Mutex
+	liveTestFetch func(ctx context.Context) (*app.Result, error)
+)
+
+func init() {
+	// 用于测试实时接口的数据源，抓取行为由各个测试设置
+	app.Register(app.Source{
+		RouteName: "service_test_live",
+		Fetch: func(ctx context.Context, f *app.Fetcher) (*app.Result, error) {
+			liveTestMu.Lock()
+			fetch := liveTestFetch
+			liveTestMu.Unlock()
+			// 其他测试启动定时任务时也会抓取该数据源
+			if fetch == nil {
+				return nil, errors.New("未设置抓取行为")
+			}
+			return fetch(ctx)
+		},
+		Expect: &app.Expectations{MinItems: 1},
+	})
+}
+
+// setLiveTestFetch 设置测试数据源的抓取行为
+func setLiveTestFetch(fetch func(ctx context.Context) (*app.Result, error)) {
+	liveTestMu.Lock()
+	defer liveTestMu.Unlock()
+	liveTestFetch = fetch
+}
+
+// 测试GetLive方法
+func TestGetLive(t *testing.T) {
+	db.InitSQLiteWithDSN("file:" + t.Name() + "?mode=memory&cache=shared")
+	service := &HotSearchService{}
+
+	setLiveTestFetch(func(ctx context.Context) (*app.Result, error) {
+		return nil, errors.New("请求失败")
+	})
+
+	// 未启用 stale 模式时直接返回错误
+	_, err := service.GetLive(context.Background(), "service_test_live")
+	assert.EqualError(t, err, "请求失败")
+
+	// 没有保存的快照时返回错误
+	service.SetLiveConfig(config.LiveConfig{StaleEnabled: true, StaleTimeout: time.Second, StaleMaxAge: time.Hour})
+	_, err = service.GetLive(context.Background(), "service_test_live")
+	assert.EqualError(t, err, "请求失败")
+
+	// 上游失败时返回保存的快照
+	savedAt := time.Now().Add(-10 * time.Minute)
+	assert.NoError(t, db.SaveDataAt("service_test_live", []model.HotSearchItem{
+		{Index: 1, Title: "Saved", URL: "http://example.com/saved"},
+	}, savedAt))
+	result, err := service.GetLive(context.Background(), "service_test_live")
+	assert.NoError(t, err)
+	assert.True(t, result.Stale)
+	assert.Equal(t, "Saved", result.Items[0].Title)
+	assert.InDelta(t, 600, result.Age(), 1)
+
+	// 快照超过最大年龄时不再返回
+	service.SetLiveConfig(config.LiveConfig{StaleEnabled: true, StaleTimeout: time.Second, StaleMaxAge: time.Minute})
+	_, err = service.GetLive(context.Background(), "service_test_live")
+	assert.EqualError(t, err, "请求失败")
+
+	// 上游正常时返回实时数据
+	setLiveTestFetch(func(ctx context.Context) (*app.Result, error) {
+		return app.NewResult("service_test_live", []app.Item{{Index: 1, Title: "Live", URL: "http://example.com/live"}}), nil
+	})
+	result, err = service.GetLive(context.Background(), "service_test_live")
+	assert.NoError(t, err)
+	assert.False(t, result.Stale)
+	assert.Equal(t, "Live", result.Items[0].Title)
+
+	// 结果未通过检查时返回快照，没有可用的快照时返回标记为 degraded 的结果
+	setLiveTestFetch(func(ctx context.Context) (*app.Result, error) {
+		return app.NewResult("service_test_live", []app.Item{{Index: 1, Title: "", URL: "/relative"}}), nil
+	})
+	result, err = service.GetLive(context.Background(), "service_test_live")
+	assert.NoError(t, err)
+	assert.True(t, result.Degraded)
+	assert.False(t, result.Stale)
+	service.SetLiveConfig(config.LiveConfig{StaleEnabled: true, StaleTimeout: time.Second, StaleMaxAge: time.Hour})
+	result, err = service.GetLive(context.Background(), "service_test_live")
+	assert.NoError(t, err)
+	assert.True(t, result.Stale)
+	assert.Equal(t, "Saved", result.Items[0].Title)
+
+	_, err = service.GetLive(context.Background(), "not_exists")
+	assert.Error(t, err)
+}
+
+// 测试上游超时时返回快照并在后台刷新
+func TestGetLiveRefreshInBackground(t *testing.T) {
+	db.InitSQLiteWithDSN("file:" + t.Name() + "?mode=memory&cache=shared")
+	service := &HotSearchService{}
+	service.SetLiveConfig(config.LiveConfig{StaleEnabled: true, StaleTimeout: 20 * time.Millisecond})
+	service.SetCacheConfig(config.CacheConfig{Enabled: true, TTL: time.Minute})
+	events := make(chan FetchEvent, 1)
+	service.Subscribe(func(event FetchEvent) { events <- event })
+
+	assert.NoError(t, db.SaveDataAt("service_test_live", []model.HotSearchItem{
+		{Index: 1, Title: "Saved", URL: "http://example.com/saved"},
+	}, time.Now().Add(-time.Hour)))
+
+	setLiveTestFetch(func(ctx context.Context) (*app.Result, error) {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(200 * time.Millisecond):
+		}
+		return app.NewResult("service_test_live", []app.Item{{Index: 1, Title: "Refreshed", URL: "http://example.com/refreshed"}}), nil
+	})
+
+	// 请求结束后后台抓取不会被取消
+	ctx, cancel := context.WithCancel(context.Background())
+	start := time.Now()
+	result, _, err := service.GetLiveCached(ctx, "service_test_live")
+	cancel()
+	assert.NoError(t, err)
+	assert.Less(t, time.Since(start), 200*time.Millisecond)
+	assert.True(t, result.Stale)
+	assert.Equal(t, "Saved", result.Items[0].Title)
+
+	// 后台抓取完成后保存到数据库
+	assert.Eventually(t, func() bool {
+		items, err := db.GetLatestData("service_test_live")
+		return err == nil && len(items) == 1 && items[0].Title == "Refreshed"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// 保存后通知订阅者，缓存中的快照被替换
+	select {
+	case event := <-events:
+		assert.True(t, event.Saved)
+		assert.False(t, event.Scheduled)
+		assert.Equal(t, "Refreshed", event.Result.Items[0].Title)
+		if assert.NotNil(t, event.Diff) {
+			assert.Len(t, event.Diff.Added, 1)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("后台刷新后没有发布事件")
+	}
+	result, info, err := service.GetLiveCached(context.Background(), "service_test_live")
+	assert.NoError(t, err)
+	assert.True(t, info.Hit)
+	assert.False(t, result.Stale)
+	assert.Equal(t, "Refreshed", result.Items[0].Title)
+}
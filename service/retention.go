@@ -0,0 +1,65 @@
+package service
+
+import (
+	"api/config"
+	"api/db"
+	"api/logging"
+	"context"
+	"log/slog"
+	"time"
+)
+
+// pruneSnapshots 按保留策略清理一次历史快照
+func (s *HotSearchService) pruneSnapshots(policy db.RetentionPolicy) {
+	result, err := db.PruneSnapshots(policy, time.Now())
+	if err != nil {
+		slog.Error("清理历史快照失败", logging.KeyError, err)
+		return
+	}
+
+	if result.SnapshotsDeleted == 0 {
+		slog.Info("清理历史快照完成，没有需要删除的快照")
+		return
+	}
+	for source, count := range result.BySource {
+		slog.Info("清理历史快照", logging.KeySource, source, "snapshots", count)
+	}
+	slog.Info("清理历史快照完成", "snapshots", result.SnapshotsDeleted, "items", result.ItemsDeleted)
+}
+
+// StartRetention 在后台启动历史快照清理任务，启动后立即清理一次，调用 Shutdown 后停止
+func (s *HotSearchService) StartRetention(cfg config.RetentionConfig) {
+	if !cfg.Enabled {
+		slog.Info("历史快照清理任务未启用")
+		return
+	}
+
+	policy := db.NewRetentionPolicy(cfg)
+	interval := cfg.Interval
+	if interval <= 0 {
+		interval = time.Hour
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.jobsMu.Lock()
+	s.stopRetention = cancel
+	s.jobsMu.Unlock()
+
+	s.background.Add(1)
+	go func() {
+		defer s.background.Done()
+		// 立即执行一次，积压的快照较多时也不会推迟服务启动
+		s.pruneSnapshots(policy)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.pruneSnapshots(policy)
+			}
+		}
+	}()
+}
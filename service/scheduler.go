@@ -0,0 +1,344 @@
+package service
+
+import (
+	"api/app"
+	"api/config"
+	"api/db"
+	"api/logging"
+	"api/metrics"
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ScheduleStatus 单个数据源的定时抓取状态
+type ScheduleStatus struct {
+	Source       string     `json:"source"`
+	Interval     string     `json:"interval,omitempty"`     // 抓取间隔，使用 cron 时为空
+	Cron         string     `json:"cron,omitempty"`         // cron 表达式
+	Jitter       string     `json:"jitter,omitempty"`       // 随机延迟的上限
+	Disabled     bool       `json:"disabled,omitempty"`     // 数据源是否已停用，停用期间跳过抓取
+	Running      bool       `json:"running"`                // 是否正在抓取
+	NextRun      *time.Time `json:"nextRun,omitempty"`      // 下一次抓取时间
+	LastRun      *time.Time `json:"lastRun,omitempty"`      // 上一次抓取开始时间
+	LastDuration string     `json:"lastDuration,omitempty"` // 上一次抓取耗时
+	LastError    string     `json:"lastError,omitempty"`    // 上一次抓取的错误信息
+	LastCount    int        `json:"lastCount"`              // 上一次抓取到的条目数
+}
+
+// scheduledJob 单个数据源的定时抓取任务
+type scheduledJob struct {
+	source app.Source
+	reset  chan struct{} // 抓取计划更新后通知任务重新计算下一次抓取时间
+
+	mu           sync.Mutex
+	interval     time.Duration
+	jitter       time.Duration
+	cronExpr     string
+	cron         *config.CronSchedule
+	running      bool
+	nextRun      time.Time
+	lastRun      time.Time
+	lastDuration time.Duration
+	lastError    string
+	lastCount    int
+}
+
+// next 根据抓取计划计算 from 之后的下一次抓取时间，并加上随机延迟
+func (j *scheduledJob) next(from time.Time) time.Time {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	var next time.Time
+	if j.cron != nil {
+		next = j.cron.Next(from)
+		if next.IsZero() {
+			return next
+		}
+	} else {
+		next = from.Add(j.interval)
+	}
+	if j.jitter > 0 {
+		next = next.Add(rand.N(j.jitter))
+	}
+	return next
+}
+
+// status 获取任务的当前状态
+func (j *scheduledJob) status() ScheduleStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	status := ScheduleStatus{
+		Source:    j.source.RouteName,
+		Cron:      j.cronExpr,
+		Disabled:  !app.Enabled(j.source.RouteName),
+		Running:   j.running,
+		LastError: j.lastError,
+		LastCount: j.lastCount,
+	}
+	if j.cron == nil {
+		status.Interval = j.interval.String()
+	}
+	if j.jitter > 0 {
+		status.Jitter = j.jitter.String()
+	}
+	if !j.nextRun.IsZero() {
+		nextRun := j.nextRun
+		status.NextRun = &nextRun
+	}
+	if !j.lastRun.IsZero() {
+		lastRun := j.lastRun
+		status.LastRun = &lastRun
+		status.LastDuration = j.lastDuration.String()
+	}
+	return status
+}
+
+// newScheduledJob 根据配置创建数据源的定时抓取任务
+func newScheduledJob(source app.Source, cfg config.ScheduleConfig) *scheduledJob {
+	job := &scheduledJob{
+		source: source,
+		reset:  make(chan struct{}, 1),
+	}
+	job.setPlan(cfg)
+	return job
+}
+
+// setPlan 根据配置设置抓取计划，cron 表达式无效时使用默认间隔
+func (j *scheduledJob) setPlan(cfg config.ScheduleConfig) {
+	plan := cfg.For(j.source.RouteName)
+	interval, jitter := plan.Interval, plan.Jitter
+	var cron *config.CronSchedule
+	if plan.Cron != "" {
+		var err error
+		cron, err = config.ParseCron(plan.Cron)
+		if err != nil {
+			slog.Error("cron 表达式无效，使用默认间隔", logging.KeySource, j.source.RouteName, "cron", plan.Cron, logging.KeyError, err)
+			interval = cfg.Interval
+		}
+	}
+	if cron == nil && interval <= 0 {
+		interval = time.Hour
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.interval = interval
+	j.jitter = jitter
+	j.cron = cron
+	j.cronExpr = ""
+	if cron != nil {
+		j.cronExpr = plan.Cron
+	}
+}
+
+// StartScheduler 启动定时抓取任务
+//
+// 每个数据源按自己的间隔或 cron 表达式独立抓取，启动后在随机延迟内各执行一次。
+// ctx 取消或调用 Shutdown 后停止，正在进行的抓取会继续完成
+func (s *HotSearchService) StartScheduler(ctx context.Context, cfg config.ScheduleConfig) {
+	ctx, cancel := context.WithCancel(ctx)
+	for name := range cfg.Sources {
+		if _, ok := app.LookupSource(name); !ok {
+			slog.Warn("定时抓取配置中的数据源不存在", logging.KeySource, name)
+		}
+	}
+
+	jobs := make(map[string]*scheduledJob)
+	for _, source := range app.Sources() {
+		jobs[source.RouteName] = newScheduledJob(source, cfg)
+	}
+
+	s.jobsMu.Lock()
+	s.jobs = jobs
+	s.stopScheduler = cancel
+	s.jobsMu.Unlock()
+
+	for _, job := range jobs {
+		s.schedulerWG.Add(1)
+		go s.runJob(ctx, job)
+	}
+	slog.Info("定时抓取任务已启动", "sources", len(jobs))
+}
+
+// WaitScheduler 等待所有定时抓取任务退出，需要先取消传给 StartScheduler 的 ctx
+func (s *HotSearchService) WaitScheduler() {
+	s.schedulerWG.Wait()
+}
+
+// UpdateSchedule 更新所有定时抓取任务的抓取计划，正在进行的抓取不受影响
+//
+// 各个任务从上一次抓取开始按新的计划重新计算下一次抓取时间，用于不重启服务重新加载配置
+func (s *HotSearchService) UpdateSchedule(cfg config.ScheduleConfig) {
+	s.jobsMu.RLock()
+	defer s.jobsMu.RUnlock()
+	for _, job := range s.jobs {
+		job.setPlan(cfg)
+		select {
+		case job.reset <- struct{}{}:
+		default:
+		}
+	}
+	slog.Info("定时抓取计划已更新", "sources", len(s.jobs))
+}
+
+// runJob 循环执行单个数据源的定时抓取
+func (s *HotSearchService) runJob(ctx context.Context, job *scheduledJob) {
+	defer s.schedulerWG.Done()
+
+	// 启动时立即抓取一次，加上随机延迟避免所有数据源同时请求
+	job.mu.Lock()
+	var delay time.Duration
+	if job.jitter > 0 {
+		delay = rand.N(job.jitter)
+	}
+	job.nextRun = time.Now().Add(delay)
+	job.mu.Unlock()
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	// 按抓取计划计算 from 之后的下一次抓取时间并重置定时器，没有下一次时返回 false
+	scheduleNext := func(from time.Time) bool {
+		next := job.next(from)
+		job.mu.Lock()
+		job.nextRun = next
+		job.mu.Unlock()
+		if next.IsZero() {
+			slog.Warn("cron 表达式没有下一次执行时间，停止定时抓取", logging.KeySource, job.source.RouteName, "cron", job.status().Cron)
+			return false
+		}
+		timer.Reset(time.Until(next))
+		return true
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-job.reset:
+			// 抓取计划更新后从上一次抓取开始重新计算，已经过了的时间点立即抓取；还没有抓取过时仍按启动时的时间抓取
+			job.mu.Lock()
+			lastRun := job.lastRun
+			job.mu.Unlock()
+			if lastRun.IsZero() {
+				continue
+			}
+			timer.Stop()
+			if !scheduleNext(lastRun) {
+				return
+			}
+			continue
+		case <-timer.C:
+		}
+
+		// 停止时不中断正在进行的抓取，保存完成后再退出；停用的数据源跳过本次抓取
+		if app.Enabled(job.source.RouteName) {
+			s.runJobOnce(context.WithoutCancel(ctx), job)
+		}
+		if !scheduleNext(time.Now()) {
+			return
+		}
+	}
+}
+
+// runJobOnce 执行一次定时抓取并记录结果
+func (s *HotSearchService) runJobOnce(ctx context.Context, job *scheduledJob) {
+	// 每次运行使用单独的运行ID，抓取和保存过程中的日志都会带上
+	ctx = logging.With(ctx, logging.KeySource, job.source.RouteName, logging.KeyRunID, logging.NewID())
+	start := time.Now()
+	job.mu.Lock()
+	job.running = true
+	job.lastRun = start
+	job.mu.Unlock()
+
+	count, err := s.fetchSource(ctx, job.source)
+
+	duration := time.Since(start)
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	metrics.SchedulerRuns.Inc(job.source.RouteName, result)
+	logger := logging.FromContext(ctx)
+	if err != nil {
+		logger.Error("定时抓取失败", logging.KeyDuration, duration, logging.KeyError, err)
+	} else {
+		logger.Info("定时抓取完成", logging.KeyDuration, duration, "count", count)
+	}
+	metrics.SchedulerRunDuration.Observe(duration.Seconds(), job.source.RouteName)
+
+	job.mu.Lock()
+	job.running = false
+	job.lastDuration = duration
+	job.lastCount = count
+	job.lastError = ""
+	if err != nil {
+		job.lastError = err.Error()
+	}
+	job.mu.Unlock()
+}
+
+// fetchSource 抓取单个数据源并保存到数据库，返回抓取到的条目数
+func (s *HotSearchService) fetchSource(ctx context.Context, source app.Source) (int, error) {
+	result, err := app.Fetch(ctx, source)
+	if err != nil {
+		return 0, err
+	}
+	// 未通过检查的结果可能是解析错误，不保存也不通知订阅者
+	if result.Degraded {
+		return len(result.Items), fmt.Errorf("抓取结果未通过检查，未保存: %s", strings.Join(result.Problems, "；"))
+	}
+
+	// 保存前先与上一次快照比较
+	diff := s.diffWithLatest(ctx, source.RouteName, result)
+
+	// 使用数据库源名称保存
+	dbSource := s.convertRouteNameToDBSource(source.RouteName)
+	if err := db.SaveDataAt(dbSource, s.convertToHotSearchItems(result.Items), result.FetchedAt); err != nil {
+		return len(result.Items), fmt.Errorf("保存到数据库失败: %w", err)
+	}
+
+	// 通知订阅者
+	s.publish(FetchEvent{Source: source.RouteName, Result: result, Scheduled: true, Saved: true, Diff: diff})
+	return len(result.Items), nil
+}
+
+// GetScheduleStatus 获取所有数据源的定时抓取状态，按路由名称排序
+func (s *HotSearchService) GetScheduleStatus() []ScheduleStatus {
+	s.jobsMu.RLock()
+	defer s.jobsMu.RUnlock()
+
+	statuses := make([]ScheduleStatus, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		statuses = append(statuses, job.status())
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Source < statuses[j].Source
+	})
+	return statuses
+}
+
+// GetScheduleHandler 获取定时抓取状态的HTTP处理器
+//
+//	@Summary		获取定时抓取状态
+//	@Description	获取每个数据源的抓取计划、上一次和下一次抓取时间
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	map[string]interface{}
+//	@Router			/admin/schedule [get]
+func (s *HotSearchService) GetScheduleHandler(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"code":    200,
+		"message": "schedule",
+		"obj":     s.GetScheduleStatus(),
+	})
+}
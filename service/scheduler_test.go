@@ -0,0 +1,301 @@
+package service
+
+import (
+	"api/app"
+	"api/config"
+	"api/db"
+	"api/logging"
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func init() {
+	// 用于测试定时抓取的数据源
+	app.Register(app.Source{
+		RouteName: "service_test_scheduled",
+		Fetch: func(ctx context.Context, f *app.Fetcher) (*app.Result, error) {
+			return app.NewResult("service_test_scheduled", []app.Item{
+				{Index: 1, Title: "A", URL: "http://example.com/a"},
+				{Index: 2, Title: "B", URL: "http://example.com/b"},
+			}), nil
+		},
+		Expect: &app.Expectations{MinItems: 1},
+	})
+}
+
+func TestNewScheduledJob(t *testing.T) {
+	source := app.Source{RouteName: "weibo"}
+	cfg := config.ScheduleConfig{
+		Interval: time.Hour,
+		Jitter:   time.Minute,
+		Sources: map[string]config.SourceSchedule{
+			"weibo":        {Interval: 5 * time.Minute},
+			"historytoday": {Cron: "5 0 * * *"},
+			"zhihu":        {Cron: "invalid"},
+		},
+	}
+
+	job := newScheduledJob(source, cfg)
+	assert.Equal(t, 5*time.Minute, job.interval)
+	assert.Equal(t, time.Minute, job.jitter)
+	assert.Nil(t, job.cron)
+	now := time.Now()
+	next := job.next(now)
+	assert.False(t, next.Before(now.Add(5*time.Minute)))
+	assert.True(t, next.Before(now.Add(6*time.Minute)))
+
+	job = newScheduledJob(app.Source{RouteName: "historytoday"}, cfg)
+	assert.NotNil(t, job.cron)
+	assert.Equal(t, "5 0 * * *", job.status().Cron)
+	assert.Empty(t, job.status().Interval)
+
+	// cron 表达式无效时使用默认间隔
+	job = newScheduledJob(app.Source{RouteName: "zhihu"}, cfg)
+	assert.Nil(t, job.cron)
+	assert.Equal(t, time.Hour, job.interval)
+}
+
+// 测试定时抓取单个数据源
+func TestFetchSource(t *testing.T) {
+	db.InitSQLiteWithDSN("file:" + t.Name() + "?mode=memory&cache=shared")
+	service := &HotSearchService{}
+
+	var events []FetchEvent
+	unsubscribe := service.Subscribe(func(event FetchEvent) {
+		events = append(events, event)
+	})
+	defer unsubscribe()
+
+	source, _ := app.LookupSource("service_test_scheduled")
+	count, err := service.fetchSource(context.Background(), source)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	// 抓取结果保存到数据库并发布定时抓取事件
+	items, err := db.GetLatestData("service_test_scheduled")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(items))
+	assert.Equal(t, 1, len(events))
+	assert.True(t, events[0].Scheduled)
+	assert.Nil(t, events[0].Diff)
+
+	// 再次抓取时与上一次快照比较
+	_, err = service.fetchSource(context.Background(), source)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(events))
+	assert.NotNil(t, events[1].Diff)
+	assert.True(t, events[1].Diff.Empty())
+}
+
+// 测试定时抓取的日志带有数据源和运行ID
+func TestRunJobOnceLogging(t *testing.T) {
+	db.InitSQLiteWithDSN("file:" + t.Name() + "?mode=memory&cache=shared")
+	service := &HotSearchService{}
+
+	var buf bytes.Buffer
+	ctx := logging.NewContext(context.Background(), slog.New(slog.NewTextHandler(&buf, nil)))
+	source, _ := app.LookupSource("service_test_scheduled")
+	service.runJobOnce(ctx, newScheduledJob(source, config.ScheduleConfig{Interval: time.Hour}))
+	service.runJobOnce(ctx, newScheduledJob(source, config.ScheduleConfig{Interval: time.Hour}))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 2)
+	pattern := regexp.MustCompile(`msg=定时抓取完成 source=service_test_scheduled run_id=([0-9a-f]{16}) duration=\S+ count=2`)
+	first := pattern.FindStringSubmatch(lines[0])
+	second := pattern.FindStringSubmatch(lines[1])
+	assert.NotNil(t, first)
+	assert.NotNil(t, second)
+	// 每次运行使用不同的运行ID
+	if first != nil && second != nil {
+		assert.NotEqual(t, first[1], second[1])
+	}
+}
+
+// 测试未通过检查的结果不保存
+func TestFetchSourceDegraded(t *testing.T) {
+	db.InitSQLiteWithDSN("file:" + t.Name() + "?mode=memory&cache=shared")
+	service := &HotSearchService{}
+
+	var events []FetchEvent
+	unsubscribe := service.Subscribe(func(event FetchEvent) {
+		events = append(events, event)
+	})
+	defer unsubscribe()
+
+	// 页面结构变化后截取到HTML片段
+	source := app.Source{
+		RouteName: "service_test_degraded",
+		Fetch: func(ctx context.Context, f *app.Fetcher) (*app.Result, error) {
+			return app.NewResult("service_test_degraded", []app.Item{
+				{Index: 1, Title: "A</div", URL: "http://example.com/a"},
+				{Index: 2, Title: "B</div", URL: "http://example.com/b"},
+			}), nil
+		},
+		Expect: &app.Expectations{MinItems: 1},
+	}
+	count, err := service.fetchSource(context.Background(), source)
+	assert.Error(t, err)
+	assert.Equal(t, 2, count)
+
+	items, err := db.GetLatestData("service_test_degraded")
+	assert.NoError(t, err)
+	assert.Empty(t, items)
+	assert.Empty(t, events)
+}
+
+// 测试StartScheduler方法
+func TestStartScheduler(t *testing.T) {
+	db.InitSQLiteWithDSN("file:" + t.Name() + "?mode=memory&cache=shared")
+	service := &HotSearchService{}
+
+	fetched := make(chan struct{}, 1)
+	unsubscribe := service.Subscribe(func(event FetchEvent) {
+		if event.Source == "service_test_scheduled" {
+			select {
+			case fetched <- struct{}{}:
+			default:
+			}
+		}
+	})
+	defer unsubscribe()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	service.StartScheduler(ctx, config.ScheduleConfig{
+		Interval: time.Hour,
+		Sources: map[string]config.SourceSchedule{
+			"historytoday": {Cron: "5 0 * * *"},
+		},
+	})
+
+	// 启动后立即抓取一次
+	select {
+	case <-fetched:
+	case <-time.After(5 * time.Second):
+		t.Fatal("定时任务没有在启动后抓取数据")
+	}
+
+	// 事件在记录状态之前发布，等待状态更新
+	var status ScheduleStatus
+	assert.Eventually(t, func() bool {
+		for _, s := range service.GetScheduleStatus() {
+			if s.Source == "service_test_scheduled" {
+				status = s
+			}
+		}
+		return status.LastRun != nil && !status.Running
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "1h0m0s", status.Interval)
+	assert.Equal(t, 2, status.LastCount)
+	assert.Empty(t, status.LastError)
+	if assert.NotNil(t, status.NextRun) {
+		assert.WithinDuration(t, status.LastRun.Add(time.Hour), *status.NextRun, time.Minute)
+	}
+
+	for _, s := range service.GetScheduleStatus() {
+		if s.Source == "historytoday" {
+			assert.Equal(t, "5 0 * * *", s.Cron)
+		}
+	}
+
+	// 取消后所有任务退出
+	cancel()
+	done := make(chan struct{})
+	go func() {
+		service.WaitScheduler()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(15 * time.Second):
+		t.Fatal("定时任务没有在取消后退出")
+	}
+}
+
+// 测试更新抓取计划和停用数据源
+func TestUpdateSchedule(t *testing.T) {
+	db.InitSQLiteWithDSN("file:" + t.Name() + "?mode=memory&cache=shared")
+	service := &HotSearchService{}
+	source, _ := app.LookupSource("service_test_scheduled")
+	job := newScheduledJob(source, config.ScheduleConfig{Interval: time.Hour})
+	service.jobs = map[string]*scheduledJob{source.RouteName: job}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		service.WaitScheduler()
+	}()
+	service.schedulerWG.Add(1)
+	go service.runJob(ctx, job)
+
+	// 启动后立即抓取一次，下一次在一小时后
+	var status ScheduleStatus
+	assert.Eventually(t, func() bool {
+		status = job.status()
+		return status.LastRun != nil && !status.Running && status.NextRun != nil && status.NextRun.After(*status.LastRun)
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.WithinDuration(t, status.LastRun.Add(time.Hour), *status.NextRun, time.Second)
+	lastRun := *status.LastRun
+
+	// 缩短间隔后从上一次抓取开始重新计算
+	service.UpdateSchedule(config.ScheduleConfig{Interval: time.Minute})
+	assert.Eventually(t, func() bool {
+		status = job.status()
+		return status.Interval == "1m0s" && status.NextRun != nil && status.NextRun.Sub(lastRun) <= time.Minute
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// 停用后不再抓取
+	assert.NoError(t, app.SetDisabledSources([]string{source.RouteName}))
+	defer app.SetDisabledSources(nil)
+	service.UpdateSchedule(config.ScheduleConfig{Interval: 20 * time.Millisecond})
+	assert.Eventually(t, func() bool {
+		status = job.status()
+		return status.NextRun != nil && status.NextRun.After(time.Now().Add(-time.Second)) && status.NextRun.Sub(lastRun) > time.Second
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.True(t, status.Disabled)
+	assert.Equal(t, lastRun, *job.status().LastRun)
+}
+
+// 测试GetScheduleHandler方法
+func TestGetScheduleHandler(t *testing.T) {
+	service := &HotSearchService{}
+	job := newScheduledJob(app.Source{RouteName: "weibo"}, config.ScheduleConfig{Interval: 10 * time.Minute})
+	job.nextRun = time.Date(2025, 10, 18, 12, 10, 0, 0, time.UTC)
+	job.lastRun = time.Date(2025, 10, 18, 12, 0, 0, 0, time.UTC)
+	job.lastDuration = 1500 * time.Millisecond
+	job.lastError = "timeout"
+	service.jobs = map[string]*scheduledJob{"weibo": job}
+
+	app := fiber.New()
+	app.Get("/admin/schedule", service.GetScheduleHandler)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/admin/schedule", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"code": 200,
+		"message": "schedule",
+		"obj": [{
+			"source": "weibo",
+			"interval": "10m0s",
+			"running": false,
+			"nextRun": "2025-10-18T12:10:00Z",
+			"lastRun": "2025-10-18T12:00:00Z",
+			"lastDuration": "1.5s",
+			"lastError": "timeout",
+			"lastCount": 0
+		}]
+	}`, string(body))
+}
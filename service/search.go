@@ -0,0 +1,63 @@
+package service
+
+import (
+	"api/db"
+	"time"
+)
+
+// searchRowLimit 搜索历史时最多读取的条目数，同一话题在每次快照中都会出现，读取的条目数远多于返回的结果
+const searchRowLimit = 5000
+
+// SearchMatch 历史中标题包含关键词的热搜，同一来源同一标题的多次上榜合并为一条
+type SearchMatch struct {
+	Source      string    `json:"source"`
+	Title       string    `json:"title"`
+	URL         string    `json:"url"`         // 最近一次上榜时的链接
+	BestRank    int       `json:"bestRank"`    // 最高排名
+	Appearances int       `json:"appearances"` // 上榜的快照数
+	FirstSeen   time.Time `json:"firstSeen"`   // 第一次上榜的时间
+	LastSeen    time.Time `json:"lastSeen"`    // 最近一次上榜的时间
+}
+
+// SearchHistory 在已保存的快照中搜索标题包含关键词的热搜，按最近一次上榜时间从新到旧排列，最多返回 limit 条
+//
+// sources 为路由名称，为空时搜索所有来源；from、to 为零值时不限制对应一侧的时间
+func (s *HotSearchService) SearchHistory(keyword string, sources []string, from, to time.Time, limit int) ([]SearchMatch, error) {
+	dbSources := make([]string, len(sources))
+	for i, source := range sources {
+		dbSources[i] = s.convertRouteNameToDBSource(source)
+	}
+	items, err := db.SearchItems(keyword, dbSources, from, to, searchRowLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	// 条目按时间从新到旧排列，第一次遇到的是最近一次上榜
+	type key struct{ source, title string }
+	index := make(map[key]int)
+	matches := []SearchMatch{}
+	for _, item := range items {
+		k := key{item.Source, item.Title}
+		i, exists := index[k]
+		if !exists {
+			index[k] = len(matches)
+			matches = append(matches, SearchMatch{
+				Source:   item.Source,
+				Title:    item.Title,
+				URL:      item.URL,
+				BestRank: item.Index,
+				LastSeen: item.CreatedAt,
+			})
+			i = len(matches) - 1
+		}
+		match := &matches[i]
+		match.Appearances++
+		match.FirstSeen = item.CreatedAt
+		match.BestRank = min(match.BestRank, item.Index)
+	}
+
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches, nil
+}
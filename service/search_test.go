@@ -0,0 +1,58 @@
+package service
+
+import (
+	"api/db"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchHistory(t *testing.T) {
+	db.InitSQLiteWithDSN("file:" + t.Name() + "?mode=memory&cache=shared")
+	service := &HotSearchService{}
+
+	base := time.Date(2099, 1, 1, 8, 0, 0, 0, time.Local)
+	saveDiffSnapshot(t, "weibo", base, "新品发布会", "天气")
+	saveDiffSnapshot(t, "weibo", base.Add(time.Hour), "天气", "新品发布会")
+	saveDiffSnapshot(t, "zhihu", base.Add(2*time.Hour), "如何评价新品发布会")
+
+	// 同一来源同一标题的多次上榜合并，按最近一次上榜时间排列
+	matches, err := service.SearchHistory("新品", nil, time.Time{}, time.Time{}, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []SearchMatch{
+		{
+			Source: "zhihu", Title: "如何评价新品发布会", URL: "https://example.com/如何评价新品发布会",
+			BestRank: 1, Appearances: 1, FirstSeen: base.Add(2 * time.Hour), LastSeen: base.Add(2 * time.Hour),
+		},
+		{
+			Source: "weibo", Title: "新品发布会", URL: "https://example.com/新品发布会",
+			BestRank: 1, Appearances: 2, FirstSeen: base, LastSeen: base.Add(time.Hour),
+		},
+	}, normalizeMatches(matches))
+
+	// 按来源、时间和数量限制
+	matches, err = service.SearchHistory("新品", []string{"weibo"}, base.Add(30*time.Minute), time.Time{}, 10)
+	assert.NoError(t, err)
+	if assert.Len(t, matches, 1) {
+		assert.Equal(t, 1, matches[0].Appearances)
+		assert.Equal(t, 2, matches[0].BestRank)
+	}
+	matches, err = service.SearchHistory("新品", nil, time.Time{}, time.Time{}, 1)
+	assert.NoError(t, err)
+	assert.Len(t, matches, 1)
+
+	// 没有结果时返回空列表
+	matches, err = service.SearchHistory("不存在", nil, time.Time{}, time.Time{}, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []SearchMatch{}, matches)
+}
+
+// normalizeMatches 将时间转换为本地时区，便于比较
+func normalizeMatches(matches []SearchMatch) []SearchMatch {
+	for i := range matches {
+		matches[i].FirstSeen = matches[i].FirstSeen.Local()
+		matches[i].LastSeen = matches[i].LastSeen.Local()
+	}
+	return matches
+}
@@ -1,12 +1,16 @@
 package service
 
 import (
+	"api/app"
 	"api/config"
 	"api/db"
 	"api/model"
+	"context"
+	"io"
 	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/stretchr/testify/assert"
@@ -30,7 +34,7 @@ func TestGetFromDBOrFetch(t *testing.T) {
 
 	// 测试从API获取数据（数据库为空的情况）
 	t.Run("FetchFromAPIWhenDBEmpty", func(t *testing.T) {
-		result, err := service.GetFromDBOrFetch("微博") // 使用一个有效的源
+		result, err := service.GetFromDBOrFetch(context.Background(), "微博") // 使用一个有效的源
 		// 这里我们只测试函数是否能正常处理错误，因为实际API可能无法访问
 		// 如果API无法访问，应该返回错误，否则应该返回数据
 		if err != nil {
@@ -39,7 +43,6 @@ func TestGetFromDBOrFetch(t *testing.T) {
 		} else {
 			// 如果API成功，验证返回格式
 			assert.NotNil(t, result)
-			assert.Contains(t, result, "code")
 		}
 	})
 
@@ -53,11 +56,11 @@ func TestGetFromDBOrFetch(t *testing.T) {
 		assert.NoError(t, err)
 
 		// 从数据库获取数据
-		result, err := service.GetFromDBOrFetch("test_source")
+		result, err := service.GetFromDBOrFetch(context.Background(), "test_source")
 		assert.NoError(t, err)
-		assert.Equal(t, 200, result["code"])
-		assert.Equal(t, "test_source", result["message"])
-		assert.NotNil(t, result["obj"])
+		assert.Equal(t, "test_source", result.Source)
+		assert.False(t, result.FetchedAt.IsZero())
+		assert.Equal(t, []app.Item{{Index: 1, Title: "Test Title", URL: "http://example.com"}}, result.Items)
 	})
 }
 
@@ -92,54 +95,52 @@ func TestGetAllFromDBOrFetch(t *testing.T) {
 		assert.NoError(t, err)
 
 		// 从数据库获取所有数据
-		result, err := service.GetAllFromDBOrFetch()
+		results, err := service.GetAllFromDBOrFetch(context.Background())
 		assert.NoError(t, err)
-		assert.Equal(t, 200, result["code"])
-		assert.NotNil(t, result["obj"])
-		obj, ok := result["obj"].(map[string]interface{})
-		assert.True(t, ok)
-		assert.Equal(t, 2, len(obj))
+		assert.Equal(t, 2, len(results))
+		assert.Equal(t, "source1", results["source1"].Source)
+		assert.Equal(t, "Source2 Title", results["source2"].Items[0].Title)
 	})
 }
 
 func TestConvertToHotSearchItems(t *testing.T) {
 	service := &HotSearchService{}
 
-	// 测试不同的API返回格式
-	t.Run("ConvertFromSliceInterface", func(t *testing.T) {
-		apiResult := map[string]interface{}{
-			"obj": []interface{}{
-				map[string]interface{}{"title": "Test Title", "url": "http://example.com", "index": 1.0},
-			},
-		}
-
-		items := service.convertToHotSearchItems(apiResult)
+	t.Run("ConvertItems", func(t *testing.T) {
+		items := service.convertToHotSearchItems([]app.Item{
+			{Index: 1, Title: "Test Title", URL: "http://example.com"},
+		})
 		assert.Equal(t, 1, len(items))
 		assert.Equal(t, "Test Title", items[0].Title)
 		assert.Equal(t, "http://example.com", items[0].URL)
 		assert.Equal(t, 1, items[0].Index)
 	})
 
-	t.Run("ConvertFromSliceMap", func(t *testing.T) {
-		apiResult := map[string]interface{}{
-			"obj": []map[string]interface{}{
-				{"title": "Test Title", "url": "http://example.com", "index": 1.0},
-			},
+	t.Run("ConvertItemFields", func(t *testing.T) {
+		item := app.Item{
+			Index:    1,
+			Title:    "Test Title",
+			URL:      "http://example.com",
+			HotValue: "123.4万",
+			HotScore: 1234000,
+			Desc:     "Description",
+			Image:    "http://example.com/cover.png",
+			Extra:    map[string]string{"time": "08:00"},
 		}
 
-		items := service.convertToHotSearchItems(apiResult)
-		assert.Equal(t, 1, len(items))
-		assert.Equal(t, "Test Title", items[0].Title)
-		assert.Equal(t, "http://example.com", items[0].URL)
-		assert.Equal(t, 1, items[0].Index)
-	})
+		items := service.convertToHotSearchItems([]app.Item{item})
+		assert.Equal(t, 1234000.0, items[0].HotScore)
+		assert.Equal(t, "123.4万", items[0].HotValue)
+		assert.Equal(t, "Description", items[0].Desc)
+		assert.Equal(t, "http://example.com/cover.png", items[0].Image)
+		assert.Equal(t, model.Extra{"time": "08:00"}, items[0].Extra)
 
-	t.Run("ConvertEmptyObj", func(t *testing.T) {
-		apiResult := map[string]interface{}{
-			"obj": []interface{}{},
-		}
+		// 转换回来后字段不丢失
+		assert.Equal(t, []app.Item{item}, service.convertFromDBItems(items))
+	})
 
-		items := service.convertToHotSearchItems(apiResult)
+	t.Run("ConvertEmptyItems", func(t *testing.T) {
+		items := service.convertToHotSearchItems(nil)
 		assert.Equal(t, 0, len(items))
 	})
 }
@@ -149,94 +150,33 @@ func TestConvertFromDBItems(t *testing.T) {
 
 	// 测试将数据库项目转换为API返回格式
 	t.Run("ConvertDBItemsToAPIFormat", func(t *testing.T) {
+		createdAt := time.Date(2099, 1, 1, 8, 0, 0, 0, time.Local)
 		dbItems := []model.HotSearchItem{
-			{Title: "Test Title", URL: "http://example.com", Index: 1},
+			{Title: "Test Title", URL: "http://example.com", Index: 1, CreatedAt: createdAt},
 		}
 
-		result := service.convertFromDBItems(dbItems, "test_source")
-		assert.Equal(t, 200, result["code"])
-		assert.Equal(t, "test_source", result["message"])
-		assert.NotNil(t, result["obj"])
-
-		obj, ok := result["obj"].([]map[string]interface{})
-		assert.True(t, ok)
-		assert.Equal(t, 1, len(obj))
-		assert.Equal(t, "Test Title", obj[0]["title"])
-		assert.Equal(t, "http://example.com", obj[0]["url"])
-		// index 可能是 int 或 float64 类型，需要进行类型检查
-		indexValue := obj[0]["index"]
-		switch v := indexValue.(type) {
-		case int:
-			assert.Equal(t, 1, v)
-		case float64:
-			assert.Equal(t, 1, int(v))
-		default:
-			assert.Fail(t, "index should be int or float64")
-		}
-	})
-}
-
-func TestConvertFromAllDBItems(t *testing.T) {
-	service := &HotSearchService{}
-
-	// 测试将所有数据库项目转换为API返回格式
-	t.Run("ConvertAllDBItemsToAPIFormat", func(t *testing.T) {
-		dbData := map[string][]model.HotSearchItem{
-			"source1": {
-				{Title: "Source1 Title", URL: "http://source1.com", Index: 1},
-			},
-			"source2": {
-				{Title: "Source2 Title", URL: "http://source2.com", Index: 1},
-			},
-		}
-
-		result := service.convertFromAllDBItems(dbData)
-		assert.Equal(t, 200, result["code"])
-		assert.NotNil(t, result["obj"])
-
-		obj, ok := result["obj"].(map[string]interface{})
-		assert.True(t, ok)
-		assert.Equal(t, 2, len(obj))
-
-		source1Obj, ok := obj["source1"].([]map[string]interface{})
-		assert.True(t, ok)
-		assert.Equal(t, 1, len(source1Obj))
-		assert.Equal(t, "Source1 Title", source1Obj[0]["title"])
-	})
-}
-
-func TestConvertSourceDataToHotSearchItems(t *testing.T) {
-	service := &HotSearchService{}
-
-	// 测试从源数据转换为HotSearchItem
-	t.Run("ConvertFromSliceInterface", func(t *testing.T) {
-		sourceData := []interface{}{
-			map[string]interface{}{"title": "Test Title", "url": "http://example.com", "index": 1.0},
-		}
+		items := service.convertFromDBItems(dbItems)
+		assert.Equal(t, []app.Item{{Index: 1, Title: "Test Title", URL: "http://example.com"}}, items)
 
-		items := service.convertSourceDataToHotSearchItems(sourceData)
-		assert.Equal(t, 1, len(items))
-		assert.Equal(t, "Test Title", items[0].Title)
-		assert.Equal(t, "http://example.com", items[0].URL)
-		assert.Equal(t, 1, items[0].Index)
+		// 抓取时间取快照的写入时间
+		result := service.convertToResult("test_source", dbItems)
+		assert.Equal(t, "test_source", result.Source)
+		assert.Equal(t, createdAt, result.FetchedAt)
+		assert.Equal(t, items, result.Items)
 	})
 
-	t.Run("ConvertFromSliceMap", func(t *testing.T) {
-		sourceData := []map[string]interface{}{
-			{"title": "Test Title", "url": "http://example.com", "index": 1.0},
+	t.Run("ConvertHistoryResult", func(t *testing.T) {
+		dbItems := []model.HotSearchItem{
+			{Title: "Test Title", URL: "http://example.com", Index: 1},
 		}
 
-		items := service.convertSourceDataToHotSearchItems(sourceData)
-		assert.Equal(t, 1, len(items))
-		assert.Equal(t, "Test Title", items[0].Title)
-		assert.Equal(t, "http://example.com", items[0].URL)
-		assert.Equal(t, 1, items[0].Index)
-	})
+		result := service.convertToHistoryResult(dbItems, "test_source")
+		assert.Equal(t, 200, result["code"])
+		assert.Equal(t, "test_source历史数据", result["message"])
+		assert.Equal(t, []app.Item{{Index: 1, Title: "Test Title", URL: "http://example.com"}}, result["obj"])
 
-	t.Run("ConvertEmptyData", func(t *testing.T) {
-		var sourceData []interface{}
-		items := service.convertSourceDataToHotSearchItems(sourceData)
-		assert.Equal(t, 0, len(items))
+		hourly := service.convertHourlyItems(map[int][]model.HotSearchItem{8: dbItems})
+		assert.Equal(t, 1, len(hourly["08:00"]))
 	})
 }
 
@@ -245,7 +185,7 @@ func TestFetchDataFromAPI(t *testing.T) {
 
 	// 测试获取已知来源的数据
 	t.Run("FetchKnownSource", func(t *testing.T) {
-		result, err := service.FetchDataFromAPI("微博")
+		result, err := service.FetchDataFromAPI(context.Background(), "微博")
 		// 这里我们只测试函数是否能处理请求，实际API可能无法访问
 		// 如果API无法访问，应该返回错误，否则应该返回数据
 		if err != nil {
@@ -254,64 +194,17 @@ func TestFetchDataFromAPI(t *testing.T) {
 		} else {
 			// 如果API成功，验证返回格式
 			assert.NotNil(t, result)
-			assert.Contains(t, result, "code")
 		}
 	})
 
 	// 测试获取未知来源的数据
 	t.Run("FetchUnknownSource", func(t *testing.T) {
-		result, err := service.FetchDataFromAPI("unknown_source")
+		result, err := service.FetchDataFromAPI(context.Background(), "unknown_source")
 		assert.NoError(t, err)
-		assert.Equal(t, 200, result["code"])
-		assert.Equal(t, "unknown_source", result["message"])
-		assert.NotNil(t, result["obj"])
-	})
-}
-
-// 测试fetchAPIData方法
-func TestFetchAPIData(t *testing.T) {
-	// 创建临时SQLite数据库文件
-	tempDB := "test_fetch_api_data.db"
-	defer os.Remove(tempDB) // 测试结束后清理
-
-	// 初始化数据库
-	cfg := &config.Config{
-		Database: config.DatabaseConfig{
-			Type: "sqlite",
-			DSN:  tempDB,
-		},
-	}
-	db.InitDBWithConfig(cfg)
-
-	service := &HotSearchService{}
-
-	// 直接调用fetchAPIData方法，不期望出现panic或死锁
-	// 由于这会尝试调用所有API，可能需要一些时间
-	assert.NotPanics(t, func() {
-		service.fetchAPIData()
-	})
-}
-
-// 测试StartScheduler方法
-func TestStartScheduler(t *testing.T) {
-	// 创建临时SQLite数据库文件
-	tempDB := "test_scheduler.db"
-	defer os.Remove(tempDB) // 测试结束后清理
-
-	// 初始化数据库
-	cfg := &config.Config{
-		Database: config.DatabaseConfig{
-			Type: "sqlite",
-			DSN:  tempDB,
-		},
-	}
-	db.InitDBWithConfig(cfg)
-
-	service := &HotSearchService{}
-
-	// 启动调度器，不期望出现panic
-	assert.NotPanics(t, func() {
-		service.StartScheduler()
+		response := result.Response()
+		assert.Equal(t, 200, response.Code)
+		assert.Equal(t, "unknown_source", response.Message)
+		assert.NotNil(t, response.Obj)
 	})
 }
 
@@ -347,6 +240,51 @@ func TestGetHistoricalDataHandler(t *testing.T) {
 	assert.Equal(t, 200, resp.StatusCode) // 即使没有数据，路由处理程序也应该成功执行
 }
 
+// 测试历史数据返回热度、描述等附加字段
+func TestGetHistoricalDataHandlerItemFields(t *testing.T) {
+	db.InitSQLiteWithDSN("file:" + t.Name() + "?mode=memory&cache=shared")
+
+	fetchedAt := time.Date(2099, 1, 1, 12, 0, 0, 0, time.Local)
+	err := db.SaveAllDataAt(map[string][]model.HotSearchItem{
+		"weibo": {{
+			Title:    "Test Title",
+			URL:      "http://example.com",
+			Index:    1,
+			HotScore: 1234000,
+			HotValue: "123.4万",
+			Desc:     "Description",
+			Image:    "http://example.com/cover.png",
+			Extra:    model.Extra{"label": "热"},
+		}},
+	}, fetchedAt)
+	assert.NoError(t, err)
+
+	service := &HotSearchService{}
+	app := fiber.New()
+	app.Get("/test/:source/:date/:hour", service.GetHistoricalDataHandler)
+
+	req := httptest.NewRequest("GET", "/test/weibo/2099-01-01/12", nil)
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+
+	body, err := io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"code": 200,
+		"message": "weibo历史数据",
+		"obj": [{
+			"index": 1,
+			"title": "Test Title",
+			"url": "http://example.com",
+			"hotScore": 1234000,
+			"hotValue": "123.4万",
+			"desc": "Description",
+			"image": "http://example.com/cover.png",
+			"extra": {"label": "热"}
+		}]
+	}`, string(body))
+}
+
 // 测试GetHistoricalDataByDateHandler方法
 func TestGetHistoricalDataByDateHandler(t *testing.T) {
 	// 创建临时SQLite数据库文件
@@ -512,3 +450,128 @@ func TestGetHistoricalDataByDateForWS(t *testing.T) {
 	assert.NotNil(t, result)
 	assert.Contains(t, result, "code")
 }
+
+// 测试GetLatestSnapshot、GetHourlySnapshot和GetDailySnapshots方法
+func TestGetSnapshots(t *testing.T) {
+	db.InitSQLiteWithDSN("file:" + t.Name() + "?mode=memory&cache=shared")
+	service := &HotSearchService{}
+
+	// 没有数据时返回 nil，不会临时抓取
+	result, err := service.GetLatestSnapshot("baidu")
+	assert.NoError(t, err)
+	assert.Nil(t, result)
+
+	morning := time.Date(2024, 5, 1, 8, 10, 0, 0, time.Local)
+	saveDiffSnapshot(t, "baidu", morning, "A", "B")
+	saveDiffSnapshot(t, "baidu", morning.Add(30*time.Minute), "C")
+	saveDiffSnapshot(t, "baidu", morning.Add(2*time.Hour), "D")
+
+	result, err = service.GetLatestSnapshot("baidu")
+	assert.NoError(t, err)
+	if assert.NotNil(t, result) {
+		assert.Equal(t, "baidu", result.Source)
+		assert.True(t, morning.Add(2*time.Hour).Equal(result.FetchedAt))
+		assert.Equal(t, "D", result.Items[0].Title)
+	}
+
+	// 同一小时取最新的一次
+	result, err = service.GetHourlySnapshot("baidu", "2024-05-01", 8)
+	assert.NoError(t, err)
+	if assert.NotNil(t, result) {
+		assert.Len(t, result.Items, 1)
+		assert.Equal(t, "C", result.Items[0].Title)
+	}
+
+	result, err = service.GetHourlySnapshot("baidu", "2024-05-01", 9)
+	assert.NoError(t, err)
+	assert.Nil(t, result)
+
+	// 整天的快照按小时分组
+	daily, err := service.GetDailySnapshots("baidu", "2024-05-01")
+	assert.NoError(t, err)
+	if assert.Len(t, daily, 2) {
+		assert.Equal(t, "C", daily[8].Items[0].Title)
+		assert.Equal(t, "D", daily[10].Items[0].Title)
+	}
+	daily, err = service.GetDailySnapshots("baidu", "2024-05-02")
+	assert.NoError(t, err)
+	assert.Empty(t, daily)
+}
+
+// 测试StartRetention方法
+func TestStartRetention(t *testing.T) {
+	db.InitSQLiteWithDSN("file:" + t.Name() + "?mode=memory&cache=shared")
+	service := &HotSearchService{}
+
+	// 过期的快照和最新的快照
+	now := time.Now()
+	for _, at := range []time.Time{now.AddDate(-1, 0, 0), now.AddDate(0, 0, -200), now} {
+		assert.NoError(t, db.SaveDataAt("retention_test", []model.HotSearchItem{
+			{Title: at.Format(time.RFC3339), URL: "http://example.com", Index: 1},
+		}, at))
+	}
+	count := func() int64 {
+		var n int64
+		assert.NoError(t, db.DB.Model(&model.HotSearchData{}).Where("source = ?", "retention_test").Count(&n).Error)
+		return n
+	}
+
+	// 未启用时不执行清理
+	service.StartRetention(config.RetentionConfig{Enabled: false})
+	assert.Equal(t, int64(3), count())
+
+	// 启用后在后台立即清理一次，只保留最新的快照
+	service.StartRetention(config.RetentionConfig{Enabled: true, Interval: time.Hour, HourlyDays: 7, DailyDays: 90})
+	assert.Eventually(t, func() bool { return count() == 1 }, 5*time.Second, 10*time.Millisecond)
+	items, err := db.GetLatestData("retention_test")
+	assert.NoError(t, err)
+	if assert.Len(t, items, 1) {
+		assert.Equal(t, now.Format(time.RFC3339), items[0].Title)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.NoError(t, service.Shutdown(ctx))
+}
+
+// 测试抓取完成后发布事件
+func TestSubscribeFetchEvents(t *testing.T) {
+	app.Register(app.Source{
+		RouteName: "service_test_events",
+		Aliases:   []string{"service_test_events_alias"},
+		Expect:    &app.Expectations{MinItems: 1},
+		Fetch: func(ctx context.Context, f *app.Fetcher) (*app.Result, error) {
+			return app.NewResult("service_test_events", []app.Item{{Index: 1, Title: "Title", URL: "http://example.com"}}), nil
+		},
+	})
+
+	db.InitSQLiteWithDSN("file:" + t.Name() + "?mode=memory&cache=shared")
+	service := &HotSearchService{}
+
+	var events []FetchEvent
+	unsubscribe := service.Subscribe(func(event FetchEvent) {
+		events = append(events, event)
+	})
+
+	// 按需抓取也发布事件，通过别名抓取时事件中使用路由名称
+	result, err := service.FetchDataFromAPI(context.Background(), "service_test_events_alias")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(events))
+	assert.Equal(t, "service_test_events", events[0].Source)
+	assert.Equal(t, result, events[0].Result)
+	assert.False(t, events[0].Scheduled)
+	assert.Nil(t, events[0].Diff) // 数据库中还没有快照
+
+	// 定时抓取保存新快照后发布的事件标记为 Scheduled
+	source, _ := app.LookupSource("service_test_events_alias")
+	_, err = service.fetchSource(context.Background(), source)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(events))
+	assert.True(t, events[1].Scheduled)
+
+	// 取消订阅后不再收到事件
+	unsubscribe()
+	_, err = service.fetchSource(context.Background(), source)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(events))
+}
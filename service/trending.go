@@ -0,0 +1,138 @@
+package service
+
+import (
+	"api/app"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// topicMinLength 按包含关系匹配话题时较短标题的最小长度（字符数），过短的标题容易误匹配
+const topicMinLength = 4
+
+// PlatformRank 话题在一个平台上的排名
+type PlatformRank struct {
+	Source string `json:"source"`
+	Title  string `json:"title"` // 该平台上的标题
+	URL    string `json:"url"`
+	Rank   int    `json:"rank"`
+}
+
+// CrossPlatformTopic 同时出现在多个平台榜单上的话题
+type CrossPlatformTopic struct {
+	Title     string         `json:"title"`     // 各平台标题中最短的一个
+	Platforms []PlatformRank `json:"platforms"` // 按排名从高到低排列
+}
+
+// groupTopics 找出同时出现在至少 minPlatforms 个平台榜单上的话题
+//
+// 规范化后（忽略大小写、空白和标点符号）标题相同，或较短的标题不少于 topicMinLength 个字符且包含在较长的标题中，
+// 视为同一话题；同一平台只取排名最高的条目。结果按平台数从多到少、平均排名从高到低排列
+func groupTopics(results []*app.Result, minPlatforms int) []CrossPlatformTopic {
+	type group struct {
+		key       string // 规范化后最短的标题
+		title     string
+		platforms map[string]PlatformRank
+	}
+	var groups []*group
+
+	// matches 判断两个规范化后的标题是否为同一话题
+	matches := func(a, b string) bool {
+		if a == b {
+			return true
+		}
+		if utf8.RuneCountInString(a) > utf8.RuneCountInString(b) {
+			a, b = b, a
+		}
+		return utf8.RuneCountInString(a) >= topicMinLength && strings.Contains(b, a)
+	}
+
+	for _, result := range results {
+		if result == nil {
+			continue
+		}
+		for _, item := range result.Items {
+			key := normalizeTitle(item.Title)
+			if key == "" {
+				continue
+			}
+			var found *group
+			for _, g := range groups {
+				if matches(g.key, key) {
+					found = g
+					break
+				}
+			}
+			if found == nil {
+				found = &group{key: key, title: item.Title, platforms: make(map[string]PlatformRank)}
+				groups = append(groups, found)
+			}
+			if utf8.RuneCountInString(key) < utf8.RuneCountInString(found.key) {
+				found.key, found.title = key, item.Title
+			}
+			if rank, exists := found.platforms[result.Source]; !exists || item.Index < rank.Rank {
+				found.platforms[result.Source] = PlatformRank{Source: result.Source, Title: item.Title, URL: item.URL, Rank: item.Index}
+			}
+		}
+	}
+
+	// 平均排名只用于排序
+	type rankedTopic struct {
+		topic   CrossPlatformTopic
+		average float64
+	}
+	var ranked []rankedTopic
+	for _, g := range groups {
+		if len(g.platforms) < minPlatforms {
+			continue
+		}
+		topic := CrossPlatformTopic{Title: g.title, Platforms: make([]PlatformRank, 0, len(g.platforms))}
+		total := 0
+		for _, rank := range g.platforms {
+			topic.Platforms = append(topic.Platforms, rank)
+			total += rank.Rank
+		}
+		sort.Slice(topic.Platforms, func(i, j int) bool {
+			if topic.Platforms[i].Rank != topic.Platforms[j].Rank {
+				return topic.Platforms[i].Rank < topic.Platforms[j].Rank
+			}
+			return topic.Platforms[i].Source < topic.Platforms[j].Source
+		})
+		ranked = append(ranked, rankedTopic{topic: topic, average: float64(total) / float64(len(g.platforms))})
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if len(ranked[i].topic.Platforms) != len(ranked[j].topic.Platforms) {
+			return len(ranked[i].topic.Platforms) > len(ranked[j].topic.Platforms)
+		}
+		return ranked[i].average < ranked[j].average
+	})
+
+	topics := make([]CrossPlatformTopic, len(ranked))
+	for i, r := range ranked {
+		topics[i] = r.topic
+	}
+	return topics
+}
+
+// GetCrossPlatformTopics 在各平台最新保存的快照中找出同时出现在至少 minPlatforms 个平台上的话题
+//
+// sources 为路由名称，为空时使用所有启用的平台
+func (s *HotSearchService) GetCrossPlatformTopics(sources []string, minPlatforms int) ([]CrossPlatformTopic, error) {
+	if len(sources) == 0 {
+		for _, source := range app.Sources() {
+			if app.Enabled(source.RouteName) {
+				sources = append(sources, source.RouteName)
+			}
+		}
+	}
+
+	results := make([]*app.Result, 0, len(sources))
+	for _, source := range sources {
+		result, err := s.GetLatestSnapshot(source)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return groupTopics(results, minPlatforms), nil
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"api/app"
+	"api/db"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// rankedResult 创建结果，titles 按顺序作为排名
+func rankedResult(source string, titles ...string) *app.Result {
+	items := make([]app.Item, len(titles))
+	for i, title := range titles {
+		items[i] = app.Item{Index: i + 1, Title: title, URL: "https://" + source + ".example.com/" + title}
+	}
+	return app.NewResult(source, items)
+}
+
+func TestGroupTopics(t *testing.T) {
+	results := []*app.Result{
+		rankedResult("weibo", "新品发布会", "天气预报", "Go 1.30"),
+		rankedResult("zhihu", "如何评价新品发布会？", "go-1.30", "春节"),
+		rankedResult("baidu", "春节", "天气", "新品发布会直播"),
+		nil,
+	}
+
+	topics := groupTopics(results, 2)
+	if assert.Len(t, topics, 3) {
+		// 包含关系匹配，标题取最短的一个，平台按排名排列
+		assert.Equal(t, "新品发布会", topics[0].Title)
+		assert.Equal(t, []PlatformRank{
+			{Source: "weibo", Title: "新品发布会", URL: "https://weibo.example.com/新品发布会", Rank: 1},
+			{Source: "zhihu", Title: "如何评价新品发布会？", URL: "https://zhihu.example.com/如何评价新品发布会？", Rank: 1},
+			{Source: "baidu", Title: "新品发布会直播", URL: "https://baidu.example.com/新品发布会直播", Rank: 3},
+		}, topics[0].Platforms)
+
+		// 平台数相同时按平均排名排列；规范化后标题相同
+		assert.Equal(t, "春节", topics[1].Title)
+		assert.Equal(t, "Go 1.30", topics[2].Title)
+	}
+
+	// 过短的标题不按包含关系匹配
+	for _, topic := range topics {
+		assert.NotEqual(t, "天气", topic.Title)
+	}
+
+	assert.Len(t, groupTopics(results, 3), 1)
+	assert.Equal(t, []CrossPlatformTopic{}, groupTopics(results, 4))
+}
+
+func TestGetCrossPlatformTopics(t *testing.T) {
+	db.InitSQLiteWithDSN("file:" + t.Name() + "?mode=memory&cache=shared")
+	service := &HotSearchService{}
+
+	base := time.Date(2099, 1, 1, 8, 0, 0, 0, time.Local)
+	saveDiffSnapshot(t, "weibo", base, "旧话题")
+	saveDiffSnapshot(t, "weibo", base.Add(time.Hour), "新话题", "春节")
+	saveDiffSnapshot(t, "zhihu", base, "旧话题", "新话题")
+
+	// 使用各平台最新的快照
+	topics, err := service.GetCrossPlatformTopics([]string{"weibo", "zhihu", "baidu"}, 2)
+	assert.NoError(t, err)
+	if assert.Len(t, topics, 1) {
+		assert.Equal(t, "新话题", topics[0].Title)
+		assert.Len(t, topics[0].Platforms, 2)
+	}
+}
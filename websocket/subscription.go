@@ -0,0 +1,130 @@
+package websocket
+
+import (
+	"api/app"
+	"api/metrics"
+	"path"
+	"sort"
+	"strings"
+)
+
+// categoryPrefix 按分类订阅时使用的前缀，如 category:tech
+const categoryPrefix = "category:"
+
+// SubscriptionsData subscriptions 消息的数据
+type SubscriptionsData struct {
+	Sources []string `json:"sources"`           // 当前订阅的数据源路由名称
+	Unknown []string `json:"unknown,omitempty"` // 无法识别的订阅名称
+}
+
+// resolveSources 将订阅名称解析为数据源路由名称
+//
+// 支持路由名称或别名（kuake）、全部（all 或 *）、通配符（wei*）和分类（category:tech），
+// 无法匹配任何数据源的名称放在 unknown 中返回
+func resolveSources(names []string) (sources []string, unknown []string) {
+	registered := app.Sources()
+	seen := make(map[string]bool)
+	add := func(routeName string) {
+		if !seen[routeName] {
+			seen[routeName] = true
+			sources = append(sources, routeName)
+		}
+	}
+
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		matched := false
+
+		switch {
+		case name == "all" || name == "*":
+			for _, source := range registered {
+				add(source.RouteName)
+			}
+			matched = len(registered) > 0
+		case strings.HasPrefix(name, categoryPrefix):
+			category := strings.TrimPrefix(name, categoryPrefix)
+			for _, source := range registered {
+				if source.Category == category {
+					add(source.RouteName)
+					matched = true
+				}
+			}
+		case strings.ContainsAny(name, "*?["):
+			for _, source := range registered {
+				if ok, _ := path.Match(name, source.RouteName); ok {
+					add(source.RouteName)
+					matched = true
+				}
+			}
+		default:
+			if source, ok := app.LookupSource(name); ok {
+				add(source.RouteName)
+				matched = true
+			}
+		}
+
+		if !matched {
+			unknown = append(unknown, name)
+		}
+	}
+	return sources, unknown
+}
+
+// subscribe 订阅数据源，返回新增的数据源
+func (c *Client) subscribe(sources []string) []string {
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+
+	if c.subscriptions == nil {
+		c.subscriptions = make(map[string]bool)
+	}
+	var added []string
+	for _, source := range sources {
+		if !c.subscriptions[source] {
+			c.subscriptions[source] = true
+			added = append(added, source)
+			metrics.WebSocketSubscriptions.Inc(source)
+		}
+	}
+	return added
+}
+
+// unsubscribe 取消订阅数据源，sources 为空时取消全部订阅
+func (c *Client) unsubscribe(sources []string) {
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+
+	if len(sources) == 0 {
+		for source := range c.subscriptions {
+			metrics.WebSocketSubscriptions.Dec(source)
+		}
+		c.subscriptions = nil
+		return
+	}
+	for _, source := range sources {
+		if c.subscriptions[source] {
+			delete(c.subscriptions, source)
+			metrics.WebSocketSubscriptions.Dec(source)
+		}
+	}
+}
+
+// Subscriptions 获取当前订阅的数据源，按路由名称排序
+func (c *Client) Subscriptions() []string {
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+
+	sources := make([]string, 0, len(c.subscriptions))
+	for source := range c.subscriptions {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	return sources
+}
+
+// isSubscribed 判断客户端是否订阅了指定数据源的推送
+func (c *Client) isSubscribed(source string) bool {
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+	return c.subscriptions[source]
+}
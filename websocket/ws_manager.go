@@ -1,17 +1,133 @@
 package websocket
 
 import (
+	"api/all"
+	"api/app"
+	"api/logging"
+	"api/metrics"
 	"api/service"
+	"context"
+	"log/slog"
+	"strings"
 	"sync"
+	"time"
 
 	websocket "github.com/gofiber/contrib/websocket"
-	"github.com/gofiber/fiber/v2/log"
 )
 
 // Client 客户端结构
 type Client struct {
 	Conn *websocket.Conn
-	Type string // api类型，如 baidu, bilibili 等
+
+	mu            sync.Mutex      // 保护对连接的写入，推送和响应来自不同的协程
+	subMu         sync.Mutex      // 保护订阅集合，与写入分开，推送时判断订阅不需要等待正在进行的写入
+	subscriptions map[string]bool // 订阅的数据源路由名称
+	send          chan Message    // 待推送的消息，由 writePump 按顺序写入连接
+	done          chan struct{}   // 客户端注销或因推送过慢被断开时关闭
+	stopOnce      sync.Once
+	writer        sync.WaitGroup // 正在运行的 writePump，注销时等待它退出后才能释放连接
+	logger        *slog.Logger   // 带有客户端ID、地址和升级请求ID的日志
+}
+
+// clientSendBufferSize 每个客户端待推送消息队列的长度，队列满时认为客户端过慢并断开连接
+const clientSendBufferSize = 64
+
+// newClient 创建客户端，并订阅指定的数据源
+func newClient(conn *websocket.Conn, sources ...string) *Client {
+	client := &Client{
+		Conn:   conn,
+		send:   make(chan Message, clientSendBufferSize),
+		done:   make(chan struct{}),
+		logger: slog.Default().With("client", logging.NewID()),
+	}
+	if conn != nil {
+		client.logger = client.logger.With("remote", conn.RemoteAddr().String())
+		if requestID, ok := conn.Locals(logging.KeyRequestID).(string); ok {
+			client.logger = client.logger.With(logging.KeyRequestID, requestID)
+		}
+	}
+	client.subscribe(sources)
+	return client
+}
+
+// context 返回带有客户端日志的 context，用于调用服务
+func (c *Client) context() context.Context {
+	return logging.NewContext(context.Background(), c.logger)
+}
+
+// close 向客户端发送关闭帧，客户端回复后读取消息会返回错误，处理函数随之退出
+func (c *Client) close(code int, text string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.Conn == nil {
+		return nil
+	}
+	return c.Conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, text), time.Now().Add(time.Second))
+}
+
+// writeTimeout 向客户端写入一条消息的超时时间，避免停止读取的客户端一直阻塞推送
+const writeTimeout = 10 * time.Second
+
+// WriteJSON 向客户端发送JSON消息，超过 writeTimeout 没有写完时返回错误
+func (c *Client) WriteJSON(v interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.Conn == nil {
+		return nil
+	}
+	if err := c.Conn.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil {
+		return err
+	}
+	return c.Conn.WriteJSON(v)
+}
+
+// enqueue 将推送消息放入客户端的队列，不会阻塞；队列已满时断开该客户端，返回 false
+func (c *Client) enqueue(message Message) bool {
+	select {
+	case <-c.done:
+		return false
+	default:
+	}
+	select {
+	case c.send <- message:
+		return true
+	default:
+		c.logger.Warn("客户端推送队列已满，断开连接", logging.KeySource, message.Source, "type", message.Type)
+		c.drop()
+		return false
+	}
+}
+
+// writePump 将队列中的推送消息逐条写入连接，直到客户端停止；写入失败时断开连接
+func (c *Client) writePump() {
+	for {
+		select {
+		case <-c.done:
+			return
+		case message := <-c.send:
+			if err := c.WriteJSON(message); err != nil {
+				c.logger.Error("发送消息失败", logging.KeySource, message.Source, logging.KeyError, err)
+				c.drop()
+				return
+			}
+		}
+	}
+}
+
+// stop 停止推送，之后放入队列的消息会被丢弃
+func (c *Client) stop() {
+	c.stopOnce.Do(func() { close(c.done) })
+}
+
+// drop 停止推送并让读取立即失败，处理函数随之退出并注销客户端
+//
+// 处理函数返回前 fasthttp 不会真正关闭被接管的连接，所以通过读取超时让处理函数退出
+func (c *Client) drop() {
+	c.stop()
+	if c.Conn != nil {
+		c.Conn.SetReadDeadline(time.Now())
+		c.Conn.Close()
+	}
 }
 
 // WsManager WebSocket管理器
@@ -22,91 +138,112 @@ type WsManager struct {
 	unregister       chan *websocket.Conn
 	hotSearchService *service.HotSearchService
 	mutex            sync.RWMutex
+
+	// 关闭管理器时使用
+	done        chan struct{}
+	closeOnce   sync.Once
+	active      sync.WaitGroup // 正在处理的连接
+	unsubscribe func()         // 取消订阅抓取事件
 }
 
 // Message WebSocket消息结构
 type Message struct {
-	Type   string      `json:"type"`
-	Data   interface{} `json:"data"`
-	Error  string      `json:"error,omitempty"`
-	Source string      `json:"source,omitempty"`
+	Type    string      `json:"type"`
+	Data    interface{} `json:"data"`
+	Error   string      `json:"error,omitempty"`
+	Source  string      `json:"source,omitempty"`
+	Sources []string    `json:"sources,omitempty"` // subscribe/unsubscribe 时可以一次指定多个数据源、通配符或分类
 }
 
+// broadcastBufferSize 推送消息队列的长度，一次定时抓取会为每个数据源各产生一条推送
+const broadcastBufferSize = 256
+
 // NewWsManager 创建新的WebSocket管理器
 func NewWsManager(hotSearchService *service.HotSearchService) *WsManager {
 	return &WsManager{
 		clients:          make(map[*websocket.Conn]*Client),
-		broadcast:        make(chan Message),
+		broadcast:        make(chan Message, broadcastBufferSize),
 		register:         make(chan *Client),
 		unregister:       make(chan *websocket.Conn),
 		hotSearchService: hotSearchService,
+		done:             make(chan struct{}),
 	}
 }
 
 // Start 启动WebSocket管理器
 func (manager *WsManager) Start() {
+	// 每次抓取到新数据后推送给订阅了该数据源的客户端
+	manager.unsubscribe = manager.hotSearchService.Subscribe(manager.handleFetchEvent)
+
 	go func() {
 		for {
 			select {
+			case <-manager.done:
+				return
+
 			case client := <-manager.register:
 				manager.mutex.Lock()
 				manager.clients[client.Conn] = client
 				manager.mutex.Unlock()
-				log.Info("客户端连接: ", client.Conn.RemoteAddr())
+				client.logger.Info("客户端连接")
 
-			case client := <-manager.unregister:
+			case conn := <-manager.unregister:
+				// 连接由处理函数返回后关闭并回收，这里不能再访问连接本身
 				manager.mutex.Lock()
-				if _, ok := manager.clients[client]; ok {
-					delete(manager.clients, client)
-					client.Close()
-				}
+				delete(manager.clients, conn)
 				manager.mutex.Unlock()
-				log.Info("客户端断开连接: ", client.RemoteAddr())
 
 			case message := <-manager.broadcast:
-				manager.mutex.RLock()
-				for conn := range manager.clients {
-					if err := conn.WriteJSON(message); err != nil {
-						log.Error("发送消息失败: ", err)
-						delete(manager.clients, conn)
-						conn.Close()
-					}
-				}
-				manager.mutex.RUnlock()
+				manager.push(message)
 			}
 		}
 	}()
 }
 
+// push 将消息放入订阅了该数据源的客户端各自的队列
+//
+// 不会等待写入，由每个客户端的 writePump 写入连接，慢客户端只会断开自己的连接
+func (manager *WsManager) push(message Message) {
+	manager.mutex.RLock()
+	defer manager.mutex.RUnlock()
+	for _, client := range manager.clients {
+		if client.isSubscribed(message.Source) {
+			client.enqueue(message)
+		}
+	}
+}
+
 // HandleWebSocket 处理WebSocket连接
 func (manager *WsManager) HandleWebSocket(c *websocket.Conn) {
-	client := &Client{
-		Conn: c,
-		Type: "default", // 默认类型，后续可通过消息设置
-	}
+	// 默认不订阅任何数据源，后续可通过消息订阅
+	client := newClient(c)
 
-	manager.register <- client
+	manager.registerClient(client)
+	defer manager.unregisterClient(client)
 
-	defer func() {
-		manager.unregister <- c
-	}()
+	manager.serveClient(client)
+}
 
+// serveClient 读取并处理客户端消息，直到连接断开
+func (manager *WsManager) serveClient(client *Client) {
 	for {
 		var msg Message
-		if err := c.ReadJSON(&msg); err != nil {
-			log.Error("读取消息失败: ", err)
+		if err := client.Conn.ReadJSON(&msg); err != nil {
+			client.logger.Error("读取消息失败", logging.KeyError, err)
 			break
 		}
 
 		// 根据消息类型处理请求
 		switch msg.Type {
 		case "subscribe":
-			// 订阅特定类型的实时数据
-			client.Type = msg.Source
-			manager.handleSubscribe(client, msg.Source)
+			// 订阅一个或多个数据源的实时数据
+			manager.handleSubscribe(client, msg)
 		case "unsubscribe":
-			// 取消订阅
-			client.Type = "default"
+			// 取消订阅指定数据源，未指定时取消全部订阅
+			manager.handleUnsubscribe(client, msg)
+		case "subscriptions":
+			// 查询当前订阅的数据源
+			manager.sendSubscriptions(client, nil)
 		case "request":
 			// 请求一次性数据
 			manager.handleRequest(client, msg.Source)
@@ -116,29 +253,177 @@ func (manager *WsManager) HandleWebSocket(c *websocket.Conn) {
 				Type: "pong",
 				Data: "pong",
 			}
-			if err := c.WriteJSON(response); err != nil {
-				log.Error("发送pong失败: ", err)
-				break
+			if err := client.WriteJSON(response); err != nil {
+				client.logger.Error("发送pong失败", logging.KeyError, err)
+				return
 			}
 		}
 	}
 }
 
+// registerClient 注册客户端，之后会收到订阅的数据源的推送；管理器已关闭时直接发送关闭帧
+func (manager *WsManager) registerClient(client *Client) {
+	manager.active.Add(1)
+	metrics.WebSocketConnections.Inc()
+	select {
+	case manager.register <- client:
+		client.writer.Add(1)
+		go func() {
+			defer client.writer.Done()
+			client.writePump()
+		}()
+	case <-manager.done:
+		client.close(websocket.CloseGoingAway, closeReason)
+	}
+}
+
+// unregisterClient 注销客户端并清除订阅，需要在连接处理函数返回前调用
+func (manager *WsManager) unregisterClient(client *Client) {
+	defer manager.active.Done()
+	client.logger.Info("客户端断开连接")
+	client.stop()
+	client.writer.Wait()
+	client.unsubscribe(nil)
+	metrics.WebSocketConnections.Dec()
+	select {
+	case manager.unregister <- client.Conn:
+	case <-manager.done:
+	}
+}
+
+// closeReason 关闭服务时关闭帧中的原因
+const closeReason = "服务器关闭"
+
+// Shutdown 关闭管理器，停止推送并向所有客户端发送关闭帧，等待连接处理函数退出
+//
+// ctx 到期后强制关闭剩余的连接并返回 ctx 的错误
+func (manager *WsManager) Shutdown(ctx context.Context) error {
+	manager.closeOnce.Do(func() {
+		close(manager.done)
+		if manager.unsubscribe != nil {
+			manager.unsubscribe()
+		}
+	})
+
+	manager.mutex.RLock()
+	clients := make([]*Client, 0, len(manager.clients))
+	for _, client := range manager.clients {
+		clients = append(clients, client)
+	}
+	manager.mutex.RUnlock()
+	for _, client := range clients {
+		if err := client.close(websocket.CloseGoingAway, closeReason); err != nil {
+			client.logger.Warn("发送关闭帧失败", logging.KeyError, err)
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		manager.active.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		// 客户端没有回复关闭帧，直接关闭连接
+		for _, client := range clients {
+			client.drop()
+		}
+		return ctx.Err()
+	}
+}
+
 // handleSubscribe 处理订阅请求
-func (manager *WsManager) handleSubscribe(client *Client, source string) {
-	// 立即发送当前数据
-	manager.handleRequest(client, source)
+func (manager *WsManager) handleSubscribe(client *Client, msg Message) {
+	sources, unknown := resolveSources(subscriptionNames(msg))
+	added := client.subscribe(sources)
+	manager.sendSubscriptions(client, unknown)
+
+	// 立即发送当前数据。只订阅单个数据源时与之前一样实时获取，
+	// 一次订阅多个数据源时从数据库读取，避免同时请求大量接口
+	if len(msg.Sources) == 0 && msg.Source != "" {
+		manager.handleRequest(client, msg.Source)
+		return
+	}
+	for _, source := range added {
+		manager.sendLatest(client, source)
+	}
+}
+
+// handleUnsubscribe 处理取消订阅请求
+func (manager *WsManager) handleUnsubscribe(client *Client, msg Message) {
+	names := subscriptionNames(msg)
+	if len(names) == 0 {
+		client.unsubscribe(nil)
+		manager.sendSubscriptions(client, nil)
+		return
+	}
+
+	sources, unknown := resolveSources(names)
+	if len(sources) > 0 {
+		client.unsubscribe(sources)
+	}
+	manager.sendSubscriptions(client, unknown)
+}
+
+// subscriptionNames 获取订阅消息中指定的数据源名称
+func subscriptionNames(msg Message) []string {
+	names := msg.Sources
+	if msg.Source != "" {
+		names = append([]string{msg.Source}, names...)
+	}
+	return names
+}
+
+// sendSubscriptions 发送客户端当前的订阅集合
+func (manager *WsManager) sendSubscriptions(client *Client, unknown []string) {
+	response := Message{
+		Type: "subscriptions",
+		Data: SubscriptionsData{
+			Sources: client.Subscriptions(),
+			Unknown: unknown,
+		},
+	}
+	if len(unknown) > 0 {
+		response.Error = "未知的数据源: " + strings.Join(unknown, ", ")
+	}
+
+	if err := client.WriteJSON(response); err != nil {
+		client.logger.Error("发送订阅信息失败", logging.KeyError, err)
+	}
+}
+
+// sendLatest 发送数据源最新保存的数据
+func (manager *WsManager) sendLatest(client *Client, source string) {
+	response := Message{
+		Type:   "response",
+		Source: source,
+	}
+
+	result, err := manager.hotSearchService.GetFromDBOrFetch(client.context(), source)
+	if err != nil {
+		response.Error = err.Error()
+	} else {
+		response.Data = result.Response()
+	}
+
+	if err := client.WriteJSON(response); err != nil {
+		client.logger.Error("发送响应失败", logging.KeySource, source, logging.KeyError, err)
+	}
 }
 
 // handleRequest 处理数据请求
 func (manager *WsManager) handleRequest(client *Client, source string) {
-	var data map[string]interface{}
+	var data interface{}
 	var err error
 
 	// 检查特殊处理的源
 	switch source {
 	case "all":
-		data, err = manager.hotSearchService.GetAllFromDBOrFetch()
+		var results map[string]*app.Result
+		results, err = manager.hotSearchService.GetAllFromDBOrFetch(client.context())
+		data = all.NewResponse(results)
 	case "list":
 		routeNames := manager.hotSearchService.GetRouteNames()
 		data = map[string]interface{}{
@@ -147,55 +432,16 @@ func (manager *WsManager) handleRequest(client *Client, source string) {
 			"obj":     routeNames,
 		}
 	default:
-		// 使用映射来处理常规API调用，减少嵌套条件
-		apiSourceMap := map[string]string{
-			"360search":    "360search",
-			"bilibili":     "bilibili",
-			"acfun":        "acfun",
-			"csdn":         "csdn",
-			"dongqiudi":    "dongqiudi",
-			"douban":       "douban",
-			"douyin":       "douyin",
-			"github":       "github",
-			"guojiadili":   "guojiadili",
-			"historytoday": "historytoday",
-			"hupu":         "hupu",
-			"ithome":       "ithome",
-			"lishipin":     "lishipin",
-			"pengpai":      "pengpai",
-			"qqnews":       "qqnews",
-			"shaoshupai":   "shaoshupai",
-			"sougou":       "sougou",
-			"souhu":        "souhu",
-			"toutiao":      "toutiao",
-			"v2ex":         "v2ex",
-			"wangyinews":   "wangyinews",
-			"weibo":        "weibo",
-			"xinjingbao":   "xinjingbao",
-			"zhihu":        "zhihu",
-			"quark":        "quark",
-			"kuake":        "quark", // kuake映射到quark
-			"baidu":        "baidu",
-			"renmin":       "renmin",
-			"nanfang":      "nanfang",
-			"360doc":       "360doc",
-			"cctv":         "cctv",
-		}
-
-		if apiSource, exists := apiSourceMap[source]; exists {
-			// 对于"kuake"，我们映射到"quark"，所以需要特殊处理
-			if source == "kuake" {
-				data, err = manager.hotSearchService.FetchDataFromAPI("quark")
-			} else {
-				data, err = manager.hotSearchService.FetchDataFromAPI(apiSource)
+		// 在数据源注册表中查找（支持别名，如 kuake -> quark）。与 HTTP 接口共用缓存，
+		// 结果只发送给请求的客户端，订阅者只会收到定时抓取保存新快照后的推送
+		if src, exists := app.LookupSource(source); exists {
+			var result *app.Result
+			if result, _, err = manager.hotSearchService.GetLiveCached(client.context(), src.RouteName); err == nil {
+				data = result.Response()
 			}
 		} else {
 			// 默认返回空结果
-			data = map[string]interface{}{
-				"code":    200,
-				"message": source,
-				"obj":     []map[string]interface{}{},
-			}
+			data = app.NewResult(source, nil).Response()
 		}
 	}
 
@@ -209,9 +455,43 @@ func (manager *WsManager) handleRequest(client *Client, source string) {
 		response.Error = err.Error()
 	}
 
-	if client.Conn != nil {
-		if err := client.Conn.WriteJSON(response); err != nil {
-			log.Error("发送响应失败: ", err)
-		}
+	if err := client.WriteJSON(response); err != nil {
+		client.logger.Error("发送响应失败", logging.KeySource, source, logging.KeyError, err)
+	}
+}
+
+// handleFetchEvent 将抓取事件放入推送队列，完整榜单以 update 推送，与上一次快照相比有变化时再推送一条 diff
+//
+// 只推送保存了新快照的抓取，没有保存的按需抓取结果已经返回给请求方
+func (manager *WsManager) handleFetchEvent(event service.FetchEvent) {
+	if !event.Saved {
+		return
+	}
+	manager.enqueue(Message{
+		Type:   "update",
+		Source: event.Source,
+		Data:   event.Result.Response(),
+	})
+
+	if event.Diff != nil && !event.Diff.Empty() {
+		manager.enqueue(Message{
+			Type:   "diff",
+			Source: event.Source,
+			Data:   event.Diff,
+		})
+	}
+}
+
+// enqueue 将消息放入推送队列，队列已满时丢弃，避免阻塞抓取流程
+func (manager *WsManager) enqueue(message Message) {
+	select {
+	case <-manager.done:
+		return
+	default:
+	}
+	select {
+	case manager.broadcast <- message:
+	default:
+		slog.Warn("推送队列已满，丢弃消息", logging.KeySource, message.Source, "type", message.Type)
 	}
 }
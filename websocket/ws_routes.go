@@ -1,21 +1,19 @@
 package websocket
 
 import (
+	app_pkg "api/app"
 	"api/config"
 	"api/service"
 
 	websocket "github.com/gofiber/contrib/websocket"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
-	"github.com/gofiber/fiber/v2/middleware/logger"
 )
 
-// SetupWebSocketRoutes 配置WebSocket路由
-func SetupWebSocketRoutes(app *fiber.App, hotSearchService *service.HotSearchService, cfg *config.Config) {
-	// 使用CORS中间件
+// SetupWebSocketRoutes 配置WebSocket路由，返回的管理器用于在关闭服务时断开所有连接
+func SetupWebSocketRoutes(app *fiber.App, hotSearchService *service.HotSearchService, cfg *config.Config) *WsManager {
+	// 使用CORS中间件，请求日志由路由中统一的日志中间件记录
 	app.Use(cors.New())
-	// 使用日志中间件
-	app.Use(logger.New())
 
 	// 创建WebSocket管理器
 	wsManager := NewWsManager(hotSearchService)
@@ -35,18 +33,19 @@ func SetupWebSocketRoutes(app *fiber.App, hotSearchService *service.HotSearchSer
 
 	// 为每个API端点创建对应的WebSocket订阅端点
 	setupWebSocketAPIRoutes(app, wsManager)
+
+	return wsManager
 }
 
 // setupWebSocketAPIRoutes 设置WebSocket API路由
 func setupWebSocketAPIRoutes(app *fiber.App, wsManager *WsManager) {
-	// 为每个API端点创建WebSocket路由
-	apiEndpoints := []string{
-		"/baidu", "/bilibili", "/list", "/360search", "/acfun", "/csdn",
-		"/dongqiudi", "/douban", "/douyin", "/github", "/guojiadili",
-		"/historytoday", "/hupu", "/ithome", "/lishipin", "/pengpai",
-		"/qqnews", "/sougou", "/souhu", "/toutiao", "/v2ex", "/wangyinews",
-		"/weibo", "/xinjingbao", "/zhihu", "/renmin", "/nanfang", "/360doc",
-		"/cctv", "/quark", "/all",
+	// 为每个API端点创建WebSocket路由，数据源端点由注册表生成（包括别名）
+	apiEndpoints := []string{"/list", "/all"}
+	for _, source := range app_pkg.Sources() {
+		apiEndpoints = append(apiEndpoints, "/"+source.RouteName)
+		for _, alias := range source.Aliases {
+			apiEndpoints = append(apiEndpoints, "/"+alias)
+		}
 	}
 
 	for _, endpoint := range apiEndpoints {
@@ -59,39 +58,19 @@ func setupWebSocketAPIRoutes(app *fiber.App, wsManager *WsManager) {
 			return func(c *fiber.Ctx) error {
 				if websocket.IsWebSocketUpgrade(c) {
 					c.Locals("allowed", true)
-					// 创建一个包装函数来处理特定源的WebSocket连接
+					// 创建一个包装函数来处理特定源的WebSocket连接，连接时即订阅该数据源（all 订阅全部）
 					return websocket.New(func(conn *websocket.Conn) {
-						client := &Client{
-							Conn: conn,
-							Type: source,
-						}
+						sources, _ := resolveSources([]string{source})
+						client := newClient(conn, sources...)
 
-						wsManager.register <- client
-
-						defer func() {
-							wsManager.unregister <- conn
-						}()
+						wsManager.registerClient(client)
+						defer wsManager.unregisterClient(client)
 
 						// 立即发送当前数据
 						wsManager.handleRequest(client, source)
 
-						// 等待客户端断开连接
-						for {
-							var msg Message
-							if err := conn.ReadJSON(&msg); err != nil {
-								break
-							}
-							// 只处理心跳消息，其他消息不做处理
-							if msg.Type == "ping" {
-								response := Message{
-									Type: "pong",
-									Data: "pong",
-								}
-								if err := conn.WriteJSON(response); err != nil {
-									break
-								}
-							}
-						}
+						// 之后与通用端点一样处理订阅、请求和心跳消息，直到客户端断开连接
+						wsManager.serveClient(client)
 					})(c)
 				}
 				return fiber.ErrUpgradeRequired
@@ -123,16 +102,11 @@ func setupWebSocketHistoryRoutes(app *fiber.App, wsManager *WsManager) {
 
 					// 创建一个包装函数来处理历史数据的WebSocket连接
 					return websocket.New(func(conn *websocket.Conn) {
-						client := &Client{
-							Conn: conn,
-							Type: "history",
-						}
+						client := newClient(conn)
 
-						wsManager.register <- client
+						wsManager.registerClient(client)
 
-						defer func() {
-							wsManager.unregister <- conn
-						}()
+						defer wsManager.unregisterClient(client)
 
 						// 构建历史数据请求参数
 						date := c.Params("date")
@@ -178,7 +152,7 @@ func setupWebSocketHistoryRoutes(app *fiber.App, wsManager *WsManager) {
 							response.Error = err.Error()
 						}
 
-						if err := conn.WriteJSON(response); err != nil {
+						if err := client.WriteJSON(response); err != nil {
 							return
 						}
 
@@ -194,7 +168,7 @@ func setupWebSocketHistoryRoutes(app *fiber.App, wsManager *WsManager) {
 									Type: "pong",
 									Data: "pong",
 								}
-								if err := conn.WriteJSON(response); err != nil {
+								if err := client.WriteJSON(response); err != nil {
 									break
 								}
 							}
@@ -1,11 +1,17 @@
 package websocket
 
 import (
+	app_pkg "api/app"
 	"api/config"
+	"api/db"
 	"api/service"
+	"context"
 	"encoding/json"
+	"net"
 	"testing"
+	"time"
 
+	fasthttpws "github.com/fasthttp/websocket"
 	"github.com/gofiber/contrib/websocket"
 	"github.com/gofiber/fiber/v2"
 	"github.com/stretchr/testify/assert"
@@ -49,12 +55,11 @@ func TestMessageStructure(t *testing.T) {
 func TestClientStructure(t *testing.T) {
 	// 创建一个模拟的WebSocket连接（仅用于测试结构）
 	var conn *websocket.Conn
-	client := &Client{
-		Conn: conn,
-		Type: "baidu",
-	}
+	client := newClient(conn, "baidu")
 
-	assert.Equal(t, "baidu", client.Type)
+	assert.Equal(t, []string{"baidu"}, client.Subscriptions())
+	assert.True(t, client.isSubscribed("baidu"))
+	assert.False(t, client.isSubscribed("zhihu"))
 }
 
 // TestStartManager 测试Start方法
@@ -99,15 +104,13 @@ func TestHandleSubscribe(t *testing.T) {
 
 	// 创建一个模拟的客户端连接
 	var conn *websocket.Conn
-	client := &Client{
-		Conn: conn,
-		Type: "baidu",
-	}
+	client := newClient(conn)
 
 	// 调用handleSubscribe，不期望出现panic
 	assert.NotPanics(t, func() {
-		manager.handleSubscribe(client, "baidu")
+		manager.handleSubscribe(client, Message{Type: "subscribe", Source: "baidu"})
 	})
+	assert.Equal(t, []string{"baidu"}, client.Subscriptions())
 }
 
 // TestHandleRequest 测试handleRequest方法
@@ -174,3 +177,343 @@ func TestSetupWebSocketHistoryRoutes(t *testing.T) {
 	// 验证应用不为nil
 	assert.NotNil(t, app)
 }
+
+// 注册测试用的数据源，避免访问真实的外部接口
+func init() {
+	for _, name := range []string{"ws_test_a", "ws_test_b"} {
+		app_pkg.Register(app_pkg.Source{
+			RouteName: name,
+			Name:      name,
+			Category:  app_pkg.CategoryNews,
+			Fetch: func(ctx context.Context, f *app_pkg.Fetcher) (*app_pkg.Result, error) {
+				return app_pkg.NewResult(name, []app_pkg.Item{{Index: 1, Title: name, URL: "http://example.com"}}), nil
+			},
+		})
+	}
+}
+
+// startTestServer 在随机端口上启动带WebSocket路由的服务，返回ws地址和管理器
+func startTestServer(t *testing.T, hotSearchService *service.HotSearchService) (string, *WsManager) {
+	db.InitSQLiteWithDSN("file:" + t.Name() + "?mode=memory&cache=shared")
+
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	manager := SetupWebSocketRoutes(app, hotSearchService, &config.Config{})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	go app.Listener(ln)
+	t.Cleanup(func() { app.Shutdown() })
+
+	return "ws://" + ln.Addr().String(), manager
+}
+
+// fetchEvent 创建定时抓取保存新快照后发布的事件
+func fetchEvent(source string, diff *service.Diff) service.FetchEvent {
+	result := app_pkg.NewResult(source, []app_pkg.Item{{Index: 1, Title: source, URL: "http://example.com"}})
+	return service.FetchEvent{Source: source, Result: result, Scheduled: true, Saved: true, Diff: diff}
+}
+
+// readMessage 读取一条消息，超时返回错误
+func readMessage(conn *fasthttpws.Conn, timeout time.Duration) (Message, error) {
+	var msg Message
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	err := conn.ReadJSON(&msg)
+	return msg, err
+}
+
+// readTypes 读取指定数量的消息，返回消息类型
+func readTypes(t *testing.T, conn *fasthttpws.Conn, n int) []string {
+	var types []string
+	for i := 0; i < n; i++ {
+		msg, err := readMessage(conn, 5*time.Second)
+		assert.NoError(t, err)
+		types = append(types, msg.Type)
+	}
+	return types
+}
+
+// TestPushUpdatesToSubscribers 测试抓取到新数据后只推送给订阅了该数据源的客户端
+func TestPushUpdatesToSubscribers(t *testing.T) {
+	hotSearchService := &service.HotSearchService{}
+	url, manager := startTestServer(t, hotSearchService)
+
+	subscriberA, _, err := fasthttpws.DefaultDialer.Dial(url+"/ws", nil)
+	assert.NoError(t, err)
+	defer subscriberA.Close()
+	subscriberB, _, err := fasthttpws.DefaultDialer.Dial(url+"/ws/ws_test_b", nil)
+	assert.NoError(t, err)
+	defer subscriberB.Close()
+
+	// 订阅或连接后只有自己立即收到一次当前数据，不会推送给其他订阅者
+	assert.NoError(t, subscriberA.WriteJSON(Message{Type: "subscribe", Source: "ws_test_a"}))
+	assert.Equal(t, []string{"subscriptions", "response"}, readTypes(t, subscriberA, 2))
+	assert.Equal(t, []string{"response"}, readTypes(t, subscriberB, 1))
+
+	// 保存 ws_test_a 的新快照后订阅者收到推送，无需轮询
+	manager.handleFetchEvent(fetchEvent("ws_test_a", nil))
+
+	msg, err := readMessage(subscriberA, 5*time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, "update", msg.Type)
+	assert.Equal(t, "ws_test_a", msg.Source)
+	data, ok := msg.Data.(map[string]interface{})
+	assert.True(t, ok)
+	assert.Equal(t, "ws_test_a", data["message"])
+
+	// 按需抓取的事件不推送
+	event := fetchEvent("ws_test_a", nil)
+	event.Scheduled, event.Saved = false, false
+	manager.handleFetchEvent(event)
+	manager.handleFetchEvent(fetchEvent("ws_test_a", nil))
+	msg, err = readMessage(subscriberA, 5*time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, "update", msg.Type)
+	_, err = readMessage(subscriberA, 200*time.Millisecond)
+	assert.Error(t, err)
+
+	// 未订阅 ws_test_a 的客户端不会收到推送
+	_, err = readMessage(subscriberB, 200*time.Millisecond)
+	assert.Error(t, err)
+}
+
+// registeredClients 等待管理器注册指定数量的客户端后返回它们
+func registeredClients(t *testing.T, manager *WsManager, n int) []*Client {
+	var clients []*Client
+	assert.Eventually(t, func() bool {
+		manager.mutex.RLock()
+		defer manager.mutex.RUnlock()
+		clients = clients[:0]
+		for _, client := range manager.clients {
+			clients = append(clients, client)
+		}
+		return len(clients) == n
+	}, 5*time.Second, 10*time.Millisecond)
+	return clients
+}
+
+// TestPushSlowClient 测试写入卡住的客户端不会阻塞其他订阅者，队列满后只断开它自己
+func TestPushSlowClient(t *testing.T) {
+	hotSearchService := &service.HotSearchService{}
+	url, manager := startTestServer(t, hotSearchService)
+
+	slow, _, err := fasthttpws.DefaultDialer.Dial(url+"/ws/ws_test_a", nil)
+	assert.NoError(t, err)
+	defer slow.Close()
+	assert.Equal(t, []string{"response"}, readTypes(t, slow, 1))
+	slowClient := registeredClients(t, manager, 1)[0]
+
+	fast, _, err := fasthttpws.DefaultDialer.Dial(url+"/ws/ws_test_a", nil)
+	assert.NoError(t, err)
+	defer fast.Close()
+	assert.Equal(t, []string{"response"}, readTypes(t, fast, 1))
+	registeredClients(t, manager, 2)
+
+	// 模拟慢客户端的写入一直没有完成
+	slowClient.mu.Lock()
+	for i := 0; i < clientSendBufferSize+2; i++ {
+		manager.push(Message{Type: "update", Source: "ws_test_a"})
+		msg, err := readMessage(fast, 5*time.Second)
+		if !assert.NoError(t, err) {
+			break
+		}
+		assert.Equal(t, "update", msg.Type)
+	}
+	select {
+	case <-slowClient.done:
+	case <-time.After(5 * time.Second):
+		t.Error("队列已满的客户端没有被断开")
+	}
+	slowClient.mu.Unlock()
+
+	// 慢客户端的连接被关闭，之后注销
+	registeredClients(t, manager, 1)
+}
+
+// TestResolveSources 测试订阅名称的解析
+func TestResolveSources(t *testing.T) {
+	// 路由名称和别名
+	sources, unknown := resolveSources([]string{"weibo", "kuake", "weibo"})
+	assert.Equal(t, []string{"weibo", "quark"}, sources)
+	assert.Empty(t, unknown)
+
+	// 通配符
+	sources, _ = resolveSources([]string{"ws_test_*"})
+	assert.Equal(t, []string{"ws_test_a", "ws_test_b"}, sources)
+
+	// 全部数据源
+	sources, _ = resolveSources([]string{"*"})
+	assert.Equal(t, len(app_pkg.Sources()), len(sources))
+	sources, _ = resolveSources([]string{"all"})
+	assert.Equal(t, len(app_pkg.Sources()), len(sources))
+
+	// 分类
+	sources, _ = resolveSources([]string{"category:" + app_pkg.CategoryTech})
+	assert.Contains(t, sources, "github")
+	for _, source := range sources {
+		src, _ := app_pkg.LookupSource(source)
+		assert.Equal(t, app_pkg.CategoryTech, src.Category)
+	}
+
+	// 无法识别的名称
+	sources, unknown = resolveSources([]string{"not_exists", "category:not_exists", "nothing*"})
+	assert.Empty(t, sources)
+	assert.Equal(t, []string{"not_exists", "category:not_exists", "nothing*"}, unknown)
+}
+
+// readSubscriptions 读取消息直到收到订阅集合
+func readSubscriptions(t *testing.T, conn *fasthttpws.Conn) Message {
+	for {
+		msg, err := readMessage(conn, 5*time.Second)
+		if !assert.NoError(t, err) {
+			return msg
+		}
+		if msg.Type == "subscriptions" {
+			return msg
+		}
+	}
+}
+
+// subscribedSources 获取订阅集合消息中的数据源
+func subscribedSources(msg Message) []string {
+	var sources []string
+	data, _ := msg.Data.(map[string]interface{})
+	list, _ := data["sources"].([]interface{})
+	for _, source := range list {
+		sources = append(sources, source.(string))
+	}
+	return sources
+}
+
+// TestMultiSourceSubscriptions 测试一次订阅多个数据源、取消单个订阅和查询订阅集合
+func TestMultiSourceSubscriptions(t *testing.T) {
+	hotSearchService := &service.HotSearchService{}
+	url, manager := startTestServer(t, hotSearchService)
+
+	conn, _, err := fasthttpws.DefaultDialer.Dial(url+"/ws", nil)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	// 订阅列表中包含通配符和无法识别的名称
+	assert.NoError(t, conn.WriteJSON(Message{Type: "subscribe", Sources: []string{"ws_test_*", "not_exists"}}))
+	msg := readSubscriptions(t, conn)
+	assert.Equal(t, []string{"ws_test_a", "ws_test_b"}, subscribedSources(msg))
+	assert.Contains(t, msg.Error, "not_exists")
+
+	// 取消单个数据源的订阅
+	assert.NoError(t, conn.WriteJSON(Message{Type: "unsubscribe", Source: "ws_test_a"}))
+	msg = readSubscriptions(t, conn)
+	assert.Equal(t, []string{"ws_test_b"}, subscribedSources(msg))
+
+	// 查询当前订阅集合
+	assert.NoError(t, conn.WriteJSON(Message{Type: "subscriptions"}))
+	msg = readSubscriptions(t, conn)
+	assert.Equal(t, []string{"ws_test_b"}, subscribedSources(msg))
+
+	// 只收到仍在订阅的数据源的推送
+	manager.handleFetchEvent(fetchEvent("ws_test_a", nil))
+	manager.handleFetchEvent(fetchEvent("ws_test_b", nil))
+	for {
+		msg, err := readMessage(conn, 5*time.Second)
+		assert.NoError(t, err)
+		if msg.Type == "update" {
+			assert.Equal(t, "ws_test_b", msg.Source)
+			break
+		}
+	}
+
+	// 不指定数据源时取消全部订阅
+	assert.NoError(t, conn.WriteJSON(Message{Type: "unsubscribe"}))
+	msg = readSubscriptions(t, conn)
+	assert.Empty(t, subscribedSources(msg))
+}
+
+// TestPreSubscribedConnection 测试 /ws/{source} 连接默认订阅对应数据源，并且可以继续订阅其他数据源
+func TestPreSubscribedConnection(t *testing.T) {
+	hotSearchService := &service.HotSearchService{}
+	url, _ := startTestServer(t, hotSearchService)
+
+	conn, _, err := fasthttpws.DefaultDialer.Dial(url+"/ws/ws_test_a", nil)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	assert.NoError(t, conn.WriteJSON(Message{Type: "subscriptions"}))
+	msg := readSubscriptions(t, conn)
+	assert.Equal(t, []string{"ws_test_a"}, subscribedSources(msg))
+
+	assert.NoError(t, conn.WriteJSON(Message{Type: "subscribe", Sources: []string{"ws_test_b"}}))
+	msg = readSubscriptions(t, conn)
+	assert.Equal(t, []string{"ws_test_a", "ws_test_b"}, subscribedSources(msg))
+}
+
+// TestPushDiffToSubscribers 测试与上一次快照相比有变化时推送 diff 消息
+func TestPushDiffToSubscribers(t *testing.T) {
+	hotSearchService := &service.HotSearchService{}
+	url, manager := startTestServer(t, hotSearchService)
+
+	conn, _, err := fasthttpws.DefaultDialer.Dial(url+"/ws", nil)
+	assert.NoError(t, err)
+	defer conn.Close()
+	assert.NoError(t, conn.WriteJSON(Message{Type: "subscribe", Sources: []string{"ws_test_a"}}))
+	readSubscriptions(t, conn)
+
+	// 上一次快照中是另一条热搜
+	previous := app_pkg.NewResult("ws_test_a", []app_pkg.Item{{Index: 1, Title: "old", URL: "http://example.com/old"}})
+	event := fetchEvent("ws_test_a", nil)
+	event.Diff = service.NewDiff("ws_test_a", previous, event.Result)
+	manager.handleFetchEvent(event)
+
+	for {
+		msg, err := readMessage(conn, 5*time.Second)
+		if !assert.NoError(t, err) {
+			return
+		}
+		if msg.Type != "diff" {
+			continue
+		}
+		assert.Equal(t, "ws_test_a", msg.Source)
+		data := msg.Data.(map[string]interface{})
+		assert.Equal(t, 1, len(data["added"].([]interface{})))
+		assert.Equal(t, 1, len(data["removed"].([]interface{})))
+		break
+	}
+}
+
+// TestWsManagerShutdown 测试关闭管理器时向客户端发送关闭帧并等待连接处理函数退出
+func TestWsManagerShutdown(t *testing.T) {
+	db.InitSQLiteWithDSN("file:" + t.Name() + "?mode=memory&cache=shared")
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	manager := SetupWebSocketRoutes(app, &service.HotSearchService{}, &config.Config{})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	go app.Listener(ln)
+	defer app.Shutdown()
+	url := "ws://" + ln.Addr().String() + "/ws"
+
+	conn, _, err := fasthttpws.DefaultDialer.Dial(url, nil)
+	assert.NoError(t, err)
+	defer conn.Close()
+	// 收到 pong 说明连接已经注册
+	assert.NoError(t, conn.WriteJSON(Message{Type: "ping"}))
+	msg, err := readMessage(conn, 5*time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, "pong", msg.Type)
+
+	// 客户端读取时收到关闭帧并回复，之后管理器的 Shutdown 返回
+	closed := make(chan error, 1)
+	go func() {
+		_, err := readMessage(conn, 5*time.Second)
+		closed <- err
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.NoError(t, manager.Shutdown(ctx))
+	assert.True(t, fasthttpws.IsCloseError(<-closed, fasthttpws.CloseGoingAway))
+
+	// 关闭后建立的连接立即收到关闭帧
+	conn2, _, err := fasthttpws.DefaultDialer.Dial(url, nil)
+	assert.NoError(t, err)
+	defer conn2.Close()
+	_, err = readMessage(conn2, 5*time.Second)
+	assert.True(t, fasthttpws.IsCloseError(err, fasthttpws.CloseGoingAway))
+}